@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/charmbracelet/x/ansi"
+)
+
+// accessible is set by --accessible for screen readers and braille
+// terminals: no colors, symbols or progress animation, and a report read
+// line by line with labeled sections.
+var accessible bool
+
+// columnGap is the padding between the columns of a report line, which a
+// screen reader skips over without a pause; numbers are also padded inside
+// parentheses.
+var (
+	columnGap  = regexp.MustCompile(` {2,}`)
+	paddedOpen = regexp.MustCompile(`\( +`)
+)
+
+// severityLabel puts label in front of a value rendered in a warning style,
+// leaving its padding around both, which displayAccessible turns into a
+// column separator.
+func severityLabel(label string) func(string) string {
+	return func(s string) string {
+		value := strings.TrimSpace(s)
+		if value == "" {
+			return s
+		}
+		start := strings.Index(s, value)
+		return s[:start] + label + " " + value + s[start+len(value):]
+	}
+}
+
+// scanAccessible runs the scan without the TUI. Progress is announced in
+// steps of 10 percent, as lines a screen reader reads once.
+func scanAccessible(config Config) (*Stats, error) {
+	fmt.Printf(tr("Scanning %s\n"), displayPath(config.Path))
+
+	events := newEventBus()
+	updates := events.Subscribe()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		announced := 0
+		for event := range updates {
+			if event.Kind != eventProgress || event.Total == 0 {
+				continue
+			}
+			if step := event.Processed * 10 / event.Total * 10; step > announced && step < 100 {
+				announced = step
+				fmt.Printf(tr("%d percent scanned, %s of %s files\n"), step,
+					formatCount(event.Processed), formatCount(event.Total))
+			}
+		}
+	}()
+
+	var stats *Stats
+	var err error
+	if config.FilesFrom != "" {
+		stats, err = analyzeFileList(context.Background(), config, events)
+	} else {
+		stats, err = analyzeDirectory(context.Background(), config, events)
+	}
+	events.Close()
+	<-done
+	if err == nil {
+		fmt.Print(tr("Scan complete\n\n"))
+	}
+	return stats, err
+}
+
+// displayAccessible renders the report sections one after another as plain
+// lines: each section starts with its name and number and ends with its
+// name, and the columns of a line are separated by commas.
+func displayAccessible(stats *Stats, maxCount int) string {
+	var sections []string
+	for _, section := range reportSections {
+		if hiddenSections[section.Name] {
+			continue
+		}
+		var b strings.Builder
+		section.Display(stats, maxCount, &b)
+		if text := strings.TrimSpace(ansi.Strip(fitPaths(b.String(), 0))); text != "" {
+			sections = append(sections, text)
+		}
+	}
+
+	var result strings.Builder
+	writeTitle(tr("MADAA - Mass Data Analysis Results"), &result)
+	for i, text := range sections {
+		lines := strings.Split(text, "\n")
+		name := strings.TrimSpace(lines[0])
+		result.WriteString(fmt.Sprintf(tr("Section %d of %d: %s\n"), i+1, len(sections), name))
+		for _, line := range lines[1:] {
+			line = paddedOpen.ReplaceAllString(strings.TrimSpace(line), "(")
+			if line = columnGap.ReplaceAllString(line, ", "); line != "" {
+				result.WriteString(line + "\n")
+			}
+		}
+		result.WriteString(fmt.Sprintf(tr("End of %s\n\n"), name))
+	}
+	result.WriteString(tr("End of report\n"))
+	return ansi.Strip(fitPaths(result.String(), 0))
+}
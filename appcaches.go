@@ -0,0 +1,191 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Kinds of application data: caches can be cleared, at most costing a slower
+// start; profiles hold settings, history and logins.
+const (
+	appCache   = "cache"
+	appProfile = "profile"
+)
+
+// appLocations are where applications keep their caches and profiles on
+// Linux, macOS and Windows, matched against the end of a directory's path
+// case-insensitively. {app} matches any directory name and names the app;
+// the generic locations come last, so a known app is named by the more
+// specific one.
+var appLocations = []struct {
+	app     string
+	pattern string
+	kind    string
+}{
+	{"Chrome", ".cache/google-chrome", appCache},
+	{"Chrome", ".config/google-chrome", appProfile},
+	{"Chrome", "Library/Caches/Google/Chrome", appCache},
+	{"Chrome", "Library/Application Support/Google/Chrome", appProfile},
+	{"Chrome", "AppData/Local/Google/Chrome/User Data", appProfile},
+	{"Chromium", ".cache/chromium", appCache},
+	{"Chromium", ".config/chromium", appProfile},
+	{"Edge", ".cache/microsoft-edge", appCache},
+	{"Edge", ".config/microsoft-edge", appProfile},
+	{"Edge", "Library/Application Support/Microsoft Edge", appProfile},
+	{"Edge", "AppData/Local/Microsoft/Edge/User Data", appProfile},
+	{"Firefox", ".cache/mozilla/firefox", appCache},
+	{"Firefox", ".mozilla/firefox", appProfile},
+	{"Firefox", "Library/Caches/Firefox", appCache},
+	{"Firefox", "Library/Application Support/Firefox", appProfile},
+	{"Firefox", "AppData/Local/Mozilla/Firefox", appCache},
+	{"Firefox", "AppData/Roaming/Mozilla/Firefox", appProfile},
+	{"Safari", "Library/Caches/com.apple.Safari", appCache},
+	{"Safari", "Library/Safari", appProfile},
+	{"Spotify", ".cache/spotify", appCache},
+	{"Spotify", "Library/Caches/com.spotify.client", appCache},
+	{"Spotify", "Library/Application Support/Spotify/PersistentCache", appCache},
+	{"Spotify", "AppData/Local/Spotify", appCache},
+	{"Steam shader cache", "steamapps/shadercache", appCache},
+	{"{app}", ".config/{app}/Cache", appCache},
+	{"{app}", ".config/{app}/Code Cache", appCache},
+	{"{app}", ".config/{app}/GPUCache", appCache},
+	{"{app}", "Library/Application Support/{app}/Cache", appCache},
+	{"{app}", "Library/Application Support/{app}/Code Cache", appCache},
+	{"{app}", "Library/Application Support/{app}/GPUCache", appCache},
+	{"{app}", "AppData/Roaming/{app}/Cache", appCache},
+	{"{app}", "AppData/Roaming/{app}/Code Cache", appCache},
+	{"{app}", "AppData/Roaming/{app}/GPUCache", appCache},
+	{"{app}", ".cache/{app}", appCache},
+	{"{app}", "Library/Caches/{app}", appCache},
+}
+
+// matchAppLocation returns the app and kind of data the directory holds, if
+// it is one of appLocations.
+func matchAppLocation(dir string) (app, kind string, ok bool) {
+	components := strings.Split(filepath.ToSlash(dir), "/")
+	for _, loc := range appLocations {
+		parts := strings.Split(loc.pattern, "/")
+		if len(parts) > len(components) {
+			continue
+		}
+		tail := components[len(components)-len(parts):]
+		name := loc.app
+		matched := true
+		for i, part := range parts {
+			if part == "{app}" {
+				name = strings.ReplaceAll(name, "{app}", tail[i])
+			} else if !strings.EqualFold(part, tail[i]) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return name, loc.kind, true
+		}
+	}
+	return "", "", false
+}
+
+// appUsage is the data of one application in the scanned tree. Path is its
+// largest location.
+type appUsage struct {
+	App          string
+	CacheBytes   int64
+	ProfileBytes int64
+	Path         string
+	pathBytes    int64
+}
+
+func (a appUsage) Total() int64 {
+	return a.CacheBytes + a.ProfileBytes
+}
+
+// appUsages sums the caches and profiles found below the scanned directory
+// per application, most bytes first. A location inside another one, like
+// .cache/mozilla/firefox in .cache/mozilla, counts for its own app and is
+// taken out of the outer one.
+func appUsages(stats *Stats) []appUsage {
+	trees := dirTrees(stats)
+	type location struct {
+		app, kind string
+		bytes     int64
+	}
+	found := make(map[string]*location)
+	for dir, tree := range trees {
+		if app, kind, ok := matchAppLocation(dir); ok {
+			found[dir] = &location{app, kind, tree.Size}
+		}
+	}
+	for dir := range found {
+		for p := filepath.Dir(dir); trees[p] != nil; p = filepath.Dir(p) {
+			if outer, ok := found[p]; ok {
+				outer.bytes -= trees[dir].Size
+				break
+			}
+		}
+	}
+
+	apps := make(map[string]*appUsage)
+	for dir, loc := range found {
+		if loc.bytes <= 0 {
+			continue
+		}
+		usage := apps[loc.app]
+		if usage == nil {
+			usage = &appUsage{App: loc.app}
+			apps[loc.app] = usage
+		}
+		if loc.kind == appCache {
+			usage.CacheBytes += loc.bytes
+		} else {
+			usage.ProfileBytes += loc.bytes
+		}
+		if loc.bytes > usage.pathBytes || loc.bytes == usage.pathBytes && dir < usage.Path {
+			usage.Path, usage.pathBytes = dir, loc.bytes
+		}
+	}
+
+	usages := make([]appUsage, 0, len(apps))
+	for _, usage := range apps {
+		usages = append(usages, *usage)
+	}
+	sort.Slice(usages, func(i, j int) bool {
+		if usages[i].Total() != usages[j].Total() {
+			return usages[i].Total() > usages[j].Total()
+		}
+		return usages[i].App < usages[j].App
+	})
+	return usages
+}
+
+// displayAppUsage lists the applications holding the most bytes in caches
+// and profiles, for the "what is eating my home directory" question.
+func displayAppUsage(stats *Stats, maxCount int, result *strings.Builder) {
+	usages := appUsages(stats)
+	if len(usages) == 0 {
+		return
+	}
+
+	var caches, profiles int64
+	for _, usage := range usages {
+		caches += usage.CacheBytes
+		profiles += usage.ProfileBytes
+	}
+	result.WriteString(headerStyle.Render(tr("Application Caches and Profiles")))
+	result.WriteString("\n")
+	result.WriteString(fmt.Sprintf(tr("%s applications: caches %s (safe to clear while the app is closed), profiles %s\n"),
+		numberStyle.Render(formatCount(len(usages))),
+		badStyle.Render(formatMB(caches)),
+		numberStyle.Render(formatMB(profiles))))
+	for _, usage := range usages[:min(maxCount, len(usages))] {
+		result.WriteString(fmt.Sprintf(tr("%s %-24s cache %s  profile %s  %s\n"),
+			numberStyle.Render(fmt.Sprintf("%11s", formatMB(usage.Total()))),
+			tr(usage.App),
+			numberStyle.Render(fmt.Sprintf("%10s", formatMB(usage.CacheBytes))),
+			numberStyle.Render(fmt.Sprintf("%10s", formatMB(usage.ProfileBytes))),
+			renderPath(usage.Path)))
+	}
+	result.WriteString("\n")
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	// maxArchiveDepth limits how deep nested archives are opened.
+	maxArchiveDepth = 8
+	// maxNestedZipSize is the largest zip inside another archive that is
+	// read into memory to look into it. Tars are streamed at any size.
+	maxNestedZipSize = 64 * 1024 * 1024
+)
+
+// ArchiveInfo describes one archive found during the scan. Uncompressed is
+// zero for formats madaa can't look into. Depth counts the levels of
+// archives nested inside it.
+type ArchiveInfo struct {
+	Path         string
+	Size         int64
+	Uncompressed int64
+	Nested       int
+	NestedBytes  int64
+	Depth        int
+}
+
+// archiveKind returns how an archive can be read: "zip", "tar" or "tgz", or
+// "other" for archives madaa only recognizes by name. Non-archives return "".
+func archiveKind(name string) string {
+	lower := strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		return "tgz"
+	case strings.HasSuffix(lower, ".tar"):
+		return "tar"
+	case strings.HasSuffix(lower, ".zip"), strings.HasSuffix(lower, ".jar"):
+		return "zip"
+	}
+	if fileTypeCategoryMap[filepath.Ext(lower)] == "archive" {
+		return "other"
+	}
+	return ""
+}
+
+// archiveBaseName strips the archive extension, so "photos.tar.gz" becomes
+// "photos".
+func archiveBaseName(name string) string {
+	lower := strings.ToLower(name)
+	if strings.HasSuffix(lower, ".tar.gz") {
+		return name[:len(name)-len(".tar.gz")]
+	}
+	return strings.TrimSuffix(name, filepath.Ext(name))
+}
+
+// processArchive looks into the file if it is an archive and records it. The
+// archive is read without holding the stats lock.
+func processArchive(path string, info os.FileInfo, stats *Stats) {
+	kind := archiveKind(path)
+	if kind == "" || !info.Mode().IsRegular() {
+		return
+	}
+
+	archive := ArchiveInfo{Path: path, Size: info.Size()}
+	if f, err := os.Open(path); err == nil {
+		switch kind {
+		case "zip":
+			inspectZip(f, info.Size(), 0, &archive)
+		case "tar":
+			inspectTar(f, 0, &archive)
+		case "tgz":
+			if zr, err := gzip.NewReader(f); err == nil {
+				inspectTar(zr, 0, &archive)
+			}
+		}
+		f.Close()
+	}
+
+	stats.mu.Lock()
+	defer stats.mu.Unlock()
+	stats.Archives = append(stats.Archives, archive)
+}
+
+func inspectZip(r io.ReaderAt, size int64, depth int, archive *ArchiveInfo) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return
+	}
+	for _, file := range zr.File {
+		if file.FileInfo().IsDir() {
+			continue
+		}
+		size := int64(file.UncompressedSize64)
+		if depth == 0 {
+			archive.Uncompressed += size
+		}
+		inspectEntry(file.Name, size, file.Open, depth, archive)
+	}
+}
+
+func inspectTar(r io.Reader, depth int, archive *ArchiveInfo) {
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err != nil {
+			return
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		if depth == 0 {
+			archive.Uncompressed += header.Size
+		}
+		inspectEntry(header.Name, header.Size, func() (io.ReadCloser, error) {
+			return io.NopCloser(tr), nil
+		}, depth, archive)
+	}
+}
+
+// inspectEntry counts an archive member that is itself an archive and looks
+// into it, so that archive.Depth reflects the deepest nesting.
+func inspectEntry(name string, size int64, open func() (io.ReadCloser, error), depth int, archive *ArchiveInfo) {
+	kind := archiveKind(name)
+	if kind == "" {
+		return
+	}
+	archive.Nested++
+	archive.NestedBytes += size
+	archive.Depth = max(archive.Depth, depth+1)
+
+	if depth+1 >= maxArchiveDepth || kind == "other" {
+		return
+	}
+	r, err := open()
+	if err != nil {
+		return
+	}
+	defer r.Close()
+
+	switch kind {
+	case "zip":
+		if size > maxNestedZipSize {
+			return
+		}
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return
+		}
+		inspectZip(bytes.NewReader(data), int64(len(data)), depth+1, archive)
+	case "tar":
+		inspectTar(r, depth+1, archive)
+	case "tgz":
+		if zr, err := gzip.NewReader(r); err == nil {
+			inspectTar(zr, depth+1, archive)
+		}
+	}
+}
+
+// extractedTwins returns the archives that sit next to a directory of the
+// same base name holding about as much data as the archive unpacks to, i.e.
+// archives that were extracted and kept.
+func extractedTwins(stats *Stats) []ArchiveInfo {
+	trees := dirTrees(stats)
+
+	var twins []ArchiveInfo
+	for _, archive := range stats.Archives {
+		if archive.Uncompressed == 0 {
+			continue
+		}
+		tree, ok := trees[archiveBaseName(archive.Path)]
+		if !ok {
+			continue
+		}
+		// Allow 20% difference for files added or removed after extracting
+		ratio := float64(tree.Size) / float64(archive.Uncompressed)
+		if ratio >= 0.8 && ratio <= 1.2 {
+			twins = append(twins, archive)
+		}
+	}
+	sort.Slice(twins, func(i, j int) bool {
+		if twins[i].Size != twins[j].Size {
+			return twins[i].Size > twins[j].Size
+		}
+		return twins[i].Path < twins[j].Path
+	})
+	return twins
+}
+
+func displayArchives(stats *Stats, maxCount int, result *strings.Builder) {
+	var nested []ArchiveInfo
+	for _, archive := range stats.Archives {
+		if archive.Nested > 0 {
+			nested = append(nested, archive)
+		}
+	}
+	sort.Slice(nested, func(i, j int) bool {
+		if nested[i].NestedBytes != nested[j].NestedBytes {
+			return nested[i].NestedBytes > nested[j].NestedBytes
+		}
+		return nested[i].Path < nested[j].Path
+	})
+
+	if len(nested) > 0 {
+		result.WriteString(headerStyle.Render(tr("Nested Archives")))
+		result.WriteString("\n")
+		for _, archive := range nested[:min(maxCount, len(nested))] {
+			result.WriteString(fmt.Sprintf(tr("%s in %s archives, %s levels deep: %s\n"),
+				numberStyle.Render(formatMB(archive.NestedBytes)),
+				numberStyle.Render(formatCount(archive.Nested)),
+				warnStyle.Render(formatCount(archive.Depth)),
+				renderPath(archive.Path)))
+		}
+		result.WriteString("\n")
+	}
+
+	twins := extractedTwins(stats)
+	if len(twins) > 0 {
+		var redundant int64
+		for _, archive := range twins {
+			redundant += archive.Size
+		}
+		result.WriteString(headerStyle.Render(tr("Archives Next to Their Extracted Copy")))
+		result.WriteString("\n")
+		result.WriteString(fmt.Sprintf(tr("Redundant: %s in %s archives\n"),
+			badStyle.Render(formatMB(redundant)),
+			numberStyle.Render(formatCount(len(twins)))))
+		for _, archive := range twins[:min(maxCount, len(twins))] {
+			result.WriteString(fmt.Sprintf("%s %s\n",
+				numberStyle.Render(formatMB(archive.Size)),
+				renderPath(archive.Path)))
+		}
+		result.WriteString("\n")
+	}
+}
@@ -0,0 +1,289 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"gopkg.in/ini.v1"
+)
+
+// The limits of madaa artifacts, set in the [artifacts] config section.
+// Generated files are only allowed below generatedDirs.
+var (
+	artifactMaxSize    int64 = 5 * 1024 * 1024
+	artifactBinarySize int64 = 1024 * 1024
+	generatedPatterns        = []string{"*.min.js", "*.min.css", "*.map", "*.pb.go", "*_pb2.py", "*_generated.*", "*.pyc", "*.class", "*.o", "*.obj", "*.a", "*.so", "*.dll", "*.exe"}
+	generatedDirs      []string
+)
+
+// Why a file is flagged, in the order they are checked. Each file is
+// reported with the first reason only.
+const (
+	artifactOversized = "over size budget"
+	artifactBinary    = "large binary"
+	artifactGenerated = "generated file"
+)
+
+var artifactKinds = []string{artifactOversized, artifactBinary, artifactGenerated}
+
+func loadArtifactsConfig(section *ini.Section) error {
+	for key, limit := range map[string]*int64{"max_size": &artifactMaxSize, "binary_size": &artifactBinarySize} {
+		if value := section.Key(key).String(); value != "" {
+			size, err := parseSize(value)
+			if err != nil {
+				return fmt.Errorf("artifacts %s: %v", key, err)
+			}
+			*limit = size
+		}
+	}
+	if section.HasKey("generated") {
+		generatedPatterns = splitList(section.Key("generated").String())
+	}
+	generatedDirs = splitList(section.Key("generated_dirs").String())
+	return nil
+}
+
+// artifactFinding is a file flagged by madaa artifacts. Path is relative to
+// the checked directory.
+type artifactFinding struct {
+	Path    string `json:"path"`
+	Size    int64  `json:"size"`
+	Kind    string `json:"kind"`
+	Message string `json:"message"`
+}
+
+// matchRelative matches a pattern against the file name, or against the
+// path relative to the checked directory if the pattern contains a slash.
+func matchRelative(pattern, rel string) bool {
+	if strings.Contains(pattern, "/") {
+		return matchGlob(pattern, rel)
+	}
+	return matchGlob(pattern, filepath.Base(rel))
+}
+
+// isBinary tells whether the file looks binary: it has a NUL byte in its
+// first 8 KB, as git decides.
+func isBinary(path string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	head := make([]byte, 8000)
+	n, _ := io.ReadFull(f, head)
+	return bytes.IndexByte(head[:n], 0) >= 0
+}
+
+// checkArtifact returns the finding for the file at rel, or nil if it is
+// fine.
+func checkArtifact(root, rel string, size int64) *artifactFinding {
+	finding := &artifactFinding{Path: rel, Size: size}
+	switch {
+	case size > artifactMaxSize:
+		finding.Kind = artifactOversized
+		finding.Message = fmt.Sprintf(tr("%s exceeds the size budget of %s"), formatMB(size), formatMB(artifactMaxSize))
+	case size > artifactBinarySize && isBinary(filepath.Join(root, rel)):
+		finding.Kind = artifactBinary
+		finding.Message = fmt.Sprintf(tr("binary file of %s, binaries over %s don't belong in the repository"), formatMB(size), formatMB(artifactBinarySize))
+	default:
+		generated := slices.ContainsFunc(generatedPatterns, func(pattern string) bool { return matchRelative(pattern, rel) })
+		allowed := slices.ContainsFunc(generatedDirs, func(dir string) bool { return matchGlob(dir, rel) })
+		if !generated || allowed {
+			return nil
+		}
+		finding.Kind = artifactGenerated
+		finding.Message = tr("generated file outside the directories allowed for them")
+	}
+	return finding
+}
+
+// findArtifacts checks the files below root, or only those listed in files,
+// relative to root, e.g. from git ls-files. .git directories are skipped.
+func findArtifacts(root string, files []string) ([]artifactFinding, error) {
+	var findings []artifactFinding
+	check := func(rel string, info fs.FileInfo) {
+		if !info.Mode().IsRegular() {
+			return
+		}
+		if finding := checkArtifact(root, filepath.Clean(rel), info.Size()); finding != nil {
+			findings = append(findings, *finding)
+		}
+	}
+
+	if files != nil {
+		for _, rel := range files {
+			info, err := os.Lstat(filepath.Join(root, rel))
+			if err != nil {
+				// Listed but deleted in the working tree
+				continue
+			}
+			check(rel, info)
+		}
+	} else {
+		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				if d.Name() == ".git" {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil {
+				return nil
+			}
+			rel, err := filepath.Rel(root, path)
+			if err != nil {
+				return err
+			}
+			check(rel, info)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	sort.Slice(findings, func(i, j int) bool {
+		if findings[i].Size != findings[j].Size {
+			return findings[i].Size > findings[j].Size
+		}
+		return findings[i].Path < findings[j].Path
+	})
+	return findings, nil
+}
+
+func displayArtifacts(root string, findings []artifactFinding, maxCount int) string {
+	var result strings.Builder
+
+	writeTitle(tr("MADAA - Repository Artifacts"), &result)
+	result.WriteString(fmt.Sprintf(tr("Root: %s\n"), renderPath(root)))
+	result.WriteString(fmt.Sprintf(tr("Size budget: %s  Binaries up to: %s\n\n"),
+		numberStyle.Render(formatMB(artifactMaxSize)),
+		numberStyle.Render(formatMB(artifactBinarySize))))
+	if len(findings) == 0 {
+		result.WriteString(goodStyle.Render(tr("No artifacts found")))
+		result.WriteString("\n")
+		return result.String()
+	}
+
+	for _, kind := range artifactKinds {
+		var files []artifactFinding
+		var bytes int64
+		for _, finding := range findings {
+			if finding.Kind == kind {
+				files = append(files, finding)
+				bytes += finding.Size
+			}
+		}
+		if len(files) == 0 {
+			continue
+		}
+		result.WriteString(fmt.Sprintf(tr("%s: %s files, %s\n"),
+			headerStyle.Render(tr(kind)),
+			badStyle.Render(formatCount(len(files))),
+			numberStyle.Render(formatMB(bytes))))
+		for _, finding := range files[:min(maxCount, len(files))] {
+			result.WriteString(fmt.Sprintf("  %s %s\n",
+				badStyle.Render(fmt.Sprintf("%11s", formatMB(finding.Size))),
+				renderPath(finding.Path)))
+		}
+		result.WriteString("\n")
+	}
+	return result.String()
+}
+
+// writeGitHubAnnotations prints the findings as GitHub Actions workflow
+// commands, which show up as annotations on the files in a pull request.
+func writeGitHubAnnotations(findings []artifactFinding, w io.Writer) {
+	escape := strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C")
+	for _, finding := range findings {
+		fmt.Fprintf(w, "::error file=%s,title=madaa %s::%s\n",
+			escape.Replace(filepath.ToSlash(finding.Path)),
+			escape.Replace(finding.Kind),
+			strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(finding.Message))
+	}
+}
+
+// runArtifacts implements "madaa artifacts <path>": it polices a source
+// repository for large binaries, generated files outside their directories
+// and files over the size budget, and exits with 2 if it finds any.
+func runArtifacts(args []string) {
+	flags := flag.NewFlagSet("artifacts", flag.ExitOnError)
+	maxSize := flags.String("max-size", "", "Size budget per file (default: max_size of [artifacts], 5M)")
+	binarySize := flags.String("binary-size", "", "Flag binary files larger than this (default: binary_size of [artifacts], 1M)")
+	filesFrom := flags.String("files-from", "", "Only check the files listed in FILE (- for stdin), relative to the path, e.g. from git ls-files -z")
+	format := flags.String("format", "text", "Output format: text, github (workflow annotations) or json")
+	count := flags.Int("count", 10, "Number of files to list per finding in the text format")
+	reportFlags(flags)
+	selectLanguage := localeFlags(flags)
+	flags.Parse(args)
+	selectLanguage()
+
+	if flags.NArg() > 1 || (*format != "text" && *format != "github" && *format != "json") {
+		fmt.Println("Usage: madaa artifacts [--max-size SIZE] [--binary-size SIZE] [--files-from FILE|-] [--format text|github|json] [--count N] [path]")
+		os.Exit(1)
+	}
+	if err := loadConfig(); err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+	var err error
+	if *maxSize != "" {
+		if artifactMaxSize, err = parseSize(*maxSize); err != nil {
+			fmt.Printf("Error parsing --max-size: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if *binarySize != "" {
+		if artifactBinarySize, err = parseSize(*binarySize); err != nil {
+			fmt.Printf("Error parsing --binary-size: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	root := "."
+	if flags.NArg() == 1 {
+		root = flags.Arg(0)
+	}
+	var files []string
+	if *filesFrom != "" {
+		if files, err = loadFileList(*filesFrom); err != nil {
+			fmt.Printf("Error reading file list: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	findings, err := findArtifacts(root, files)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	switch *format {
+	case "github":
+		writeGitHubAnnotations(findings, os.Stdout)
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if findings == nil {
+			findings = []artifactFinding{}
+		}
+		enc.Encode(findings)
+	default:
+		fmt.Print(fitPaths(displayArtifacts(root, findings, *count), pathWidth))
+	}
+	if len(findings) > 0 {
+		os.Exit(checkFailed)
+	}
+}
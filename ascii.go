@@ -0,0 +1,39 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/bubbles/progress"
+)
+
+// asciiOnly is set by --ascii for terminals, serial consoles and log files
+// that can't show emoji or arrows.
+var asciiOnly bool
+
+// asciiReplacer spells out the non-ASCII symbols of the views and reports.
+var asciiReplacer = strings.NewReplacer(
+	"🔍", ">",
+	"↑/↓", "up/down",
+	"←/→", "left/right",
+	"→", "->",
+	"×", "x",
+	"…", "...",
+)
+
+// plainText replaces the symbols in s with ASCII under --ascii.
+func plainText(s string) string {
+	if !asciiOnly {
+		return s
+	}
+	return asciiReplacer.Replace(s)
+}
+
+// newProgressBar is the progress bar of the scan, drawn with # and - under
+// --ascii instead of block characters.
+func newProgressBar() progress.Model {
+	opts := []progress.Option{progress.WithDefaultGradient()}
+	if asciiOnly {
+		opts = append(opts, progress.WithFillCharacters('#', '-'))
+	}
+	return progress.New(opts...)
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"container/heap"
+	"io/fs"
+	"path/filepath"
+)
+
+// bigFirst orders the walk by directory size instead of name, set by
+// --big-first, so that partial results of a scan cut short by --timeout or
+// --max-duration already hold the biggest consumers.
+var bigFirst bool
+
+// pendingDir is a directory found by walkBiggestFirst and read, but whose
+// entries haven't been visited yet. weight is its size from the previous
+// scan, or its number of entries without one.
+type pendingDir struct {
+	path    string
+	depth   int
+	weight  int64
+	entries []fs.DirEntry
+}
+
+// dirQueue is a max-heap of the pending directories, heaviest on top and
+// the shallower of two equally heavy ones first.
+type dirQueue []*pendingDir
+
+func (q dirQueue) Len() int { return len(q) }
+func (q dirQueue) Less(i, j int) bool {
+	if q[i].weight != q[j].weight {
+		return q[i].weight > q[j].weight
+	}
+	if q[i].depth != q[j].depth {
+		return q[i].depth < q[j].depth
+	}
+	return q[i].path < q[j].path
+}
+func (q dirQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+
+func (q *dirQueue) Push(x interface{}) {
+	*q = append(*q, x.(*pendingDir))
+}
+
+func (q *dirQueue) Pop() interface{} {
+	old := *q
+	n := len(old)
+	dir := old[n-1]
+	*q = old[0 : n-1]
+	return dir
+}
+
+// sizeHints returns the bytes below each directory in the scan cache of
+// root, for ordering the walk by the sizes of the previous scan. It is
+// empty when root was never scanned with --cache.
+func sizeHints(root string) map[string]int64 {
+	cache := loadScanCache(root)
+	hints := make(map[string]int64, len(cache.Dirs))
+	for dir, listing := range cache.Dirs {
+		var bytes int64
+		for _, file := range listing.Files {
+			bytes += file.Size
+		}
+		for p := dir; ; p = filepath.Dir(p) {
+			if _, ok := cache.Dirs[p]; !ok {
+				break
+			}
+			hints[p] += bytes
+			if p == cache.Root || p == filepath.Dir(p) {
+				break
+			}
+		}
+	}
+	return hints
+}
+
+// walkBiggestFirst calls fn for root and everything below it like
+// walkBreadthFirst, but always continues with the largest directory found so
+// far, by hints or else by its number of entries. Directories are read when
+// found to weigh them, so the listings of the pending ones are held in
+// memory.
+func walkBiggestFirst(root string, hints map[string]int64, coverage *Coverage, fn fs.WalkDirFunc) error {
+	info, err := lstat(root)
+	if err != nil {
+		return fn(root, nil, err)
+	}
+
+	queue := &dirQueue{}
+	// enqueue passes a directory to fn and reads it, false if fn stopped
+	// the walk with err
+	enqueue := func(path string, d fs.DirEntry, depth int) (bool, error) {
+		if err := fn(path, d, nil); err != nil {
+			if err == filepath.SkipDir {
+				return true, nil
+			}
+			return false, err
+		}
+		if !d.IsDir() {
+			return true, nil
+		}
+		coverage.DirsDiscovered++
+		entries, err := readDir(path)
+		if err != nil {
+			if err := fn(path, d, err); err != nil && err != filepath.SkipDir {
+				return false, err
+			}
+			return true, nil
+		}
+		weight, ok := hints[path]
+		if !ok && len(hints) == 0 {
+			weight = int64(len(entries))
+		}
+		heap.Push(queue, &pendingDir{path: path, depth: depth, weight: weight, entries: entries})
+		return true, nil
+	}
+
+	walk := func() error {
+		if ok, err := enqueue(root, fs.FileInfoToDirEntry(info), 0); !ok {
+			return err
+		}
+		for queue.Len() > 0 {
+			dir := heap.Pop(queue).(*pendingDir)
+			for _, entry := range dir.entries {
+				if ok, err := enqueue(filepath.Join(dir.path, entry.Name()), entry, dir.depth+1); !ok {
+					return err
+				}
+			}
+			coverage.DirsVisited++
+		}
+		return nil
+	}
+	if err := walk(); err != filepath.SkipAll {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// defaultBlockSize is assumed where the block size of the volume is unknown.
+const defaultBlockSize = 4096
+
+// BlockStats sums up what storing the files in whole allocation blocks
+// costs. Slack is the space between the end of each file and the end of its
+// last block. Allocated is what the files actually take up on disk where the
+// platform tells, which can be less for sparse files or file systems that
+// store tiny files inline.
+type BlockStats struct {
+	BlockSize      int64
+	Assumed        bool
+	SubBlock       int
+	SubBlockBytes  int64
+	Slack          int64
+	Allocated      int64
+	AllocatedKnown bool
+}
+
+// analyzeBlocks is called by processFile, with the stats locked. Empty
+// files take no data blocks.
+func analyzeBlocks(info os.FileInfo, stats *Stats) {
+	blocks := &stats.Blocks
+	if blocks.BlockSize == 0 {
+		blocks.BlockSize = stats.Volume.BlockSize
+		if blocks.BlockSize == 0 {
+			blocks.BlockSize, blocks.Assumed = defaultBlockSize, true
+		}
+	}
+
+	size := info.Size()
+	if rest := size % blocks.BlockSize; rest > 0 {
+		blocks.Slack += blocks.BlockSize - rest
+	}
+	if size > 0 && size < blocks.BlockSize {
+		blocks.SubBlock++
+		blocks.SubBlockBytes += size
+	}
+	if allocated, ok := allocatedSize(info); ok {
+		blocks.Allocated += allocated
+		blocks.AllocatedKnown = true
+	}
+}
+
+func mergeBlockStats(dst, src *BlockStats) {
+	if dst.BlockSize == 0 {
+		dst.BlockSize, dst.Assumed = src.BlockSize, src.Assumed
+	}
+	dst.SubBlock += src.SubBlock
+	dst.SubBlockBytes += src.SubBlockBytes
+	dst.Slack += src.Slack
+	dst.Allocated += src.Allocated
+	dst.AllocatedKnown = dst.AllocatedKnown || src.AllocatedKnown
+}
+
+func displayBlocks(stats *Stats, result *strings.Builder) {
+	blocks := stats.Blocks
+	if blocks.BlockSize == 0 || stats.TotalFiles == 0 {
+		return
+	}
+
+	result.WriteString(headerStyle.Render(tr("Small-File Overhead")))
+	result.WriteString("\n")
+	source := tr("of the volume")
+	if blocks.Assumed {
+		source = tr("assumed")
+	}
+	result.WriteString(fmt.Sprintf(tr("Block size: %s bytes (%s)\n"),
+		numberStyle.Render(formatCount(int(blocks.BlockSize))), source))
+
+	share := float64(blocks.SubBlock) / float64(stats.TotalFiles) * 100
+	style := numberStyle
+	if share >= 50 {
+		style = warnStyle
+	}
+	result.WriteString(fmt.Sprintf(tr("Smaller than one block: %s files %s holding %s\n"),
+		style.Render(formatCount(blocks.SubBlock)),
+		percentStyle.Render(fmt.Sprintf("(%s)", formatPercent(share))),
+		numberStyle.Render(formatMB(blocks.SubBlockBytes))))
+
+	var slackShare float64
+	if stats.TotalSize > 0 {
+		slackShare = float64(blocks.Slack) / float64(stats.TotalSize) * 100
+	}
+	result.WriteString(fmt.Sprintf(tr("Lost to block rounding: %s %s of the data\n"),
+		warnStyle.Render(formatMB(blocks.Slack)),
+		percentStyle.Render(fmt.Sprintf("(%s)", formatPercent(slackShare)))))
+	if blocks.AllocatedKnown {
+		result.WriteString(fmt.Sprintf(tr("Allocated on disk: %s for %s of data\n"),
+			numberStyle.Render(formatMB(blocks.Allocated)),
+			numberStyle.Render(formatMB(stats.TotalSize))))
+	}
+	result.WriteString("\n")
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"math"
+	"path/filepath"
+	"strconv"
+)
+
+// bloomFalsePositives is the share of new files a compact snapshot mistakes
+// for files it has seen. It costs about 14 bits per file.
+const bloomFalsePositives = 0.001
+
+// bloomFilter records the path, size and mtime of every file of a compact
+// snapshot, in a fraction of the memory of the listings. It tells for sure
+// that a file was never seen, but mistakes about one in a thousand new
+// files for one it has seen. Files and Bytes total the files added.
+type bloomFilter struct {
+	Bits   []uint64
+	Hashes int
+	Files  int
+	Bytes  int64
+}
+
+// newBloomFilter sizes a filter for files entries at bloomFalsePositives.
+func newBloomFilter(files int) *bloomFilter {
+	n := float64(max(files, 1))
+	bits := math.Ceil(-n * math.Log(bloomFalsePositives) / (math.Ln2 * math.Ln2))
+	words := max(int(bits+63)/64, 1)
+	hashes := max(int(math.Round(float64(words*64)/n*math.Ln2)), 1)
+	return &bloomFilter{Bits: make([]uint64, words), Hashes: hashes}
+}
+
+// bloomKey identifies a file by its path relative to the snapshot root, its
+// size and mtime, so a file that was modified counts as new again.
+func bloomKey(rel string, f *cachedFile) []byte {
+	key := []byte(filepath.ToSlash(rel))
+	key = append(key, 0)
+	key = strconv.AppendInt(key, f.Size, 10)
+	key = append(key, 0)
+	return strconv.AppendInt(key, f.ModTime.UnixNano(), 10)
+}
+
+// positions derives the bits of key from the two halves of its 128-bit FNV
+// hash, as h1 + i*h2.
+func (b *bloomFilter) positions(key []byte, visit func(word int, bit uint64)) {
+	h := fnv.New128a()
+	h.Write(key)
+	sum := h.Sum(nil)
+	h1 := binary.BigEndian.Uint64(sum[:8])
+	h2 := binary.BigEndian.Uint64(sum[8:]) | 1
+	m := uint64(len(b.Bits) * 64)
+	for i := 0; i < b.Hashes; i++ {
+		pos := (h1 + uint64(i)*h2) % m
+		visit(int(pos/64), 1<<(pos%64))
+	}
+}
+
+func (b *bloomFilter) add(rel string, f *cachedFile) {
+	b.positions(bloomKey(rel, f), func(word int, bit uint64) {
+		b.Bits[word] |= bit
+	})
+	b.Files++
+	b.Bytes += f.Size
+}
+
+// has reports whether the file may have been added; false is certain.
+func (b *bloomFilter) has(rel string, f *cachedFile) bool {
+	found := true
+	b.positions(bloomKey(rel, f), func(word int, bit uint64) {
+		found = found && b.Bits[word]&bit != 0
+	})
+	return found
+}
+
+// compactSnapshot replaces the listings of snap by a bloom filter of its
+// files.
+func compactSnapshot(snap *Snapshot) *Snapshot {
+	dirs := relativeDirs(snap)
+	files := 0
+	for _, listing := range dirs {
+		files += len(listing.Files)
+	}
+	seen := newBloomFilter(files)
+	for dir, listing := range dirs {
+		for i := range listing.Files {
+			seen.add(filepath.Join(dir, listing.Files[i].Name), &listing.Files[i])
+		}
+	}
+	return &Snapshot{
+		Root:    snap.Root,
+		Created: snap.Created,
+		Label:   snap.Label,
+		Note:    snap.Note,
+		Dirs:    make(map[string]*dirCacheEntry),
+		Seen:    seen,
+	}
+}
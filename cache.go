@@ -0,0 +1,241 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/gob"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// scanCache remembers the per-directory lstat results of a previous scan.
+// A directory is reused when its mtime and entry count are unchanged, which
+// saves one lstat per file. Changes that only touch file contents do not
+// update the directory mtime and are therefore not picked up from the cache.
+type scanCache struct {
+	Root       string
+	TotalFiles int64
+	Dirs       map[string]*dirCacheEntry
+}
+
+type dirCacheEntry struct {
+	ModTime time.Time
+	Entries int
+	Files   []cachedFile
+	Subdirs []string
+}
+
+type cachedFile struct {
+	Name      string
+	Size      int64
+	Mode      os.FileMode
+	ModTime   time.Time
+	ATime     time.Time
+	Uid       uint32
+	Gid       uint32
+	Allocated int64
+	// Hash is the SHA-256 of the content, recorded by rescan --hash
+	Hash []byte
+}
+
+// cachedFileInfo presents a cached file as an os.FileInfo. Sys returns the
+// cachedFile itself so helpers like accessTime can still find the atime.
+type cachedFileInfo struct {
+	file *cachedFile
+}
+
+func (c cachedFileInfo) Name() string       { return c.file.Name }
+func (c cachedFileInfo) Size() int64        { return c.file.Size }
+func (c cachedFileInfo) Mode() os.FileMode  { return c.file.Mode }
+func (c cachedFileInfo) ModTime() time.Time { return c.file.ModTime }
+func (c cachedFileInfo) IsDir() bool        { return c.file.Mode.IsDir() }
+func (c cachedFileInfo) Sys() interface{}   { return c.file }
+
+func newScanCache(root string) *scanCache {
+	return &scanCache{Root: root, Dirs: make(map[string]*dirCacheEntry)}
+}
+
+func cachePath(root string) (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(abs))
+	return filepath.Join(dir, "madaa", fmt.Sprintf("%x.gob", sum[:8])), nil
+}
+
+// loadScanCache returns the cache for root, or an empty cache if there is
+// none yet or it can't be read.
+func loadScanCache(root string) *scanCache {
+	path, err := cachePath(root)
+	if err != nil {
+		return newScanCache(root)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return newScanCache(root)
+	}
+	defer f.Close()
+
+	var cache scanCache
+	if err := gob.NewDecoder(f).Decode(&cache); err != nil || cache.Dirs == nil {
+		return newScanCache(root)
+	}
+	return &cache
+}
+
+func saveScanCache(cache *scanCache) error {
+	path, err := cachePath(cache.Root)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if err := gob.NewEncoder(f).Encode(cache); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// listDirectory returns the cached listing of dir if it is still valid and
+// reads the directory otherwise.
+func listDirectory(dir string, info os.FileInfo, old *scanCache) (*dirCacheEntry, error) {
+	names, err := withDeadline("readdir", dir, func() ([]string, error) {
+		f, err := os.Open(dir)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		return f.Readdirnames(-1)
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+
+	if cached, ok := old.Dirs[dir]; ok && cached.ModTime.Equal(info.ModTime()) && cached.Entries == len(names) {
+		return cached, nil
+	}
+
+	listing := &dirCacheEntry{ModTime: info.ModTime(), Entries: len(names)}
+	for _, name := range names {
+		fi, err := lstat(filepath.Join(dir, name))
+		if err != nil {
+			continue
+		}
+		if fi.IsDir() {
+			listing.Subdirs = append(listing.Subdirs, name)
+			continue
+		}
+		file := cachedFile{Name: name, Size: fi.Size(), Mode: fi.Mode(), ModTime: fi.ModTime()}
+		if atime, ok := accessTime(fi); ok {
+			file.ATime = atime
+		}
+		file.Uid, file.Gid, _ = fileOwnerIDs(fi)
+		file.Allocated, _ = allocatedSize(fi)
+		listing.Files = append(listing.Files, file)
+	}
+	return listing, nil
+}
+
+// walkListings visits every directory below dir and each of its files,
+// listing directories through listDirectory and recording the listings in
+// fresh. Files and directories skip returns true for are not visited; the
+// listings still hold them, so the cache serves scans without skipping too.
+func walkListings(ctx context.Context, dir string, old, fresh *scanCache, skip func(path string) bool, visit func(item scanItem) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	info, err := lstat(dir)
+	if err != nil {
+		switch {
+		case os.IsNotExist(err):
+			return nil
+		case strictScan:
+			return err
+		}
+		// The worker stats it again and records why it can't
+		return visit(scanItem{path: dir})
+	}
+
+	listing, err := listDirectory(dir, info, old)
+	if err != nil {
+		if strictScan && !os.IsNotExist(err) {
+			return err
+		}
+		return visit(scanItem{path: dir, info: info})
+	}
+	fresh.Dirs[dir] = listing
+	fresh.TotalFiles += int64(len(listing.Files))
+
+	if err := visit(scanItem{path: dir, info: info, listing: listing}); err != nil {
+		return err
+	}
+	for i := range listing.Files {
+		file := &listing.Files[i]
+		if skip != nil && skip(filepath.Join(dir, file.Name)) {
+			continue
+		}
+		if err := visit(scanItem{path: filepath.Join(dir, file.Name), info: cachedFileInfo{file}}); err != nil {
+			return err
+		}
+	}
+	for _, sub := range listing.Subdirs {
+		if skip != nil && skip(filepath.Join(dir, sub)) {
+			continue
+		}
+		if err := walkListings(ctx, filepath.Join(dir, sub), old, fresh, skip, visit); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// analyzeDirectoryCached is analyzeDirectory backed by the scan cache. The
+// file count of the previous scan stands in for the counting pass.
+func analyzeDirectoryCached(ctx context.Context, config Config, events *eventBus) (*Stats, error) {
+	old := loadScanCache(config.Path)
+	fresh := newScanCache(config.Path)
+	totalFiles := old.TotalFiles
+
+	stats, err := runAnalysis(ctx, config, &totalFiles, events, func(ctx context.Context, pathChan chan<- scanItem) error {
+		return walkListings(ctx, config.Path, old, fresh, config.skipped, func(item scanItem) error {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case pathChan <- item:
+				return nil
+			}
+		})
+	})
+	if err != nil || stats.TimedOut {
+		// A partial walk would drop the rest of the tree from the cache
+		return stats, err
+	}
+
+	if err := saveScanCache(fresh); err != nil {
+		return stats, fmt.Errorf("saving scan cache: %w", err)
+	}
+	return stats, nil
+}
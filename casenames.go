@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// analyzeNameCase notes names that differ from another name in the same
+// directory only by case, like Report.PDF and report.pdf. Those can't both
+// exist on Windows, macOS or in SharePoint and OneDrive, so copying or
+// syncing the directory there loses one of them.
+func analyzeNameCase(path string, stats *Stats) {
+	dir, name := filepath.Dir(path), filepath.Base(path)
+	folded := strings.ToLower(name)
+
+	names := stats.caseNames[dir]
+	if names == nil {
+		names = make(map[string]string)
+		stats.caseNames[dir] = names
+	}
+	first, seen := names[folded]
+	if !seen {
+		names[folded] = name
+		return
+	}
+
+	collisions := stats.CaseCollisions[dir]
+	if collisions == nil {
+		collisions = make(map[string][]string)
+		stats.CaseCollisions[dir] = collisions
+	}
+	if len(collisions[folded]) == 0 {
+		collisions[folded] = []string{first}
+	}
+	collisions[folded] = append(collisions[folded], name)
+}
+
+func mergeCaseCollisions(dst, src map[string]map[string][]string) {
+	for dir, collisions := range src {
+		if dst[dir] == nil {
+			dst[dir] = make(map[string][]string)
+		}
+		for folded, names := range collisions {
+			dst[dir][folded] = append(dst[dir][folded], names...)
+		}
+	}
+}
+
+// mergeCaseNames adds the names seen by the worker shard src to dst. The
+// files of a directory are spread over the workers, so each name is noted
+// again to find the collisions between shards.
+func mergeCaseNames(dst, src *Stats) {
+	for dir, names := range src.caseNames {
+		for folded, first := range names {
+			collided := src.CaseCollisions[dir][folded]
+			if len(collided) == 0 {
+				collided = []string{first}
+			}
+			for _, name := range collided {
+				analyzeNameCase(filepath.Join(dir, name), dst)
+			}
+		}
+	}
+}
+
+func displayCaseCollisions(stats *Stats, maxCount int, result *strings.Builder) {
+	if len(stats.CaseCollisions) == 0 {
+		return
+	}
+
+	dirs := make([]string, 0, len(stats.CaseCollisions))
+	total := 0
+	for dir, collisions := range stats.CaseCollisions {
+		dirs = append(dirs, dir)
+		total += len(collisions)
+	}
+	sort.Slice(dirs, func(i, j int) bool {
+		if len(stats.CaseCollisions[dirs[i]]) != len(stats.CaseCollisions[dirs[j]]) {
+			return len(stats.CaseCollisions[dirs[i]]) > len(stats.CaseCollisions[dirs[j]])
+		}
+		return dirs[i] < dirs[j]
+	})
+
+	result.WriteString(headerStyle.Render(tr("Case-Insensitive Name Collisions")))
+	result.WriteString("\n")
+	result.WriteString(fmt.Sprintf(tr("Colliding names: %s in %s directories (break on Windows, macOS and cloud sync)\n"),
+		badStyle.Render(formatCount(total)),
+		numberStyle.Render(formatCount(len(dirs)))))
+	for _, dir := range dirs[:min(maxCount, len(dirs))] {
+		collisions := stats.CaseCollisions[dir]
+		folded := make([]string, 0, len(collisions))
+		for name := range collisions {
+			folded = append(folded, name)
+		}
+		sort.Strings(folded)
+
+		result.WriteString(fmt.Sprintf("  %s\n", renderPath(dir)))
+		for _, name := range folded {
+			names := append([]string(nil), collisions[name]...)
+			sort.Strings(names)
+			result.WriteString(fmt.Sprintf("    %s\n", warnStyle.Render(strings.Join(names, ", "))))
+		}
+	}
+	result.WriteString("\n")
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// recheckChanges re-stats the files that changed during the scan once it is
+// done. Set by --recheck.
+var recheckChanges bool
+
+// Ways a file can change while it is being scanned.
+const (
+	changeVanished = "vanished"
+	changeModified = "modified"
+	changeReplaced = "replaced"
+	changeMoved    = "moved"
+)
+
+// fileID identifies a file independent of its name. As a map key in JSON,
+// e.g. of Stats.HardLinks, it is "dev:ino".
+type fileID struct {
+	Dev, Ino uint64
+}
+
+func (id fileID) MarshalText() ([]byte, error) {
+	return fmt.Appendf(nil, "%d:%d", id.Dev, id.Ino), nil
+}
+
+func (id *fileID) UnmarshalText(text []byte) error {
+	if _, err := fmt.Sscanf(string(text), "%d:%d", &id.Dev, &id.Ino); err != nil {
+		return fmt.Errorf("invalid file ID %q", text)
+	}
+	return nil
+}
+
+// ChangedFile is a file that changed while the scan was running. Size and
+// ModTime are what the scan saw; Recheck is the state at the end of the
+// scan with --recheck.
+type ChangedFile struct {
+	Path    string
+	Change  string
+	Size    int64
+	ModTime time.Time
+	Recheck string
+}
+
+func recordChange(path string, change string, info os.FileInfo, stats *Stats) {
+	changed := ChangedFile{Path: path, Change: change}
+	if info != nil {
+		changed.Size = info.Size()
+		changed.ModTime = info.ModTime()
+	}
+	stats.Changes = append(stats.Changes, changed)
+}
+
+// seenSet is the set of files a scan has counted, shared by the workers. It
+// is split into stripes locked separately, so workers rarely wait for each
+// other.
+type seenSet struct {
+	stripes [64]struct {
+		mu  sync.Mutex
+		ids map[fileID]struct{}
+	}
+}
+
+func newSeenSet() *seenSet {
+	s := &seenSet{}
+	for i := range s.stripes {
+		s.stripes[i].ids = make(map[fileID]struct{})
+	}
+	return s
+}
+
+// add adds id to the set and reports whether it was in it already.
+func (s *seenSet) add(id fileID) bool {
+	stripe := &s.stripes[(id.Ino^id.Dev)%uint64(len(s.stripes))]
+	stripe.mu.Lock()
+	defer stripe.mu.Unlock()
+	_, seen := stripe.ids[id]
+	stripe.ids[id] = struct{}{}
+	return seen
+}
+
+// admitFile notes files that were modified or replaced since the scan
+// started and reports whether the file is to be counted. A changed file
+// whose inode was already counted under another name was moved during the
+// scan and is skipped, so it isn't counted twice.
+func admitFile(path string, info os.FileInfo, stats *Stats) bool {
+	ctime, ok := changeTime(info)
+	changed := ok && !ctime.Before(stats.ScanStart)
+	id, links, ok := fileIdentity(info)
+	moved := ok && links == 1 && stats.seenFiles.add(id) && changed
+	if !changed {
+		return true
+	}
+
+	stats.mu.Lock()
+	defer stats.mu.Unlock()
+	if moved {
+		recordChange(path, changeMoved, info, stats)
+		return false
+	}
+	// Renaming a file over another updates the ctime but not the mtime
+	change := changeReplaced
+	if !info.ModTime().Before(stats.ScanStart) {
+		change = changeModified
+	}
+	recordChange(path, change, info, stats)
+	return true
+}
+
+// recheckChangedFiles stats the changed files again after the scan to tell
+// files that have settled from ones still being written.
+func recheckChangedFiles(stats *Stats) {
+	for i := range stats.Changes {
+		changed := &stats.Changes[i]
+		if changed.Change == changeMoved {
+			continue
+		}
+		info, err := os.Lstat(changed.Path)
+		switch {
+		case err != nil:
+			changed.Recheck = tr("gone")
+		case changed.Change == changeVanished:
+			changed.Recheck = tr("recreated")
+		case info.Size() == changed.Size && info.ModTime().Equal(changed.ModTime):
+			changed.Recheck = tr("settled")
+		default:
+			changed.Recheck = fmt.Sprintf(tr("still changing, now %s"), formatMB(info.Size()))
+		}
+	}
+}
+
+func displayChangedFiles(stats *Stats, maxCount int, result *strings.Builder) {
+	if len(stats.Changes) == 0 {
+		return
+	}
+
+	counts := make(map[string]int)
+	var bytes int64
+	for _, changed := range stats.Changes {
+		counts[changed.Change]++
+		bytes += changed.Size
+	}
+	changes := make([]ChangedFile, len(stats.Changes))
+	copy(changes, stats.Changes)
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].Size != changes[j].Size {
+			return changes[i].Size > changes[j].Size
+		}
+		return changes[i].Path < changes[j].Path
+	})
+
+	result.WriteString(headerStyle.Render(tr("Files Changed During Scan")))
+	result.WriteString("\n")
+	result.WriteString(fmt.Sprintf(tr("Modified: %s  Replaced: %s  Vanished: %s  Moved (not counted twice): %s  Size seen: %s\n"),
+		warnStyle.Render(formatCount(counts[changeModified])),
+		warnStyle.Render(formatCount(counts[changeReplaced])),
+		badStyle.Render(formatCount(counts[changeVanished])),
+		numberStyle.Render(formatCount(counts[changeMoved])),
+		numberStyle.Render(formatMB(bytes))))
+	for _, changed := range changes[:min(maxCount, len(changes))] {
+		recheck := ""
+		if changed.Recheck != "" {
+			recheck = " (" + changed.Recheck + ")"
+		}
+		result.WriteString(fmt.Sprintf("  %-8s %s %s%s\n",
+			tr(changed.Change),
+			numberStyle.Render(fmt.Sprintf("%10s", formatMB(changed.Size))),
+			renderPath(changed.Path),
+			recheck))
+	}
+	result.WriteString("\n")
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAdmitFileMovedBetweenShards(t *testing.T) {
+	dir := t.TempDir()
+	before, after := filepath.Join(dir, "a.txt"), filepath.Join(dir, "b.txt")
+	if err := os.WriteFile(before, []byte("data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Lstat(before)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := changeTime(info); !ok {
+		t.Skip("no change times on this platform")
+	}
+
+	stats := newStats()
+	stats.ScanStart = time.Now().Add(-time.Minute)
+	shards := []*Stats{newStats(), newStats()}
+	for _, shard := range shards {
+		shard.ScanStart, shard.seenFiles = stats.ScanStart, stats.seenFiles
+	}
+
+	if !admitFile(before, info, shards[0]) {
+		t.Fatal("file not counted when first seen")
+	}
+	if err := os.Rename(before, after); err != nil {
+		t.Fatal(err)
+	}
+	if info, err = os.Lstat(after); err != nil {
+		t.Fatal(err)
+	}
+	if admitFile(after, info, shards[1]) {
+		t.Error("moved file counted twice")
+	}
+
+	for _, shard := range shards {
+		mergeStats(stats, shard, 10)
+	}
+	if len(stats.Changes) != 2 || stats.Changes[1].Change != changeMoved {
+		t.Errorf("changes %v, want the file modified, then moved", stats.Changes)
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Chargeback settings from --rate, --currency and --chargeback-by. A rate of
+// zero leaves the chargeback report out.
+var (
+	chargebackRate     float64
+	chargebackCurrency = "$"
+	chargebackBy       = "dir"
+)
+
+const bytesPerGB = 1024 * 1024 * 1024
+
+// chargebackLine is the storage one top-level directory or owner is billed
+// for.
+type chargebackLine struct {
+	Name  string
+	Bytes int64
+}
+
+func (l chargebackLine) Cost() float64 {
+	return float64(l.Bytes) / bytesPerGB * chargebackRate
+}
+
+// chargebackLines splits the scanned bytes by top-level directory or by
+// owner, largest first. Files directly in the scanned directory are billed
+// to it as a whole.
+func chargebackLines(stats *Stats) []chargebackLine {
+	var lines []chargebackLine
+	switch chargebackBy {
+	case "owner":
+		for owner, ownerAge := range stats.OwnerAges {
+			lines = append(lines, chargebackLine{displayName(owner), ownerAge.Total()})
+		}
+	default:
+		rest := stats.TotalSize
+		for dir, tree := range dirTrees(stats) {
+			if stats.DirDepths[dir] == 0 && tree.Size > 0 {
+				lines = append(lines, chargebackLine{displayPath(dir), tree.Size})
+				rest -= tree.Size
+			}
+		}
+		if rest > 0 {
+			lines = append(lines, chargebackLine{tr("(files in the scanned directory)"), rest})
+		}
+	}
+	sort.Slice(lines, func(i, j int) bool {
+		if lines[i].Bytes != lines[j].Bytes {
+			return lines[i].Bytes > lines[j].Bytes
+		}
+		return lines[i].Name < lines[j].Name
+	})
+	return lines
+}
+
+func formatCost(cost float64) string {
+	return chargebackCurrency + formatFloat(cost, 2)
+}
+
+func displayChargeback(stats *Stats, maxCount int, result *strings.Builder) {
+	if chargebackRate <= 0 {
+		return
+	}
+	lines := chargebackLines(stats)
+	if len(lines) == 0 {
+		return
+	}
+
+	var total float64
+	for _, line := range lines {
+		total += line.Cost()
+	}
+
+	title := tr("Chargeback by Directory")
+	if chargebackBy == "owner" {
+		title = tr("Chargeback by Owner")
+	}
+	result.WriteString(headerStyle.Render(title))
+	result.WriteString("\n")
+	result.WriteString(fmt.Sprintf(tr("Monthly cost: %s at %s per GB\n"),
+		badStyle.Render(formatCost(total)),
+		numberStyle.Render(fmt.Sprintf("%s%g", chargebackCurrency, chargebackRate))))
+	for _, line := range lines[:min(maxCount, len(lines))] {
+		result.WriteString(fmt.Sprintf("  %s %s %s\n",
+			numberStyle.Render(fmt.Sprintf("%10s", formatCost(line.Cost()))),
+			numberStyle.Render(fmt.Sprintf("%12s", formatMB(line.Bytes))),
+			pathStyle.Render(line.Name)))
+	}
+	result.WriteString("\n")
+}
+
+// writeChargebackCSV writes every chargeback line for finance, with sizes
+// in bytes and GB.
+func writeChargebackCSV(stats *Stats, w io.Writer) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{chargebackBy, "bytes", "gb", "rate_per_gb_month", "monthly_cost"})
+	for _, line := range chargebackLines(stats) {
+		cw.Write([]string{
+			line.Name,
+			strconv.FormatInt(line.Bytes, 10),
+			strconv.FormatFloat(float64(line.Bytes)/bytesPerGB, 'f', 3, 64),
+			strconv.FormatFloat(chargebackRate, 'f', -1, 64),
+			strconv.FormatFloat(line.Cost(), 'f', 2, 64),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// saveChargebackCSV writes the CSV to path, or to stdout for "-".
+func saveChargebackCSV(stats *Stats, path string) error {
+	if path == "-" {
+		return writeChargebackCSV(stats, os.Stdout)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := writeChargebackCSV(stats, f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
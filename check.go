@@ -0,0 +1,345 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// checkFailed is the exit code of madaa check when the policy is violated,
+// so CI can tell drift from errors, which exit with 1.
+const checkFailed = 2
+
+// policyPeriods are the periods growth can be allowed per.
+var policyPeriods = map[string]time.Duration{
+	"day":   24 * time.Hour,
+	"week":  7 * 24 * time.Hour,
+	"month": 30 * 24 * time.Hour,
+	"year":  365 * 24 * time.Hour,
+}
+
+// policyRule is one rule of a check policy:
+//
+//	allow growth 5%/month [in DIR]
+//	allow growth 500M/week [in DIR]
+//	no new files matching GLOB [in DIR]
+//	no new files where EXPR
+//
+// DIR is relative to the checked root. GLOB matches the file name, or the
+// path relative to the root if it contains a slash. EXPR takes the --filter
+// syntax.
+type policyRule struct {
+	Text string
+	Dir  string
+	// Growth rules allow Percent or Bytes of growth per Period
+	Period  time.Duration
+	Percent float64
+	Bytes   int64
+	// New file rules forbid added files matching Glob or Filter
+	Glob   string
+	Filter *Filter
+}
+
+func parsePolicyRule(text string) (policyRule, error) {
+	rule := policyRule{Text: text}
+	fields := strings.Fields(text)
+
+	if rest, ok := strings.CutPrefix(text, "no new files where "); ok {
+		filter, err := ParseFilter(rest)
+		if err != nil {
+			return rule, fmt.Errorf("policy rule %q: %v", text, err)
+		}
+		rule.Filter = filter
+		return rule, nil
+	}
+	if n := len(fields); n > 2 && fields[n-2] == "in" {
+		rule.Dir = filepath.Clean(strings.Trim(fields[n-1], "/"))
+		fields = fields[:n-2]
+	}
+
+	switch {
+	case len(fields) == 3 && fields[0] == "allow" && fields[1] == "growth":
+		amount, per, ok := strings.Cut(fields[2], "/")
+		period, known := policyPeriods[per]
+		if !ok || !known {
+			return rule, fmt.Errorf("policy rule %q: want growth per day, week, month or year, e.g. 5%%/month", text)
+		}
+		rule.Period = period
+		if percent, ok := strings.CutSuffix(amount, "%"); ok {
+			value, err := strconv.ParseFloat(percent, 64)
+			if err != nil || value < 0 {
+				return rule, fmt.Errorf("policy rule %q: invalid percentage %q", text, amount)
+			}
+			rule.Percent = value
+		} else {
+			bytes, err := parseSize(amount)
+			if err != nil {
+				return rule, fmt.Errorf("policy rule %q: %v", text, err)
+			}
+			rule.Bytes = bytes
+		}
+	case len(fields) == 5 && strings.Join(fields[:4], " ") == "no new files matching":
+		rule.Glob = fields[4]
+	default:
+		return rule, fmt.Errorf("unknown policy rule %q", text)
+	}
+	return rule, nil
+}
+
+// loadPolicy reads a policy file with one rule per line. Empty lines and
+// lines starting with # are skipped.
+func loadPolicy(path string) ([]policyRule, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var rules []policyRule
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		rule, err := parsePolicyRule(line)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, scanner.Err()
+}
+
+// within tells whether rel, a path relative to the root, is below dir.
+func within(rel, dir string) bool {
+	return dir == "" || dir == "." || rel == dir || strings.HasPrefix(rel, dir+string(filepath.Separator))
+}
+
+// snapshotBytes sums the sizes of the files below dir in the snapshot.
+func snapshotBytes(snap *Snapshot, dir string) int64 {
+	var total int64
+	for rel, listing := range relativeDirs(snap) {
+		if !within(rel, dir) {
+			continue
+		}
+		for _, f := range listing.Files {
+			total += f.Size
+		}
+	}
+	return total
+}
+
+// snapshotFile looks up the file at rel, relative to the root, in snap.
+func snapshotFile(snap *Snapshot, rel string) *cachedFile {
+	listing := snap.Dirs[filepath.Join(snap.Root, filepath.Dir(rel))]
+	if listing == nil {
+		return nil
+	}
+	name := filepath.Base(rel)
+	for i := range listing.Files {
+		if listing.Files[i].Name == name {
+			return &listing.Files[i]
+		}
+	}
+	return nil
+}
+
+// PolicyResult is the outcome of one rule. Paths are the files breaking a
+// new file rule, largest first.
+type PolicyResult struct {
+	Rule    policyRule
+	Passed  bool
+	Paths   []string
+	Allowed int64
+	Growth  int64
+}
+
+// checkPolicy evaluates the rules against the changes from baseline to cur.
+// The growth allowed grows with the time since the baseline was taken, but
+// is never less than one period's, so a fresh baseline isn't failed by the
+// first change.
+func checkPolicy(rules []policyRule, baseline, cur *Snapshot, diff *SnapshotDiff) []PolicyResult {
+	elapsed := cur.Created.Sub(baseline.Created)
+	var results []PolicyResult
+	for _, rule := range rules {
+		result := PolicyResult{Rule: rule, Passed: true}
+		switch {
+		case rule.Period > 0:
+			before := snapshotBytes(baseline, rule.Dir)
+			result.Growth = snapshotBytes(cur, rule.Dir) - before
+			factor := max(float64(elapsed)/float64(rule.Period), 1)
+			if rule.Percent > 0 || rule.Bytes == 0 {
+				result.Allowed = int64(float64(before) * rule.Percent / 100 * factor)
+			} else {
+				result.Allowed = int64(float64(rule.Bytes) * factor)
+			}
+			result.Passed = result.Growth <= result.Allowed
+		default:
+			for _, change := range diff.Added {
+				if !within(change.Path, rule.Dir) {
+					continue
+				}
+				var match bool
+				switch {
+				case rule.Filter != nil:
+					if f := snapshotFile(cur, change.Path); f != nil {
+						match = rule.Filter.Match(filepath.Join(cur.Root, change.Path), cachedFileInfo{f})
+					}
+				case strings.Contains(rule.Glob, "/"):
+					match = matchGlob(rule.Glob, change.Path)
+				default:
+					match = matchGlob(rule.Glob, filepath.Base(change.Path))
+				}
+				if match {
+					result.Paths = append(result.Paths, change.Path)
+				}
+			}
+			result.Passed = len(result.Paths) == 0
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+func displayCheck(diff *SnapshotDiff, results []PolicyResult, maxCount int) string {
+	var result strings.Builder
+
+	writeTitle(tr("MADAA - Policy Check"), &result)
+	result.WriteString(fmt.Sprintf(tr("Root: %s\n"), renderPath(diff.Root)))
+	result.WriteString(fmt.Sprintf(tr("Baseline: %s  Now: %s\n"),
+		snapshotTitle(diff.OldCreated, diff.OldLabel),
+		snapshotTitle(diff.NewCreated, diff.NewLabel)))
+	result.WriteString("\n")
+
+	var failed int
+	for _, r := range results {
+		status := goodStyle.Render(tr("pass"))
+		if !r.Passed {
+			status = badStyle.Render(tr("FAIL"))
+			failed++
+		}
+		result.WriteString(fmt.Sprintf("%s %s\n", status, r.Rule.Text))
+		if r.Rule.Period > 0 {
+			style := numberStyle
+			if !r.Passed {
+				style = badStyle
+			}
+			result.WriteString(fmt.Sprintf(tr("  grew %s, allowed %s\n"),
+				style.Render(formatDeltaMB(r.Growth)),
+				numberStyle.Render(formatMB(r.Allowed))))
+			continue
+		}
+		if !r.Passed {
+			result.WriteString(fmt.Sprintf(tr("  %s new files\n"), badStyle.Render(formatCount(len(r.Paths)))))
+		}
+		for _, path := range r.Paths[:min(maxCount, len(r.Paths))] {
+			result.WriteString(fmt.Sprintf("  %s\n", renderPath(path)))
+		}
+	}
+	result.WriteString("\n")
+	if failed > 0 {
+		result.WriteString(badStyle.Render(fmt.Sprintf(tr("%s of %s rules violated"), formatCount(failed), formatCount(len(results)))))
+	} else {
+		result.WriteString(goodStyle.Render(fmt.Sprintf(tr("All %s rules passed"), formatCount(len(results)))))
+	}
+	result.WriteString("\n")
+	return result.String()
+}
+
+// runCheck implements "madaa check --baseline FILE --policy FILE <path>":
+// it compares path with a committed baseline snapshot and fails with exit
+// code 2 when the changes break the policy, for use in CI.
+func runCheck(args []string) {
+	flags := flag.NewFlagSet("check", flag.ExitOnError)
+	baselinePath := flags.String("baseline", "", "Baseline snapshot to compare with, e.g. committed to the repository")
+	policyPath := flags.String("policy", "", "File with the policy rules, one per line")
+	var inline []string
+	flags.Func("rule", "A policy rule, in addition to those of --policy (repeatable)", func(rule string) error {
+		inline = append(inline, rule)
+		return nil
+	})
+	update := flags.Bool("update", false, "Write the current state to the baseline after checking, accepting the changes")
+	count := flags.Int("count", 10, "Number of offending files to list per rule")
+	enableRedaction := redactFlags(flags)
+	reportFlags(flags)
+	selectLanguage := localeFlags(flags)
+	flags.Parse(args)
+	enableRedaction()
+	selectLanguage()
+
+	if *baselinePath == "" || flags.NArg() < 1 {
+		fmt.Println("Usage: madaa check --baseline FILE [--policy FILE] [--rule RULE]... [--update] [--count N] <path>")
+		os.Exit(1)
+	}
+	if err := loadConfig(); err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+
+	var rules []policyRule
+	if *policyPath != "" {
+		var err error
+		if rules, err = loadPolicy(*policyPath); err != nil {
+			fmt.Printf("Error loading policy: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	for _, text := range inline {
+		rule, err := parsePolicyRule(text)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		rules = append(rules, rule)
+	}
+	ignore, err := diffIgnoreFilter("")
+	if err != nil {
+		fmt.Printf("Error parsing ignore rules: %v\n", err)
+		os.Exit(1)
+	}
+
+	baseline, err := loadSnapshot(*baselinePath)
+	if err != nil && !(errors.Is(err, fs.ErrNotExist) && *update) {
+		fmt.Printf("Error loading baseline: %v\n", err)
+		os.Exit(1)
+	}
+	if baseline != nil {
+		if err := requireListings(*baselinePath, baseline); err != nil {
+			fmt.Printf("Error loading baseline: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	snap, err := takeSnapshot(context.Background(), flags.Arg(0), baseline)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	if *update {
+		if err := saveSnapshot(*baselinePath, snap); err != nil {
+			fmt.Printf("Error writing snapshot: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if baseline == nil {
+		fmt.Printf(tr("No baseline found, wrote initial snapshot of %s to %s\n"), snap.Root, *baselinePath)
+		return
+	}
+
+	diff := diffSnapshots(baseline, snap, DiffOptions{Ignore: ignore})
+	results := checkPolicy(rules, baseline, snap, diff)
+	fmt.Print(fitPaths(displayCheck(diff, results, *count), pathWidth))
+	for _, r := range results {
+		if !r.Passed {
+			os.Exit(checkFailed)
+		}
+	}
+}
@@ -0,0 +1,360 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/ini.v1"
+)
+
+// Guardrails of madaa clean, from the [clean] config section: files below
+// the system directories or matching a protect pattern are never touched,
+// and cleaning up more than cleanConfirmAbove bytes has to be confirmed by
+// typing the number of files.
+var (
+	protectedPatterns []string
+	cleanConfirmAbove int64 = 1024 * 1024 * 1024
+)
+
+// systemDirs are protected no matter what the config says.
+var systemDirs = []string{
+	"/bin", "/boot", "/dev", "/etc", "/lib", "/lib32", "/lib64", "/opt",
+	"/proc", "/run", "/sbin", "/sys", "/usr", "/var/lib",
+	"/System", "/Library", "/Applications", "/private/etc", "/private/var/db",
+	`C:\Windows`, `C:\Program Files`, `C:\Program Files (x86)`, `C:\ProgramData`,
+}
+
+// cleanFile is a file madaa clean is going to remove, or leaves alone
+// because it is protected. Reason is why it is a candidate, or why it is
+// protected.
+type cleanFile struct {
+	Path   string
+	Size   int64
+	Reason string
+}
+
+func loadCleanConfig(section *ini.Section) error {
+	protectedPatterns = nil
+	for _, pattern := range strings.Split(section.Key("protect").String(), ",") {
+		if pattern = strings.TrimSpace(pattern); pattern != "" {
+			protectedPatterns = append(protectedPatterns, pattern)
+		}
+	}
+	if value := section.Key("confirm_above").String(); value != "" {
+		size, err := parseSize(value)
+		if err != nil {
+			return fmt.Errorf("clean confirm_above: %v", err)
+		}
+		cleanConfirmAbove = size
+	}
+	quarantineRoot = section.Key("quarantine_root").String()
+	quarantineDays = section.Key("quarantine_days").MustInt(30)
+	return nil
+}
+
+// withinDir reports whether path is dir or below it. Windows paths compare
+// case-insensitively.
+func withinDir(path, dir string) bool {
+	if runtime.GOOS == "windows" {
+		path, dir = strings.ToLower(path), strings.ToLower(dir)
+	}
+	return path == dir || strings.HasPrefix(path, dir+string(os.PathSeparator))
+}
+
+// protectedReason tells why path must not be cleaned up, or returns "" if
+// it may be. The path is checked as given and with the symlinks of its
+// directory resolved, so a link to /etc doesn't lead around the guardrails.
+func protectedReason(path string) string {
+	full := fullPath(path)
+	paths := []string{full}
+	if dir, err := filepath.EvalSymlinks(filepath.Dir(full)); err == nil {
+		if resolved := filepath.Join(dir, filepath.Base(full)); resolved != full {
+			paths = append(paths, resolved)
+		}
+	}
+
+	for _, full := range paths {
+		for _, dir := range systemDirs {
+			if withinDir(full, filepath.FromSlash(dir)) {
+				return fmt.Sprintf(tr("system directory %s"), dir)
+			}
+		}
+		for _, pattern := range protectedPatterns {
+			if matchGlob(pattern, full) {
+				return fmt.Sprintf(tr("protected by %s"), pattern)
+			}
+		}
+	}
+	return ""
+}
+
+// collectCleanup finds the cleanup candidates below config.Path and splits
+// off the protected ones.
+func collectCleanup(config Config) (files, protected []cleanFile, err error) {
+	err = walkMatchingFiles(config, func(path string, info os.FileInfo) {
+		reason, ok := cleanupCandidate(path, info)
+		if !ok {
+			return
+		}
+		if why := protectedReason(path); why != "" {
+			protected = append(protected, cleanFile{path, info.Size(), why})
+		} else {
+			files = append(files, cleanFile{path, info.Size(), reason})
+		}
+	})
+	return files, protected, err
+}
+
+func cleanBytes(files []cleanFile) int64 {
+	var bytes int64
+	for _, f := range files {
+		bytes += f.Size
+	}
+	return bytes
+}
+
+// displayCleanPlan is the dry-run summary shown before anything is touched.
+func displayCleanPlan(files, protected []cleanFile, maxCount int) string {
+	var result strings.Builder
+
+	result.WriteString(headerStyle.Render(tr("Cleanup Plan")))
+	result.WriteString("\n")
+	result.WriteString(fmt.Sprintf(tr("Remove: %s files, %s\n"),
+		badStyle.Render(formatCount(len(files))),
+		badStyle.Render(formatMB(cleanBytes(files)))))
+
+	reasons := make(map[string]int)
+	for _, f := range files {
+		reasons[f.Reason]++
+	}
+	names := make([]string, 0, len(reasons))
+	for reason := range reasons {
+		names = append(names, reason)
+	}
+	sort.Strings(names)
+	for _, reason := range names {
+		result.WriteString(fmt.Sprintf(tr("  %-22s %s files\n"), tr(reason), numberStyle.Render(formatCount(reasons[reason]))))
+	}
+
+	largest := make([]cleanFile, len(files))
+	copy(largest, files)
+	sort.Slice(largest, func(i, j int) bool {
+		if largest[i].Size != largest[j].Size {
+			return largest[i].Size > largest[j].Size
+		}
+		return largest[i].Path < largest[j].Path
+	})
+	for _, f := range largest[:min(maxCount, len(largest))] {
+		result.WriteString(fmt.Sprintf("  %s %s\n",
+			numberStyle.Render(fmt.Sprintf("%11s", formatMB(f.Size))),
+			pathStyle.Render(f.Path)))
+	}
+
+	if len(protected) > 0 {
+		result.WriteString(fmt.Sprintf(tr("Protected, left alone: %s files, %s\n"),
+			goodStyle.Render(formatCount(len(protected))),
+			goodStyle.Render(formatMB(cleanBytes(protected)))))
+		for _, f := range protected[:min(maxCount, len(protected))] {
+			result.WriteString(fmt.Sprintf("  %s (%s)\n", pathStyle.Render(f.Path), f.Reason))
+		}
+	}
+	result.WriteString("\n")
+	return result.String()
+}
+
+// confirmClean asks before files are removed. Above cleanConfirmAbove the
+// number of files has to be typed, even with --yes.
+func confirmClean(files []cleanFile, yes bool, in *bufio.Reader) bool {
+	bytes := cleanBytes(files)
+	if bytes > cleanConfirmAbove {
+		fmt.Printf(tr("This removes %s. Type the number of files (%d) to confirm: "), formatMB(bytes), len(files))
+		answer, _ := in.ReadString('\n')
+		return strings.TrimSpace(answer) == strconv.Itoa(len(files))
+	}
+	if yes {
+		return true
+	}
+	fmt.Printf(tr("Remove %s files? [y/N] "), formatCount(len(files)))
+	answer, _ := in.ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes", "j", "ja":
+		return true
+	}
+	return false
+}
+
+// removeFiles deletes the files, or moves them away with remove if it is
+// set. Each file is checked again right before, so one that was modified or
+// replaced since the plan was made is skipped.
+func removeFiles(files []cleanFile, remove func(path string, info os.FileInfo) error) (removed int, bytes int64, errs []error) {
+	if remove == nil {
+		remove = func(path string, info os.FileInfo) error { return os.Remove(path) }
+	}
+	for _, f := range files {
+		info, err := os.Lstat(f.Path)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if _, ok := cleanupCandidate(f.Path, info); !ok || protectedReason(f.Path) != "" {
+			errs = append(errs, fmt.Errorf("%s changed since the plan was made, skipped", f.Path))
+			continue
+		}
+		if err := remove(f.Path, info); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		removed++
+		bytes += info.Size()
+	}
+	return removed, bytes, errs
+}
+
+// runClean implements "madaa clean <path>": it removes the temporary and
+// lock files --cleanup-candidates lists, after showing what it is going to
+// do and asking for confirmation. With --trash or --quarantine the files
+// are moved away instead, and "madaa clean --undo SESSION" puts them back.
+func runClean(args []string) {
+	flags := flag.NewFlagSet("clean", flag.ExitOnError)
+	filterExpr := flags.String("filter", "", "Only clean up files matching the expression")
+	count := flags.Int("count", 10, "Number of files to show in the plan")
+	dryRun := flags.Bool("dry-run", false, "Only show what would be removed")
+	yes := flags.Bool("yes", false, "Don't ask before removing, unless the size needs a typed confirmation")
+	trash := flags.Bool("trash", false, "Move the files to madaa's trash instead of deleting them, so the session can be undone")
+	undo := flags.String("undo", "", "Restore the files moved away by this session")
+	quarantine := flags.Bool("quarantine", false, "Move the files into the quarantine_root from config.ini, to be purged after quarantine_days")
+	listSessions := flags.Bool("sessions", false, "List the sessions that can be undone")
+	purge := flags.Bool("purge", false, "Delete the files that have been in quarantine for longer than quarantine_days")
+	selectLanguage := localeFlags(flags)
+	flags.Parse(args)
+	selectLanguage()
+
+	if *purge || *quarantine {
+		if err := loadConfig(); err != nil {
+			fmt.Printf("Error loading config: %v\n", err)
+			os.Exit(1)
+		}
+		if quarantineRoot == "" {
+			fmt.Println("Error: no quarantine_root in the [clean] section of config.ini")
+			os.Exit(1)
+		}
+	}
+
+	switch {
+	case *purge:
+		purged, bytes, errs := purgeQuarantine(quarantineRoot, time.Duration(quarantineDays)*24*time.Hour)
+		for _, err := range errs {
+			fmt.Printf("Error: %v\n", err)
+		}
+		fmt.Printf(tr("Purged %s files, %s\n"), formatCount(purged), formatMB(bytes))
+		if len(errs) > 0 {
+			os.Exit(1)
+		}
+		return
+	case *listSessions:
+		ids, err := cleanSessions()
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Print(displaySessions(ids))
+		return
+	case *undo != "":
+		restored, renamed, errs := undoSession(*undo)
+		for original, target := range renamed {
+			fmt.Printf(tr("%s was taken, restored as %s\n"), original, target)
+		}
+		for _, err := range errs {
+			fmt.Printf("Error: %v\n", err)
+		}
+		fmt.Printf(tr("Restored %s files\n"), formatCount(restored))
+		if len(errs) > 0 {
+			os.Exit(1)
+		}
+		return
+	}
+
+	if flags.NArg() < 1 {
+		fmt.Println("Usage: madaa clean [--filter EXPR] [--count N] [--dry-run] [--yes] [--trash|--quarantine] <path>")
+		fmt.Println("       madaa clean --sessions")
+		fmt.Println("       madaa clean --undo SESSION")
+		fmt.Println("       madaa clean --purge")
+		os.Exit(1)
+	}
+	if err := loadConfig(); err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+
+	config := Config{Path: flags.Arg(0)}
+	if *filterExpr != "" {
+		filter, err := ParseFilter(*filterExpr)
+		if err != nil {
+			fmt.Printf("Error parsing filter: %v\n", err)
+			os.Exit(1)
+		}
+		config.Filter = filter
+	}
+
+	files, protected, err := collectCleanup(config)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Print(displayCleanPlan(files, protected, *count))
+	if *dryRun || len(files) == 0 {
+		return
+	}
+	if !confirmClean(files, *yes, bufio.NewReader(os.Stdin)) {
+		fmt.Println(tr("Nothing removed."))
+		return
+	}
+
+	var session *cleanSession
+	var remove func(path string, info os.FileInfo) error
+	if *trash || *quarantine {
+		var err error
+		if *quarantine {
+			session, err = newQuarantineSession(quarantineRoot)
+		} else {
+			var dir string
+			if dir, err = trashDir(); err == nil {
+				session, err = newCleanSession(dir)
+			}
+		}
+		if err != nil {
+			fmt.Printf("Error starting clean session: %v\n", err)
+			os.Exit(1)
+		}
+		remove = session.move
+	}
+
+	removed, bytes, errs := removeFiles(files, remove)
+	for _, err := range errs {
+		fmt.Printf("Error: %v\n", err)
+	}
+	if session != nil {
+		if err := session.Close(); err != nil {
+			fmt.Printf("Error: %v\n", err)
+		}
+		if *quarantine {
+			fmt.Printf(tr("Moved %s files, %s to %s for %s days. Undo with: madaa clean --undo %s\n"),
+				formatCount(removed), formatMB(bytes), session.dir(), formatCount(quarantineDays), session.ID)
+		} else {
+			fmt.Printf(tr("Moved %s files, %s to the trash. Undo with: madaa clean --undo %s\n"), formatCount(removed), formatMB(bytes), session.ID)
+		}
+	} else {
+		fmt.Printf(tr("Removed %s files, %s\n"), formatCount(removed), formatMB(bytes))
+	}
+	if len(errs) > 0 {
+		os.Exit(1)
+	}
+}
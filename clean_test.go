@@ -0,0 +1,86 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRemoveFilesRechecksPlan(t *testing.T) {
+	dir := t.TempDir()
+	old := time.Now().Add(-2 * tempAge)
+	stale, touched, kept := filepath.Join(dir, "a.tmp"), filepath.Join(dir, "b.tmp"), filepath.Join(dir, "c.tmp")
+	var files []cleanFile
+	for _, path := range []string{stale, touched, kept} {
+		if err := os.WriteFile(path, []byte("data"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, old, old); err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, cleanFile{Path: path})
+	}
+
+	// Modified since the plan, and protected since the plan
+	if err := os.Chtimes(touched, time.Now(), time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	defer func(patterns []string) { protectedPatterns = patterns }(protectedPatterns)
+	protectedPatterns = []string{"**/c.tmp"}
+
+	removed, _, errs := removeFiles(files, nil)
+	if removed != 1 || len(errs) != 2 {
+		t.Fatalf("removed %d, errors %v", removed, errs)
+	}
+	if _, err := os.Lstat(stale); !os.IsNotExist(err) {
+		t.Errorf("a.tmp not removed: %v", err)
+	}
+	for _, path := range []string{touched, kept} {
+		if _, err := os.Lstat(path); err != nil {
+			t.Errorf("%s removed: %v", path, err)
+		}
+	}
+}
+
+func TestProtectedReason(t *testing.T) {
+	defer func(patterns []string) { protectedPatterns = patterns }(protectedPatterns)
+	protectedPatterns = []string{"**/.git/**"}
+
+	for path, protected := range map[string]bool{
+		"/usr/lib/a.tmp":             true,
+		"/home/u/src/.git/index.lck": true,
+		"/home/u/src/a.tmp":          false,
+		"/usrdata/a.tmp":             false,
+	} {
+		if got := protectedReason(path) != ""; got != protected {
+			t.Errorf("protectedReason(%q) protected = %v, want %v", path, got, protected)
+		}
+	}
+}
+
+func TestProtectedReasonResolvesSymlinks(t *testing.T) {
+	defer func(patterns []string) { protectedPatterns = patterns }(protectedPatterns)
+	protectedPatterns = []string{"**/keep/**"}
+
+	dir := t.TempDir()
+	keep := filepath.Join(dir, "keep")
+	if err := os.Mkdir(keep, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for link, target := range map[string]string{"etc": "/etc", "kept": keep} {
+		if err := os.Symlink(target, filepath.Join(dir, link)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for path, protected := range map[string]bool{
+		filepath.Join(dir, "etc", "passwd.tmp"): true,
+		filepath.Join(dir, "kept", "a.tmp"):     true,
+		filepath.Join(dir, "a.tmp"):             false,
+	} {
+		if got := protectedReason(path) != ""; got != protected {
+			t.Errorf("protectedReason(%q) protected = %v, want %v", path, got, protected)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// clipboardCommands are the programs that put their stdin on the system
+// clipboard, in the order they are tried.
+func clipboardCommands() [][]string {
+	switch runtime.GOOS {
+	case "darwin":
+		return [][]string{{"pbcopy"}}
+	case "windows":
+		return [][]string{{"clip.exe"}}
+	}
+	var commands [][]string
+	if os.Getenv("WAYLAND_DISPLAY") != "" {
+		commands = append(commands, []string{"wl-copy"})
+	}
+	return append(commands,
+		[]string{"xclip", "-selection", "clipboard"},
+		[]string{"xsel", "--clipboard", "--input"})
+}
+
+// copyToClipboard puts text on the system clipboard. Over SSH, or where no
+// clipboard program is installed, it asks the terminal to do it with an
+// OSC 52 escape sequence, which most terminals and tmux with set-clipboard
+// understand.
+func copyToClipboard(text string) error {
+	if os.Getenv("SSH_TTY") == "" {
+		var errs []error
+		for _, command := range clipboardCommands() {
+			if _, err := exec.LookPath(command[0]); err != nil {
+				continue
+			}
+			cmd := exec.Command(command[0], command[1:]...)
+			cmd.Stdin = strings.NewReader(text)
+			if err := cmd.Run(); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", command[0], err))
+				continue
+			}
+			return nil
+		}
+		if len(errs) > 0 {
+			return errors.Join(errs...)
+		}
+	}
+	_, err := fmt.Fprintf(os.Stdout, "\x1b]52;c;%s\a", base64.StdEncoding.EncodeToString([]byte(text)))
+	return err
+}
+
+// clipboardMsg reports how a copy to the clipboard went.
+type clipboardMsg struct {
+	lines int
+	err   error
+}
+
+// copyCmd copies a view or report to the clipboard as plain text with full
+// paths, ready to be pasted into a chat or ticket.
+func copyCmd(view string) tea.Cmd {
+	return func() tea.Msg {
+		text := ansi.Strip(fitPaths(view, 0))
+		return clipboardMsg{strings.Count(text, "\n"), copyToClipboard(text)}
+	}
+}
+
+// clipboardStatus is the line shown after a copy.
+func clipboardStatus(msg clipboardMsg) string {
+	if msg.err != nil {
+		return badStyle.Render(fmt.Sprintf(tr("Copying failed: %v"), msg.err))
+	}
+	return goodStyle.Render(fmt.Sprintf(tr("Copied %s lines to the clipboard"), formatCount(msg.lines)))
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// installerAge is how old an installer must be to count as clutter. Set by
+// --installer-days.
+var installerAge = 90 * 24 * time.Hour
+
+var installerExts = map[string]bool{
+	".dmg": true, ".pkg": true, ".msi": true, ".exe": true,
+	".deb": true, ".rpm": true, ".appimage": true,
+}
+
+// downloadCopyPattern matches the names browsers give repeated downloads,
+// e.g. "report (1).pdf".
+var downloadCopyPattern = regexp.MustCompile(`^(.+) \(\d+\)(\.[^.]*)?$`)
+
+var screenshotPrefixes = []string{"screenshot", "screen shot", "bildschirmfoto", "capture d'écran", "capture d’écran"}
+
+// minScreenshots is the number of screenshots in one directory from which
+// they are reported as an accumulation.
+const minScreenshots = 10
+
+// Clutter collects the typical leftovers of a user's home directory:
+// forgotten installers, repeated browser downloads and screenshots.
+type Clutter struct {
+	Installers      *FileSizeHeap
+	InstallerCount  int
+	InstallerBytes  int64
+	Copies          *FileSizeHeap
+	CopyCount       int
+	CopyBytes       int64
+	Screenshots     map[string]int
+	ScreenshotBytes map[string]int64
+}
+
+func newClutter() Clutter {
+	return Clutter{
+		Installers:      &FileSizeHeap{},
+		Copies:          &FileSizeHeap{},
+		Screenshots:     make(map[string]int),
+		ScreenshotBytes: make(map[string]int64),
+	}
+}
+
+// isDownloadCopy reports whether the file is a numbered copy of a download
+// whose original, with the same size, is still next to it.
+func isDownloadCopy(path string, info os.FileInfo) bool {
+	match := downloadCopyPattern.FindStringSubmatch(filepath.Base(path))
+	if match == nil {
+		return false
+	}
+	original, err := os.Lstat(filepath.Join(filepath.Dir(path), match[1]+match[2]))
+	return err == nil && original.Size() == info.Size()
+}
+
+func isScreenshot(name string) bool {
+	lower := strings.ToLower(name)
+	for _, prefix := range screenshotPrefixes {
+		if strings.HasPrefix(lower, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func analyzeClutter(path string, info os.FileInfo, stats *Stats, maxFiles int) {
+	clutter := &stats.Clutter
+	name := filepath.Base(path)
+	ext := strings.ToLower(filepath.Ext(name))
+
+	if installerExts[ext] && time.Since(info.ModTime()) > installerAge {
+		clutter.InstallerCount++
+		clutter.InstallerBytes += info.Size()
+		pushLargest(clutter.Installers, FileSize{path, info.Size(), ext}, maxFiles)
+	}
+
+	if isDownloadCopy(path, info) {
+		clutter.CopyCount++
+		clutter.CopyBytes += info.Size()
+		pushLargest(clutter.Copies, FileSize{path, info.Size(), ext}, maxFiles)
+	}
+
+	if isScreenshot(name) {
+		dir := filepath.Dir(path)
+		clutter.Screenshots[dir]++
+		clutter.ScreenshotBytes[dir] += info.Size()
+	}
+}
+
+func mergeClutter(dst, src *Clutter, maxFiles int) {
+	dst.InstallerCount += src.InstallerCount
+	dst.InstallerBytes += src.InstallerBytes
+	mergeLargest(dst.Installers, src.Installers, maxFiles)
+	dst.CopyCount += src.CopyCount
+	dst.CopyBytes += src.CopyBytes
+	mergeLargest(dst.Copies, src.Copies, maxFiles)
+	for dir, count := range src.Screenshots {
+		dst.Screenshots[dir] += count
+		dst.ScreenshotBytes[dir] += src.ScreenshotBytes[dir]
+	}
+}
+
+func displayClutter(stats *Stats, maxCount int, result *strings.Builder) {
+	clutter := &stats.Clutter
+
+	var screenshotDirs []string
+	var screenshotBytes int64
+	for dir, count := range clutter.Screenshots {
+		if count >= minScreenshots {
+			screenshotDirs = append(screenshotDirs, dir)
+			screenshotBytes += clutter.ScreenshotBytes[dir]
+		}
+	}
+	sort.Slice(screenshotDirs, func(i, j int) bool {
+		if clutter.ScreenshotBytes[screenshotDirs[i]] != clutter.ScreenshotBytes[screenshotDirs[j]] {
+			return clutter.ScreenshotBytes[screenshotDirs[i]] > clutter.ScreenshotBytes[screenshotDirs[j]]
+		}
+		return screenshotDirs[i] < screenshotDirs[j]
+	})
+
+	if clutter.InstallerCount == 0 && clutter.CopyCount == 0 && len(screenshotDirs) == 0 {
+		return
+	}
+
+	result.WriteString(headerStyle.Render(tr("Clutter")))
+	result.WriteString("\n")
+	result.WriteString(fmt.Sprintf(tr("Reclaimable: %s  Screenshots to review: %s\n\n"),
+		badStyle.Render(formatMB(clutter.InstallerBytes+clutter.CopyBytes)),
+		warnStyle.Render(formatMB(screenshotBytes))))
+
+	if clutter.InstallerCount > 0 {
+		result.WriteString(fmt.Sprintf(tr("Installers older than %d days: %s files, %s\n"),
+			int(installerAge.Hours()/24),
+			numberStyle.Render(formatCount(clutter.InstallerCount)),
+			numberStyle.Render(formatMB(clutter.InstallerBytes))))
+		displayLargestFiles(clutter.Installers, result)
+	}
+
+	if clutter.CopyCount > 0 {
+		result.WriteString(fmt.Sprintf(tr("Repeated downloads: %s files, %s\n"),
+			numberStyle.Render(formatCount(clutter.CopyCount)),
+			numberStyle.Render(formatMB(clutter.CopyBytes))))
+		displayLargestFiles(clutter.Copies, result)
+	}
+
+	if len(screenshotDirs) > 0 {
+		result.WriteString(tr("Screenshot accumulations:\n"))
+		for _, dir := range screenshotDirs[:min(maxCount, len(screenshotDirs))] {
+			result.WriteString(fmt.Sprintf(tr("  %s in %s screenshots: %s\n"),
+				numberStyle.Render(formatMB(clutter.ScreenshotBytes[dir])),
+				numberStyle.Render(formatCount(clutter.Screenshots[dir])),
+				renderPath(dir)))
+		}
+		result.WriteString("\n")
+	}
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"gopkg.in/ini.v1"
+)
+
+// panicError is a panic of a scan goroutine, turned into an error so the
+// scan stops like on any other error and the TUI restores the terminal
+// before it is reported.
+type panicError struct {
+	value any
+	stack []byte
+}
+
+func (e *panicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.value)
+}
+
+// recoverPanic turns a panic of the goroutine into a *panicError in err. It
+// has to be deferred itself, not called from a deferred function.
+func recoverPanic(err *error) {
+	if r := recover(); r != nil {
+		*err = &panicError{value: r, stack: debug.Stack()}
+	}
+}
+
+// recoverMain reports a panic of the main goroutine like one of a scan.
+func recoverMain() {
+	if r := recover(); r != nil {
+		reportCrash(&panicError{value: r, stack: debug.Stack()}, "", nil)
+	}
+}
+
+// tuiPanic is the error reported when the TUI caught a panic. Bubble Tea
+// has printed the panic and its stack after restoring the terminal.
+var tuiPanic = &panicError{value: tea.ErrProgramPanic}
+
+// activeConfig is the config loadConfig loaded, base config included, for
+// the diagnostic bundle.
+var activeConfig *ini.File
+
+// reportCrash ends madaa if err is a panic: it prints it, writes the
+// diagnostic bundle with the partial stats of the scan of root, and exits
+// non-zero. Other errors are left to the caller.
+func reportCrash(err error, root string, stats *Stats) {
+	var crash *panicError
+	if !errors.As(err, &crash) {
+		return
+	}
+	fmt.Printf(tr("madaa crashed: %v\n"), crash)
+	path, bundleErr := writeCrashBundle(crash, root, stats)
+	if bundleErr != nil {
+		fmt.Printf("Error writing the diagnostic bundle: %v\n", bundleErr)
+	} else {
+		fmt.Printf(tr("Please report it at https://github.com/dahead/madaa/issues with the diagnostic bundle %s. It contains the config, the command line and paths of the scanned tree.\n"), path)
+	}
+	os.Exit(1)
+}
+
+// writeCrashBundle writes a zip archive for the bug report to a new
+// directory in the temp directory, readable only by the user: the panic with
+// its stack, the command line and the config in use with their secrets
+// redacted, and the partial results of the scan as a JSON export.
+func writeCrashBundle(crash *panicError, root string, stats *Stats) (string, error) {
+	dir, err := os.MkdirTemp("", "madaa-crash-*")
+	if err != nil {
+		return "", err
+	}
+	f, err := os.OpenFile(filepath.Join(dir, "madaa-crash.zip"), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return "", err
+	}
+	type file struct {
+		name string
+		data []byte
+	}
+	files := []file{
+		{"panic.txt", fmt.Appendf(nil, "madaa %s, %s %s/%s\n\n%v\n\n%s", currentVersion(), runtime.Version(), runtime.GOOS, runtime.GOARCH, crash, crash.stack)},
+		{"args.txt", []byte(strings.Join(redactArgs(os.Args), "\n") + "\n")},
+	}
+	if activeConfig != nil {
+		if config, err := redactConfig(activeConfig); err == nil {
+			files = append(files, file{"config.ini", config})
+		}
+	}
+	if stats != nil {
+		if export, err := exportPartialStats(stats, root); err == nil {
+			files = append(files, file{"stats.json", export})
+		}
+	}
+
+	archive := zip.NewWriter(f)
+	for _, entry := range files {
+		w, err := archive.CreateHeader(&zip.FileHeader{Name: entry.name, Method: zip.Deflate, Modified: time.Now()})
+		if err == nil {
+			_, err = w.Write(entry.data)
+		}
+		if err != nil {
+			f.Close()
+			return "", err
+		}
+	}
+	if err := archive.Close(); err != nil {
+		f.Close()
+		return "", err
+	}
+	return f.Name(), f.Close()
+}
+
+// exportPartialStats renders the stats of a crashed scan as a JSON export.
+// The panic may have left them half updated, so a panic of the export is
+// an error too.
+func exportPartialStats(stats *Stats, root string) (data []byte, err error) {
+	defer recoverPanic(&err)
+	var export bytes.Buffer
+	if err := writeExport(newExport(stats, root), &export); err != nil {
+		return nil, err
+	}
+	return export.Bytes(), nil
+}
+
+const redactedValue = "[redacted]"
+
+// secretName tells whether a flag or config key of this name holds a
+// secret, like --redact-key or --token.
+func secretName(name string) bool {
+	name = strings.ToLower(name)
+	for _, word := range []string{"key", "token", "secret", "password", "credential"} {
+		if strings.Contains(name, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactArgs returns the command line with the values of secret flags
+// replaced, in both the "--flag value" and the "--flag=value" form.
+func redactArgs(args []string) []string {
+	redacted := make([]string, len(args))
+	copy(redacted, args)
+	for i := 1; i < len(redacted); i++ {
+		arg := redacted[i]
+		if arg == "--" {
+			break
+		}
+		if !strings.HasPrefix(arg, "-") {
+			continue
+		}
+		name, _, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if !secretName(name) {
+			continue
+		}
+		if hasValue {
+			redacted[i] = arg[:strings.Index(arg, "=")+1] + redactedValue
+		} else if i+1 < len(redacted) {
+			redacted[i+1] = redactedValue
+			i++
+		}
+	}
+	return redacted
+}
+
+// redactConfig writes the config with the values of secret keys replaced
+// and the passwords of URLs left out.
+func redactConfig(config *ini.File) ([]byte, error) {
+	var buf bytes.Buffer
+	if _, err := config.WriteTo(&buf); err != nil {
+		return nil, err
+	}
+	redacted, err := ini.Load(buf.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	for _, section := range redacted.Sections() {
+		for _, key := range section.Keys() {
+			if secretName(key.Name()) {
+				key.SetValue(redactedValue)
+			} else if u, err := url.Parse(key.Value()); err == nil && u.User != nil {
+				key.SetValue(u.Redacted())
+			}
+		}
+	}
+	buf.Reset()
+	if _, err := redacted.WriteTo(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Journals are flagged when they outgrow their data and are at least
+// minFlaggedJournal, or when they reach largeJournalSize regardless.
+const (
+	minFlaggedJournal = 64 * 1024 * 1024
+	largeJournalSize  = 1024 * 1024 * 1024
+)
+
+var sqliteExts = map[string]bool{".sqlite": true, ".sqlite3": true, ".db": true, ".db3": true}
+
+var sqliteJournalSuffixes = []string{"-wal", "-journal", "-shm"}
+
+// levelDBFilePattern matches the files LevelDB and RocksDB keep in their
+// directory: tables, write-ahead logs and the manifest.
+var levelDBFilePattern = regexp.MustCompile(`^(\d+\.(ldb|sst|log)|MANIFEST-\d+|CURRENT)$`)
+
+// Database is a database taken as one unit: its data files plus the WAL or
+// journal files that belong to it. Path is the main file, or the directory
+// for engines that keep one directory per database.
+type Database struct {
+	Path         string
+	Kind         string
+	Files        int
+	DataBytes    int64
+	JournalBytes int64
+	// LevelDB directories are only reported once their manifest was seen
+	Confirmed bool
+}
+
+func (d *Database) Total() int64 {
+	return d.DataBytes + d.JournalBytes
+}
+
+func (d *Database) LargeJournal() bool {
+	return d.JournalBytes >= largeJournalSize ||
+		(d.JournalBytes > d.DataBytes && d.JournalBytes >= minFlaggedJournal)
+}
+
+// classifyDatabaseFile returns the database the file belongs to, the kind
+// of database and whether the file is a journal. An empty unit means the
+// file is not part of a known database.
+func classifyDatabaseFile(path string) (unit, kind string, journal bool) {
+	dir, name := filepath.Split(path)
+	dir = filepath.Clean(dir)
+	lower := strings.ToLower(name)
+	ext := filepath.Ext(lower)
+
+	for _, suffix := range sqliteJournalSuffixes {
+		if strings.HasSuffix(lower, suffix) {
+			return path[:len(path)-len(suffix)], "sqlite", true
+		}
+	}
+	switch {
+	case sqliteExts[ext]:
+		return path, "sqlite", false
+	case ext == ".mdf":
+		return path, "sqlserver", false
+	case ext == ".ndf":
+		return filepath.Join(dir, strings.TrimSuffix(name, filepath.Ext(name))+".mdf"), "sqlserver", false
+	case ext == ".ldf":
+		base := strings.TrimSuffix(name, filepath.Ext(name))
+		if strings.HasSuffix(strings.ToLower(base), "_log") {
+			base = base[:len(base)-len("_log")]
+		}
+		return filepath.Join(dir, base+".mdf"), "sqlserver", true
+	case ext == ".ibd" || strings.HasPrefix(lower, "ibdata"):
+		return dir, "innodb", false
+	case strings.HasPrefix(lower, "ib_logfile") || strings.HasPrefix(lower, "#ib_redo"):
+		return dir, "innodb", true
+	case levelDBFilePattern.MatchString(name):
+		return dir, "leveldb", ext == ".log"
+	}
+	return "", "", false
+}
+
+func analyzeDatabases(path string, info os.FileInfo, stats *Stats) {
+	unit, kind, journal := classifyDatabaseFile(path)
+	if unit == "" {
+		return
+	}
+
+	db := stats.Databases[unit]
+	if db == nil {
+		db = &Database{Path: unit, Kind: kind, Confirmed: kind != "leveldb"}
+		stats.Databases[unit] = db
+	}
+	db.Files++
+	if journal {
+		db.JournalBytes += info.Size()
+	} else {
+		db.DataBytes += info.Size()
+	}
+	if strings.HasPrefix(filepath.Base(path), "MANIFEST-") {
+		db.Confirmed = true
+	}
+}
+
+func mergeDatabases(dst, src map[string]*Database) {
+	for unit, db := range src {
+		if dst[unit] == nil {
+			dst[unit] = &Database{Path: db.Path, Kind: db.Kind}
+		}
+		dst[unit].Files += db.Files
+		dst[unit].DataBytes += db.DataBytes
+		dst[unit].JournalBytes += db.JournalBytes
+		dst[unit].Confirmed = dst[unit].Confirmed || db.Confirmed
+	}
+}
+
+func displayDatabases(stats *Stats, maxCount int, result *strings.Builder) {
+	var databases []*Database
+	for _, db := range stats.Databases {
+		// A journal without its data file is left over from a deleted database
+		// or a false match on the file name
+		if db.Confirmed && db.DataBytes > 0 {
+			databases = append(databases, db)
+		}
+	}
+	if len(databases) == 0 {
+		return
+	}
+	sort.Slice(databases, func(i, j int) bool {
+		if databases[i].Total() != databases[j].Total() {
+			return databases[i].Total() > databases[j].Total()
+		}
+		return databases[i].Path < databases[j].Path
+	})
+
+	result.WriteString(headerStyle.Render(tr("Databases")))
+	result.WriteString("\n")
+	for _, db := range databases[:min(maxCount, len(databases))] {
+		journal := numberStyle.Render(formatMB(db.JournalBytes))
+		if db.LargeJournal() {
+			journal = badStyle.Render(formatMB(db.JournalBytes) + tr(" large journal"))
+		}
+		result.WriteString(fmt.Sprintf(tr("%s %-9s data %s, journal %s: %s\n"),
+			numberStyle.Render(fmt.Sprintf("%10s", formatMB(db.Total()))),
+			db.Kind,
+			numberStyle.Render(formatMB(db.DataBytes)),
+			journal,
+			renderPath(db.Path)))
+	}
+	result.WriteString("\n")
+}
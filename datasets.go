@@ -0,0 +1,181 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Kinds of files datasets are made of. Labels only count towards image
+// datasets; on their own they are just text files.
+const (
+	dsImages = iota
+	dsLabels
+	dsShards
+	dsCheckpoints
+	dsOther
+	dsKinds
+)
+
+var datasetExts = map[string]int{
+	".jpg": dsImages, ".jpeg": dsImages, ".png": dsImages, ".bmp": dsImages,
+	".tif": dsImages, ".tiff": dsImages, ".webp": dsImages, ".dcm": dsImages,
+	".txt": dsLabels, ".xml": dsLabels, ".json": dsLabels, ".csv": dsLabels,
+	".npz": dsShards, ".npy": dsShards, ".parquet": dsShards, ".tfrecord": dsShards,
+	".tfrecords": dsShards, ".arrow": dsShards, ".h5": dsShards, ".hdf5": dsShards,
+	".ckpt": dsCheckpoints, ".pt": dsCheckpoints, ".pth": dsCheckpoints, ".safetensors": dsCheckpoints,
+}
+
+// A tree is a dataset when files of one kind make up datasetShare of it
+// (of the files for images, of the bytes otherwise) and there are at least
+// the minimum number of them.
+const (
+	datasetShare          = 0.9
+	datasetMinImages      = 1000
+	datasetMinShards      = 8
+	datasetMinCheckpoints = 3
+)
+
+// DatasetCounts are the files and bytes of each kind in one directory.
+type DatasetCounts struct {
+	Files [dsKinds]int
+	Bytes [dsKinds]int64
+}
+
+// Dataset is a directory tree reported as one unit of accounting instead
+// of the many files in it.
+type Dataset struct {
+	Path  string
+	Kind  string
+	Files int
+	Size  int64
+}
+
+func analyzeDatasetFile(path, ext string, size int64, stats *Stats) {
+	kind, ok := datasetExts[ext]
+	if !ok {
+		kind = dsOther
+	}
+	dir := filepath.Dir(path)
+	counts := stats.DatasetDirs[dir]
+	if counts == nil {
+		counts = &DatasetCounts{}
+		stats.DatasetDirs[dir] = counts
+	}
+	counts.Files[kind]++
+	counts.Bytes[kind] += size
+}
+
+func mergeDatasetDirs(dst, src map[string]*DatasetCounts) {
+	for dir, counts := range src {
+		if dst[dir] == nil {
+			dst[dir] = &DatasetCounts{}
+		}
+		for kind := range dsKinds {
+			dst[dir].Files[kind] += counts.Files[kind]
+			dst[dir].Bytes[kind] += counts.Bytes[kind]
+		}
+	}
+}
+
+// datasetKind tells what kind of dataset a tree is, or "" if it isn't one.
+func datasetKind(c *DatasetCounts) string {
+	var files int
+	var bytes int64
+	for kind := range dsKinds {
+		files += c.Files[kind]
+		bytes += c.Bytes[kind]
+	}
+	mostBytes := func(kind int) bool {
+		return float64(c.Bytes[kind]) >= datasetShare*float64(bytes)
+	}
+	switch {
+	case c.Files[dsCheckpoints] >= datasetMinCheckpoints && mostBytes(dsCheckpoints):
+		return "checkpoints"
+	case c.Files[dsShards] >= datasetMinShards && mostBytes(dsShards):
+		return "shards"
+	case c.Files[dsImages] >= datasetMinImages && float64(c.Files[dsImages]+c.Files[dsLabels]) >= datasetShare*float64(files):
+		return "images"
+	}
+	return ""
+}
+
+// findDatasets returns the outermost directory trees that are datasets,
+// largest first.
+func findDatasets(stats *Stats) []Dataset {
+	trees := make(map[string]*DatasetCounts, len(stats.DirDepths))
+	for dir := range stats.DirDepths {
+		trees[dir] = &DatasetCounts{}
+	}
+	for dir, counts := range stats.DatasetDirs {
+		for p := dir; ; p = filepath.Dir(p) {
+			tree, ok := trees[p]
+			if !ok {
+				break
+			}
+			for kind := range dsKinds {
+				tree.Files[kind] += counts.Files[kind]
+				tree.Bytes[kind] += counts.Bytes[kind]
+			}
+		}
+	}
+
+	found := make(map[string]Dataset)
+	for dir, tree := range trees {
+		kind := datasetKind(tree)
+		if kind == "" {
+			continue
+		}
+		d := Dataset{Path: dir, Kind: kind}
+		for k := range dsKinds {
+			d.Files += tree.Files[k]
+			d.Size += tree.Bytes[k]
+		}
+		found[dir] = d
+	}
+
+	var datasets []Dataset
+	for dir, d := range found {
+		// A dataset's splits and subfolders are part of it
+		if _, ok := found[filepath.Dir(dir)]; ok {
+			continue
+		}
+		datasets = append(datasets, d)
+	}
+	sort.Slice(datasets, func(i, j int) bool {
+		if datasets[i].Size != datasets[j].Size {
+			return datasets[i].Size > datasets[j].Size
+		}
+		return datasets[i].Path < datasets[j].Path
+	})
+	return datasets
+}
+
+func displayDatasets(stats *Stats, maxCount int, result *strings.Builder) {
+	datasets := findDatasets(stats)
+	if len(datasets) == 0 {
+		return
+	}
+
+	var files int
+	var bytes int64
+	for _, d := range datasets {
+		files += d.Files
+		bytes += d.Size
+	}
+	result.WriteString(headerStyle.Render(tr("Datasets")))
+	result.WriteString("\n")
+	result.WriteString(fmt.Sprintf(tr("%s datasets with %s files, %s\n"),
+		numberStyle.Render(formatCount(len(datasets))),
+		numberStyle.Render(formatCount(files)),
+		numberStyle.Render(formatMB(bytes))))
+	for _, d := range datasets[:min(maxCount, len(datasets))] {
+		result.WriteString(fmt.Sprintf(tr("%s %s files %s %s\n"),
+			numberStyle.Render(fmt.Sprintf("%11s", formatMB(d.Size))),
+			numberStyle.Render(fmt.Sprintf("%9s", formatCount(d.Files))),
+			warnStyle.Render(fmt.Sprintf("%-12s", tr(d.Kind))),
+			renderPath(d.Path)))
+	}
+	result.WriteString("\n")
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// maxDepthRows is how many depths the report lists; deeper files are summed
+// in the last row.
+const maxDepthRows = 20
+
+// DepthStats sums the files at one depth below the scanned directory.
+type DepthStats struct {
+	Files int
+	Bytes int64
+}
+
+// processDepth counts the file by its depth below root: 0 for files
+// directly in it, 1 for those in its subdirectories, and so on.
+func processDepth(path string, info os.FileInfo, stats *Stats, root string) {
+	rel, err := filepath.Rel(root, path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return
+	}
+	depth := strings.Count(rel, string(os.PathSeparator))
+
+	stats.mu.Lock()
+	defer stats.mu.Unlock()
+	d := stats.Depths[depth]
+	if d == nil {
+		d = &DepthStats{}
+		stats.Depths[depth] = d
+	}
+	d.Files++
+	d.Bytes += info.Size()
+}
+
+func mergeDepths(dst, src map[int]*DepthStats) {
+	for depth, d := range src {
+		if dst[depth] == nil {
+			dst[depth] = &DepthStats{}
+		}
+		dst[depth].Files += d.Files
+		dst[depth].Bytes += d.Bytes
+	}
+}
+
+// displayDepths shows how the bytes are spread over the depths of the tree,
+// with the share at each depth or deeper, which tells a flat tree from a
+// deeply nested one.
+func displayDepths(stats *Stats, maxCount int, result *strings.Builder) {
+	if len(stats.Depths) == 0 {
+		return
+	}
+	depths := make([]int, 0, len(stats.Depths))
+	var total int64
+	for depth, d := range stats.Depths {
+		depths = append(depths, depth)
+		total += d.Bytes
+	}
+	sort.Ints(depths)
+
+	result.WriteString(headerStyle.Render(tr("Size by Depth")))
+	result.WriteString("\n")
+	share := func(bytes int64) float64 {
+		if total == 0 {
+			return 0
+		}
+		return float64(bytes) / float64(total) * 100
+	}
+
+	// deeper[i] is the bytes at depths[i] or below
+	deeper := make([]int64, len(depths))
+	var sum int64
+	for i := len(depths) - 1; i >= 0; i-- {
+		sum += stats.Depths[depths[i]].Bytes
+		deeper[i] = sum
+	}
+	// The median depth: half of the data is at least this deep
+	median := depths[0]
+	for i, depth := range depths {
+		if deeper[i]*2 >= total {
+			median = depth
+		}
+	}
+	result.WriteString(fmt.Sprintf(tr("Half of the data lies %s or more levels deep, the deepest files %s levels\n"),
+		numberStyle.Render(fmt.Sprint(median)),
+		numberStyle.Render(fmt.Sprint(depths[len(depths)-1]))))
+
+	for i, depth := range depths {
+		d := *stats.Depths[depth]
+		label := fmt.Sprint(depth)
+		if depth >= maxDepthRows {
+			// Sum up the rest
+			for _, deep := range depths[i+1:] {
+				d.Files += stats.Depths[deep].Files
+				d.Bytes += stats.Depths[deep].Bytes
+			}
+			label = fmt.Sprintf("%d+", depth)
+		}
+		result.WriteString(fmt.Sprintf("  %4s %s %s %s %s\n",
+			label,
+			numberStyle.Render(fmt.Sprintf("%9s", formatCount(d.Files))),
+			numberStyle.Render(fmt.Sprintf("%14s", formatMB(d.Bytes))),
+			percentStyle.Render(fmt.Sprintf("%8s", "("+formatPercent(share(d.Bytes))+")")),
+			fmt.Sprintf(tr("%s at this depth or deeper"), formatPercent(share(deeper[i])))))
+		if depth >= maxDepthRows {
+			break
+		}
+	}
+	result.WriteString("\n")
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// devEnvAge is how long a developer environment must have gone unused to be
+// reclaimable. Set by --env-days.
+var devEnvAge = 90 * 24 * time.Hour
+
+// devEnvMarkers recognize developer environments by a file they always
+// contain. The marker is matched against the end of the file's path, one
+// glob per path component; the environment is the directory root
+// components above the file.
+var devEnvMarkers = []struct {
+	marker string
+	root   int
+	kind   string
+}{
+	{"pyvenv.cfg", 1, "Python virtualenv"},
+	{"conda-meta/history", 2, "conda environment"},
+	{"node_modules/.package-lock.json", 1, "node_modules"},
+	{".rustc_info.json", 1, "Rust build output"},
+	{"mod/cache/lock", 2, "Go module cache"},
+	{"go-build/trim.txt", 1, "Go build cache"},
+	{".cargo/registry/cache/*/*.crate", 3, "cargo registry"},
+	{".gradle/caches/modules-2/modules-2.lock", 2, "Gradle cache"},
+	{"toolchains/*/lib/rustlib/components", 3, "Rust toolchain"},
+	{"pkg/tool/*/compile", 3, "Go SDK"},
+	{"licenses/android-sdk-license", 1, "Android SDK"},
+	{"lib/jrt-fs.jar", 2, "JDK"},
+}
+
+// analyzeDevEnv records the environment the file marks, if it is a marker.
+func analyzeDevEnv(path string, stats *Stats) {
+	name := filepath.Base(path)
+	var components []string
+	for _, m := range devEnvMarkers {
+		// Most files fail on their name, before the path is split
+		if ok, _ := filepath.Match(m.marker[strings.LastIndex(m.marker, "/")+1:], name); !ok {
+			continue
+		}
+		if components == nil {
+			components = strings.Split(filepath.ToSlash(path), "/")
+		}
+		parts := strings.Split(m.marker, "/")
+		if len(parts) >= len(components) {
+			continue
+		}
+		tail := components[len(components)-len(parts):]
+		matched := true
+		for i, part := range parts {
+			if ok, _ := filepath.Match(part, tail[i]); !ok {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			root := path
+			for range m.root {
+				root = filepath.Dir(root)
+			}
+			stats.DevEnvs[root] = m.kind
+			return
+		}
+	}
+}
+
+func mergeDevEnvs(dst, src map[string]string) {
+	for dir, kind := range src {
+		dst[dir] = kind
+	}
+}
+
+// devEnv is a developer environment reported as one unit, with the size of
+// its tree and the last time any of its files was used.
+type devEnv struct {
+	Path     string
+	Kind     string
+	Size     int64
+	Files    int
+	LastUsed time.Time
+}
+
+// unused reports whether the environment is reclaimable.
+func (e devEnv) unused() bool {
+	return time.Since(e.LastUsed) > devEnvAge
+}
+
+// devEnvs returns the outermost developer environments, largest first. An
+// environment inside another one, like a Go SDK in the module cache, is part
+// of it.
+func devEnvs(stats *Stats) []devEnv {
+	if len(stats.DevEnvs) == 0 {
+		return nil
+	}
+	trees := dirTrees(stats)
+
+	var envs []devEnv
+	for dir, kind := range stats.DevEnvs {
+		tree := trees[dir]
+		if tree == nil || tree.Files == 0 {
+			continue
+		}
+		nested := false
+		for p := filepath.Dir(dir); trees[p] != nil; p = filepath.Dir(p) {
+			if _, ok := stats.DevEnvs[p]; ok {
+				nested = true
+				break
+			}
+		}
+		if !nested {
+			envs = append(envs, devEnv{Path: dir, Kind: kind, Size: tree.Size, Files: tree.Files, LastUsed: tree.LastUsed})
+		}
+	}
+	sort.Slice(envs, func(i, j int) bool {
+		if envs[i].Size != envs[j].Size {
+			return envs[i].Size > envs[j].Size
+		}
+		return envs[i].Path < envs[j].Path
+	})
+	return envs
+}
+
+// unusedDevEnvBytes are the bytes of the environments unused for devEnvAge.
+func unusedDevEnvBytes(stats *Stats) int64 {
+	var bytes int64
+	for _, env := range devEnvs(stats) {
+		if env.unused() {
+			bytes += env.Size
+		}
+	}
+	return bytes
+}
+
+func displayDevEnvs(stats *Stats, maxCount int, result *strings.Builder) {
+	envs := devEnvs(stats)
+	if len(envs) == 0 {
+		return
+	}
+
+	var total, unused int64
+	for _, env := range envs {
+		total += env.Size
+		if env.unused() {
+			unused += env.Size
+		}
+	}
+	result.WriteString(headerStyle.Render(tr("Developer Environments")))
+	result.WriteString("\n")
+	result.WriteString(fmt.Sprintf(tr("%s environments, %s; unused for %d days and reclaimable: %s\n"),
+		numberStyle.Render(formatCount(len(envs))),
+		numberStyle.Render(formatMB(total)),
+		int(devEnvAge.Hours()/24),
+		badStyle.Render(formatMB(unused))))
+	for _, env := range envs[:min(maxCount, len(envs))] {
+		used := goodStyle.Render(formatDate(env.LastUsed))
+		if env.unused() {
+			used = warnStyle.Render(formatDate(env.LastUsed))
+		}
+		result.WriteString(fmt.Sprintf(tr("%s %-20s last used %s  %s\n"),
+			numberStyle.Render(fmt.Sprintf("%11s", formatMB(env.Size))),
+			tr(env.Kind),
+			used,
+			renderPath(env.Path)))
+	}
+	result.WriteString("\n")
+}
@@ -0,0 +1,577 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// FileChange describes one file that differs between two snapshots. Path is
+// relative to the snapshot root.
+type FileChange struct {
+	Path       string
+	OldSize    int64
+	NewSize    int64
+	OldModTime time.Time
+	NewModTime time.Time
+}
+
+func (c FileChange) Delta() int64 {
+	return c.NewSize - c.OldSize
+}
+
+type SnapshotDiff struct {
+	Root        string
+	OldCreated  time.Time
+	NewCreated  time.Time
+	OldLabel    string
+	NewLabel    string
+	Added       []FileChange
+	Removed     []FileChange
+	Changed     []FileChange
+	AddedDirs   int
+	RemovedDirs int
+	Drift       []PermissionDrift
+	// Stale are the files that went stale between the snapshots: not
+	// modified for staleAfter when the newer one was taken, but not when the
+	// older one was, or not there at all
+	Stale []FileChange
+	// Moved are the removed files that were added again under another
+	// path, recognized by their content hash; they are in neither Added
+	// nor Removed
+	Moved []FileMove
+	// Compact is set when the baseline was a compact snapshot: Added are
+	// then the files it hasn't seen, modified ones included, and nothing is
+	// known about removed files
+	Compact bool
+}
+
+// PermissionDrift is a file added to a directory whose files used to share
+// one mode, with a different mode: the sign of a service running with the
+// wrong umask. Norm is the usual mode and Files the baseline files with it.
+type PermissionDrift struct {
+	Path  string
+	Mode  os.FileMode
+	Norm  os.FileMode
+	Files int
+}
+
+// Looser reports whether the file grants permissions the norm doesn't.
+func (d PermissionDrift) Looser() bool {
+	return d.Mode&^d.Norm != 0
+}
+
+// driftNormShare is the share of its regular files, at least permMinFiles,
+// that must have one mode for a directory to have a norm.
+const driftNormShare = 0.9
+
+// permissionNorm returns the mode most regular files in the listing share
+// and how many do, or ok false if they don't agree enough.
+func permissionNorm(listing *dirCacheEntry) (mode os.FileMode, files int, ok bool) {
+	modes := make(map[os.FileMode]int)
+	total := 0
+	for _, f := range listing.Files {
+		if f.Mode.IsRegular() {
+			modes[f.Mode.Perm()]++
+			total++
+		}
+	}
+	for m, count := range modes {
+		if count > files || count == files && m < mode {
+			mode, files = m, count
+		}
+	}
+	return mode, files, total >= permMinFiles && float64(files) >= driftNormShare*float64(total)
+}
+
+// relativeDirs re-keys the snapshot's directories relative to its root so
+// that snapshots of the same tree taken via different paths still line up.
+func relativeDirs(snap *Snapshot) map[string]*dirCacheEntry {
+	dirs := make(map[string]*dirCacheEntry, len(snap.Dirs))
+	for path, listing := range snap.Dirs {
+		rel, err := filepath.Rel(snap.Root, path)
+		if err != nil {
+			rel = path
+		}
+		dirs[rel] = listing
+	}
+	return dirs
+}
+
+// DiffOptions leave immaterial changes out of a diff. Ignore takes the same
+// expressions as --filter and is matched against the file's full path.
+type DiffOptions struct {
+	Ignore    *Filter
+	MinChange int64
+}
+
+// keep reports whether change, made to file f below root, is material.
+func (o DiffOptions) keep(root string, change FileChange, f *cachedFile) bool {
+	if absInt64(change.Delta()) < o.MinChange {
+		return false
+	}
+	return o.Ignore == nil || !o.Ignore.Match(filepath.Join(root, change.Path), cachedFileInfo{f})
+}
+
+// diffIgnoreFilter combines the ignore rules from the [diff] config section
+// with the ones given on the command line. Either may be empty.
+func diffIgnoreFilter(expr string) (*Filter, error) {
+	switch {
+	case diffIgnore != "" && expr != "":
+		expr = "(" + diffIgnore + ") || (" + expr + ")"
+	case expr == "":
+		expr = diffIgnore
+	}
+	if expr == "" {
+		return nil, nil
+	}
+	return ParseFilter(expr)
+}
+
+func diffSnapshots(old, cur *Snapshot, opts DiffOptions) *SnapshotDiff {
+	diff := &SnapshotDiff{
+		Root:       cur.Root,
+		OldCreated: old.Created,
+		NewCreated: cur.Created,
+		OldLabel:   old.Label,
+		NewLabel:   cur.Label,
+	}
+	// The hashes of the added and removed files, by path, to find moves
+	hashes := make(map[*[]FileChange]map[string]string)
+	add := func(changes *[]FileChange, change FileChange, f cachedFile) {
+		if !opts.keep(cur.Root, change, &f) {
+			return
+		}
+		*changes = append(*changes, change)
+		if len(f.Hash) > 0 && f.Size > 0 {
+			if hashes[changes] == nil {
+				hashes[changes] = make(map[string]string)
+			}
+			hashes[changes][change.Path] = string(f.Hash)
+		}
+	}
+
+	oldDirs := relativeDirs(old)
+	newDirs := relativeDirs(cur)
+
+	if old.compact() {
+		diff.Compact = true
+		for dir, listing := range newDirs {
+			for _, f := range listing.Files {
+				path := filepath.Join(dir, f.Name)
+				if !old.Seen.has(path, &f) {
+					add(&diff.Added, FileChange{Path: path, NewSize: f.Size, NewModTime: f.ModTime}, f)
+				}
+			}
+		}
+		sortDiff(diff)
+		return diff
+	}
+
+	for dir, newListing := range newDirs {
+		oldListing, ok := oldDirs[dir]
+		if !ok {
+			diff.AddedDirs++
+			for _, f := range newListing.Files {
+				add(&diff.Added, FileChange{Path: filepath.Join(dir, f.Name), NewSize: f.Size, NewModTime: f.ModTime}, f)
+			}
+			continue
+		}
+		// Listings reused from the baseline are unchanged by construction
+		if oldListing == newListing {
+			continue
+		}
+
+		oldFiles := make(map[string]cachedFile, len(oldListing.Files))
+		for _, f := range oldListing.Files {
+			oldFiles[f.Name] = f
+		}
+		norm, normFiles, hasNorm := permissionNorm(oldListing)
+		for _, f := range newListing.Files {
+			path := filepath.Join(dir, f.Name)
+			before, existed := oldFiles[f.Name]
+			delete(oldFiles, f.Name)
+			switch {
+			case !existed:
+				add(&diff.Added, FileChange{Path: path, NewSize: f.Size, NewModTime: f.ModTime}, f)
+				if hasNorm && f.Mode.IsRegular() && f.Mode.Perm() != norm &&
+					(opts.Ignore == nil || !opts.Ignore.Match(filepath.Join(cur.Root, path), cachedFileInfo{&f})) {
+					diff.Drift = append(diff.Drift, PermissionDrift{path, f.Mode.Perm(), norm, normFiles})
+				}
+			case before.Size != f.Size || !before.ModTime.Equal(f.ModTime):
+				add(&diff.Changed, FileChange{Path: path, OldSize: before.Size, NewSize: f.Size, OldModTime: before.ModTime, NewModTime: f.ModTime}, f)
+			}
+		}
+		for _, f := range oldFiles {
+			add(&diff.Removed, FileChange{Path: filepath.Join(dir, f.Name), OldSize: f.Size, OldModTime: f.ModTime}, f)
+		}
+	}
+
+	for dir, oldListing := range oldDirs {
+		if _, ok := newDirs[dir]; ok {
+			continue
+		}
+		diff.RemovedDirs++
+		for _, f := range oldListing.Files {
+			add(&diff.Removed, FileChange{Path: filepath.Join(dir, f.Name), OldSize: f.Size, OldModTime: f.ModTime}, f)
+		}
+	}
+
+	diff.Stale = newlyStale(oldDirs, newDirs, old.Created, cur.Created, cur.Root, opts)
+	sortDiff(diff)
+	detectMoves(diff, hashes[&diff.Added], hashes[&diff.Removed])
+	return diff
+}
+
+// newlyStale returns the files of newDirs stale at now that weren't stale
+// at then, or weren't in oldDirs.
+func newlyStale(oldDirs, newDirs map[string]*dirCacheEntry, then, now time.Time, root string, opts DiffOptions) []FileChange {
+	var stale []FileChange
+	for dir, listing := range newDirs {
+		var before map[string]cachedFile
+		if oldListing := oldDirs[dir]; oldListing != nil {
+			before = make(map[string]cachedFile, len(oldListing.Files))
+			for _, f := range oldListing.Files {
+				before[f.Name] = f
+			}
+		}
+		for _, f := range listing.Files {
+			if !f.Mode.IsRegular() || now.Sub(f.ModTime) <= staleAfter {
+				continue
+			}
+			if old, ok := before[f.Name]; ok && then.Sub(old.ModTime) > staleAfter {
+				continue
+			}
+			path := filepath.Join(dir, f.Name)
+			if opts.Ignore != nil && opts.Ignore.Match(filepath.Join(root, path), cachedFileInfo{&f}) {
+				continue
+			}
+			stale = append(stale, FileChange{Path: path, OldSize: f.Size, NewSize: f.Size, OldModTime: f.ModTime, NewModTime: f.ModTime})
+		}
+	}
+	return stale
+}
+
+// sortDiff orders the changes largest first and the drift by severity.
+func sortDiff(diff *SnapshotDiff) {
+	sort.Slice(diff.Added, func(i, j int) bool {
+		if diff.Added[i].NewSize != diff.Added[j].NewSize {
+			return diff.Added[i].NewSize > diff.Added[j].NewSize
+		}
+		return diff.Added[i].Path < diff.Added[j].Path
+	})
+	sort.Slice(diff.Removed, func(i, j int) bool {
+		if diff.Removed[i].OldSize != diff.Removed[j].OldSize {
+			return diff.Removed[i].OldSize > diff.Removed[j].OldSize
+		}
+		return diff.Removed[i].Path < diff.Removed[j].Path
+	})
+	sort.Slice(diff.Changed, func(i, j int) bool {
+		if absInt64(diff.Changed[i].Delta()) != absInt64(diff.Changed[j].Delta()) {
+			return absInt64(diff.Changed[i].Delta()) > absInt64(diff.Changed[j].Delta())
+		}
+		return diff.Changed[i].Path < diff.Changed[j].Path
+	})
+	sort.Slice(diff.Stale, func(i, j int) bool {
+		if diff.Stale[i].NewSize != diff.Stale[j].NewSize {
+			return diff.Stale[i].NewSize > diff.Stale[j].NewSize
+		}
+		return diff.Stale[i].Path < diff.Stale[j].Path
+	})
+	// Loosened permissions first, world-writable files before the rest
+	sort.Slice(diff.Drift, func(i, j int) bool {
+		a, b := diff.Drift[i], diff.Drift[j]
+		if a.Looser() != b.Looser() {
+			return a.Looser()
+		}
+		if wa, wb := a.Mode&0002 != 0, b.Mode&0002 != 0; wa != wb {
+			return wa
+		}
+		return a.Path < b.Path
+	})
+}
+
+func absInt64(n int64) int64 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+func sumChanges(changes []FileChange) int64 {
+	var total int64
+	for _, c := range changes {
+		total += c.Delta()
+	}
+	return total
+}
+
+func formatDeltaMB(delta int64) string {
+	sign := "+"
+	if delta < 0 {
+		sign = ""
+	}
+	return sign + formatMB(delta)
+}
+
+func deltaStyle(delta int64) string {
+	text := formatDeltaMB(delta)
+	if delta > 0 {
+		return warnStyle.Render(text)
+	}
+	return goodStyle.Render(text)
+}
+
+func displayDiff(diff *SnapshotDiff, maxCount int) string {
+	var result strings.Builder
+
+	writeTitle(tr("MADAA - Change Report"), &result)
+
+	result.WriteString(headerStyle.Render(tr("Overview")))
+	result.WriteString("\n")
+	result.WriteString(fmt.Sprintf(tr("Root: %s\n"), renderPath(diff.Root)))
+	result.WriteString(fmt.Sprintf(tr("Baseline: %s  Now: %s\n"),
+		snapshotTitle(diff.OldCreated, diff.OldLabel),
+		snapshotTitle(diff.NewCreated, diff.NewLabel)))
+	if diff.Compact {
+		result.WriteString(fmt.Sprintf(tr("New files: %s %s, not seen by the compact baseline\n"),
+			numberStyle.Render(formatCount(len(diff.Added))),
+			deltaStyle(sumChanges(diff.Added))))
+		result.WriteString(warnStyle.Render(fmt.Sprintf(tr("Modified files count as new; removed files aren't known, and about %s of the new files are missed"), formatPercent(bloomFalsePositives*100))))
+		result.WriteString("\n\n")
+		displayDiffBreakdown(diff, maxCount, &result)
+		displayChanges(tr("Largest New Files"), diff.Added, maxCount, &result)
+		return result.String()
+	}
+	result.WriteString(fmt.Sprintf(tr("Added: %s files %s  Removed: %s files %s  Changed: %s files %s\n"),
+		numberStyle.Render(formatCount(len(diff.Added))),
+		deltaStyle(sumChanges(diff.Added)),
+		numberStyle.Render(formatCount(len(diff.Removed))),
+		deltaStyle(sumChanges(diff.Removed)),
+		numberStyle.Render(formatCount(len(diff.Changed))),
+		deltaStyle(sumChanges(diff.Changed))))
+	if len(diff.Moved) > 0 {
+		var moved int64
+		for _, move := range diff.Moved {
+			moved += move.Size
+		}
+		result.WriteString(fmt.Sprintf(tr("Moved or renamed: %s files %s\n"),
+			numberStyle.Render(formatCount(len(diff.Moved))),
+			numberStyle.Render(formatMB(moved))))
+	}
+	result.WriteString(fmt.Sprintf(tr("Directories added: %s  removed: %s\n"),
+		numberStyle.Render(formatCount(diff.AddedDirs)),
+		numberStyle.Render(formatCount(diff.RemovedDirs))))
+	net := sumChanges(diff.Added) + sumChanges(diff.Removed) + sumChanges(diff.Changed)
+	result.WriteString(fmt.Sprintf(tr("Net change: %s\n\n"), deltaStyle(net)))
+
+	displayDiffBreakdown(diff, maxCount, &result)
+	displayPermissionDrift(diff, maxCount, &result)
+	displayChanges(tr("Largest Added Files"), diff.Added, maxCount, &result)
+	displayChanges(tr("Largest Removed Files"), diff.Removed, maxCount, &result)
+	displayMoves(diff, maxCount, &result)
+	displayChanges(tr("Largest Changes"), diff.Changed, maxCount, &result)
+	displayNewlyStale(diff, maxCount, &result)
+
+	return result.String()
+}
+
+// snapshotTitle shows when a snapshot was taken and its label, if any.
+func snapshotTitle(created time.Time, label string) string {
+	title := goodStyle.Render(formatDateTime(created))
+	if label != "" {
+		title += " " + warnStyle.Render("["+label+"]")
+	}
+	return title
+}
+
+func displayChanges(title string, changes []FileChange, maxCount int, result *strings.Builder) {
+	if len(changes) == 0 {
+		return
+	}
+	result.WriteString(headerStyle.Render(title))
+	result.WriteString("\n")
+	for _, change := range changes[:min(maxCount, len(changes))] {
+		result.WriteString(fmt.Sprintf("  %s %s\n",
+			deltaStyle(change.Delta()),
+			renderPath(change.Path)))
+	}
+	result.WriteString("\n")
+}
+
+// displayPermissionDrift lists the added files whose mode differs from the
+// one the other files in their directory have.
+func displayPermissionDrift(diff *SnapshotDiff, maxCount int, result *strings.Builder) {
+	if len(diff.Drift) == 0 {
+		return
+	}
+	result.WriteString(headerStyle.Render(tr("Permission Drift")))
+	result.WriteString("\n")
+	result.WriteString(fmt.Sprintf(tr("%s new files differ from the permissions of their directory\n"),
+		warnStyle.Render(formatCount(len(diff.Drift)))))
+	for _, d := range diff.Drift[:min(maxCount, len(diff.Drift))] {
+		mode := fmt.Sprintf("%04o", d.Mode)
+		if d.Looser() {
+			mode = badStyle.Render(mode)
+		}
+		result.WriteString(fmt.Sprintf(tr("  %s (usually %04o, %s files)  %s\n"),
+			mode, d.Norm, formatCount(d.Files), renderPath(d.Path)))
+	}
+	result.WriteString("\n")
+}
+
+// displayNewlyStale lists the largest files that went stale since the
+// baseline.
+func displayNewlyStale(diff *SnapshotDiff, maxCount int, result *strings.Builder) {
+	if len(diff.Stale) == 0 {
+		return
+	}
+	var size int64
+	for _, change := range diff.Stale {
+		size += change.NewSize
+	}
+	result.WriteString(headerStyle.Render(tr("Newly Stale Files")))
+	result.WriteString("\n")
+	result.WriteString(fmt.Sprintf(tr("%s files (%s) went stale since the baseline, unmodified for 6 months\n"),
+		warnStyle.Render(formatCount(len(diff.Stale))),
+		numberStyle.Render(formatMB(size))))
+	for _, change := range diff.Stale[:min(maxCount, len(diff.Stale))] {
+		result.WriteString(fmt.Sprintf("  %s %s  %s\n",
+			numberStyle.Render(fmt.Sprintf("%11s", formatMB(change.NewSize))),
+			goodStyle.Render(formatDate(change.NewModTime)),
+			renderPath(change.Path)))
+	}
+	result.WriteString("\n")
+}
+
+type deltaGroup struct {
+	Name  string
+	Delta int64
+}
+
+// sortedDeltas orders the groups by the size of their change, growth and
+// shrinkage alike.
+func sortedDeltas(deltas map[string]int64) []deltaGroup {
+	groups := make([]deltaGroup, 0, len(deltas))
+	for name, delta := range deltas {
+		if delta != 0 {
+			groups = append(groups, deltaGroup{name, delta})
+		}
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		if absInt64(groups[i].Delta) != absInt64(groups[j].Delta) {
+			return absInt64(groups[i].Delta) > absInt64(groups[j].Delta)
+		}
+		return groups[i].Name < groups[j].Name
+	})
+	return groups
+}
+
+// displayDiffBreakdown attributes the net change to file categories, their
+// extensions and the directories the changed files are in.
+func displayDiffBreakdown(diff *SnapshotDiff, maxCount int, result *strings.Builder) {
+	categories := make(map[string]int64)
+	exts := make(map[string]map[string]int64)
+	dirs := make(map[string]int64)
+
+	for _, changes := range [][]FileChange{diff.Added, diff.Removed, diff.Changed} {
+		for _, change := range changes {
+			ext := strings.ToLower(filepath.Ext(change.Path))
+			if ext == "" {
+				ext = "no extension"
+			}
+			category := fileTypeCategoryMap[ext]
+			if category == "" {
+				category = "other"
+			}
+
+			categories[category] += change.Delta()
+			if exts[category] == nil {
+				exts[category] = make(map[string]int64)
+			}
+			exts[category][ext] += change.Delta()
+			dirs[filepath.Dir(change.Path)] += change.Delta()
+		}
+	}
+	if len(categories) == 0 {
+		return
+	}
+
+	result.WriteString(headerStyle.Render(tr("Change by Category")))
+	result.WriteString("\n")
+	for _, category := range sortedDeltas(categories)[:min(maxCount, len(categories))] {
+		line := fmt.Sprintf("  %-10s %s", tr(category.Name), deltaStyle(category.Delta))
+		if top := sortedDeltas(exts[category.Name]); len(top) > 0 {
+			line += fmt.Sprintf(tr(", of which %s %s"), tr(top[0].Name), deltaStyle(top[0].Delta))
+		}
+		result.WriteString(line + "\n")
+	}
+	result.WriteString("\n")
+
+	topDirs := sortedDeltas(dirs)
+	result.WriteString(headerStyle.Render(tr("Top Contributing Directories")))
+	result.WriteString("\n")
+	for _, dir := range topDirs[:min(maxCount, len(topDirs))] {
+		result.WriteString(fmt.Sprintf("  %s %s\n",
+			deltaStyle(dir.Delta),
+			renderPath(dir.Name)))
+	}
+	result.WriteString("\n")
+}
+
+// writeFileLists writes the diff as file lists that rsync --files-from (or
+// --from0 with sep 0) can take with the snapshot root as source:
+// PREFIX-copy.txt holds added, changed and the new paths of moved files,
+// PREFIX-delete.txt removed files and the old paths of moved ones, and
+// PREFIX-changed.txt only the changed files.
+func writeFileLists(diff *SnapshotDiff, prefix string, sep byte) error {
+	// A sync copies moved files to their new path and deletes the old one
+	var movedTo, movedFrom []FileChange
+	for _, move := range diff.Moved {
+		movedTo = append(movedTo, FileChange{Path: move.To})
+		movedFrom = append(movedFrom, FileChange{Path: move.From})
+	}
+	lists := []struct {
+		suffix  string
+		changes [][]FileChange
+	}{
+		{"-copy.txt", [][]FileChange{diff.Added, diff.Changed, movedTo}},
+		{"-delete.txt", [][]FileChange{diff.Removed, movedFrom}},
+		{"-changed.txt", [][]FileChange{diff.Changed}},
+	}
+
+	for _, list := range lists {
+		var paths []string
+		for _, changes := range list.changes {
+			for _, change := range changes {
+				paths = append(paths, change.Path)
+			}
+		}
+		sort.Strings(paths)
+		if err := writePathList(prefix+list.suffix, paths, sep); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writePathList(path string, paths []string, sep byte) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	for _, p := range paths {
+		w.WriteString(p)
+		w.WriteByte(sep)
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// DirTotals are the files and bytes of a directory, either directly in it
+// or in the whole tree below it.
+type DirTotals struct {
+	Files int
+	Size  int64
+}
+
+// dirNode is a directory of the --browse directory view. It keeps both
+// aggregates, so switching between them with r needs no recomputation.
+type dirNode struct {
+	Path      string
+	Direct    DirTotals
+	Recursive DirTotals
+	Children  []string
+}
+
+// Totals returns the recursive or the direct aggregate.
+func (n *dirNode) Totals(recursive bool) DirTotals {
+	if recursive {
+		return n.Recursive
+	}
+	return n.Direct
+}
+
+// dirNodes builds the directory tree below root from the files counted per
+// directory, rolling them up like dirTrees does.
+func dirNodes(stats *Stats, root string) map[string]*dirNode {
+	root = filepath.Clean(root)
+	nodes := map[string]*dirNode{root: {Path: root}}
+	for dir := range stats.DirDepths {
+		nodes[dir] = &dirNode{Path: dir}
+	}
+	for dir := range stats.DirDepths {
+		if parent := nodes[filepath.Dir(dir)]; parent != nil {
+			parent.Children = append(parent.Children, dir)
+		}
+	}
+	for dir, activity := range stats.DirActivity {
+		if node := nodes[dir]; node != nil {
+			node.Direct = DirTotals{activity.Files, activity.Size}
+		}
+		for p := dir; ; p = filepath.Dir(p) {
+			node := nodes[p]
+			if node == nil {
+				break
+			}
+			node.Recursive.Files += activity.Files
+			node.Recursive.Size += activity.Size
+			if p == root {
+				break
+			}
+		}
+	}
+	return nodes
+}
+
+// sortedChildren lists the subdirectories of node, largest first by the
+// aggregate shown.
+func sortedChildren(nodes map[string]*dirNode, node *dirNode, recursive bool) []*dirNode {
+	children := make([]*dirNode, 0, len(node.Children))
+	for _, child := range node.Children {
+		children = append(children, nodes[child])
+	}
+	sort.Slice(children, func(i, j int) bool {
+		a, b := children[i].Totals(recursive), children[j].Totals(recursive)
+		if a.Size != b.Size {
+			return a.Size > b.Size
+		}
+		return children[i].Path < children[j].Path
+	})
+	return children
+}
+
+func dirModeLabel(recursive bool) string {
+	if recursive {
+		return tr("recursive")
+	}
+	return tr("direct children only")
+}
+
+func (m model) updateDirs(key tea.KeyMsg) (tea.Model, tea.Cmd) {
+	node := m.dirs[m.dirPath]
+	children := sortedChildren(m.dirs, node, m.recursive)
+	switch key.String() {
+	case "q", "ctrl+c":
+		m.browsing = false
+		return m, tea.Quit
+	case "up", "k":
+		m.dirCursor = max(m.dirCursor-1, 0)
+	case "down", "j":
+		m.dirCursor = max(min(m.dirCursor+1, len(children)-1), 0)
+	case "enter", "right", "l":
+		if len(children) > 0 {
+			m.dirPath, m.dirCursor = children[m.dirCursor].Path, 0
+		}
+	case "backspace", "left", "h", "esc":
+		if m.dirPath == filepath.Clean(m.config.Path) {
+			return m, nil
+		}
+		parent := filepath.Dir(m.dirPath)
+		siblings := sortedChildren(m.dirs, m.dirs[parent], m.recursive)
+		m.dirCursor = 0
+		for i, sibling := range siblings {
+			if sibling.Path == m.dirPath {
+				m.dirCursor = i
+			}
+		}
+		m.dirPath = parent
+	case "x":
+		// Mark or unmark the selected directory as deleted in the simulation
+		if len(children) > 0 {
+			if m.deleted == nil {
+				m.deleted = make(map[string]bool)
+			}
+			if dir := children[m.dirCursor].Path; m.deleted[dir] {
+				delete(m.deleted, dir)
+			} else {
+				m.deleted[dir] = true
+			}
+		}
+	case "X":
+		m.deleted = nil
+	case "r":
+		// Keep the selected directory selected in the other order
+		var selected string
+		if len(children) > 0 {
+			selected = children[m.dirCursor].Path
+		}
+		m.recursive = !m.recursive
+		for i, child := range sortedChildren(m.dirs, node, m.recursive) {
+			if child.Path == selected {
+				m.dirCursor = i
+			}
+		}
+	}
+	return m, nil
+}
+
+// dirsView lists the subdirectories of the current directory with their
+// size and file count, direct or recursive, less the directories marked
+// deleted, and the impact of deleting them.
+func (m model) dirsView() string {
+	node := m.dirs[m.dirPath]
+	children := sortedChildren(m.dirs, node, m.recursive)
+	totals := m.remainingTotals(node)
+
+	var result strings.Builder
+	result.WriteString(headerStyle.Render(fmt.Sprintf(tr("Directories (%s)"), dirModeLabel(m.recursive))))
+	result.WriteString("\n")
+	result.WriteString(fmt.Sprintf("  %s %s %s\n",
+		numberStyle.Render(fmt.Sprintf("%11s", formatMB(totals.Size))),
+		numberStyle.Render(fmt.Sprintf("%9s", formatCount(totals.Files))),
+		renderPath(node.Path)))
+	first := max(0, min(m.dirCursor-typePageSize/2, len(children)-typePageSize))
+	for i := first; i < min(first+typePageSize, len(children)); i++ {
+		child := children[i]
+		marker := "  "
+		if i == m.dirCursor {
+			marker = "> "
+		}
+		name := pathStyle.Render(displayName(filepath.Base(child.Path)) + string(filepath.Separator))
+		if m.deleted[child.Path] {
+			totals := child.Totals(m.recursive)
+			result.WriteString(fmt.Sprintf("%s%s %s %s %s\n",
+				marker,
+				badStyle.Render(fmt.Sprintf("%11s", formatMB(totals.Size))),
+				badStyle.Render(fmt.Sprintf("%9s", formatCount(totals.Files))),
+				name,
+				badStyle.Render(tr("(deleted)"))))
+			continue
+		}
+		totals := m.remainingTotals(child)
+		result.WriteString(fmt.Sprintf("%s%s %s %s\n",
+			marker,
+			numberStyle.Render(fmt.Sprintf("%11s", formatMB(totals.Size))),
+			numberStyle.Render(fmt.Sprintf("%9s", formatCount(totals.Files))),
+			name))
+	}
+	result.WriteString("\n")
+	result.WriteString(m.simulationView())
+	return result.String()
+}
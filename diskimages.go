@@ -0,0 +1,164 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+var diskImageExts = map[string]string{
+	".vmdk":  "vmdk",
+	".qcow2": "qcow2",
+	".vdi":   "vdi",
+	".vhdx":  "vhdx",
+	".vhd":   "vhd",
+}
+
+// DiskImage is a VM disk found during the scan. Allocated is what the file
+// takes on disk, Virtual the size the guest sees and Used the part of it the
+// image format reports as written. Fields the format doesn't tell are zero.
+type DiskImage struct {
+	Path      string
+	Format    string
+	Size      int64
+	Allocated int64
+	Virtual   int64
+	Used      int64
+}
+
+// diskImageFormat returns the image format of the file, or "" if it isn't a
+// disk image. Docker Desktop keeps all containers in one raw image.
+func diskImageFormat(path string) string {
+	if filepath.Base(path) == "Docker.raw" {
+		return "raw"
+	}
+	return diskImageExts[strings.ToLower(filepath.Ext(path))]
+}
+
+// processDiskImage records disk images and reads their headers without
+// holding the stats lock.
+func processDiskImage(path string, info os.FileInfo, stats *Stats) {
+	format := diskImageFormat(path)
+	if format == "" || !info.Mode().IsRegular() {
+		return
+	}
+
+	image := DiskImage{Path: path, Format: format, Size: info.Size()}
+	if allocated, ok := allocatedSize(info); ok {
+		image.Allocated = allocated
+	}
+	if f, err := os.Open(path); err == nil {
+		readDiskImageHeader(f, &image)
+		f.Close()
+	}
+
+	stats.mu.Lock()
+	defer stats.mu.Unlock()
+	stats.DiskImages = append(stats.DiskImages, image)
+}
+
+// readDiskImageHeader fills in the virtual and used size from the image
+// header for the formats that keep them there.
+func readDiskImageHeader(r io.ReaderAt, image *DiskImage) {
+	header := make([]byte, 512)
+	n, _ := r.ReadAt(header, 0)
+	header = header[:n]
+
+	switch image.Format {
+	case "qcow2":
+		// Magic "QFI\xfb", virtual size as big endian uint64 at offset 24
+		if len(header) >= 32 && string(header[:4]) == "QFI\xfb" {
+			image.Virtual = int64(binary.BigEndian.Uint64(header[24:32]))
+		}
+	case "vmdk":
+		// Hosted sparse extent: magic "KDMV", capacity in sectors at offset 12
+		if len(header) >= 20 && string(header[:4]) == "KDMV" {
+			image.Virtual = int64(binary.LittleEndian.Uint64(header[12:20])) * 512
+		}
+	case "vdi":
+		// Signature at 0x40, then disk size, block size and the number of
+		// allocated blocks in the version 1.1 header
+		if len(header) >= 0x188 && binary.LittleEndian.Uint32(header[0x40:]) == 0xbeda107f {
+			image.Virtual = int64(binary.LittleEndian.Uint64(header[0x170:]))
+			blockSize := int64(binary.LittleEndian.Uint32(header[0x178:]))
+			image.Used = int64(binary.LittleEndian.Uint32(header[0x184:])) * blockSize
+		}
+	}
+}
+
+// containerVolumes returns the Docker and Podman volume directories found in
+// the scan with the size of their contents.
+func containerVolumes(stats *Stats) []dirSummary {
+	var volumes []dirSummary
+	for dir, tree := range dirTrees(stats) {
+		parent := filepath.Dir(dir)
+		if filepath.Base(parent) != "volumes" {
+			continue
+		}
+		switch filepath.Base(filepath.Dir(parent)) {
+		case "docker", "storage":
+			volumes = append(volumes, dirSummary{Path: dir, Size: tree.Size, LastUsed: tree.LastUsed})
+		}
+	}
+	sort.Slice(volumes, func(i, j int) bool {
+		if volumes[i].Size != volumes[j].Size {
+			return volumes[i].Size > volumes[j].Size
+		}
+		return volumes[i].Path < volumes[j].Path
+	})
+	return volumes
+}
+
+func formatOptionalMB(size int64) string {
+	if size == 0 {
+		return "-"
+	}
+	return formatMB(size)
+}
+
+func displayDiskImages(stats *Stats, maxCount int, result *strings.Builder) {
+	images := make([]DiskImage, len(stats.DiskImages))
+	copy(images, stats.DiskImages)
+	sort.Slice(images, func(i, j int) bool {
+		if images[i].Size != images[j].Size {
+			return images[i].Size > images[j].Size
+		}
+		return images[i].Path < images[j].Path
+	})
+
+	volumes := containerVolumes(stats)
+	if len(images) == 0 && len(volumes) == 0 {
+		return
+	}
+
+	result.WriteString(headerStyle.Render(tr("Disk Images and Volumes")))
+	result.WriteString("\n")
+
+	if len(images) > 0 {
+		result.WriteString(fmt.Sprintf("%-6s %12s %12s %12s %12s  %s\n", tr("Format"), tr("Apparent"), tr("Allocated"), tr("Virtual"), tr("Used"), tr("Path")))
+		for _, image := range images[:min(maxCount, len(images))] {
+			result.WriteString(fmt.Sprintf("%-6s %s %s %s %s  %s\n",
+				image.Format,
+				numberStyle.Render(fmt.Sprintf("%12s", formatMB(image.Size))),
+				numberStyle.Render(fmt.Sprintf("%12s", formatOptionalMB(image.Allocated))),
+				numberStyle.Render(fmt.Sprintf("%12s", formatOptionalMB(image.Virtual))),
+				goodStyle.Render(fmt.Sprintf("%12s", formatOptionalMB(image.Used))),
+				renderPath(image.Path)))
+		}
+		result.WriteString("\n")
+	}
+
+	if len(volumes) > 0 {
+		result.WriteString(tr("Container volumes:\n"))
+		for _, volume := range volumes[:min(maxCount, len(volumes))] {
+			result.WriteString(fmt.Sprintf("  %s %s\n",
+				numberStyle.Render(fmt.Sprintf("%10s", formatMB(volume.Size))),
+				renderPath(volume.Path)))
+		}
+		result.WriteString("\n")
+	}
+}
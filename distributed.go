@@ -0,0 +1,415 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/rpc"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Assignment is a subtree handed to an agent. An empty Path with Done unset
+// means every remaining subtree is being worked on and the agent should ask
+// again later.
+type Assignment struct {
+	ID     int
+	Root   string
+	Path   string
+	Count  int
+	Filter string
+	Done   bool
+}
+
+// PartialResult carries the statistics an agent collected for one
+// assignment back to the coordinator.
+type PartialResult struct {
+	ID    int
+	Agent string
+	Stats *Stats
+	Err   string
+}
+
+// Coordinator is the RPC service agents talk to. It hands out the
+// subdirectories of the scan root and merges the partial statistics.
+type Coordinator struct {
+	mu       sync.Mutex
+	count    int
+	lease    time.Duration
+	live     bool
+	pending  []Assignment
+	issued   map[int]time.Time
+	byID     map[int]Assignment
+	done     map[int]bool
+	failed   []string
+	stats    *Stats
+	finished chan struct{}
+}
+
+// Next hands out the next unscanned subtree. Subtrees whose agent hasn't
+// reported back within the lease are handed out again.
+func (c *Coordinator) Next(agent string, reply *Assignment) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.done) == len(c.byID) {
+		*reply = Assignment{Done: true}
+		return nil
+	}
+
+	if len(c.pending) == 0 {
+		for id, issued := range c.issued {
+			if !c.done[id] && time.Since(issued) > c.lease {
+				fmt.Printf("Lease expired for %s, reassigning\n", c.byID[id].Path)
+				c.pending = append(c.pending, c.byID[id])
+			}
+		}
+	}
+	if len(c.pending) == 0 {
+		*reply = Assignment{}
+		return nil
+	}
+
+	*reply = c.pending[0]
+	c.pending = c.pending[1:]
+	c.issued[reply.ID] = time.Now()
+	fmt.Printf("Assigned %s to %s\n", reply.Path, agent)
+	return nil
+}
+
+// Submit merges an agent's result. Late duplicates of an already reported
+// subtree are ignored.
+func (c *Coordinator) Submit(result PartialResult, reply *bool) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	assignment, ok := c.byID[result.ID]
+	if !ok {
+		return fmt.Errorf("unknown assignment %d", result.ID)
+	}
+	*reply = true
+	if c.done[result.ID] {
+		return nil
+	}
+	c.done[result.ID] = true
+
+	if result.Err != "" || result.Stats == nil {
+		c.failed = append(c.failed, fmt.Sprintf("%s (%s: %s)", assignment.Path, result.Agent, result.Err))
+		fmt.Printf("[%d/%d] %s failed on %s: %s\n", len(c.done), len(c.byID), assignment.Path, result.Agent, result.Err)
+	} else {
+		mergeStats(c.stats, result.Stats, c.count)
+		fmt.Printf("[%d/%d] %s scanned by %s (%d files), merged so far: %s files, %s\n", len(c.done), len(c.byID), assignment.Path, result.Agent, result.Stats.TotalFiles, formatCount(c.stats.TotalFiles), formatMB(c.stats.TotalSize))
+		if c.live && len(c.done) < len(c.byID) {
+			fmt.Println(fitPaths(displayResults(c.stats, c.count), pathWidth))
+		}
+	}
+
+	if len(c.done) == len(c.byID) {
+		close(c.finished)
+	}
+	return nil
+}
+
+// runCoordinator implements "madaa coordinator": the root's own files are
+// scanned locally, every subdirectory becomes an assignment for the agents.
+func runCoordinator(args []string) {
+	flags := flag.NewFlagSet("coordinator", flag.ExitOnError)
+	listen := flags.String("listen", "127.0.0.1:7070", "Address to accept agents on")
+	token := flags.String("token", "", "Token agents must send (default: $MADAA_COORDINATOR_TOKEN)")
+	certFile := flags.String("tls-cert", "", "TLS certificate file, required off loopback")
+	keyFile := flags.String("tls-key", "", "TLS key file of --tls-cert")
+	count := flags.Int("count", 3, "Number of top files to show")
+	filterExpr := flags.String("filter", "", "Only analyze files matching the expression")
+	lease := flags.Duration("lease", 30*time.Minute, "Reassign a subtree if its agent hasn't reported back after this long")
+	live := flags.Bool("live", false, "Print the merged report each time an agent reports, not only at the end")
+	flags.Parse(args)
+
+	if flags.NArg() < 1 {
+		fmt.Println("Usage: madaa coordinator [--listen ADDR] [--token TOKEN] [--tls-cert FILE --tls-key FILE] [--count N] [--filter EXPR] [--lease D] [--live] <path>")
+		os.Exit(1)
+	}
+	if *token == "" {
+		*token = os.Getenv("MADAA_COORDINATOR_TOKEN")
+	}
+	if *token == "" && !isLoopback(*listen) {
+		fmt.Println("Error: accepting agents on a non-loopback address needs a token, see --token")
+		os.Exit(1)
+	}
+	tlsConfig, err := serverTLS(*listen, *certFile, *keyFile)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	if err := loadConfig(); err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+
+	config := Config{Count: *count, Path: filepath.Clean(flags.Arg(0))}
+	if *filterExpr != "" {
+		filter, err := ParseFilter(*filterExpr)
+		if err != nil {
+			fmt.Printf("Error parsing filter: %v\n", err)
+			os.Exit(1)
+		}
+		config.Filter = filter
+	}
+
+	entries, err := os.ReadDir(config.Path)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	coordinator := &Coordinator{
+		count:    *count,
+		lease:    *lease,
+		live:     *live,
+		issued:   make(map[int]time.Time),
+		byID:     make(map[int]Assignment),
+		done:     make(map[int]bool),
+		finished: make(chan struct{}),
+	}
+
+	// The root directory and its files are scanned here, subdirectories by
+	// the agents
+	config.Files = []string{config.Path}
+	for _, entry := range entries {
+		path := filepath.Join(config.Path, entry.Name())
+		if !entry.IsDir() {
+			config.Files = append(config.Files, path)
+			continue
+		}
+		assignment := Assignment{ID: len(coordinator.byID) + 1, Root: config.Path, Path: path, Count: *count, Filter: *filterExpr}
+		coordinator.pending = append(coordinator.pending, assignment)
+		coordinator.byID[assignment.ID] = assignment
+	}
+
+	stats, err := analyzeFileList(context.Background(), config, newEventBus())
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	coordinator.stats = stats
+	if len(coordinator.byID) == 0 {
+		close(coordinator.finished)
+	}
+
+	server := rpc.NewServer()
+	if err := server.Register(coordinator); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	listener, err := net.Listen("tcp", *listen)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	if tlsConfig != nil {
+		listener = tls.NewListener(listener, tlsConfig)
+	}
+	go acceptAgents(server, listener, *token)
+
+	fmt.Printf("Coordinating %d subtrees of %s on %s\n", len(coordinator.byID), config.Path, listener.Addr())
+	<-coordinator.finished
+
+	// Give agents a moment to receive their final Done before shutting down
+	time.Sleep(time.Second)
+	listener.Close()
+
+	fmt.Println()
+	fmt.Println(fitPaths(displayResults(coordinator.stats, *count), pathWidth))
+	if len(coordinator.failed) > 0 {
+		sort.Strings(coordinator.failed)
+		fmt.Printf("Failed subtrees:\n  %s\n", strings.Join(coordinator.failed, "\n  "))
+		os.Exit(1)
+	}
+}
+
+// acceptAgents serves the coordinator to the agents connecting to listener.
+// With a token, connections that don't start with it are closed.
+func acceptAgents(server *rpc.Server, listener net.Listener, token string) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			if token != "" {
+				authed, err := authenticate(conn, token)
+				if err != nil {
+					fmt.Printf("Rejected agent: %v\n", err)
+					conn.Close()
+					return
+				}
+				conn = authed
+			}
+			server.ServeConn(conn)
+		}()
+	}
+}
+
+// tokenConn is a connection whose first line, the token, was read through
+// r; the RPC traffic continues from what r has buffered.
+type tokenConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (c tokenConn) Read(p []byte) (int, error) {
+	return c.r.Read(p)
+}
+
+// authenticate checks the token an agent sends as the first line of the
+// connection, before any RPC.
+func authenticate(conn net.Conn, token string) (net.Conn, error) {
+	conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+	r := bufio.NewReader(conn)
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, fmt.Errorf("%s: no token: %w", conn.RemoteAddr(), err)
+	}
+	if subtle.ConstantTimeCompare([]byte(strings.TrimRight(line, "\r\n")), []byte(token)) != 1 {
+		return nil, fmt.Errorf("%s: wrong token", conn.RemoteAddr())
+	}
+	conn.SetReadDeadline(time.Time{})
+	return tokenConn{conn, r}, nil
+}
+
+// dialCoordinator connects to the coordinator at addr and sends the token.
+// Coordinators on other hosts are reached over TLS, verified against the
+// certificates in caFile or else the system's.
+func dialCoordinator(addr, token, caFile string) (*rpc.Client, error) {
+	var conn net.Conn
+	var err error
+	if isLoopback(addr) && caFile == "" {
+		conn, err = net.Dial("tcp", addr)
+	} else {
+		config := &tls.Config{MinVersion: tls.VersionTLS12}
+		if caFile != "" {
+			pem, err := os.ReadFile(caFile)
+			if err != nil {
+				return nil, err
+			}
+			config.RootCAs = x509.NewCertPool()
+			if !config.RootCAs.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("%s: no certificates", caFile)
+			}
+		}
+		conn, err = tls.Dial("tcp", addr, config)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if token != "" {
+		if _, err := fmt.Fprintf(conn, "%s\n", token); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return rpc.NewClient(conn), nil
+}
+
+// runAgent implements "madaa agent": it keeps asking the coordinator for
+// subtrees, scans them locally and sends back the statistics. Paths are
+// opened as given by the coordinator, so all hosts need the same mounts.
+func runAgent(args []string) {
+	flags := flag.NewFlagSet("agent", flag.ExitOnError)
+	addr := flags.String("coordinator", "", "Address of the coordinator, e.g. host:7070")
+	name := flags.String("name", "", "Agent name shown by the coordinator (default: hostname)")
+	token := flags.String("token", "", "Token of the coordinator (default: $MADAA_COORDINATOR_TOKEN)")
+	caFile := flags.String("tls-ca", "", "CA certificates to verify the coordinator with (default: the system's)")
+	flags.Parse(args)
+
+	if *addr == "" {
+		fmt.Println("Usage: madaa agent --coordinator HOST:PORT [--token TOKEN] [--tls-ca FILE] [--name NAME]")
+		os.Exit(1)
+	}
+	if *token == "" {
+		*token = os.Getenv("MADAA_COORDINATOR_TOKEN")
+	}
+	if *name == "" {
+		*name, _ = os.Hostname()
+	}
+	if err := loadConfig(); err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+
+	client, err := dialCoordinator(*addr, *token, *caFile)
+	if err != nil {
+		fmt.Printf("Error connecting to coordinator: %v\n", err)
+		os.Exit(1)
+	}
+	defer client.Close()
+
+	for {
+		var assignment Assignment
+		if err := client.Call("Coordinator.Next", *name, &assignment); err != nil {
+			if errors.Is(err, rpc.ErrShutdown) {
+				return
+			}
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		if assignment.Done {
+			return
+		}
+		if assignment.Path == "" {
+			time.Sleep(5 * time.Second)
+			continue
+		}
+
+		fmt.Printf("Scanning %s\n", assignment.Path)
+		result := PartialResult{ID: assignment.ID, Agent: *name}
+		stats, err := scanAssignment(assignment)
+		if err != nil {
+			result.Err = err.Error()
+		} else {
+			result.Stats = stats
+		}
+
+		var ok bool
+		if err := client.Call("Coordinator.Submit", result, &ok); err != nil {
+			fmt.Printf("Error sending result: %v\n", err)
+			os.Exit(1)
+		}
+	}
+}
+
+func scanAssignment(assignment Assignment) (*Stats, error) {
+	config := Config{Count: assignment.Count, Path: assignment.Path, SkipMounts: mountSkips(assignment.Path)}
+	if assignment.Filter != "" {
+		filter, err := ParseFilter(assignment.Filter)
+		if err != nil {
+			return nil, err
+		}
+		config.Filter = filter
+	}
+
+	stats, err := analyzeDirectory(context.Background(), config, newEventBus())
+	if err != nil {
+		return nil, err
+	}
+
+	// Depths were counted from the subtree; make them relative to the
+	// coordinator's root like a local scan would
+	depths := make(map[string]int, len(stats.DirDepths)+1)
+	for path := range stats.DirDepths {
+		rel, _ := filepath.Rel(assignment.Root, path)
+		depths[path] = strings.Count(rel, string(os.PathSeparator))
+	}
+	depths[assignment.Path] = 0
+	stats.DirDepths = depths
+	return stats, nil
+}
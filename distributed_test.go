@@ -0,0 +1,132 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/rpc"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// selfSigned writes a certificate for 127.0.0.1 and its key, and returns
+// their files.
+func selfSigned(t *testing.T) (certFile, keyFile string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "madaa test"},
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	certFile, keyFile = filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+// coordinate serves a coordinator without subtrees, which tells every agent
+// it is done.
+func coordinate(t *testing.T, listener net.Listener, token string) {
+	t.Helper()
+	server := rpc.NewServer()
+	coordinator := &Coordinator{byID: make(map[int]Assignment), done: make(map[int]bool)}
+	if err := server.Register(coordinator); err != nil {
+		t.Fatal(err)
+	}
+	go acceptAgents(server, listener, token)
+	t.Cleanup(func() { listener.Close() })
+}
+
+func TestAgentToken(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	coordinate(t, listener, "secret")
+
+	for token, ok := range map[string]bool{"secret": true, "guess": false} {
+		client, err := dialCoordinator(listener.Addr().String(), token, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		var assignment Assignment
+		err = client.Call("Coordinator.Next", "agent", &assignment)
+		if (err == nil) != ok || (ok && !assignment.Done) {
+			t.Errorf("token %q: got %v, %v", token, assignment, err)
+		}
+		client.Close()
+	}
+}
+
+func TestAgentTLS(t *testing.T) {
+	certFile, keyFile := selfSigned(t)
+	config, err := serverTLS("127.0.0.1:0", certFile, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	coordinate(t, tls.NewListener(listener, config), "secret")
+
+	client, err := dialCoordinator(listener.Addr().String(), "secret", certFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	var assignment Assignment
+	if err := client.Call("Coordinator.Next", "agent", &assignment); err != nil || !assignment.Done {
+		t.Errorf("call over TLS: %v, %v", assignment, err)
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	for token, ok := range map[string]bool{"secret\n": true, "secret\r\n": true, "guess\n": false} {
+		client, conn := net.Pipe()
+		go client.Write([]byte(token + "rest"))
+		authed, err := authenticate(conn, "secret")
+		if (err == nil) != ok {
+			t.Errorf("token %q: got %v", token, err)
+		}
+		if err == nil {
+			rest := make([]byte, 4)
+			if _, err := authed.Read(rest); err != nil || string(rest) != "rest" {
+				t.Errorf("token %q: read %q after the token: %v", token, rest, err)
+			}
+		}
+		client.Close()
+		conn.Close()
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// A directory is dominated by an extension that accounts for dominantShare
+// of its bytes. Directories below dominantMinSize are left out, a handful
+// of files is trivially dominated by one type.
+const (
+	dominantShare   = 0.9
+	dominantMinSize = 100 * 1024 * 1024
+)
+
+// dominantDir is a directory tree that is effectively all one file type,
+// like a folder of .bam files: a dataset or an archival candidate.
+type dominantDir struct {
+	Path    string
+	Ext     string
+	Size    int64
+	ExtSize int64
+}
+
+func (d dominantDir) Share() float64 {
+	return float64(d.ExtSize) / float64(d.Size) * 100
+}
+
+// analyzeDirTypes adds the file to the bytes per extension of its directory.
+func analyzeDirTypes(path, ext string, size int64, stats *Stats) {
+	dir := filepath.Dir(path)
+	types := stats.DirTypes[dir]
+	if types == nil {
+		types = make(map[string]int64)
+		stats.DirTypes[dir] = types
+	}
+	types[ext] += size
+}
+
+func mergeDirTypes(dst, src map[string]map[string]int64) {
+	for dir, types := range src {
+		if dst[dir] == nil {
+			dst[dir] = make(map[string]int64)
+		}
+		for ext, size := range types {
+			dst[dir][ext] += size
+		}
+	}
+}
+
+// dominantDirs returns the outermost directories one extension dominates,
+// largest first. The bytes per extension are rolled up like dirTrees does,
+// so a dataset spread over subdirectories is found as a whole.
+func dominantDirs(stats *Stats) []dominantDir {
+	trees := make(map[string]map[string]int64, len(stats.DirDepths))
+	for dir := range stats.DirDepths {
+		trees[dir] = make(map[string]int64)
+	}
+	for dir, types := range stats.DirTypes {
+		for p := dir; ; p = filepath.Dir(p) {
+			tree, ok := trees[p]
+			if !ok {
+				break
+			}
+			for ext, size := range types {
+				tree[ext] += size
+			}
+		}
+	}
+
+	dominated := make(map[string]dominantDir)
+	for dir, tree := range trees {
+		d := dominantDir{Path: dir}
+		for ext, size := range tree {
+			d.Size += size
+			if size > d.ExtSize || size == d.ExtSize && ext < d.Ext {
+				d.Ext, d.ExtSize = ext, size
+			}
+		}
+		if d.Size >= dominantMinSize && float64(d.ExtSize) >= dominantShare*float64(d.Size) {
+			dominated[dir] = d
+		}
+	}
+
+	var dirs []dominantDir
+	for dir, d := range dominated {
+		// Only report the top of a tree of one type, not each subdirectory
+		if parent, ok := dominated[filepath.Dir(dir)]; ok && parent.Ext == d.Ext {
+			continue
+		}
+		dirs = append(dirs, d)
+	}
+	sort.Slice(dirs, func(i, j int) bool {
+		if dirs[i].Size != dirs[j].Size {
+			return dirs[i].Size > dirs[j].Size
+		}
+		return dirs[i].Path < dirs[j].Path
+	})
+	return dirs
+}
+
+func displayDominantDirs(stats *Stats, maxCount int, result *strings.Builder) {
+	dirs := dominantDirs(stats)
+	if len(dirs) == 0 {
+		return
+	}
+
+	result.WriteString(headerStyle.Render(fmt.Sprintf(tr("Single-Type Directories (%s of the bytes)"), formatPercent(dominantShare*100))))
+	result.WriteString("\n")
+	for _, dir := range dirs[:min(maxCount, len(dirs))] {
+		result.WriteString(fmt.Sprintf("%s %s %s %s\n",
+			numberStyle.Render(fmt.Sprintf("%11s", formatMB(dir.Size))),
+			getFileTypeStyle(dir.Ext).Render(fmt.Sprintf("%-12s", tr(dir.Ext))),
+			percentStyle.Render(fmt.Sprintf("%6s", formatPercent(dir.Share()))),
+			renderPath(dir.Path)))
+	}
+	result.WriteString("\n")
+}
@@ -0,0 +1,395 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// dupHeadSize is how much of each file is hashed before same-size files are
+// hashed in full, so most files of equal size but different content are
+// told apart after reading their first block.
+const dupHeadSize = 64 * 1024
+
+// Strategies pick the copy of a duplicate group that is kept.
+var dupStrategies = []string{"newest", "oldest", "shortest-path"}
+
+// Actions replace the other copies of a duplicate group.
+var dupActions = []string{"delete", "hardlink", "reflink", "symlink"}
+
+type dupFile struct {
+	Path    string
+	ModTime time.Time
+}
+
+// dupGroup is a set of files with the same content, whose SHA-256 is Sum.
+// Keep is the index of the file that is kept, the others are redundant.
+type dupGroup struct {
+	Size  int64
+	Sum   [sha256.Size]byte
+	Files []dupFile
+	Keep  int
+}
+
+// Redundant is the space taken by the copies beyond the first.
+func (g *dupGroup) Redundant() int64 {
+	return g.Size * int64(len(g.Files)-1)
+}
+
+// findDuplicates groups the files matching config by content. Hardlinks to
+// a file already seen are left out, they take no extra space.
+func findDuplicates(config Config, minSize int64) ([]*dupGroup, error) {
+	bySize := make(map[int64][]dupFile)
+	seen := make(map[fileID]bool)
+	err := walkMatchingFiles(config, func(path string, info os.FileInfo) {
+		if !info.Mode().IsRegular() || info.Size() < max(minSize, 1) {
+			return
+		}
+		if id, links, ok := fileIdentity(info); ok && links > 1 {
+			if seen[id] {
+				return
+			}
+			seen[id] = true
+		}
+		bySize[info.Size()] = append(bySize[info.Size()], dupFile{path, info.ModTime()})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return duplicateGroups(bySize), nil
+}
+
+// duplicateGroups finds the files with the same content among files of the
+// same size: by a hash of their first block, then by a hash of their whole
+// content. Groups are ordered by the space they waste.
+func duplicateGroups(bySize map[int64][]dupFile) []*dupGroup {
+	var groups []*dupGroup
+	for size, files := range bySize {
+		if len(files) < 2 {
+			continue
+		}
+		for sum, heads := range groupByHash(files, dupHeadSize) {
+			contents := map[[sha256.Size]byte][]dupFile{sum: heads}
+			if size > dupHeadSize {
+				contents = groupByHash(heads, -1)
+			}
+			for sum, same := range contents {
+				groups = append(groups, &dupGroup{Size: size, Sum: sum, Files: same})
+			}
+		}
+	}
+	for _, g := range groups {
+		sort.Slice(g.Files, func(i, j int) bool { return g.Files[i].Path < g.Files[j].Path })
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].Redundant() != groups[j].Redundant() {
+			return groups[i].Redundant() > groups[j].Redundant()
+		}
+		return groups[i].Files[0].Path < groups[j].Files[0].Path
+	})
+	return groups
+}
+
+// groupByHash splits files by the hash of their first limit bytes, or of
+// all of them for a negative limit. Groups of one and unreadable files are
+// dropped.
+func groupByHash(files []dupFile, limit int64) map[[sha256.Size]byte][]dupFile {
+	byHash := make(map[[sha256.Size]byte][]dupFile)
+	for _, f := range files {
+		sum, err := hashFile(f.Path, limit)
+		if err != nil {
+			continue
+		}
+		byHash[sum] = append(byHash[sum], f)
+	}
+	for sum, same := range byHash {
+		if len(same) < 2 {
+			delete(byHash, sum)
+		}
+	}
+	return byHash
+}
+
+func hashFile(path string, limit int64) ([sha256.Size]byte, error) {
+	var sum [sha256.Size]byte
+	f, err := os.Open(path)
+	if err != nil {
+		return sum, err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if limit >= 0 {
+		r = io.LimitReader(f, limit)
+	}
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return sum, err
+	}
+	copy(sum[:], h.Sum(nil))
+	return sum, nil
+}
+
+// applyStrategy selects the file to keep. Ties go to the first path.
+func applyStrategy(g *dupGroup, strategy string) {
+	better := func(a, b dupFile) bool {
+		switch strategy {
+		case "oldest":
+			return a.ModTime.Before(b.ModTime)
+		case "shortest-path":
+			return len(a.Path) < len(b.Path)
+		default:
+			return a.ModTime.After(b.ModTime)
+		}
+	}
+	g.Keep = 0
+	for i, f := range g.Files {
+		if better(f, g.Files[g.Keep]) {
+			g.Keep = i
+		}
+	}
+}
+
+// Reasons a redundant file is not replaced.
+var (
+	errDupChanged     = errors.New("changed since it was compared")
+	errKeptChanged    = errors.New("the kept file changed since it was compared")
+	errDupOtherDevice = errors.New("on another file system than the kept file")
+	errDupOtherOwner  = errors.New("other owner or permissions than the kept file")
+)
+
+// checkKept makes sure the kept file of the group is still the one that
+// was compared, by its size, mtime and content, before any other copy is
+// replaced. Otherwise the group is skipped: replacing the copies of a file
+// that changed or is gone would lose its content.
+func checkKept(g *dupGroup) error {
+	keep := g.Files[g.Keep]
+	info, err := os.Lstat(keep.Path)
+	if err != nil || !info.Mode().IsRegular() || info.Size() != g.Size || !info.ModTime().Equal(keep.ModTime) {
+		return errDupChanged
+	}
+	if sum, err := hashFile(keep.Path, -1); err != nil || sum != g.Sum {
+		return errDupChanged
+	}
+	return nil
+}
+
+// checkReplace tells whether file i of the group can be replaced with
+// action, and returns its current state. Hardlinks and reflinks need the
+// kept file on the same file system; a hardlink also shares the owner and
+// permissions of the kept file, and a reflink is owned by whoever creates
+// it, so both are only made where these are the same.
+func checkReplace(g *dupGroup, i int, action string) (os.FileInfo, error) {
+	f := g.Files[i]
+	if why := protectedReason(f.Path); why != "" {
+		return nil, errors.New(why)
+	}
+	info, err := os.Lstat(f.Path)
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() != g.Size || !info.ModTime().Equal(f.ModTime) {
+		return nil, errDupChanged
+	}
+	if action != "hardlink" && action != "reflink" {
+		return info, nil
+	}
+
+	keepInfo, err := os.Lstat(g.Files[g.Keep].Path)
+	if err != nil {
+		return nil, err
+	}
+	id, _, ok := fileIdentity(info)
+	keepID, _, keepOK := fileIdentity(keepInfo)
+	if ok && keepOK && id.Dev != keepID.Dev {
+		return nil, errDupOtherDevice
+	}
+	uid, gid, ok := fileOwnerIDs(info)
+	keepUID, keepGID, keepOK := fileOwnerIDs(keepInfo)
+	if ok && keepOK && (uid != keepUID || gid != keepGID) {
+		return nil, errDupOtherOwner
+	}
+	if action == "hardlink" && info.Mode().Perm() != keepInfo.Mode().Perm() {
+		return nil, errDupOtherOwner
+	}
+	return info, nil
+}
+
+// freedBytes is the space replacing the file frees: its allocated size,
+// unless other hardlinks keep its data around.
+func freedBytes(info os.FileInfo) int64 {
+	if _, links, ok := fileIdentity(info); ok && links > 1 {
+		return 0
+	}
+	if allocated, ok := allocatedSize(info); ok {
+		return allocated
+	}
+	return info.Size()
+}
+
+// createTemp creates a new file next to path with create, under a name no
+// file has yet, and returns that name. create must fail with fs.ErrExist if
+// the name is taken, so nothing that was there before is ever touched.
+func createTemp(path string, create func(tmp string) error) (string, error) {
+	dir, base := filepath.Split(path)
+	for {
+		suffix := make([]byte, 6)
+		rand.Read(suffix)
+		tmp := filepath.Join(dir, "."+base+".madaa-"+hex.EncodeToString(suffix))
+		err := create(tmp)
+		if errors.Is(err, fs.ErrExist) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		return tmp, nil
+	}
+}
+
+// resolveGroup applies action to every file of the group but the kept one,
+// skipping the files checkReplace rejects and those whose content changed
+// since the comparison. Links and reflinks replace the file atomically, so
+// an interrupted run never leaves a path missing, and reflinks keep the
+// permissions and mtime of the file they replace. Deleted files are moved
+// away with trash, so the deletion can be undone.
+func resolveGroup(g *dupGroup, action string, trash func(path string, info os.FileInfo) error) (resolved int, reclaimed int64, errs []error) {
+	keep := g.Files[g.Keep]
+	if err := checkKept(g); err != nil {
+		for i, f := range g.Files {
+			if i != g.Keep {
+				errs = append(errs, fmt.Errorf("%s: kept file %s %w, skipped", f.Path, keep.Path, err))
+			}
+		}
+		return 0, 0, errs
+	}
+	for i, f := range g.Files {
+		if i == g.Keep {
+			continue
+		}
+		info, err := checkReplace(g, i, action)
+		if err == nil {
+			// Right before replacing it, as size and mtime can be kept
+			// while the content changes
+			if sum, hashErr := hashFile(f.Path, -1); hashErr != nil || sum != g.Sum {
+				err = errDupChanged
+			}
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w, skipped", f.Path, err))
+			continue
+		}
+
+		var tmp string
+		switch action {
+		case "hardlink":
+			tmp, err = createTemp(f.Path, func(tmp string) error { return os.Link(keep.Path, tmp) })
+		case "reflink":
+			tmp, err = createTemp(f.Path, func(tmp string) error { return cloneFile(keep.Path, tmp) })
+			if err == nil {
+				err = os.Chmod(tmp, info.Mode().Perm())
+			}
+			if err == nil {
+				err = os.Chtimes(tmp, info.ModTime(), info.ModTime())
+			}
+		case "symlink":
+			tmp, err = createTemp(f.Path, func(tmp string) error { return os.Symlink(fullPath(keep.Path), tmp) })
+		default:
+			err = trash(f.Path, info)
+		}
+		if err == nil && tmp != "" {
+			err = os.Rename(tmp, f.Path)
+		}
+		if err != nil {
+			if tmp != "" {
+				os.Remove(tmp)
+			}
+			if errors.Is(err, fs.ErrPermission) && action == "hardlink" {
+				err = fmt.Errorf("%w (linking files of other users may be restricted by fs.protected_hardlinks)", err)
+			}
+			errs = append(errs, err)
+			continue
+		}
+		resolved++
+		reclaimed += freedBytes(info)
+	}
+	return resolved, reclaimed, errs
+}
+
+// dupPlan is what resolving the groups would do, for --dry-run.
+type dupPlan struct {
+	Files   int
+	Bytes   int64
+	Skipped []cleanFile
+}
+
+func planDuplicates(groups []*dupGroup, action string) dupPlan {
+	var plan dupPlan
+	for _, g := range groups {
+		keptErr := checkKept(g)
+		for i, f := range g.Files {
+			if i == g.Keep {
+				continue
+			}
+			if keptErr != nil {
+				plan.Skipped = append(plan.Skipped, cleanFile{f.Path, g.Size, errKeptChanged.Error()})
+				continue
+			}
+			info, err := checkReplace(g, i, action)
+			if err != nil {
+				plan.Skipped = append(plan.Skipped, cleanFile{f.Path, g.Size, err.Error()})
+				continue
+			}
+			plan.Files++
+			plan.Bytes += freedBytes(info)
+		}
+	}
+	return plan
+}
+
+// displayDupesPlan shows the largest groups with the file kept in each and
+// the files that would be skipped.
+func displayDupesPlan(groups []*dupGroup, action string, maxCount int) string {
+	var result strings.Builder
+	plan := planDuplicates(groups, action)
+
+	result.WriteString(headerStyle.Render(tr("Deduplication Plan")))
+	result.WriteString("\n")
+	result.WriteString(fmt.Sprintf(tr("%s: %s files, freeing %s\n"),
+		tr(action),
+		badStyle.Render(formatCount(plan.Files)),
+		badStyle.Render(formatMB(plan.Bytes))))
+	for _, g := range groups[:min(maxCount, len(groups))] {
+		result.WriteString(fmt.Sprintf(tr("  %s %s copies, keeping %s\n"),
+			numberStyle.Render(fmt.Sprintf("%11s", formatMB(g.Redundant()))),
+			numberStyle.Render(fmt.Sprintf("%3s", formatCount(len(g.Files)))),
+			pathStyle.Render(g.Files[g.Keep].Path)))
+	}
+	if len(plan.Skipped) > 0 {
+		result.WriteString(fmt.Sprintf(tr("Skipped: %s files, %s\n"),
+			warnStyle.Render(formatCount(len(plan.Skipped))),
+			warnStyle.Render(formatMB(cleanBytes(plan.Skipped)))))
+		for _, f := range plan.Skipped[:min(maxCount, len(plan.Skipped))] {
+			result.WriteString(fmt.Sprintf("  %s (%s)\n", pathStyle.Render(f.Path), tr(f.Reason)))
+		}
+	}
+	result.WriteString("\n")
+	return result.String()
+}
+
+func dupTotals(groups []*dupGroup) (files int, bytes int64) {
+	for _, g := range groups {
+		files += len(g.Files) - 1
+		bytes += g.Redundant()
+	}
+	return files, bytes
+}
@@ -0,0 +1,277 @@
+package main
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeDupGroup writes content to each path and returns the group
+// duplicateGroups finds for them, keeping the first path.
+func writeDupGroup(t *testing.T, content string, paths ...string) *dupGroup {
+	t.Helper()
+	bySize := make(map[int64][]dupFile)
+	for _, path := range paths {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		bySize[info.Size()] = append(bySize[info.Size()], dupFile{path, info.ModTime()})
+	}
+	groups := duplicateGroups(bySize)
+	if len(groups) != 1 {
+		t.Fatalf("got %d duplicate groups, want 1", len(groups))
+	}
+	g := groups[0]
+	for i, f := range g.Files {
+		if f.Path == paths[0] {
+			g.Keep = i
+		}
+	}
+	return g
+}
+
+// trashSession starts a clean session moving files to a trash in a
+// temporary data directory.
+func trashSession(t *testing.T) *cleanSession {
+	t.Helper()
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	dir, err := trashDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	session, err := newCleanSession(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { session.Close() })
+	return session
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestResolveGroupReplacesCopies(t *testing.T) {
+	for _, action := range []string{"delete", "hardlink", "symlink"} {
+		t.Run(action, func(t *testing.T) {
+			dir := t.TempDir()
+			keep, copy := filepath.Join(dir, "a.txt"), filepath.Join(dir, "b.txt")
+			g := writeDupGroup(t, "same content", keep, copy)
+
+			resolved, _, errs := resolveGroup(g, action, trashSession(t).move)
+			if resolved != 1 || len(errs) != 0 {
+				t.Fatalf("resolved %d, errors %v", resolved, errs)
+			}
+			if readFile(t, keep) != "same content" {
+				t.Error("kept file changed")
+			}
+			_, err := os.Lstat(copy)
+			if action == "delete" {
+				if !os.IsNotExist(err) {
+					t.Errorf("copy still exists: %v", err)
+				}
+			} else if readFile(t, copy) != "same content" {
+				t.Error("replaced copy has other content")
+			}
+		})
+	}
+}
+
+func TestResolveGroupSkipsChangedKeptFile(t *testing.T) {
+	for _, action := range dupActions {
+		t.Run(action, func(t *testing.T) {
+			dir := t.TempDir()
+			keep, copy := filepath.Join(dir, "a.txt"), filepath.Join(dir, "b.txt")
+			g := writeDupGroup(t, "same content", keep, copy)
+
+			// Same size and mtime, other content: only the hash tells
+			mtime := g.Files[g.Keep].ModTime
+			if err := os.WriteFile(keep, []byte("other content"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.Chtimes(keep, mtime, mtime); err != nil {
+				t.Fatal(err)
+			}
+
+			resolved, _, errs := resolveGroup(g, action, trashSession(t).move)
+			if resolved != 0 || len(errs) != 1 || !errors.Is(errs[0], errDupChanged) {
+				t.Fatalf("resolved %d, errors %v", resolved, errs)
+			}
+			if readFile(t, copy) != "same content" {
+				t.Error("copy was replaced")
+			}
+		})
+	}
+}
+
+func TestResolveGroupSkipsMissingKeptFile(t *testing.T) {
+	for _, action := range dupActions {
+		t.Run(action, func(t *testing.T) {
+			dir := t.TempDir()
+			keep, copy := filepath.Join(dir, "a.txt"), filepath.Join(dir, "b.txt")
+			g := writeDupGroup(t, "same content", keep, copy)
+			if err := os.Remove(keep); err != nil {
+				t.Fatal(err)
+			}
+
+			resolved, _, errs := resolveGroup(g, action, trashSession(t).move)
+			if resolved != 0 || len(errs) != 1 || !errors.Is(errs[0], errDupChanged) {
+				t.Fatalf("resolved %d, errors %v", resolved, errs)
+			}
+			if readFile(t, copy) != "same content" {
+				t.Error("last copy was replaced")
+			}
+		})
+	}
+}
+
+func TestCheckReplaceChangedCopy(t *testing.T) {
+	dir := t.TempDir()
+	keep, copy := filepath.Join(dir, "a.txt"), filepath.Join(dir, "b.txt")
+	g := writeDupGroup(t, "same content", keep, copy)
+	later := time.Now().Add(time.Hour)
+	if err := os.Chtimes(copy, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := checkReplace(g, 1-g.Keep, "delete"); !errors.Is(err, errDupChanged) {
+		t.Errorf("got %v, want %v", err, errDupChanged)
+	}
+}
+
+func TestCheckReplacePermissionMismatch(t *testing.T) {
+	dir := t.TempDir()
+	keep, copy := filepath.Join(dir, "a.txt"), filepath.Join(dir, "b.txt")
+	g := writeDupGroup(t, "same content", keep, copy)
+	if err := os.Chmod(copy, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := checkReplace(g, 1-g.Keep, "hardlink"); !errors.Is(err, errDupOtherOwner) {
+		t.Errorf("hardlink: got %v, want %v", err, errDupOtherOwner)
+	}
+	if _, err := checkReplace(g, 1-g.Keep, "delete"); err != nil {
+		t.Errorf("delete: %v", err)
+	}
+}
+
+func TestCheckReplaceOtherDevice(t *testing.T) {
+	other, err := os.MkdirTemp("/dev/shm", "madaa-test")
+	if err != nil {
+		t.Skip("no /dev/shm")
+	}
+	defer os.RemoveAll(other)
+	keep, copy := filepath.Join(other, "a.txt"), filepath.Join(t.TempDir(), "b.txt")
+	g := writeDupGroup(t, "same content", keep, copy)
+
+	keepInfo, _ := os.Stat(keep)
+	copyInfo, _ := os.Stat(copy)
+	keepID, _, ok1 := fileIdentity(keepInfo)
+	copyID, _, ok2 := fileIdentity(copyInfo)
+	if !ok1 || !ok2 || keepID.Dev == copyID.Dev {
+		t.Skip("temp directory and /dev/shm are on the same file system")
+	}
+	for _, action := range []string{"hardlink", "reflink"} {
+		if _, err := checkReplace(g, 1-g.Keep, action); !errors.Is(err, errDupOtherDevice) {
+			t.Errorf("%s: got %v, want %v", action, err, errDupOtherDevice)
+		}
+	}
+}
+
+func TestResolveGroupDeleteCanBeUndone(t *testing.T) {
+	dir := t.TempDir()
+	keep, copy := filepath.Join(dir, "a.txt"), filepath.Join(dir, "b.txt")
+	g := writeDupGroup(t, "same content", keep, copy)
+	session := trashSession(t)
+
+	resolved, _, errs := resolveGroup(g, "delete", session.move)
+	if resolved != 1 || len(errs) != 0 {
+		t.Fatalf("resolved %d, errors %v", resolved, errs)
+	}
+	if err := session.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if restored, _, errs := undoSession(session.ID); restored != 1 || len(errs) != 0 {
+		t.Fatalf("restored %d, errors %v", restored, errs)
+	}
+	if readFile(t, copy) != "same content" {
+		t.Error("deleted copy not restored")
+	}
+}
+
+func TestResolveGroupSkipsChangedCopy(t *testing.T) {
+	for _, action := range dupActions {
+		t.Run(action, func(t *testing.T) {
+			dir := t.TempDir()
+			keep, copy := filepath.Join(dir, "a.txt"), filepath.Join(dir, "b.txt")
+			g := writeDupGroup(t, "same content", keep, copy)
+
+			// Same size and mtime, other content: only the hash tells
+			mtime := g.Files[1-g.Keep].ModTime
+			if err := os.WriteFile(copy, []byte("other content"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.Chtimes(copy, mtime, mtime); err != nil {
+				t.Fatal(err)
+			}
+
+			resolved, _, errs := resolveGroup(g, action, trashSession(t).move)
+			if resolved != 0 || len(errs) != 1 || !errors.Is(errs[0], errDupChanged) {
+				t.Fatalf("resolved %d, errors %v", resolved, errs)
+			}
+			if readFile(t, copy) != "other content" {
+				t.Error("changed copy was replaced")
+			}
+		})
+	}
+}
+
+func TestResolveGroupKeepsFilesNamedLikeTemps(t *testing.T) {
+	for _, action := range []string{"hardlink", "symlink"} {
+		t.Run(action, func(t *testing.T) {
+			dir := t.TempDir()
+			keep, copy := filepath.Join(dir, "a.txt"), filepath.Join(dir, "b.txt")
+			g := writeDupGroup(t, "same content", keep, copy)
+			other := copy + ".madaa-link"
+			if err := os.WriteFile(other, []byte("user data"), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			resolved, _, errs := resolveGroup(g, action, nil)
+			if resolved != 1 || len(errs) != 0 {
+				t.Fatalf("resolved %d, errors %v", resolved, errs)
+			}
+			if readFile(t, other) != "user data" {
+				t.Error("file next to the copy was changed")
+			}
+			entries, err := os.ReadDir(dir)
+			if err != nil || len(entries) != 3 {
+				t.Errorf("%d files left in the directory, want 3: %v", len(entries), err)
+			}
+		})
+	}
+}
+
+func TestCreateTempRetriesTakenNames(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "b.txt")
+	tries := 0
+	tmp, err := createTemp(path, func(tmp string) error {
+		if tries++; tries < 3 {
+			return fs.ErrExist
+		}
+		return os.WriteFile(tmp, nil, 0600)
+	})
+	if err != nil || tries != 3 || filepath.Dir(tmp) != filepath.Dir(path) {
+		t.Errorf("created %q after %d tries: %v", tmp, tries, err)
+	}
+}
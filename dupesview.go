@@ -0,0 +1,317 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// dupesPageSize is the number of duplicate groups shown at once.
+const dupesPageSize = 10
+
+// dupesModel is the TUI of madaa dupes: one line per duplicate group, the
+// selected group expanded to its files. A strategy preselects the file kept
+// in every group, which can then be overridden group by group.
+type dupesModel struct {
+	root       string
+	groups     []*dupGroup
+	cursor     int
+	strategy   int
+	action     int
+	confirming bool
+	typed      string
+	done       bool
+	status     string
+	errs       []error
+	width      int
+	fullPaths  bool
+}
+
+func newDupesModel(root string, groups []*dupGroup, strategy, action string) dupesModel {
+	m := dupesModel{
+		root:     root,
+		groups:   groups,
+		strategy: max(slices.Index(dupStrategies, strategy), 0),
+		action:   max(slices.Index(dupActions, action), 0),
+	}
+	m.applyStrategy()
+	return m
+}
+
+func (m *dupesModel) applyStrategy() {
+	for _, g := range m.groups {
+		applyStrategy(g, dupStrategies[m.strategy])
+	}
+}
+
+// needsTyped tells whether the action is large enough that the number of
+// files has to be typed to confirm it, as for madaa clean.
+func (m dupesModel) needsTyped() bool {
+	_, bytes := dupTotals(m.groups)
+	return bytes > cleanConfirmAbove
+}
+
+func (m dupesModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m dupesModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if size, ok := msg.(tea.WindowSizeMsg); ok {
+		m.width = size.Width
+		return m, nil
+	}
+	key, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return m, nil
+	}
+	if key.String() == "ctrl+c" {
+		return m, tea.Quit
+	}
+
+	if m.confirming {
+		files, _ := dupTotals(m.groups)
+		switch k := key.String(); {
+		case k == "y" && !m.needsTyped():
+			m.execute()
+		case k == "enter" && m.needsTyped() && m.typed == strconv.Itoa(files):
+			m.execute()
+		case k == "backspace" && m.typed != "":
+			m.typed = m.typed[:len(m.typed)-1]
+		case len(k) == 1 && k[0] >= '0' && k[0] <= '9' && m.needsTyped():
+			m.typed += k
+		default:
+			m.confirming = false
+			m.typed = ""
+		}
+		return m, nil
+	}
+
+	switch key.String() {
+	case "q", "esc":
+		return m, tea.Quit
+	case "p":
+		m.fullPaths = !m.fullPaths
+		return m, nil
+	}
+	if m.done || len(m.groups) == 0 {
+		return m, nil
+	}
+	group := m.groups[m.cursor]
+	switch key.String() {
+	case "up", "k":
+		m.cursor = max(m.cursor-1, 0)
+	case "down", "j":
+		m.cursor = min(m.cursor+1, len(m.groups)-1)
+	case "left", "h":
+		group.Keep = (group.Keep + len(group.Files) - 1) % len(group.Files)
+	case "right", "l":
+		group.Keep = (group.Keep + 1) % len(group.Files)
+	case "s":
+		m.strategy = (m.strategy + 1) % len(dupStrategies)
+		m.applyStrategy()
+	case "a":
+		m.action = (m.action + 1) % len(dupActions)
+	case "x", "enter":
+		m.confirming = true
+	}
+	return m, nil
+}
+
+// execute applies the chosen action to every group. Deleted files go to the
+// trash in a clean session, which madaa clean --undo restores. Where the
+// volume's free space is known, it is reported too, as the space actually
+// gained.
+func (m *dupesModel) execute() {
+	m.confirming = false
+	m.done = true
+	action := dupActions[m.action]
+	var session *cleanSession
+	var trash func(path string, info os.FileInfo) error
+	if action == "delete" {
+		dir, err := trashDir()
+		if err == nil {
+			session, err = newCleanSession(dir)
+		}
+		if err != nil {
+			m.errs = append(m.errs, err)
+			m.status = fmt.Sprintf(tr("Error starting clean session: %v"), err)
+			return
+		}
+		trash = session.move
+	}
+
+	before, measured := volumeSpace(m.root)
+	var resolved int
+	var reclaimed int64
+	for _, g := range m.groups {
+		n, bytes, errs := resolveGroup(g, action, trash)
+		resolved += n
+		reclaimed += bytes
+		m.errs = append(m.errs, errs...)
+	}
+	if session != nil {
+		if err := session.Close(); err != nil {
+			m.errs = append(m.errs, err)
+		}
+		m.status = fmt.Sprintf(tr("Moved %s files, %s to the trash, %s skipped. Undo with: madaa clean --undo %s"),
+			formatCount(resolved), formatMB(reclaimed), formatCount(len(m.errs)), session.ID)
+		return
+	}
+	m.status = fmt.Sprintf(tr("Replaced %s files, reclaimed %s, %s skipped"),
+		formatCount(resolved), formatMB(reclaimed), formatCount(len(m.errs)))
+	if after, ok := volumeSpace(m.root); ok && measured {
+		m.status += fmt.Sprintf(tr("\nFree space on the volume: %s → %s"), formatMB(before.Free), formatMB(after.Free))
+	}
+}
+
+func (m dupesModel) View() string {
+	width := m.width
+	if m.fullPaths {
+		width = 0
+	}
+	return fitPaths(m.view(), width)
+}
+
+func (m dupesModel) view() string {
+	var result strings.Builder
+
+	files, bytes := dupTotals(m.groups)
+	writeTitle(tr("MADAA - Duplicates"), &result)
+	result.WriteString(fmt.Sprintf(tr("Groups: %s  Redundant: %s files, %s\n"),
+		numberStyle.Render(formatCount(len(m.groups))),
+		numberStyle.Render(formatCount(files)),
+		badStyle.Render(formatMB(bytes))))
+	result.WriteString(fmt.Sprintf(tr("Keep: %s  Others: %s\n\n"),
+		goodStyle.Render(tr(dupStrategies[m.strategy])),
+		warnStyle.Render(tr(dupActions[m.action]))))
+
+	first := max(0, min(m.cursor-dupesPageSize/2, len(m.groups)-dupesPageSize))
+	for i := first; i < min(first+dupesPageSize, len(m.groups)); i++ {
+		g := m.groups[i]
+		marker := "  "
+		if i == m.cursor {
+			marker = "> "
+		}
+		result.WriteString(fmt.Sprintf("%s%s %s %s\n",
+			marker,
+			numberStyle.Render(fmt.Sprintf("%11s", formatMB(g.Redundant()))),
+			numberStyle.Render(fmt.Sprintf("%4s", plainText("×")+strconv.Itoa(len(g.Files)))),
+			renderPath(g.Files[g.Keep].Path)))
+		if i != m.cursor {
+			continue
+		}
+		for j, f := range g.Files {
+			label := warnStyle.Render(fmt.Sprintf("%-8s", tr(dupActions[m.action])))
+			var reason string
+			if j == g.Keep {
+				label = goodStyle.Render(fmt.Sprintf("%-8s", tr("keep")))
+			} else if _, err := checkReplace(g, j, dupActions[m.action]); err != nil && !m.done {
+				label = badStyle.Render(fmt.Sprintf("%-8s", tr("skip")))
+				reason = " (" + tr(err.Error()) + ")"
+			}
+			result.WriteString(fmt.Sprintf("      %s %s %s%s\n", label, formatDateTime(f.ModTime), renderPath(f.Path), reason))
+		}
+	}
+	result.WriteString("\n")
+
+	switch {
+	case m.done:
+		result.WriteString(m.status)
+		result.WriteString("\n" + tr("q quit") + "\n")
+	case m.confirming && m.needsTyped():
+		result.WriteString(fmt.Sprintf(tr("This affects %s. Type the number of files (%d) and press enter to confirm: %s"),
+			formatMB(bytes), files, m.typed))
+		result.WriteString("\n")
+	case m.confirming:
+		result.WriteString(fmt.Sprintf(tr("%s %s files? [y/N]"), tr(dupActions[m.action]), formatCount(files)))
+		result.WriteString("\n")
+	default:
+		result.WriteString(tr("↑/↓ group  ←/→ file to keep  s strategy  a action  x apply  p full paths  q quit"))
+		result.WriteString("\n")
+	}
+	return result.String()
+}
+
+// runDupes implements "madaa dupes <path>": it finds files with the same
+// content and lets the user resolve them interactively.
+func runDupes(args []string) {
+	flags := flag.NewFlagSet("dupes", flag.ExitOnError)
+	filterExpr := flags.String("filter", "", "Only look at files matching the expression")
+	minSize := flags.String("min-size", "1M", "Ignore files smaller than this")
+	strategy := flags.String("strategy", "newest", "File to keep in every group: newest, oldest or shortest-path")
+	action := flags.String("action", "delete", "What to do with the other files: delete, hardlink, reflink or symlink")
+	dryRun := flags.Bool("dry-run", false, "Only show what would be done, without the interactive view")
+	count := flags.Int("count", 10, "Number of groups and skipped files to show with --dry-run")
+	reportFlags(flags)
+	selectLanguage := localeFlags(flags)
+	flags.Parse(args)
+	selectLanguage()
+
+	if flags.NArg() < 1 {
+		fmt.Println("Usage: madaa dupes [--filter EXPR] [--min-size SIZE] [--strategy newest|oldest|shortest-path] [--action delete|hardlink|reflink|symlink] [--dry-run] [--count N] <path>")
+		os.Exit(1)
+	}
+	if !slices.Contains(dupStrategies, *strategy) {
+		fmt.Printf("Unknown strategy %q, use %s\n", *strategy, strings.Join(dupStrategies, ", "))
+		os.Exit(1)
+	}
+	if !slices.Contains(dupActions, *action) {
+		fmt.Printf("Unknown action %q, use %s\n", *action, strings.Join(dupActions, ", "))
+		os.Exit(1)
+	}
+	if err := loadConfig(); err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+
+	config := Config{Path: flags.Arg(0)}
+	if *filterExpr != "" {
+		filter, err := ParseFilter(*filterExpr)
+		if err != nil {
+			fmt.Printf("Error parsing filter: %v\n", err)
+			os.Exit(1)
+		}
+		config.Filter = filter
+	}
+	size, err := parseSize(*minSize)
+	if err != nil {
+		fmt.Printf("Error parsing --min-size: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf(tr("Looking for duplicates in %s...\n"), displayPath(config.Path))
+	groups, err := findDuplicates(config, size)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	if len(groups) == 0 {
+		fmt.Println(tr("No duplicates found."))
+		return
+	}
+
+	if *dryRun {
+		for _, g := range groups {
+			applyStrategy(g, *strategy)
+		}
+		fmt.Print(fitPaths(displayDupesPlan(groups, *action, *count), pathWidth))
+		return
+	}
+
+	final, err := tea.NewProgram(newDupesModel(config.Path, groups, *strategy, *action)).Run()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	if errs := final.(dupesModel).errs; len(errs) > 0 {
+		for _, err := range errs {
+			fmt.Printf("Error: %v\n", err)
+		}
+		os.Exit(1)
+	}
+}
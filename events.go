@@ -0,0 +1,132 @@
+package main
+
+import "sync"
+
+// Kinds of scan events.
+const (
+	eventProgress = "progress"
+	eventDone     = "done"
+)
+
+// scanEvent is what a running scan tells its observers.
+type scanEvent struct {
+	Kind      string
+	Processed int
+	Total     int
+	Recent    []RecentFile
+}
+
+// eventBus fans scan events out to any number of subscribers. Publishing
+// never blocks and never drops events: each subscriber has its own queue,
+// so a slow consumer only delays itself.
+type eventBus struct {
+	mu     sync.Mutex
+	subs   []*subscriber
+	closed bool
+}
+
+type subscriber struct {
+	mu     sync.Mutex
+	wake   *sync.Cond
+	queue  []scanEvent
+	closed bool
+	events chan scanEvent
+	done   chan struct{}
+}
+
+func newEventBus() *eventBus {
+	return &eventBus{}
+}
+
+// Subscribe returns a channel receiving every event published from now on.
+// It is closed after the bus is closed and all events have been received,
+// or by Unsubscribe.
+func (b *eventBus) Subscribe() <-chan scanEvent {
+	sub := &subscriber{events: make(chan scanEvent), done: make(chan struct{})}
+	sub.wake = sync.NewCond(&sub.mu)
+
+	b.mu.Lock()
+	if b.closed {
+		sub.closed = true
+	} else {
+		b.subs = append(b.subs, sub)
+	}
+	b.mu.Unlock()
+
+	go sub.deliver()
+	return sub.events
+}
+
+// Unsubscribe stops the delivery to a channel Subscribe returned and closes
+// it, dropping the events still queued. Observers that stop reading before
+// the bus is closed, like the TUI restarting a scan, must unsubscribe, or
+// the delivery waits for them forever.
+func (b *eventBus) Unsubscribe(events <-chan scanEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for i, sub := range b.subs {
+		if sub.events != events {
+			continue
+		}
+		b.subs = append(b.subs[:i], b.subs[i+1:]...)
+		sub.mu.Lock()
+		sub.queue, sub.closed = nil, true
+		sub.mu.Unlock()
+		close(sub.done)
+		sub.wake.Signal()
+		return
+	}
+}
+
+func (b *eventBus) Publish(event scanEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return
+	}
+	for _, sub := range b.subs {
+		sub.mu.Lock()
+		sub.queue = append(sub.queue, event)
+		sub.mu.Unlock()
+		sub.wake.Signal()
+	}
+}
+
+// Close ends the event stream. Queued events are still delivered.
+func (b *eventBus) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return
+	}
+	b.closed = true
+	for _, sub := range b.subs {
+		sub.mu.Lock()
+		sub.closed = true
+		sub.mu.Unlock()
+		sub.wake.Signal()
+	}
+}
+
+func (s *subscriber) deliver() {
+	defer close(s.events)
+	for {
+		s.mu.Lock()
+		for len(s.queue) == 0 && !s.closed {
+			s.wake.Wait()
+		}
+		if len(s.queue) == 0 {
+			s.mu.Unlock()
+			return
+		}
+		event := s.queue[0]
+		s.queue = s.queue[1:]
+		s.mu.Unlock()
+
+		select {
+		case s.events <- event:
+		case <-s.done:
+			return
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEventBusSlowConsumer(t *testing.T) {
+	bus := newEventBus()
+	fast, slow := bus.Subscribe(), bus.Subscribe()
+
+	const n = 500
+	go func() {
+		for i := 1; i <= n; i++ {
+			bus.Publish(scanEvent{Kind: eventProgress, Processed: i})
+		}
+		bus.Close()
+	}()
+
+	// The fast consumer isn't held up by the slow one
+	for i := 1; i <= n; i++ {
+		if event := <-fast; event.Processed != i {
+			t.Fatalf("fast consumer got event %d, want %d", event.Processed, i)
+		}
+	}
+	if _, ok := <-fast; ok {
+		t.Error("fast consumer's channel not closed after the bus")
+	}
+
+	var got int
+	for event := range slow {
+		got++
+		if event.Processed != got {
+			t.Fatalf("slow consumer got event %d, want %d", event.Processed, got)
+		}
+		if got%50 == 0 {
+			time.Sleep(time.Millisecond)
+		}
+	}
+	if got != n {
+		t.Errorf("slow consumer got %d events, want %d", got, n)
+	}
+}
+
+func TestEventBusUnsubscribe(t *testing.T) {
+	bus := newEventBus()
+	abandoned, kept := bus.Subscribe(), bus.Subscribe()
+	for i := 1; i <= 10; i++ {
+		bus.Publish(scanEvent{Kind: eventProgress, Processed: i})
+	}
+
+	// Nobody reads the abandoned channel; unsubscribing ends its delivery
+	// and closes it
+	bus.Unsubscribe(abandoned)
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case _, ok := <-abandoned:
+			if ok {
+				continue
+			}
+		case <-timeout:
+			t.Fatal("channel not closed after Unsubscribe")
+		}
+		break
+	}
+
+	bus.Publish(scanEvent{Kind: eventProgress, Processed: 11})
+	bus.Close()
+	var got int
+	for range kept {
+		got++
+	}
+	if got != 11 {
+		t.Errorf("remaining subscriber got %d events, want 11", got)
+	}
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// gitignoreRule is a pattern line of a .gitignore file. Anchored patterns
+// are matched against the path relative to the directory of the .gitignore,
+// the others against the name at any depth below it.
+type gitignoreRule struct {
+	pattern  string
+	negate   bool
+	dirOnly  bool
+	anchored bool
+}
+
+// parseGitignoreRule parses a .gitignore line, false for blank lines and
+// comments.
+func parseGitignoreRule(line string) (gitignoreRule, bool) {
+	line = strings.TrimRight(line, " \r")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return gitignoreRule{}, false
+	}
+	var rule gitignoreRule
+	if strings.HasPrefix(line, "!") {
+		rule.negate = true
+		line = line[1:]
+	}
+	line = strings.TrimPrefix(line, `\`)
+	if strings.HasSuffix(line, "/") {
+		rule.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if strings.Contains(line, "/") {
+		rule.anchored = true
+		line = strings.TrimPrefix(line, "/")
+	}
+	rule.pattern = line
+	return rule, line != ""
+}
+
+// gitignores applies the .gitignore files of the scanned tree, set by
+// --respect-gitignore. Each directory's file is read once, the first time a
+// path below it is checked.
+type gitignores struct {
+	mu    sync.Mutex
+	rules map[string][]gitignoreRule
+}
+
+func newGitignores() *gitignores {
+	return &gitignores{rules: make(map[string][]gitignoreRule)}
+}
+
+func (g *gitignores) load(dir string) []gitignoreRule {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if rules, ok := g.rules[dir]; ok {
+		return rules
+	}
+
+	var rules []gitignoreRule
+	if f, err := os.Open(filepath.Join(dir, ".gitignore")); err == nil {
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			if rule, ok := parseGitignoreRule(scanner.Text()); ok {
+				rules = append(rules, rule)
+			}
+		}
+		f.Close()
+	}
+	g.rules[dir] = rules
+	return rules
+}
+
+// ignored reports whether path below root is ignored by the .gitignore
+// files of root and the directories between them. As in git, the last
+// matching rule wins and deeper files override shallower ones; paths in
+// ignored directories are never checked, since those are pruned.
+func (g *gitignores) ignored(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return false
+	}
+	parts := strings.Split(filepath.ToSlash(rel), "/")
+	name := parts[len(parts)-1]
+
+	ignored := false
+	isDir := func() bool {
+		info, err := lstat(path)
+		return err == nil && info.IsDir()
+	}
+	dir := root
+	for i := range parts {
+		sub := strings.Join(parts[i:], "/")
+		for _, rule := range g.load(dir) {
+			matched := false
+			if rule.anchored {
+				matched = matchGlob(rule.pattern, sub)
+			} else {
+				matched = matchGlob(rule.pattern, name)
+			}
+			if matched && (!rule.dirOnly || isDir()) {
+				ignored = !rule.negate
+			}
+		}
+		dir = filepath.Join(dir, parts[i])
+	}
+	return ignored
+}
+
+// excluded tells whether --exclude or --respect-gitignore leave path out of
+// the scan. A pattern without a slash is matched against the name, as in
+// node_modules or *.o, one with a slash against the path relative to the
+// scanned directory.
+func (c Config) excluded(path string) bool {
+	if len(c.Exclude) == 0 && c.Gitignore == nil {
+		return false
+	}
+	name := filepath.Base(path)
+	rel, err := filepath.Rel(c.Path, path)
+	if err != nil {
+		rel = path
+	}
+	for _, pattern := range c.Exclude {
+		if strings.Contains(pattern, "/") {
+			if matchGlob(pattern, rel) {
+				return true
+			}
+		} else if matchGlob(pattern, name) {
+			return true
+		}
+	}
+	if c.Gitignore != nil {
+		return name == ".git" || c.Gitignore.ignored(c.Path, path)
+	}
+	return false
+}
+
+// excludedListed is excluded for a path from --files-from, which is also
+// excluded if one of the directories it is in matches --exclude. The
+// .gitignore files don't apply to listed paths.
+func (c Config) excludedListed(path string) bool {
+	if len(c.Exclude) == 0 {
+		return false
+	}
+	path = filepath.Clean(path)
+	for p := path; ; p = filepath.Dir(p) {
+		for _, pattern := range c.Exclude {
+			target := filepath.Base(p)
+			if strings.Contains(pattern, "/") {
+				target = p
+			}
+			if matchGlob(pattern, target) {
+				return true
+			}
+		}
+		if p == filepath.Dir(p) {
+			return false
+		}
+	}
+}
@@ -0,0 +1,284 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// exportVersion is the version of the JSON export. It changes whenever a
+// field is renamed, removed or changes its meaning; new optional fields
+// keep it. Each version has its own schema, which madaa schema prints.
+const exportVersion = 1
+
+//go:embed export.schema.json
+var exportSchema []byte
+
+// Export is the stable model of the results written with --json. Unlike
+// Stats, which follows the analyzers, its fields only change along with
+// exportVersion and export.schema.json.
+type Export struct {
+	Version          int            `json:"version"`
+	Host             string         `json:"host,omitempty"`
+	Root             string         `json:"root"`
+	ScannedAt        time.Time      `json:"scanned_at"`
+	Totals           ExportTotals   `json:"totals"`
+	Categories       []ExportCount  `json:"categories"`
+	Types            []ExportType   `json:"types"`
+	SizeDistribution []ExportCount  `json:"size_distribution"`
+	Years            []ExportYear   `json:"years"`
+	LargestFiles     []ExportFile   `json:"largest_files"`
+	OldestFile       *ExportFileAge `json:"oldest_file,omitempty"`
+	NewestFile       *ExportFileAge `json:"newest_file,omitempty"`
+	// Sources are the scans a merged export combines, see madaa merge
+	Sources []ExportSource `json:"sources,omitempty"`
+	Report  *ReportMeta    `json:"report,omitempty"`
+	// SampleRate is the share of files a --sample scan analyzed; the
+	// counts cover only those
+	SampleRate float64 `json:"sample_rate,omitempty"`
+}
+
+type ExportTotals struct {
+	Files          int   `json:"files"`
+	Dirs           int   `json:"dirs"`
+	Bytes          int64 `json:"bytes"`
+	EmptyFiles     int   `json:"empty_files"`
+	EmptyDirs      int   `json:"empty_dirs"`
+	HiddenFiles    int   `json:"hidden_files"`
+	SystemFiles    int   `json:"system_files"`
+	Symlinks       int   `json:"symlinks"`
+	WriteProtected int   `json:"write_protected"`
+	StaleFiles     int   `json:"stale_files"`
+	RecentChanges  int   `json:"recent_changes"`
+}
+
+type ExportCount struct {
+	Name  string `json:"name"`
+	Files int    `json:"files"`
+}
+
+type ExportType struct {
+	Extension string `json:"extension"`
+	Category  string `json:"category,omitempty"`
+	Files     int    `json:"files"`
+	Bytes     int64  `json:"bytes"`
+}
+
+type ExportYear struct {
+	Year  int `json:"year"`
+	Files int `json:"files"`
+}
+
+// ExportFile and ExportFileAge name the host they are on in merged exports.
+type ExportFile struct {
+	Path  string `json:"path"`
+	Bytes int64  `json:"bytes"`
+	Host  string `json:"host,omitempty"`
+}
+
+type ExportFileAge struct {
+	Path     string    `json:"path"`
+	Modified time.Time `json:"modified"`
+	Host     string    `json:"host,omitempty"`
+}
+
+// newExport converts the results of a scan of root to the export model.
+// Lists are sorted so that exports of an unchanged tree are identical.
+func newExport(stats *Stats, root string) *Export {
+	hostname, _ := os.Hostname()
+	export := &Export{
+		Version:   exportVersion,
+		Host:      displayName(hostname),
+		Root:      displayPath(root),
+		ScannedAt: stats.ScanStart.UTC(),
+		Totals: ExportTotals{
+			Files:          stats.TotalFiles,
+			Dirs:           stats.TotalDirs,
+			Bytes:          stats.TotalSize,
+			EmptyFiles:     stats.EmptyFiles,
+			EmptyDirs:      stats.EmptyDirs,
+			HiddenFiles:    stats.HiddenFiles,
+			SystemFiles:    stats.SystemFiles,
+			Symlinks:       stats.Symlinks,
+			WriteProtected: stats.WriteProtected,
+			StaleFiles:     stats.StaleFiles,
+			RecentChanges:  stats.RecentMods,
+		},
+		Categories:       []ExportCount{},
+		Types:            []ExportType{},
+		SizeDistribution: []ExportCount{},
+		Years:            []ExportYear{},
+		LargestFiles:     []ExportFile{},
+		Report:           exportReportMeta(),
+		SampleRate:       stats.Sample.Rate,
+	}
+
+	categories := make(map[string]int)
+	for _, ext := range sortedTypes(stats) {
+		category := fileTypeCategoryMap[ext]
+		export.Types = append(export.Types, ExportType{
+			Extension: ext,
+			Category:  category,
+			Files:     stats.TypeFreq[ext],
+			Bytes:     stats.TypeSizes[ext],
+		})
+		if category != "" {
+			categories[category] += stats.TypeFreq[ext]
+		}
+	}
+	for _, category := range categoryNames() {
+		if categories[category] > 0 {
+			export.Categories = append(export.Categories, ExportCount{category, categories[category]})
+		}
+	}
+	for _, bucket := range []string{"tiny", "small", "medium", "large"} {
+		if count, ok := stats.SizeDistribution[bucket]; ok {
+			export.SizeDistribution = append(export.SizeDistribution, ExportCount{bucket, count})
+		}
+	}
+	for year, count := range stats.YearDistribution {
+		export.Years = append(export.Years, ExportYear{year, count})
+	}
+	sort.Slice(export.Years, func(i, j int) bool { return export.Years[i].Year < export.Years[j].Year })
+
+	largest := make([]FileSize, stats.LargestFiles.Len())
+	copy(largest, *stats.LargestFiles)
+	sort.Slice(largest, func(i, j int) bool { return largest[j].Less(largest[i]) })
+	for _, file := range largest {
+		export.LargestFiles = append(export.LargestFiles, ExportFile{Path: displayPath(file.Path), Bytes: file.Size})
+	}
+	if stats.OldestFile != nil {
+		export.OldestFile = &ExportFileAge{Path: displayPath(stats.OldestFile.Path), Modified: stats.OldestFile.ModTime.UTC()}
+	}
+	if stats.NewestFile != nil {
+		export.NewestFile = &ExportFileAge{Path: displayPath(stats.NewestFile.Path), Modified: stats.NewestFile.ModTime.UTC()}
+	}
+	return export
+}
+
+// exportReaders decode each export version this version of madaa still
+// reads into the current Export. When exportVersion is raised, the reader
+// of the previous version converts its fields, so saved exports stay
+// readable.
+var exportReaders = map[int]func(data []byte) (*Export, error){
+	1: func(data []byte) (*Export, error) {
+		var export Export
+		err := json.Unmarshal(data, &export)
+		return &export, err
+	},
+}
+
+// readExport reads an export written by any version of madaa that had one.
+func readExport(r io.Reader) (*Export, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	var header struct {
+		Version int `json:"version"`
+	}
+	if err := json.Unmarshal(data, &header); err != nil {
+		return nil, fmt.Errorf("not a madaa export: %w", err)
+	}
+	read, ok := exportReaders[header.Version]
+	if !ok {
+		return nil, fmt.Errorf("export version %d is not supported, this version of madaa reads up to version %d", header.Version, exportVersion)
+	}
+	return read(data)
+}
+
+func loadExport(path string) (*Export, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	export, err := readExport(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return export, nil
+}
+
+func writeExport(export *Export, w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(export)
+}
+
+// saveExport writes the JSON export to path, or to stdout for "-".
+func saveExport(stats *Stats, root, path string) error {
+	return saveExportFile(newExport(stats, root), path)
+}
+
+func saveExportFile(export *Export, path string) error {
+	if path == "-" {
+		return writeExport(export, os.Stdout)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := writeExport(export, f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// writeTablesCSV writes the file type, category, size distribution and
+// year tables of the export as one CSV for spreadsheets, a row per entry
+// with the table it belongs to. Sizes and years have no bytes.
+func writeTablesCSV(export *Export, w io.Writer) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"table", "name", "files", "bytes"})
+	categoryBytes := make(map[string]int64)
+	for _, t := range export.Types {
+		cw.Write([]string{"type", t.Extension, strconv.Itoa(t.Files), strconv.FormatInt(t.Bytes, 10)})
+		categoryBytes[t.Category] += t.Bytes
+	}
+	for _, c := range export.Categories {
+		cw.Write([]string{"category", c.Name, strconv.Itoa(c.Files), strconv.FormatInt(categoryBytes[c.Name], 10)})
+	}
+	for _, c := range export.SizeDistribution {
+		cw.Write([]string{"size", c.Name, strconv.Itoa(c.Files), ""})
+	}
+	for _, y := range export.Years {
+		cw.Write([]string{"year", strconv.Itoa(y.Year), strconv.Itoa(y.Files), ""})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// saveTablesCSV writes the tables of --csv to path, or to stdout for "-".
+func saveTablesCSV(stats *Stats, root, path string) error {
+	export := newExport(stats, root)
+	if path == "-" {
+		return writeTablesCSV(export, os.Stdout)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := writeTablesCSV(export, f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// runSchema implements "madaa schema": it prints the JSON Schema of the
+// --json export, to validate exports or generate code from.
+func runSchema(args []string) {
+	if len(args) > 0 {
+		fmt.Println("Usage: madaa schema")
+		os.Exit(1)
+	}
+	os.Stdout.Write(exportSchema)
+}
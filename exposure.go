@@ -0,0 +1,206 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// secretPatterns match the names of files holding keys and credentials.
+// Unlike the PII indicators they are always checked, for the exposure audit
+// only.
+var secretPatterns = []struct {
+	indicator string
+	pattern   *regexp.Regexp
+}{
+	{"private key", regexp.MustCompile(`^id_(rsa|dsa|ecdsa|ed25519)$|(?i)(key|priv).*\.pem$|\.(key|ppk)$`)},
+	{"keystore", regexp.MustCompile(`(?i)\.(p12|pfx|jks|keystore)$`)},
+	{"password store", regexp.MustCompile(`(?i)\.kdbx?$|^\.(netrc|pgpass|htpasswd)$|^shadow$`)},
+	{"credentials", regexp.MustCompile(`(?i)^\.env(\..+)?$|credentials|secrets?\.(ya?ml|json|toml)$|\.tfstate$`)},
+}
+
+// Exposure severities, most severe first: a secret anyone can read, a
+// secret the group can read or PII anyone can read, PII the group can read.
+const (
+	exposureCritical = iota
+	exposureHigh
+	exposureMedium
+	exposureLevels
+)
+
+var exposureLabels = [exposureLevels]string{"critical", "high", "medium"}
+
+// maxExposedFiles is how many files each severity keeps for the report; all
+// of them are counted.
+const maxExposedFiles = 100
+
+// ExposedFile is a sensitive looking file that others than its owner can
+// read.
+type ExposedFile struct {
+	Path       string
+	Size       int64
+	Perm       os.FileMode
+	Access     string
+	Indicators []string
+}
+
+// ExposureStats lists the exposed files by severity.
+type ExposureStats struct {
+	Files [exposureLevels]int
+	Bytes [exposureLevels]int64
+	List  [exposureLevels][]ExposedFile
+}
+
+func secretIndicators(name string) []string {
+	var found []string
+	for _, p := range secretPatterns {
+		if p.pattern.MatchString(name) {
+			found = append(found, p.indicator)
+		}
+	}
+	return found
+}
+
+// dirReach returns the group and other execute bits that let others reach
+// dir: those set on dir and each of its parents. The results are cached in
+// stats.
+func dirReach(dir string, stats *Stats) os.FileMode {
+	stats.mu.Lock()
+	reach, ok := stats.reachable[dir]
+	stats.mu.Unlock()
+	if ok {
+		return reach
+	}
+
+	reach = 0011
+	if parent := filepath.Dir(dir); parent != dir {
+		reach = dirReach(parent, stats)
+	}
+	if info, err := os.Stat(dir); err == nil {
+		reach &= info.Mode().Perm()
+	}
+
+	stats.mu.Lock()
+	stats.reachable[dir] = reach
+	stats.mu.Unlock()
+	return reach
+}
+
+// processExposure flags regular files with names of keys and credentials,
+// or with --pii of PII, that their group or everyone can read. A readable
+// file only counts when its directories let the same readers through.
+func processExposure(path string, info os.FileInfo, stats *Stats) {
+	perm := info.Mode().Perm()
+	if !info.Mode().IsRegular() || perm&0044 == 0 {
+		return
+	}
+	if _, _, ok := fileOwnerIDs(info); !ok {
+		// No Unix permissions to go by
+		return
+	}
+
+	name := filepath.Base(path)
+	secrets := secretIndicators(name)
+	var pii []string
+	if piiScan {
+		pii = piiIndicators(name)
+	}
+	if len(secrets) == 0 && len(pii) == 0 {
+		return
+	}
+
+	reach := dirReach(filepath.Dir(path), stats)
+	world := perm&0004 != 0 && reach&0001 != 0
+	group := perm&0040 != 0 && reach&0010 != 0
+	var level int
+	switch {
+	case len(secrets) > 0 && world:
+		level = exposureCritical
+	case len(secrets) > 0 && group, world:
+		level = exposureHigh
+	case group:
+		level = exposureMedium
+	default:
+		return
+	}
+
+	stats.mu.Lock()
+	defer stats.mu.Unlock()
+	e := &stats.Exposure
+	e.Files[level]++
+	e.Bytes[level] += info.Size()
+	if len(e.List[level]) < maxExposedFiles {
+		e.List[level] = append(e.List[level], ExposedFile{path, info.Size(), perm, fileAccess(info), append(secrets, pii...)})
+	}
+}
+
+func mergeExposure(dst, src *ExposureStats) {
+	for level := range exposureLevels {
+		dst.Files[level] += src.Files[level]
+		dst.Bytes[level] += src.Bytes[level]
+		room := max(0, maxExposedFiles-len(dst.List[level]))
+		dst.List[level] = append(dst.List[level], src.List[level][:min(room, len(src.List[level]))]...)
+	}
+}
+
+// displayExposure lists the sensitive looking files others can read, most
+// severe first and within a severity the world-writable and then the
+// largest first.
+func displayExposure(stats *Stats, maxCount int, result *strings.Builder) {
+	e := &stats.Exposure
+	total := 0
+	for level := range exposureLevels {
+		total += e.Files[level]
+	}
+	if total == 0 {
+		return
+	}
+
+	result.WriteString(headerStyle.Render(tr("Exposed Sensitive Files")))
+	result.WriteString("\n")
+	result.WriteString(fmt.Sprintf(tr("%s files with keys, credentials or PII in their names are readable by their group or everyone\n"),
+		badStyle.Render(formatCount(total))))
+	for level := range exposureLevels {
+		if e.Files[level] == 0 {
+			continue
+		}
+		style := warnStyle
+		if level == exposureCritical {
+			style = badStyle
+		}
+		result.WriteString(fmt.Sprintf(tr("%s: %s files, %s\n"),
+			style.Render(strings.ToUpper(tr(exposureLabels[level]))),
+			numberStyle.Render(formatCount(e.Files[level])),
+			numberStyle.Render(formatMB(e.Bytes[level]))))
+
+		files := append([]ExposedFile(nil), e.List[level]...)
+		sort.Slice(files, func(i, j int) bool {
+			wi, wj := files[i].Perm&0002 != 0, files[j].Perm&0002 != 0
+			if wi != wj {
+				return wi
+			}
+			if files[i].Size != files[j].Size {
+				return files[i].Size > files[j].Size
+			}
+			return files[i].Path < files[j].Path
+		})
+		for _, file := range files[:min(maxCount, len(files))] {
+			access := file.Access
+			if perm, name, ok := strings.Cut(access, " "); ok {
+				access = perm + " " + displayName(name)
+			}
+			indicators := make([]string, len(file.Indicators))
+			for i, indicator := range file.Indicators {
+				indicators[i] = tr(indicator)
+			}
+			result.WriteString(fmt.Sprintf("  %s  %s (%s)\n",
+				access,
+				renderPath(file.Path),
+				strings.Join(indicators, ", ")))
+		}
+	}
+	result.WriteString("\n")
+}
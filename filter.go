@@ -0,0 +1,445 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Filter is a compiled --filter expression that is evaluated per file, e.g.
+//
+//	size>100M && mtime<2020-01-01 && ext in (mp4,mkv)
+//
+// Supported fields are size, mtime, atime, btime (creation time), age, ext,
+// name, path and category.
+// Comparisons use = == != < <= > >=, "in (a,b,...)" for lists and ~ for glob
+// matching on string fields. Terms combine with &&, ||, ! and parentheses.
+type Filter struct {
+	expr  string
+	match filterFunc
+}
+
+type filterFunc func(path string, info os.FileInfo) bool
+
+// Match reports whether the file satisfies the filter. A nil filter matches
+// every file.
+func (f *Filter) Match(path string, info os.FileInfo) bool {
+	if f == nil {
+		return true
+	}
+	return f.match(path, info)
+}
+
+func (f *Filter) String() string {
+	if f == nil {
+		return ""
+	}
+	return f.expr
+}
+
+// ParseFilter compiles a filter expression.
+func ParseFilter(expr string) (*Filter, error) {
+	tokens, err := tokenizeFilter(expr)
+	if err != nil {
+		return nil, err
+	}
+	p := &filterParser{tokens: tokens}
+	match, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %q in filter", p.tokens[p.pos].text)
+	}
+	return &Filter{expr: expr, match: match}, nil
+}
+
+type filterToken struct {
+	text   string
+	quoted bool
+}
+
+func tokenizeFilter(expr string) ([]filterToken, error) {
+	var tokens []filterToken
+	runes := []rune(expr)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '"' || r == '\'':
+			end := i + 1
+			for end < len(runes) && runes[end] != r {
+				end++
+			}
+			if end == len(runes) {
+				return nil, fmt.Errorf("unterminated string in filter")
+			}
+			tokens = append(tokens, filterToken{text: string(runes[i+1 : end]), quoted: true})
+			i = end + 1
+		case r == '&' || r == '|':
+			if i+1 >= len(runes) || runes[i+1] != r {
+				return nil, fmt.Errorf("expected %c%c in filter", r, r)
+			}
+			tokens = append(tokens, filterToken{text: string([]rune{r, r})})
+			i += 2
+		case r == '!' || r == '<' || r == '>' || r == '=':
+			if i+1 < len(runes) && runes[i+1] == '=' {
+				tokens = append(tokens, filterToken{text: string([]rune{r, '='})})
+				i += 2
+			} else {
+				tokens = append(tokens, filterToken{text: string(r)})
+				i++
+			}
+		case r == '(' || r == ')' || r == ',' || r == '~':
+			tokens = append(tokens, filterToken{text: string(r)})
+			i++
+		default:
+			end := i
+			for end < len(runes) && !unicode.IsSpace(runes[end]) && !strings.ContainsRune("\"'&|!<>=(),~", runes[end]) {
+				end++
+			}
+			tokens = append(tokens, filterToken{text: string(runes[i:end])})
+			i = end
+		}
+	}
+	return tokens, nil
+}
+
+type filterParser struct {
+	tokens []filterToken
+	pos    int
+}
+
+func (p *filterParser) peek() string {
+	if p.pos < len(p.tokens) && !p.tokens[p.pos].quoted {
+		return p.tokens[p.pos].text
+	}
+	return ""
+}
+
+func (p *filterParser) next() (filterToken, error) {
+	if p.pos >= len(p.tokens) {
+		return filterToken{}, fmt.Errorf("unexpected end of filter")
+	}
+	tok := p.tokens[p.pos]
+	p.pos++
+	return tok, nil
+}
+
+func (p *filterParser) expect(text string) error {
+	tok, err := p.next()
+	if err != nil {
+		return err
+	}
+	if tok.quoted || tok.text != text {
+		return fmt.Errorf("expected %q in filter, got %q", text, tok.text)
+	}
+	return nil
+}
+
+func (p *filterParser) parseOr() (filterFunc, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == "||" {
+		p.pos++
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(path string, info os.FileInfo) bool {
+			return l(path, info) || right(path, info)
+		}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseAnd() (filterFunc, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == "&&" {
+		p.pos++
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(path string, info os.FileInfo) bool {
+			return l(path, info) && right(path, info)
+		}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseUnary() (filterFunc, error) {
+	switch p.peek() {
+	case "!":
+		p.pos++
+		inner, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return func(path string, info os.FileInfo) bool {
+			return !inner(path, info)
+		}, nil
+	case "(":
+		p.pos++
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		return inner, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *filterParser) parseComparison() (filterFunc, error) {
+	fieldTok, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	field := strings.ToLower(fieldTok.text)
+
+	opTok, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	op := strings.ToLower(opTok.text)
+
+	var values []string
+	switch op {
+	case "in":
+		if err := p.expect("("); err != nil {
+			return nil, err
+		}
+		for {
+			tok, err := p.next()
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, tok.text)
+			if p.peek() == "," {
+				p.pos++
+				continue
+			}
+			break
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+	case "=", "==", "!=", "<", "<=", ">", ">=", "~":
+		tok, err := p.next()
+		if err != nil {
+			return nil, err
+		}
+		values = []string{tok.text}
+	default:
+		return nil, fmt.Errorf("unknown operator %q after %s", opTok.text, field)
+	}
+
+	return compileComparison(field, op, values)
+}
+
+// compileComparison builds one comparator per value. Each comparator returns
+// the ordering of the file's field against the value; the operator is then
+// applied to that ordering.
+func compileComparison(field, op string, values []string) (filterFunc, error) {
+	var cmps []func(path string, info os.FileInfo) int
+
+	isString := field == "ext" || field == "name" || field == "path" || field == "category"
+	if op == "~" && !isString {
+		return nil, fmt.Errorf("~ only applies to ext, name, path and category")
+	}
+
+	for _, value := range values {
+		switch field {
+		case "size":
+			size, err := parseSize(value)
+			if err != nil {
+				return nil, err
+			}
+			cmps = append(cmps, func(_ string, info os.FileInfo) int {
+				return compareInt64(info.Size(), size)
+			})
+		case "mtime", "atime", "btime":
+			t, err := parseDate(value)
+			if err != nil {
+				return nil, err
+			}
+			f := field
+			cmps = append(cmps, func(_ string, info os.FileInfo) int {
+				switch f {
+				case "atime":
+					if accessed, ok := accessTime(info); ok {
+						return accessed.Compare(t)
+					}
+				case "btime":
+					if created, ok := birthTime(info); ok {
+						return created.Compare(t)
+					}
+				}
+				return info.ModTime().Compare(t)
+			})
+		case "age":
+			age, err := parseAge(value)
+			if err != nil {
+				return nil, err
+			}
+			cmps = append(cmps, func(_ string, info os.FileInfo) int {
+				return compareInt64(int64(time.Since(info.ModTime())), int64(age))
+			})
+		case "ext", "name", "path", "category":
+			want := value
+			if field == "ext" || field == "category" {
+				want = strings.TrimPrefix(strings.ToLower(want), ".")
+			}
+			f := field
+			if op == "~" {
+				if _, err := filepath.Match(want, ""); err != nil {
+					return nil, fmt.Errorf("invalid pattern %q: %v", value, err)
+				}
+				cmps = append(cmps, func(path string, info os.FileInfo) int {
+					if matchGlob(want, filterStringField(f, path)) {
+						return 0
+					}
+					return 1
+				})
+			} else {
+				cmps = append(cmps, func(path string, info os.FileInfo) int {
+					return strings.Compare(filterStringField(f, path), want)
+				})
+			}
+		default:
+			return nil, fmt.Errorf("unknown filter field %q", field)
+		}
+	}
+
+	if op == "~" || op == "in" {
+		op = "="
+	}
+	return func(path string, info os.FileInfo) bool {
+		for _, cmp := range cmps {
+			if applyFilterOp(op, cmp(path, info)) {
+				return true
+			}
+		}
+		return false
+	}, nil
+}
+
+func filterStringField(field, path string) string {
+	switch field {
+	case "ext":
+		return strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+	case "category":
+		return fileTypeCategoryMap[strings.ToLower(filepath.Ext(path))]
+	case "name":
+		return filepath.Base(path)
+	}
+	return path
+}
+
+func applyFilterOp(op string, cmp int) bool {
+	switch op {
+	case "=", "==":
+		return cmp == 0
+	case "!=":
+		return cmp != 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	}
+	return false
+}
+
+func compareInt64(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// parseSize parses sizes such as 512, 10K, 100M, 1.5G or 2TB using binary
+// multiples.
+func parseSize(s string) (int64, error) {
+	value := strings.ToUpper(strings.TrimSpace(s))
+	value = strings.TrimSuffix(strings.TrimSuffix(value, "IB"), "B")
+
+	multiplier := int64(1)
+	if value != "" {
+		switch value[len(value)-1] {
+		case 'K':
+			multiplier = 1024
+		case 'M':
+			multiplier = 1024 * 1024
+		case 'G':
+			multiplier = 1024 * 1024 * 1024
+		case 'T':
+			multiplier = 1024 * 1024 * 1024 * 1024
+		}
+		if multiplier > 1 {
+			value = value[:len(value)-1]
+		}
+	}
+
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return int64(n * float64(multiplier)), nil
+}
+
+// parseAge parses durations such as 30d, 2w, 1y or anything accepted by
+// time.ParseDuration.
+func parseAge(s string) (time.Duration, error) {
+	units := map[string]time.Duration{
+		"d": 24 * time.Hour,
+		"w": 7 * 24 * time.Hour,
+		"y": 365 * 24 * time.Hour,
+	}
+	value := strings.ToLower(strings.TrimSpace(s))
+	for suffix, unit := range units {
+		if strings.HasSuffix(value, suffix) {
+			n, err := strconv.ParseFloat(strings.TrimSuffix(value, suffix), 64)
+			if err != nil {
+				return 0, fmt.Errorf("invalid age %q", s)
+			}
+			return time.Duration(n * float64(unit)), nil
+		}
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid age %q", s)
+	}
+	return d, nil
+}
+
+// parseDate parses a date in local time, optionally with a time of day.
+func parseDate(s string) (time.Time, error) {
+	for _, layout := range []string{"2006-01-02", "2006-01-02T15:04:05", "2006-01-02T15:04", "2006-01-02 15:04:05"} {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q (want YYYY-MM-DD)", s)
+}
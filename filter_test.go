@@ -0,0 +1,183 @@
+package main
+
+import (
+	"io/fs"
+	"testing"
+	"time"
+)
+
+// filterFile is a file to match filters against without creating it.
+type filterFile struct {
+	name    string
+	size    int64
+	modTime time.Time
+}
+
+func (f filterFile) Name() string       { return f.name }
+func (f filterFile) Size() int64        { return f.size }
+func (f filterFile) Mode() fs.FileMode  { return 0644 }
+func (f filterFile) ModTime() time.Time { return f.modTime }
+func (f filterFile) IsDir() bool        { return false }
+func (f filterFile) Sys() any           { return nil }
+
+func TestParseFilter(t *testing.T) {
+	defer func(categories map[string]string) { fileTypeCategoryMap = categories }(fileTypeCategoryMap)
+	fileTypeCategoryMap = map[string]string{".mp4": "video", ".go": "code"}
+
+	old := time.Date(2019, 6, 1, 12, 0, 0, 0, time.Local)
+	recent := time.Now().Add(-48 * time.Hour)
+	video := filterFile{"holiday.mp4", 1536 * 1024 * 1024, old}
+	source := filterFile{"main.go", 2048, recent}
+	notes := filterFile{"notes.txt", 100, recent}
+	paths := map[filterFile]string{
+		video:  "/home/u/videos/holiday.mp4",
+		source: "/home/u/src/madaa/main.go",
+		notes:  "/home/u/notes.txt",
+	}
+
+	tests := []struct {
+		expr string
+		file filterFile
+		want bool
+	}{
+		// Sizes with units, binary multiples
+		{"size > 1G", video, true},
+		{"size >= 1.5G", video, true},
+		{"size > 1.5GB", video, false},
+		{"size = 2K", source, true},
+		{"size == 2KiB", source, true},
+		{"size != 2048", source, false},
+		{"size < 1k", notes, true},
+		{"size <= 100", notes, true},
+		{"size > 100", notes, false},
+
+		// Dates and ages
+		{"mtime < 2020-01-01", video, true},
+		{"mtime < 2020-01-01", source, false},
+		{"mtime >= '2019-06-01 12:00:00'", video, true},
+		{"mtime > 2019-06-01T12:00", video, false},
+		{"atime < 2020-01-01", video, true},
+		{"age > 1y", video, true},
+		{"age > 30d", source, false},
+		{"age < 1w", source, true},
+		{"age > 24h", source, true},
+
+		// Strings, lists and globs
+		{"ext = mp4", video, true},
+		{"ext = .MP4", video, true},
+		{"ext in (mp4, mkv)", video, true},
+		{"ext in (mkv,avi)", video, false},
+		{"ext ~ 'm*'", video, true},
+		{"name = main.go", source, true},
+		{"name ~ \"*.txt\"", notes, true},
+		{"path ~ '**/src/**'", source, true},
+		{"path ~ '**/src/**'", notes, false},
+		{"category = video", video, true},
+		{"category in (code, text)", source, true},
+		{"category ~ 'vid*'", source, false},
+		{"EXT IN (go)", source, true},
+
+		// Logic and precedence: ! binds tighter than &&, && tighter than ||
+		{"ext = go && size > 1K", source, true},
+		{"ext = go && size > 1M", source, false},
+		{"ext = txt || ext = go", source, true},
+		{"ext = txt || ext = go && size > 1M", notes, true},
+		{"(ext = txt || ext = go) && size > 1M", notes, false},
+		{"!ext = txt && size > 1M", source, false},
+		{"!(ext = txt && size > 1M)", source, true},
+		{"!!ext = go", source, true},
+		{"! (ext = go)", source, false},
+	}
+	for _, tt := range tests {
+		filter, err := ParseFilter(tt.expr)
+		if err != nil {
+			t.Errorf("ParseFilter(%q): %v", tt.expr, err)
+			continue
+		}
+		if got := filter.Match(paths[tt.file], tt.file); got != tt.want {
+			t.Errorf("%q on %s = %v, want %v", tt.expr, tt.file.name, got, tt.want)
+		}
+		if filter.String() != tt.expr {
+			t.Errorf("String() = %q, want %q", filter.String(), tt.expr)
+		}
+	}
+
+	var none *Filter
+	if !none.Match("/a", notes) || none.String() != "" {
+		t.Error("nil filter doesn't match everything")
+	}
+}
+
+func TestParseFilterErrors(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"size",
+		"size >",
+		"size > big",
+		"size > -1",
+		"size ? 1",
+		"size ~ 1K",
+		"mtime < yesterday",
+		"age > old",
+		"age > 3x",
+		"color = red",
+		"ext = mp4 &",
+		"ext = mp4 | ext = mkv",
+		"ext = mp4 &&",
+		"ext = mp4 ext = mkv",
+		"(ext = mp4",
+		"ext = mp4)",
+		"()",
+		"!",
+		"ext in mp4",
+		"ext in (mp4",
+		"ext in (mp4,",
+		"ext in ()",
+		"name = 'unterminated",
+		"name ~ '[a-'",
+		"size > 1 (",
+	} {
+		t.Run(expr, func(t *testing.T) {
+			defer func() {
+				if r := recover(); r != nil {
+					t.Fatalf("ParseFilter(%q) panicked: %v", expr, r)
+				}
+			}()
+			if filter, err := ParseFilter(expr); err == nil {
+				t.Errorf("ParseFilter(%q) = %v, want an error", expr, filter)
+			}
+		})
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	for value, want := range map[string]int64{
+		"512":   512,
+		"10K":   10 * 1024,
+		"10kb":  10 * 1024,
+		"100M":  100 * 1024 * 1024,
+		"1.5G":  1536 * 1024 * 1024,
+		"2TiB":  2 * 1024 * 1024 * 1024 * 1024,
+		" 1MB ": 1024 * 1024,
+	} {
+		if got, err := parseSize(value); err != nil || got != want {
+			t.Errorf("parseSize(%q) = %d, %v, want %d", value, got, err, want)
+		}
+	}
+}
+
+func TestParseAge(t *testing.T) {
+	day := 24 * time.Hour
+	for value, want := range map[string]time.Duration{
+		"30d":   30 * day,
+		"2w":    14 * day,
+		"1y":    365 * day,
+		"0.5d":  12 * time.Hour,
+		"90m":   90 * time.Minute,
+		"1h30m": 90 * time.Minute,
+	} {
+		if got, err := parseAge(value); err != nil || got != want {
+			t.Errorf("parseAge(%q) = %v, %v, want %v", value, got, err, want)
+		}
+	}
+}
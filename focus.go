@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// fileCategory is the category config.ini's [file_types] assigns to the
+// file's extension, or "" for extensions it doesn't list.
+func fileCategory(path string) string {
+	return fileTypeCategoryMap[strings.ToLower(filepath.Ext(path))]
+}
+
+// categoryNames lists the categories used in [file_types].
+func categoryNames() []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, category := range fileTypeCategoryMap {
+		if !seen[category] {
+			seen[category] = true
+			names = append(names, category)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// inFocus tells whether the file is analyzed in depth: with --only-category
+// only files of that category are, the others are just counted.
+func (c Config) inFocus(path string) bool {
+	return c.OnlyCategory == "" || strings.EqualFold(fileCategory(path), c.OnlyCategory)
+}
+
+// countOutsideFocus adds a file outside the --only-category focus to the
+// total for the rest.
+func countOutsideFocus(size int64, stats *Stats) {
+	stats.mu.Lock()
+	defer stats.mu.Unlock()
+	stats.OtherFiles++
+	stats.OtherSize += size
+}
+
+// displayFocus is the one-line total for the files outside the category
+// the report focuses on.
+func displayFocus(stats *Stats, result *strings.Builder) {
+	if stats.Focus == "" {
+		return
+	}
+	var share float64
+	if total := stats.TotalSize + stats.OtherSize; total > 0 {
+		share = float64(stats.OtherSize) / float64(total) * 100
+	}
+	result.WriteString(fmt.Sprintf(tr("Only %s files are analyzed. Other files: %s, %s (%s of the total)\n\n"),
+		warnStyle.Render(tr(stats.Focus)),
+		numberStyle.Render(formatCount(stats.OtherFiles)),
+		numberStyle.Render(formatMB(stats.OtherSize)),
+		percentStyle.Render(formatPercent(share))))
+}
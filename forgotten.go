@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// forgottenAfter is how long a directory's contents must have gone untouched
+// for it to be listed as forgotten. Set by --forgotten-days.
+var forgottenAfter = 365 * 24 * time.Hour
+
+// DirActivity sums the files directly inside one directory and records when
+// any of them was last used.
+type DirActivity struct {
+	Files    int
+	Size     int64
+	LastUsed time.Time
+	// The newest mtime and the files older than staleAfter
+	NewestMod time.Time
+	Stale     int
+	// The files and bytes per access tier, indexed like accessTiers
+	TierFiles [len(accessTiers)]int
+	TierBytes [len(accessTiers)]int64
+}
+
+// addDetails adds the stale files, newest mtime and per-tier counts of src
+// to a.
+func (a *DirActivity) addDetails(src *DirActivity) {
+	a.Stale += src.Stale
+	if src.NewestMod.After(a.NewestMod) {
+		a.NewestMod = src.NewestMod
+	}
+	for i := range a.TierFiles {
+		a.TierFiles[i] += src.TierFiles[i]
+		a.TierBytes[i] += src.TierBytes[i]
+	}
+}
+
+// lastUsed is the later of a file's access and modification time. Taking the
+// later one keeps noatime mounts, where atime never moves, from making
+// recently edited files look abandoned.
+func lastUsed(info os.FileInfo) time.Time {
+	used := info.ModTime()
+	if atime, ok := accessTime(info); ok && atime.After(used) {
+		used = atime
+	}
+	return used
+}
+
+func analyzeDirActivity(path string, info os.FileInfo, stats *Stats) {
+	dir := filepath.Dir(path)
+	activity := stats.DirActivity[dir]
+	if activity == nil {
+		activity = &DirActivity{}
+		stats.DirActivity[dir] = activity
+	}
+	activity.Files++
+	activity.Size += info.Size()
+	if info.ModTime().After(activity.NewestMod) {
+		activity.NewestMod = info.ModTime()
+	}
+	if time.Since(info.ModTime()) > staleAfter {
+		activity.Stale++
+	}
+	tier := fileTier(info)
+	activity.TierFiles[tier]++
+	activity.TierBytes[tier] += info.Size()
+	if used := lastUsed(info); used.After(activity.LastUsed) {
+		activity.LastUsed = used
+	}
+}
+
+// dirSummary is a directory tree with the total size of its files and the
+// last time any of them was used.
+type dirSummary struct {
+	Path     string
+	Size     int64
+	LastUsed time.Time
+}
+
+// dirTrees rolls the per-directory activity up into every scanned
+// subdirectory, so each entry covers the whole tree below it.
+func dirTrees(stats *Stats) map[string]*DirActivity {
+	trees := make(map[string]*DirActivity, len(stats.DirDepths))
+	for dir := range stats.DirDepths {
+		trees[dir] = &DirActivity{}
+	}
+	for dir, activity := range stats.DirActivity {
+		for p := dir; ; p = filepath.Dir(p) {
+			tree, ok := trees[p]
+			if !ok {
+				break
+			}
+			tree.Files += activity.Files
+			tree.Size += activity.Size
+			tree.addDetails(activity)
+			if activity.LastUsed.After(tree.LastUsed) {
+				tree.LastUsed = activity.LastUsed
+			}
+		}
+	}
+	return trees
+}
+
+// forgottenDirs returns the outermost directories whose whole contents are
+// older than forgottenAfter, largest first.
+func forgottenDirs(stats *Stats) []dirSummary {
+	trees := dirTrees(stats)
+	cutoff := time.Now().Add(-forgottenAfter)
+	isForgotten := func(dir string) bool {
+		tree := trees[dir]
+		return tree != nil && tree.Size > 0 && tree.LastUsed.Before(cutoff)
+	}
+
+	var dirs []dirSummary
+	for dir, tree := range trees {
+		// Only report the top of an abandoned tree, not each of its subdirectories
+		if !isForgotten(dir) || isForgotten(filepath.Dir(dir)) {
+			continue
+		}
+		dirs = append(dirs, dirSummary{Path: dir, Size: tree.Size, LastUsed: tree.LastUsed})
+	}
+	sort.Slice(dirs, func(i, j int) bool {
+		if dirs[i].Size != dirs[j].Size {
+			return dirs[i].Size > dirs[j].Size
+		}
+		return dirs[i].Path < dirs[j].Path
+	})
+	return dirs
+}
+
+func displayForgottenDirs(stats *Stats, maxCount int, result *strings.Builder) {
+	dirs := forgottenDirs(stats)
+	if len(dirs) == 0 {
+		return
+	}
+
+	result.WriteString(headerStyle.Render(fmt.Sprintf(tr("Forgotten Directories (untouched for %d days)"), int(forgottenAfter.Hours()/24))))
+	result.WriteString("\n")
+	for _, dir := range dirs[:min(maxCount, len(dirs))] {
+		result.WriteString(fmt.Sprintf(tr("%s last used %s: %s\n"),
+			numberStyle.Render(formatMB(dir.Size)),
+			warnStyle.Render(formatDate(dir.LastUsed)),
+			renderPath(dir.Path)))
+	}
+	result.WriteString("\n")
+}
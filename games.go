@@ -0,0 +1,275 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxManifestSize caps the game manifests read, real ones are a few KB.
+const maxManifestSize = 1024 * 1024
+
+// gameInstallerPatterns match the offline installers of GOG, which are
+// often kept long after the game was installed: setup_*.exe with its .bin
+// parts on Windows, gog_*.sh on Linux.
+var gameInstallerPatterns = []string{"setup_*.exe", "setup_*-*.bin", "gog_*.sh"}
+
+// Game is a game installed from a launcher, as found by its manifest.
+// ManifestBytes is the install size the launcher recorded, used when the
+// install directory wasn't scanned. LastPlayed is only known for Steam.
+type Game struct {
+	Name          string
+	Store         string
+	Path          string
+	ManifestBytes int64
+	LastPlayed    time.Time
+}
+
+// GameStats are the games found, by install directory, and the largest
+// game installers.
+type GameStats struct {
+	Games      map[string]*Game
+	Installers *FileSizeHeap
+}
+
+func newGameStats() GameStats {
+	return GameStats{
+		Games:      make(map[string]*Game),
+		Installers: &FileSizeHeap{},
+	}
+}
+
+// processGame recognizes the manifests of Steam (steamapps/appmanifest_*.acf),
+// Epic (Manifests/*.item) and GOG (goggame-*.info) and game installers.
+func processGame(path string, info os.FileInfo, stats *Stats, maxFiles int) {
+	if !info.Mode().IsRegular() {
+		return
+	}
+
+	name := strings.ToLower(filepath.Base(path))
+	var game *Game
+	switch {
+	case strings.HasPrefix(name, "appmanifest_") && strings.HasSuffix(name, ".acf"):
+		game = readSteamManifest(path)
+	case strings.HasSuffix(name, ".item") && strings.EqualFold(filepath.Base(filepath.Dir(path)), "Manifests"):
+		game = readEpicManifest(path)
+	case strings.HasPrefix(name, "goggame-") && strings.HasSuffix(name, ".info"):
+		game = readGOGManifest(path)
+	default:
+		for _, pattern := range gameInstallerPatterns {
+			if ok, _ := filepath.Match(pattern, name); ok {
+				stats.mu.Lock()
+				pushLargest(stats.Games.Installers, FileSize{path, info.Size(), filepath.Ext(name)}, maxFiles)
+				stats.mu.Unlock()
+				return
+			}
+		}
+		return
+	}
+	if game == nil {
+		return
+	}
+
+	stats.mu.Lock()
+	defer stats.mu.Unlock()
+	stats.Games.Games[game.Path] = game
+}
+
+// readManifest returns the contents of a manifest, or nil if it can't be
+// read or is too large to be one.
+func readManifest(path string) []byte {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	data, err := io.ReadAll(io.LimitReader(f, maxManifestSize+1))
+	if err != nil || len(data) > maxManifestSize {
+		return nil
+	}
+	return data
+}
+
+// readSteamManifest reads the name, install directory, size and last play
+// of a Steam app from its manifest, a VDF file of quoted keys and values.
+// The game is installed in common/<installdir> next to the manifest.
+func readSteamManifest(path string) *Game {
+	data := readManifest(path)
+	if data == nil {
+		return nil
+	}
+
+	values := make(map[string]string)
+	depth := 0
+	scanner := bufio.NewScanner(strings.NewReader(string(data)))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch line {
+		case "{":
+			depth++
+			continue
+		case "}":
+			depth--
+			continue
+		}
+		// Only the keys of AppState itself, not of its nested blocks
+		fields := strings.Split(line, "\"")
+		if depth == 1 && len(fields) >= 4 {
+			values[strings.ToLower(fields[1])] = fields[3]
+		}
+	}
+	if values["installdir"] == "" {
+		return nil
+	}
+
+	game := &Game{
+		Name:  values["name"],
+		Store: "Steam",
+		Path:  filepath.Join(filepath.Dir(path), "common", values["installdir"]),
+	}
+	if game.Name == "" {
+		game.Name = values["installdir"]
+	}
+	game.ManifestBytes, _ = strconv.ParseInt(values["sizeondisk"], 10, 64)
+	if played, err := strconv.ParseInt(values["lastplayed"], 10, 64); err == nil && played > 0 {
+		game.LastPlayed = time.Unix(played, 0)
+	}
+	return game
+}
+
+// readEpicManifest reads the JSON manifest the Epic Games Launcher keeps per
+// installed game.
+func readEpicManifest(path string) *Game {
+	data := readManifest(path)
+	if data == nil {
+		return nil
+	}
+	var manifest struct {
+		DisplayName     string
+		InstallLocation string
+		InstallSize     int64
+	}
+	if err := json.Unmarshal(data, &manifest); err != nil || manifest.InstallLocation == "" {
+		return nil
+	}
+	return &Game{
+		Name:          manifest.DisplayName,
+		Store:         "Epic",
+		Path:          filepath.Clean(filepath.FromSlash(strings.ReplaceAll(manifest.InstallLocation, `\`, "/"))),
+		ManifestBytes: manifest.InstallSize,
+	}
+}
+
+// readGOGManifest reads the goggame-<id>.info file GOG puts into the
+// directory of each game it installs.
+func readGOGManifest(path string) *Game {
+	data := readManifest(path)
+	if data == nil {
+		return nil
+	}
+	var manifest struct {
+		Name string `json:"name"`
+	}
+	if err := json.Unmarshal(data, &manifest); err != nil || manifest.Name == "" {
+		return nil
+	}
+	return &Game{Name: manifest.Name, Store: "GOG", Path: filepath.Dir(path)}
+}
+
+func mergeGameStats(dst, src *GameStats, maxFiles int) {
+	for dir, game := range src.Games {
+		dst.Games[dir] = game
+	}
+	mergeLargest(dst.Installers, src.Installers, maxFiles)
+}
+
+// gameInstall is a game reported with the size of its install directory.
+// LastPlayed falls back to the last use of the directory's files for the
+// launchers that don't record it.
+type gameInstall struct {
+	Game
+	Size int64
+}
+
+// gameInstalls returns the games found, largest first.
+func gameInstalls(stats *Stats) []gameInstall {
+	if len(stats.Games.Games) == 0 {
+		return nil
+	}
+	trees := dirTrees(stats)
+
+	var games []gameInstall
+	for dir, game := range stats.Games.Games {
+		install := gameInstall{Game: *game, Size: game.ManifestBytes}
+		if tree := trees[dir]; tree != nil && tree.Files > 0 {
+			install.Size = tree.Size
+			if install.LastPlayed.IsZero() {
+				install.LastPlayed = tree.LastUsed
+			}
+		}
+		games = append(games, install)
+	}
+	sort.Slice(games, func(i, j int) bool {
+		if games[i].Size != games[j].Size {
+			return games[i].Size > games[j].Size
+		}
+		return games[i].Path < games[j].Path
+	})
+	return games
+}
+
+// displayGames lists the installed games, which are routinely the largest
+// consumers of personal machines, and the installers kept around.
+func displayGames(stats *Stats, maxCount int, result *strings.Builder) {
+	games := gameInstalls(stats)
+	installers := stats.Games.Installers
+	if len(games) == 0 && (installers == nil || installers.Len() == 0) {
+		return
+	}
+
+	var total int64
+	for _, game := range games {
+		total += game.Size
+	}
+	result.WriteString(headerStyle.Render(tr("Games")))
+	result.WriteString("\n")
+	if len(games) > 0 {
+		result.WriteString(fmt.Sprintf(tr("%s games installed, %s\n"),
+			numberStyle.Render(formatCount(len(games))),
+			numberStyle.Render(formatMB(total))))
+	}
+	for _, game := range games[:min(maxCount, len(games))] {
+		var played string
+		switch {
+		case game.LastPlayed.IsZero():
+			played = warnStyle.Render(tr("never"))
+		case time.Since(game.LastPlayed) > staleAfter:
+			played = warnStyle.Render(formatDate(game.LastPlayed))
+		default:
+			played = goodStyle.Render(formatDate(game.LastPlayed))
+		}
+		result.WriteString(fmt.Sprintf(tr("%s %-6s %-30s last played %s  %s\n"),
+			numberStyle.Render(fmt.Sprintf("%11s", formatMB(game.Size))),
+			game.Store,
+			game.Name,
+			played,
+			renderPath(game.Path)))
+	}
+	if installers != nil && installers.Len() > 0 {
+		var bytes int64
+		for _, file := range *installers {
+			bytes += file.Size
+		}
+		result.WriteString(fmt.Sprintf(tr("Largest game installers: %s\n"), numberStyle.Render(formatMB(bytes))))
+		displayLargestFiles(installers, result)
+		return
+	}
+	result.WriteString("\n")
+}
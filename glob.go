@@ -0,0 +1,40 @@
+package main
+
+import (
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// matchGlob is filepath.Match extended with "**", which matches any number
+// of path segments, so "**/cache/**" matches every file below any directory
+// named cache. Other wildcards don't cross a path separator.
+func matchGlob(pattern, name string) bool {
+	return matchSegments(
+		strings.Split(filepath.ToSlash(pattern), "/"),
+		strings.Split(filepath.ToSlash(name), "/"))
+}
+
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			if len(pattern) == 1 {
+				return true
+			}
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// dirGrowth is how much the tree below one directory grew between two
+// snapshots. Path is relative to the snapshot root.
+type dirGrowth struct {
+	Path    string
+	OldSize int64
+	NewSize int64
+}
+
+func (g dirGrowth) Delta() int64 {
+	return g.NewSize - g.OldSize
+}
+
+// treeSizes rolls the file sizes of the snapshot up into every directory
+// at or below sub, keyed relative to the snapshot root.
+func treeSizes(snap *Snapshot, sub string) map[string]int64 {
+	sizes := make(map[string]int64)
+	for dir, listing := range relativeDirs(snap) {
+		if !isBelow(dir, sub) {
+			continue
+		}
+		var size int64
+		for _, f := range listing.Files {
+			size += f.Size
+		}
+		for p := dir; ; p = filepath.Dir(p) {
+			sizes[p] += size
+			if p == sub || p == "." {
+				break
+			}
+		}
+	}
+	return sizes
+}
+
+// isBelow reports whether the relative path dir is sub or inside it.
+func isBelow(dir, sub string) bool {
+	return sub == "." || dir == sub || strings.HasPrefix(dir, sub+string(os.PathSeparator))
+}
+
+// snapshotSubdir returns path relative to the snapshot root if the snapshot
+// covers it.
+func snapshotSubdir(snap *Snapshot, path string) (string, bool) {
+	root, err := filepath.Abs(snap.Root)
+	if err != nil {
+		return "", false
+	}
+	target, err := filepath.Abs(path)
+	if err != nil {
+		return "", false
+	}
+	rel, err := filepath.Rel(root, target)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(os.PathSeparator)) {
+		return "", false
+	}
+	return rel, true
+}
+
+// growthSince picks the newest snapshot and the newest one taken at least
+// since before it, falling back to the oldest if none is that old.
+func growthSince(snaps []*Snapshot, since time.Duration) (older, newer *Snapshot) {
+	sort.Slice(snaps, func(i, j int) bool { return snaps[i].Created.Before(snaps[j].Created) })
+	newer = snaps[len(snaps)-1]
+	older = snaps[0]
+	cutoff := newer.Created.Add(-since)
+	for _, snap := range snaps[:len(snaps)-1] {
+		if !snap.Created.After(cutoff) {
+			older = snap
+		}
+	}
+	return older, newer
+}
+
+// directoryGrowth compares the tree sizes below sub, largest growth first.
+// sub itself is left out; it is the total.
+func directoryGrowth(older, newer *Snapshot, sub string) (dirGrowth, []dirGrowth) {
+	var dirs []dirGrowth
+	oldSizes := treeSizes(older, sub)
+	newSizes := treeSizes(newer, sub)
+	for dir, size := range newSizes {
+		g := dirGrowth{Path: dir, OldSize: oldSizes[dir], NewSize: size}
+		if dir != sub && g.Delta() > 0 {
+			dirs = append(dirs, g)
+		}
+	}
+	sort.Slice(dirs, func(i, j int) bool {
+		if dirs[i].Delta() != dirs[j].Delta() {
+			return dirs[i].Delta() > dirs[j].Delta()
+		}
+		return dirs[i].Path < dirs[j].Path
+	})
+	return dirGrowth{Path: sub, OldSize: oldSizes[sub], NewSize: newSizes[sub]}, dirs
+}
+
+func formatGrowthPercent(g dirGrowth) string {
+	if g.OldSize == 0 {
+		return tr("new")
+	}
+	percent := float64(g.Delta()) / float64(g.OldSize) * 100
+	sign := "+"
+	if percent < 0 {
+		sign = ""
+	}
+	return sign + formatPercent(percent)
+}
+
+// runHistoryGrowth implements "madaa history growth --since 30d <path>
+// <snapshot>...": it lists the directories below path that grew the most
+// between the snapshots.
+func runHistoryGrowth(args []string) {
+	flags := flag.NewFlagSet("history growth", flag.ExitOnError)
+	sinceFlag := flags.String("since", "30d", "Period to compare, e.g. 30d, 12w or 1y")
+	count := flags.Int("count", 10, "Number of directories to show")
+	selectLanguage := localeFlags(flags)
+	flags.Parse(args)
+	selectLanguage()
+
+	if flags.NArg() < 2 {
+		fmt.Println("Usage: madaa history growth [--since AGE] [--count N] <path> <snapshot> [<snapshot>...]")
+		os.Exit(1)
+	}
+	since, err := parseAge(*sinceFlag)
+	if err != nil {
+		fmt.Printf("Error parsing --since: %v\n", err)
+		os.Exit(1)
+	}
+
+	path := flags.Arg(0)
+	var snaps []*Snapshot
+	var sub string
+	for _, snapPath := range flags.Args()[1:] {
+		snap, err := loadSnapshot(snapPath)
+		if err == nil {
+			err = requireListings(snapPath, snap)
+		}
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		rel, ok := snapshotSubdir(snap, path)
+		if !ok {
+			continue
+		}
+		if len(snaps) > 0 && rel != sub {
+			fmt.Printf("Error: %s has a different root than the other snapshots\n", snapPath)
+			os.Exit(1)
+		}
+		sub = rel
+		snaps = append(snaps, snap)
+	}
+	if len(snaps) < 2 {
+		fmt.Printf("Error: need at least two snapshots covering %s\n", path)
+		os.Exit(1)
+	}
+
+	older, newer := growthSince(snaps, since)
+	total, dirs := directoryGrowth(older, newer, sub)
+
+	var result strings.Builder
+	result.WriteString(headerStyle.Render(fmt.Sprintf(tr("Growth of %s"), path)))
+	result.WriteString("\n")
+	result.WriteString(fmt.Sprintf(tr("From %s %s to %s %s\n"),
+		goodStyle.Render(formatDateTime(older.Created)), warnStyle.Render(older.Label),
+		goodStyle.Render(formatDateTime(newer.Created)), warnStyle.Render(newer.Label)))
+	if newer.Created.Sub(older.Created) < since {
+		result.WriteString(warnStyle.Render(fmt.Sprintf(tr("No snapshot is old enough, comparing with the oldest one (%s days)"),
+			formatCount(int(newer.Created.Sub(older.Created).Hours()/24)))))
+		result.WriteString("\n")
+	}
+	result.WriteString(fmt.Sprintf(tr("Total: %s -> %s, %s %s\n"),
+		numberStyle.Render(formatMB(total.OldSize)),
+		numberStyle.Render(formatMB(total.NewSize)),
+		deltaStyle(total.Delta()),
+		percentStyle.Render("("+formatGrowthPercent(total)+")")))
+	for _, g := range dirs[:min(*count, len(dirs))] {
+		result.WriteString(fmt.Sprintf("  %s %s %s\n",
+			badStyle.Render(fmt.Sprintf("%12s", formatDeltaMB(g.Delta()))),
+			percentStyle.Render(fmt.Sprintf("(%7s)", formatGrowthPercent(g))),
+			renderPath(filepath.Join(newer.Root, g.Path))))
+	}
+	fmt.Print(fitPaths(result.String(), pathWidth))
+}
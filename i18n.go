@@ -0,0 +1,126 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// lang is the language of reports, set by --lang, and precision the number
+// of decimals of sizes and percentages, set by --precision.
+var (
+	lang      = "en"
+	precision = 1
+)
+
+// locale holds the number and date conventions of a language and the
+// translations of report text. Messages are keyed by their English text, so
+// untranslated messages fall back to English.
+type locale struct {
+	Decimal  string
+	Group    string
+	Date     string
+	DateTime string
+	Messages map[string]string
+}
+
+var locales = map[string]*locale{
+	"en": {Decimal: ".", Group: ",", Date: "2006-01-02", DateTime: "2006-01-02 15:04"},
+	"de": {Decimal: ",", Group: ".", Date: "02.01.2006", DateTime: "02.01.2006 15:04", Messages: germanMessages},
+}
+
+func currentLocale() *locale {
+	if l, ok := locales[lang]; ok {
+		return l
+	}
+	return locales["en"]
+}
+
+// setLanguage selects the report language for --lang.
+func setLanguage(name string) error {
+	if _, ok := locales[name]; !ok {
+		names := make([]string, 0, len(locales))
+		for name := range locales {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return fmt.Errorf("unknown language %q, available: %s", name, strings.Join(names, ", "))
+	}
+	lang = name
+	return nil
+}
+
+// localeFlags registers --lang and --precision on flags. The returned
+// function selects the language after parsing and exits on unknown ones.
+func localeFlags(flags *flag.FlagSet) func() {
+	name := flags.String("lang", "en", "Language of the report (en, de)")
+	flags.IntVar(&precision, "precision", 1, "Decimals of sizes and percentages in the report")
+	flags.BoolVar(&asciiOnly, "ascii", false, "Use only ASCII characters, no emoji or arrows")
+	flags.BoolVar(&accessible, "accessible", false, "Linear, labeled output without colors, animation or symbols, for screen readers and braille terminals")
+	flags.IntVar(&pathWidth, "width", 0, "Shorten paths in the middle so printed report lines fit N columns (0 keeps full paths)")
+	return func() {
+		if err := setLanguage(*name); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		if accessible {
+			asciiOnly = true
+		}
+	}
+}
+
+// tr translates a report message or format string.
+func tr(msg string) string {
+	if translated, ok := currentLocale().Messages[msg]; ok {
+		return plainText(translated)
+	}
+	return plainText(msg)
+}
+
+// groupDigits inserts the locale's grouping separator into a string of
+// digits with an optional sign: "-1234567" becomes "-1,234,567".
+func groupDigits(digits string) string {
+	sign := ""
+	if strings.HasPrefix(digits, "-") || strings.HasPrefix(digits, "+") {
+		sign, digits = digits[:1], digits[1:]
+	}
+	var b strings.Builder
+	for i, d := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			b.WriteString(currentLocale().Group)
+		}
+		b.WriteRune(d)
+	}
+	return sign + b.String()
+}
+
+// formatCount formats a count with grouping separators.
+func formatCount[T int | int64](n T) string {
+	return groupDigits(strconv.FormatInt(int64(n), 10))
+}
+
+// formatFloat formats v with prec decimals and the locale's separators.
+func formatFloat(v float64, prec int) string {
+	whole, frac, _ := strings.Cut(strconv.FormatFloat(v, 'f', max(prec, 0), 64), ".")
+	if frac == "" {
+		return groupDigits(whole)
+	}
+	return groupDigits(whole) + currentLocale().Decimal + frac
+}
+
+// formatPercent formats a percentage with --precision decimals.
+func formatPercent(v float64) string {
+	return formatFloat(v, precision) + "%"
+}
+
+func formatDate(t time.Time) string {
+	return t.Format(currentLocale().Date)
+}
+
+func formatDateTime(t time.Time) string {
+	return t.Format(currentLocale().DateTime)
+}
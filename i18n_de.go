@@ -0,0 +1,461 @@
+package main
+
+// germanMessages translates the report into German.
+var germanMessages = map[string]string{
+	// Titles and sections
+	"MADAA - Mass Data Analysis Results": "MADAA - Ergebnisse der Massendatenanalyse",
+	"MADAA - Change Report":              "MADAA - Änderungsbericht",
+	"MADAA - Replica Verification":       "MADAA - Prüfung der Replikate",
+	"Overview":                           "Übersicht",
+	"File Categories":                    "Dateikategorien",
+	"File Types":                         "Dateitypen",
+	"Top %d Largest Files":               "Die %d größten Dateien",
+	"Top %d Largest Directories":         "Die %d größten Verzeichnisse",
+	"Size Distribution":                  "Größenverteilung",
+	"Age Analysis":                       "Altersanalyse",
+	"Special Files":                      "Besondere Dateien",
+	"Directory Info":                     "Verzeichnisse",
+	"Owners by Age":                      "Besitzer nach Alter",
+	"Forgotten Directories (untouched for %d days)": "Vergessene Verzeichnisse (seit %d Tagen unberührt)",
+	"Nested Archives":                       "Verschachtelte Archive",
+	"Archives Next to Their Extracted Copy": "Archive neben ihrer entpackten Kopie",
+	"Clutter":                               "Unordnung",
+	"Disk Images and Volumes":               "Disk-Images und Volumes",
+	"Databases":                             "Datenbanken",
+	"Log Files":                             "Logdateien",
+	"Cleanup Plan":                          "Bereinigungsplan",
+	"Remove: %s files, %s\n":                "Entfernen: %s Dateien, %s\n",
+	"  %-22s %s files\n":                    "  %-22s %s Dateien\n",
+	"Protected, left alone: %s files, %s\n": "Geschützt, bleiben erhalten: %s Dateien, %s\n",
+	"system directory %s":                   "Systemverzeichnis %s",
+	"protected by %s":                       "geschützt durch %s",
+	"This removes %s. Type the number of files (%d) to confirm: ": "Dies entfernt %s. Zur Bestätigung die Anzahl der Dateien (%d) eingeben: ",
+	"Remove %s files? [y/N] ":                                     "%s Dateien entfernen? [j/N] ",
+	"Nothing removed.":                                            "Nichts entfernt.",
+	"Removed %s files, %s\n":                                      "%s Dateien entfernt, %s\n",
+	"Purged %s files, %s\n":                                       "%s Dateien endgültig gelöscht, %s\n",
+	"Moved %s files, %s to %s for %s days. Undo with: madaa clean --undo %s\n": "%[1]s Dateien, %[2]s für %[4]s Tage nach %[3]s verschoben. Rückgängig mit: madaa clean --undo %[5]s\n",
+	"MADAA - Duplicates":                    "MADAA - Duplikate",
+	"Groups: %s  Redundant: %s files, %s\n": "Gruppen: %s  Überzählig: %s Dateien, %s\n",
+	"Keep: %s  Others: %s\n\n":              "Behalten: %s  Übrige: %s\n\n",
+	"newest":                                "neueste",
+	"oldest":                                "älteste",
+	"shortest-path":                         "kürzester Pfad",
+	"hardlink":                              "Hardlink",
+	"symlink":                               "Symlink",
+	"keep":                                  "behalten",
+	"q quit":                                "q beenden",
+	"This affects %s. Type the number of files (%d) and press enter to confirm: %s": "Betrifft %s. Zur Bestätigung die Anzahl der Dateien (%d) eingeben und Enter drücken: %s",
+	"%s %s files? [y/N]": "%s Dateien: %s? [y/N]",
+	"↑/↓ group  ←/→ file to keep  s strategy  a action  x apply  p full paths  q quit": "↑/↓ Gruppe  ←/→ zu behaltende Datei  s Strategie  a Aktion  x ausführen  p volle Pfade  q beenden",
+	"MADAA - Setup":              "MADAA - Einrichtung",
+	"Step %d of %d\n\n":          "Schritt %d von %d\n\n",
+	"Where to keep the settings": "Wo die Einstellungen liegen",
+	"The current directory applies only when madaa runs from here.": "Das aktuelle Verzeichnis gilt nur, wenn madaa von hier aus läuft.",
+	"Left out of every scan": "Von jedem Scan ausgenommen",
+	"Comma separated patterns, like --exclude, e.g. node_modules, *.o, build/**": "Kommagetrennte Muster wie bei --exclude, z. B. node_modules, *.o, build/**",
+	"Colors":       "Farben",
+	"Access tiers": "Zugriffsstufen",
+	"A file is in the first tier it was used within, e.g. 7d, 6mo or 1y; older files are frozen.": "Eine Datei gehört zur ersten Stufe, in der sie benutzt wurde, z. B. 7d, 6mo oder 1y; ältere Dateien sind eingefroren.",
+	"File type categories": "Dateityp-Kategorien",
+	"Files of unchecked categories are counted as other files.": "Dateien nicht ausgewählter Kategorien zählen als sonstige Dateien.",
+	"Save settings":                          "Einstellungen speichern",
+	"Writing %s\n":                           "Schreibe %s\n",
+	"Excludes: %s\n":                         "Ausnahmen: %s\n",
+	"Colors: %s\n":                           "Farben: %s\n",
+	"Tiers: hot %s, warm %s, cold %s\n":      "Stufen: heiß %s, warm %s, kalt %s\n",
+	"%s must be longer than the tier before": "%s muss länger als die Stufe davor sein",
+	"enter save  esc back  ctrl+c cancel":    "Enter speichern  Esc zurück  Strg+C abbrechen",
+	"↑/↓ select  space toggle  enter next  esc back  ctrl+c cancel": "↑/↓ auswählen  Leertaste umschalten  Enter weiter  Esc zurück  Strg+C abbrechen",
+	"enter next  esc back  ctrl+c cancel":                           "Enter weiter  Esc zurück  Strg+C abbrechen",
+	"↑/↓ select  enter next  esc back  ctrl+c cancel":               "↑/↓ auswählen  Enter weiter  Esc zurück  Strg+C abbrechen",
+	"Using the default settings; run madaa init to change them.":    "Standardeinstellungen werden verwendet; madaa init ändert sie.",
+	"Cancelled, nothing was written.":                               "Abgebrochen, nichts geschrieben.",
+	"Wrote %s\n":                                                    "%s geschrieben\n",
+	"madaa %s is the latest release\n":                              "madaa %s ist die neueste Version\n",
+	"madaa %s is available, this is %s\n":                           "madaa %s ist verfügbar, installiert ist %s\n",
+	"Use --force to replace it with the release.":                   "Mit --force wird sie durch das Release ersetzt.",
+	"Replaced %s files, reclaimed %s, %s skipped":                   "%s Dateien ersetzt, %s frei geworden, %s übersprungen",
+	"Error starting clean session: %v":                              "Fehler beim Starten der Aufräumsitzung: %v",
+	"Looking for duplicates in %s...\n":                             "Suche Duplikate in %s...\n",
+	"No duplicates found.":                                          "Keine Duplikate gefunden.",
+	"reflink":                                                       "Reflink",
+	"skip":                                                          "auslassen",
+	"Deduplication Plan":                                            "Plan zur Deduplizierung",
+	"%s: %s files, freeing %s\n":                                    "%s: %s Dateien, %s werden frei\n",
+	"  %s %s copies, keeping %s\n":                                  "  %s %s Kopien, behalten wird %s\n",
+	"Skipped: %s files, %s\n":                                       "Ausgelassen: %s Dateien, %s\n",
+	"\nFree space on the volume: %s → %s":                           "\nFreier Platz auf dem Volume: %s → %s",
+	"changed since it was compared":                                 "seit dem Vergleich geändert",
+	"the kept file changed since it was compared":                   "die behaltene Datei wurde seit dem Vergleich geändert",
+	"on another file system than the kept file":                     "auf einem anderen Dateisystem als die behaltene Datei",
+	"other owner or permissions than the kept file":                 "anderer Besitzer oder andere Rechte als die behaltene Datei",
+	"h hidden files: %s  s system files: %s  p full paths\n\n":      "h versteckte Dateien: %s  s Systemdateien: %s  p volle Pfade\n\n",
+	"skipped":  "ausgelassen",
+	"included": "einbezogen",
+	"Only %s files are analyzed. Other files: %s, %s (%s of the total)\n\n":                 "Nur %s-Dateien werden analysiert. Andere Dateien: %s, %s (%s der Gesamtgröße)\n\n",
+	"madaa %s is available, this is a development build (%s)\n":                             "madaa %s ist verfügbar, installiert ist ein Entwicklungsbuild (%s)\n",
+	"The latest release %s is older than this version %s; use --force to downgrade.\n":      "Das neueste Release %s ist älter als diese Version %s; --force installiert es trotzdem.\n",
+	"Updated %s from %s to %s; restart running madaa serve and agent processes to use it\n": "%s von %s auf %s aktualisiert; laufende madaa serve- und agent-Prozesse neu starten, um sie zu verwenden\n",
+	"madaa crashed: %v\n": "madaa ist abgestürzt: %v\n",
+	"Please report it at https://github.com/dahead/madaa/issues with the diagnostic bundle %s. It contains the config, the command line and paths of the scanned tree.\n": "Bitte den Fehler mit dem Diagnosepaket %s unter https://github.com/dahead/madaa/issues melden. Es enthält die Konfiguration, die Befehlszeile und Pfade des gescannten Verzeichnisbaums.\n",
+	"Largest Files":         "Größte Dateien",
+	"Age":                   "Alter",
+	"last 30 days":          "letzte 30 Tage",
+	"last year":             "letztes Jahr",
+	"last 3 years":          "letzte 3 Jahre",
+	"older":                 "älter",
+	"  %-14s %s files %s\n": "  %-14s %s Dateien %s\n",
+	"File Type %s":          "Dateityp %s",
+	"Files: %s  Size: %s (%s of the total)\n\n":                      "Dateien: %s  Größe: %s (%s der Gesamtgröße)\n\n",
+	"↑/↓ select  enter details  d directories  p full paths  q quit": "↑/↓ auswählen  Enter Details  d Verzeichnisse  p volle Pfade  q beenden",
+	"r direct/recursive  p full paths  esc back  q quit":             "r direkt/rekursiv  p volle Pfade  Esc zurück  q beenden",
+	"Single-Type Directories (%s of the bytes)":                      "Verzeichnisse eines Dateityps (%s der Bytes)",
+	"Datasets":                        "Datensätze",
+	"%s datasets with %s files, %s\n": "%s Datensätze mit %s Dateien, %s\n",
+	"%s %s files %s %s\n":             "%s %s Dateien %s %s\n",
+	"images":                          "Bilder",
+	"shards":                          "Shards",
+	"checkpoints":                     "Checkpoints",
+	"Model":                           "Modell",
+	"model":                           "Modell",
+	"Model Files":                     "Modelldateien",
+	"Files: %s  Size: %s  Older than %d days: %s files, %s\n": "Dateien: %s  Größe: %s  Älter als %d Tage: %s Dateien, %s\n",
+	"Duplicates: %s redundant copies, %s\n":                   "Duplikate: %s überzählige Kopien, %s\n",
+	"  %s %s copies, %s each\n":                               "  %s %s Kopien zu je %s\n",
+	"Mail Storage":                                            "Mail-Speicher",
+	"Stores: %s  Messages: %s  Size: %s\n":                    "Speicher: %s  Nachrichten: %s  Größe: %s\n",
+	"%s %s %s messages %s\n":                                  "%s %s %s Nachrichten %s\n",
+	"Portability Warnings":                                    "Portabilitätswarnungen",
+	"%s: %s files over %s, %s\n":                              "%s: %s Dateien über %s, %s\n",
+	"%s: %s directories near the limit of %s entries\n":       "%s: %s Verzeichnisse nahe der Grenze von %s Einträgen\n",
+	"Copying to %s":                                           "Kopieren nach %s",
+	"Everything would copy cleanly":                           "Alles ließe sich fehlerfrei kopieren",
+	"Would fail: %s files and directories, %s\n":              "Würden fehlschlagen: %s Dateien und Verzeichnisse, %s\n",
+	"too large":                            "zu groß",
+	"invalid characters":                   "ungültige Zeichen",
+	"reserved name":                        "reservierter Name",
+	"ends in a dot or space":               "endet auf Punkt oder Leerzeichen",
+	"name too long":                        "Name zu lang",
+	"path too long":                        "Pfad zu lang",
+	"too many entries":                     "zu viele Einträge",
+	"%s: %s directories over %s entries\n": "%s: %s Verzeichnisse mit mehr als %s Einträgen\n",
+	"case collisions":                      "Groß-/Kleinschreibungskollisionen",
+	"%s: %s names, only one of each would be kept (see Case-Insensitive Name Collisions)\n": "%s: %s Namen, nur jeweils einer bliebe erhalten (siehe Namenskollisionen ohne Groß-/Kleinschreibung)\n",
+	"Migration Estimate":                               "Migrationsschätzung",
+	"%s files, %s at %s/s and %s per file: %s\n":       "%s Dateien, %s bei %s/s und %s pro Datei: %s\n",
+	"Transfer: %s  Per-file overhead: %s (%s)\n":       "Übertragung: %s  Aufwand pro Datei: %s (%s)\n",
+	"Small-File Overhead":                              "Aufwand kleiner Dateien",
+	"of the volume":                                    "des Volumes",
+	"assumed":                                          "angenommen",
+	"Block size: %s bytes (%s)\n":                      "Blockgröße: %s Bytes (%s)\n",
+	"Smaller than one block: %s files %s holding %s\n": "Kleiner als ein Block: %s Dateien %s mit %s\n",
+	"Lost to block rounding: %s %s of the data\n":      "Verlust durch Blockrundung: %s %s der Daten\n",
+	"Allocated on disk: %s for %s of data\n":           "Belegt auf der Platte: %s für %s Daten\n",
+	"recursive":                                        "rekursiv",
+	"direct children only":                             "nur direkte Inhalte",
+	"Directories (%s)":                                 "Verzeichnisse (%s)",
+	"↑/↓ select  enter open  backspace up  r direct/recursive  x simulate deletion  X undo all  p full paths  q quit": "↑/↓ auswählen  Enter öffnen  Rücktaste hoch  r direkt/rekursiv  x Löschen simulieren  X alles zurück  p volle Pfade  q beenden",
+	"(deleted)": "(gelöscht)",
+	"Simulated Deletion (nothing is deleted)": "Simuliertes Löschen (es wird nichts gelöscht)",
+	"%s directories, %s files, %s\n":          "%s Verzeichnisse, %s Dateien, %s\n",
+	"Scanned: %s -> %s\n":                     "Gescannt: %s -> %s\n",
+	"Volume: %s free -> %s free (%s)\n":       "Datenträger: %s frei -> %s frei (%s)\n",
+	"%s files, %s dirs, %s":                   "%s Dateien, %s Verzeichnisse, %s",
+	", largest %s (%s)":                       ", größte %s (%s)",
+	", %s stale":                              ", %s veraltet",
+	"MADAA - Policy Check":                    "MADAA - Richtlinienprüfung",
+	"pass":                                    "ok",
+	"FAIL":                                    "VERSTOSS",
+	"  grew %s, allowed %s\n":                 "  gewachsen um %s, erlaubt %s\n",
+	"  %s new files\n":                        "  %s neue Dateien\n",
+	"%s of %s rules violated":                 "%s von %s Regeln verletzt",
+	"All %s rules passed":                     "Alle %s Regeln eingehalten",
+	"%s exceeds the size budget of %s":        "%s überschreitet das Größenbudget von %s",
+	"binary file of %s, binaries over %s don't belong in the repository": "Binärdatei mit %s, Binärdateien über %s gehören nicht ins Repository",
+	"generated file outside the directories allowed for them":            "generierte Datei außerhalb der dafür erlaubten Verzeichnisse",
+	"MADAA - Repository Artifacts":                                       "MADAA - Artefakte im Repository",
+	"Size budget: %s  Binaries up to: %s\n\n":                            "Größenbudget: %s  Binärdateien bis: %s\n\n",
+	"No artifacts found":                                                 "Keine Artefakte gefunden",
+	"over size budget":                                                   "über dem Größenbudget",
+	"large binary":                                                       "große Binärdatei",
+	"generated file":                                                     "generierte Datei",
+	"%s: %s files, %s\n":                                                 "%s: %s Dateien, %s\n",
+	"Skipped Paths":                                                      "Übersprungene Pfade",
+	"Could not be read: %s paths, not included in the totals\n":          "Nicht lesbar: %s Pfade, nicht in den Summen enthalten\n",
+	"Scan stopped after %s (--timeout), the report covers only the files analyzed until then": "Scan nach %s abgebrochen (--timeout), der Bericht umfasst nur die bis dahin analysierten Dateien",
+	"Quick scan within %s (--max-duration): %s of %s directories found were visited (%s), %s": "Schnellscan in %s (--max-duration): %s von %s gefundenen Verzeichnissen besucht (%s), %s",
+	"breadth-first":     "in die Breite",
+	"biggest first":     "die größten zuerst",
+	"Access Tiers":      "Zugriffsstufen",
+	"Last use by: %s\n": "Letzte Nutzung nach: %s\n",
+	"used within %s":    "genutzt innerhalb von %s",
+	"hot":               "heiß",
+	"warm":              "warm",
+	"cold":              "kalt",
+	"frozen":            "eingefroren",
+	"Size by Depth":     "Größe nach Tiefe",
+	"Half of the data lies %s or more levels deep, the deepest files %s levels\n": "Die Hälfte der Daten liegt %s oder mehr Ebenen tief, die tiefsten Dateien %s Ebenen\n",
+	"%s at this depth or deeper": "%s in dieser Tiefe oder tiefer",
+	"Top-Level Directories":      "Verzeichnisse der obersten Ebene",
+	"Size":                       "Größe",
+	"Share":                      "Anteil",
+	"Files":                      "Dateien",
+	"Newest":                     "Neueste",
+	"Modified":                   "Geändert",
+	"Extension":                  "Endung",
+	"Category":                   "Kategorie",
+	"Stale":                      "Veraltet",
+	"Directory":                  "Verzeichnis",
+	"Exclusive and Shared Size":  "Exklusive und geteilte Größe",
+	"%s hardlinked files, %s counted more than once in the directory sizes\n": "%s Dateien mit Hardlinks, %s mehrfach in den Verzeichnisgrößen gezählt\n",
+	"Exclusive":                        "Exklusiv",
+	"Shared":                           "Geteilt",
+	"(files directly in the root)":     "(Dateien direkt im Wurzelverzeichnis)",
+	"%s more directories holding %s\n": "%s weitere Verzeichnisse mit %s\n",
+	"Other":                            "Sonstige",
+	"MADAA - Combined Report":          "MADAA - Gesamtbericht",
+	"Hosts (%s scans)":                 "Hosts (%s Scans)",
+	"Categories by Host":               "Kategorien nach Host",
+	"Orphaned Owners":                  "Verwaiste Besitzer",
+	"Files of uids and gids without a user or group on this system\n": "Dateien von UIDs und GIDs ohne Benutzer oder Gruppe auf diesem System\n",
+	"Inconsistent Permissions": "Uneinheitliche Berechtigungen",
+	"%s directories mix owners or permissions, most deviating files first\n": "%s Verzeichnisse mischen Besitzer oder Berechtigungen, die mit den meisten abweichenden Dateien zuerst\n",
+	"%s of %s files differ  %s\n":                                            "%s von %s Dateien weichen ab  %s\n",
+	"  and %s more combinations\n":                                           "  und %s weitere Kombinationen\n",
+	"Exposed Sensitive Files":                                                "Ungeschützte sensible Dateien",
+	"%s files with keys, credentials or PII in their names are readable by their group or everyone\n": "%s Dateien mit Schlüsseln, Zugangsdaten oder personenbezogenen Daten im Namen sind für ihre Gruppe oder alle lesbar\n",
+	"critical":          "kritisch",
+	"private key":       "privater Schlüssel",
+	"keystore":          "Keystore",
+	"password store":    "Passwortspeicher",
+	"credentials":       "Zugangsdaten",
+	"Permission Drift":  "Abweichende Berechtigungen",
+	"Newly Stale Files": "Neu veraltete Dateien",
+	"%s files (%s) went stale since the baseline, unmodified for 6 months\n": "%s Dateien (%s) seit der Basis veraltet, seit 6 Monaten unverändert\n",
+	"Wrote snapshot of %s (%s files, %s) to %s\n":                            "Snapshot von %s (%s Dateien, %s) nach %s geschrieben\n",
+	"%s new files differ from the permissions of their directory\n":          "%s neue Dateien weichen von den Berechtigungen ihres Verzeichnisses ab\n",
+	"  %s (usually %04o, %s files)  %s\n":                                    "  %s (sonst %04o, %s Dateien)  %s\n",
+	"Organization":                                                           "Organisation",
+	"Operator":                                                               "Durchgeführt von",
+	"Ticket":                                                                 "Ticket",
+	"Page %d of %d":                                                          "Seite %d von %d",
+	"tab/1-5 switch tabs  c copy view  C copy report":                        "Tab/1-5 Reiter wechseln  c Ansicht kopieren  C Bericht kopieren",
+	"↑/↓ scroll  pgup/pgdn page  p full paths  q quit":                       "↑/↓ blättern  Bild↑/Bild↓ Seite  p volle Pfade  q beenden",
+	"  (line %d of %d)":                                                      "  (Zeile %d von %d)",
+	"  v next view":                                                          "  v nächste Ansicht",
+	"view %s":                                                                "Ansicht %s",
+	"Error in view %s: %v":                                                   "Fehler in Ansicht %s: %v",
+	"Types":                                                                  "Typen",
+	"Largest":                                                                "Größte",
+	"Dirs":                                                                   "Verzeichnisse",
+	"Copying failed: %v":                                                     "Kopieren fehlgeschlagen: %v",
+	"Copied %s lines to the clipboard":                                       "%s Zeilen in die Zwischenablage kopiert",
+	"warning:":                                                               "Warnung:",
+	"problem:":                                                               "Problem:",
+	"Scanning %s\n":                                                          "Durchsuche %s\n",
+	"%d percent scanned, %s of %s files\n":                                   "%d Prozent durchsucht, %s von %s Dateien\n",
+	"Scan complete\n\n":                                                      "Suche abgeschlossen\n\n",
+	"Section %d of %d: %s\n":                                                 "Abschnitt %d von %d: %s\n",
+	"End of %s\n\n":                                                          "Ende von %s\n\n",
+	"End of report\n":                                                        "Ende des Berichts\n",
+	"Sampling Estimates":                                                     "Hochrechnung aus der Stichprobe",
+	"%s of %s files sampled (%s), estimates with 95%% confidence intervals\n":                                                  "%s von %s Dateien in der Stichprobe (%s), Schätzungen mit 95-%%-Konfidenzintervallen\n",
+	"Only %s of the files were sampled (--sample), the other sections count just those; see the sampling estimates for totals": "Nur %s der Dateien wurden als Stichprobe analysiert (--sample), die übrigen Abschnitte zählen nur diese; Gesamtwerte stehen in der Hochrechnung",
+	"New files: %s %s, not seen by the compact baseline\n":                                                                     "Neue Dateien: %s %s, der kompakten Baseline unbekannt\n",
+	"Modified files count as new; removed files aren't known, and about %s of the new files are missed":                        "Geänderte Dateien zählen als neu; gelöschte Dateien sind nicht bekannt, und etwa %s der neuen Dateien werden übersehen",
+	"Largest New Files":               "Größte neue Dateien",
+	"Moved or renamed: %s files %s\n": "Verschoben oder umbenannt: %s Dateien %s\n",
+	"Largest Moved Files":             "Größte verschobene Dateien",
+	"%s symlinks: %s into the scanned tree, %s out of it, %s broken; %s point to directories\n":                                       "%s Symlinks: %s in den gescannten Baum, %s aus ihm heraus, %s defekt; %s zeigen auf Verzeichnisse\n",
+	"Owned: %s  Reached through links: %s, %s of it outside the tree\n":                                                               "Eigen: %s  Über Links erreicht: %s, davon %s außerhalb des Baums\n",
+	"Sizes in this report count the files the tree owns; linked bytes belong to the targets and aren't freed by deleting the links\n": "Größen in diesem Bericht zählen die eigenen Dateien des Baums; verlinkte Bytes gehören zu den Zielen und werden durch Löschen der Links nicht frei\n",
+	"Symlink farms (%s or more of the files are links):\n":                                                                            "Symlink-Farmen (%s oder mehr der Dateien sind Links):\n",
+	"  %s links %s  %s linked  %s\n":                                "  %s Links %s  %s verlinkt  %s\n",
+	"Developer Environments":                                        "Entwicklungsumgebungen",
+	"%s environments, %s; unused for %d days and reclaimable: %s\n": "%s Umgebungen, %s; seit %d Tagen unbenutzt und freigebbar: %s\n",
+	"%s %-20s last used %s  %s\n":                                   "%s %-20s zuletzt benutzt %s  %s\n",
+	"conda environment":                                             "conda-Umgebung",
+	"Rust build output":                                             "Rust-Build-Ausgabe",
+	"Go module cache":                                               "Go-Modulcache",
+	"Go build cache":                                                "Go-Buildcache",
+	"cargo registry":                                                "cargo-Registry",
+	"Gradle cache":                                                  "Gradle-Cache",
+	"unused dev environments":                                       "ungenutzte Dev-Umgebungen",
+	"Application Caches and Profiles":                               "Anwendungscaches und -profile",
+	"%s applications: caches %s (safe to clear while the app is closed), profiles %s\n": "%s Anwendungen: Caches %s (bei geschlossener Anwendung gefahrlos löschbar), Profile %s\n",
+	"%s %-24s cache %s  profile %s  %s\n":                                               "%s %-24s Cache %s  Profil %s  %s\n",
+	"Steam shader cache":                                                                "Steam-Shader-Cache",
+	"Games":                                                                             "Spiele",
+	"%s games installed, %s\n":                                                          "%s Spiele installiert, %s\n",
+	"%s %-6s %-30s last played %s  %s\n":                                                "%s %-6s %-30s zuletzt gespielt %s  %s\n",
+	"never":                                                                             "nie",
+	"Largest game installers: %s\n":                                                     "Größte Spiele-Installer: %s\n",
+	"Clean Sessions":                                                                    "Bereinigungssitzungen",
+	"  %s %s files %s\n":                                                                "  %s %s Dateien %s\n",
+	"%s was taken, restored as %s\n":                                                    "%s war belegt, wiederhergestellt als %s\n",
+	"Restored %s files\n":                                                               "%s Dateien wiederhergestellt\n",
+	"Moved %s files, %s to the trash. Undo with: madaa clean --undo %s\n":           "%s Dateien, %s in den Papierkorb verschoben. Rückgängig mit: madaa clean --undo %s\n",
+	"Moved %s files, %s to the trash, %s skipped. Undo with: madaa clean --undo %s": "%s Dateien, %s in den Papierkorb verschoben, %s übersprungen. Rückgängig mit: madaa clean --undo %s",
+	"Cleanup Impact":                  "Wirkung einer Bereinigung",
+	"Volume: %s free of %s %s\n":      "Datenträger: %s von %s frei %s\n",
+	" -> %s free":                     " -> %s frei",
+	"  %s %-9s %-26s effort %-6s%s\n": "  %s %-9s %-26s Aufwand %-6s%s\n",
+	"delete":                          "löschen",
+	"compress":                        "packen",
+	"review":                          "sichten",
+	"offload":                         "auslagern",
+	"temporary and lock files":        "temporäre Dateien",
+	"repeated downloads":              "doppelte Downloads",
+	"uncompressed rotated logs":       "ungepackte alte Logs",
+	"old installers":                  "alte Installer",
+	"files past retention":            "abgelaufene Dateien",
+	"screenshot piles":                "Screenshot-Sammlungen",
+	"forgotten directories":           "vergessene Verzeichnisse",
+	"low":                             "gering",
+	"medium":                          "mittel",
+	"high":                            "hoch",
+	"Growth of %s":                    "Wachstum von %s",
+	"From %s %s to %s %s\n":           "Von %s %s bis %s %s\n",
+	"No snapshot is old enough, comparing with the oldest one (%s days)": "Kein Snapshot ist alt genug, verglichen wird mit dem ältesten (%s Tage)",
+	"Total: %s -> %s, %s %s\n":         "Gesamt: %s -> %s, %s %s\n",
+	"new":                              "neu",
+	"Recent Changes":                   "Zuletzt geändert",
+	"Recent Changes (%s)":              "Zuletzt geändert (%s)",
+	"Path Lengths":                     "Pfadlängen",
+	"%s bytes":                         "%s Bytes",
+	"over %s bytes: %s":                "über %s Bytes: %s",
+	"name over %s bytes: %s":           "Name über %s Bytes: %s",
+	"Case-Insensitive Name Collisions": "Namenskollisionen ohne Groß-/Kleinschreibung",
+	"Temporary and Lock Files (older than %d days)": "Temporäre Dateien und Sperrdateien (älter als %d Tage)",
+	"PII Indicators":               "Hinweise auf personenbezogene Daten",
+	"Retention Policy":             "Aufbewahrungsrichtlinie",
+	"Chargeback by Directory":      "Kostenverrechnung nach Verzeichnis",
+	"Chargeback by Owner":          "Kostenverrechnung nach Besitzer",
+	"Files Changed During Scan":    "Während des Scans geänderte Dateien",
+	"Change by Category":           "Änderung nach Kategorie",
+	"Top Contributing Directories": "Verzeichnisse mit den größten Änderungen",
+	"Largest Added Files":          "Größte hinzugefügte Dateien",
+	"Largest Removed Files":        "Größte entfernte Dateien",
+	"Largest Changes":              "Größte Änderungen",
+	"Snapshots":                    "Snapshots",
+
+	// Report lines
+	"Files: %s  Directories: %s  Size: %s\n\n": "Dateien: %s  Verzeichnisse: %s  Größe: %s\n\n",
+	"Oldest: %s %s\n":                          "Älteste: %s %s\n",
+	"Newest: %s %s\n":                          "Neueste: %s %s\n",
+	"Stale (>6mo): %s %s\n":                    "Veraltet (>6 Monate): %s %s\n",
+	"Hidden: %s  System: %s  Symlinks: %s  Write-protected: %s\n":                       "Versteckt: %s  System: %s  Symlinks: %s  Schreibgeschützt: %s\n",
+	"Empty dirs: %s  Recent changes: %s %s\n":                                           "Leere Verzeichnisse: %s  Kürzlich geändert: %s %s\n",
+	"%s last used %s: %s\n":                                                             "%s zuletzt genutzt %s: %s\n",
+	"%s in %s archives, %s levels deep: %s\n":                                           "%s in %s Archiven, %s Ebenen tief: %s\n",
+	"Redundant: %s in %s archives\n":                                                    "Redundant: %s in %s Archiven\n",
+	"Reclaimable: %s  Screenshots to review: %s\n\n":                                    "Freizugeben: %s  Zu sichtende Screenshots: %s\n\n",
+	"Installers older than %d days: %s files, %s\n":                                     "Installer älter als %d Tage: %s Dateien, %s\n",
+	"Repeated downloads: %s files, %s\n":                                                "Mehrfache Downloads: %s Dateien, %s\n",
+	"Screenshot accumulations:\n":                                                       "Angesammelte Screenshots:\n",
+	"  %s in %s screenshots: %s\n":                                                      "  %s in %s Screenshots: %s\n",
+	"Container volumes:\n":                                                              "Container-Volumes:\n",
+	"%s %-9s data %s, journal %s: %s\n":                                                 "%s %-9s Daten %s, Journal %s: %s\n",
+	" large journal":                                                                    " großes Journal",
+	"Logs: %s files, %s  Rotated uncompressed: %s files, %s  Compressed: %s\n":          "Logs: %s Dateien, %s  Rotiert, unkomprimiert: %s Dateien, %s  Komprimiert: %s\n",
+	"Estimated savings with rotation and compression: %s\n\n":                           "Geschätzte Einsparung durch Rotation und Komprimierung: %s\n\n",
+	"Largest unrotated logs (rotate above %s):\n":                                       "Größte nicht rotierte Logs (ab %s rotieren):\n",
+	"Rotated logs worth compressing:\n":                                                 "Rotierte Logs, die sich zu komprimieren lohnen:\n",
+	"Reclaimable: %s (list them with --cleanup-candidates)\n":                           "Freizugeben: %s (Liste mit --cleanup-candidates)\n",
+	"  %-22s %s files, %s\n":                                                            "  %-22s %s Dateien, %s\n",
+	"Colliding names: %s in %s directories (break on Windows, macOS and cloud sync)\n":  "Kollidierende Namen: %s in %s Verzeichnissen (scheitern unter Windows, macOS und bei Cloud-Sync)\n",
+	"Files: %s in %s directories\n":                                                     "Dateien: %s in %s Verzeichnissen\n",
+	"  %s files %s: %s\n":                                                               "  %s Dateien %s: %s\n",
+	"Too old to keep: %s in %s files  Must-keep in deletable areas: %s in %s files\n\n": "Zu alt zum Aufbewahren: %s in %s Dateien  Aufzubewahren in löschbaren Bereichen: %s in %s Dateien\n\n",
+	"%s %s (keep %s): %s files, %s\n":                                                   "%s %s (aufbewahren %s): %s Dateien, %s\n",
+	"Expired under":                                                                     "Abgelaufen unter",
+	"Kept under":                                                                        "Aufzubewahren unter",
+	"Monthly cost: %s at %s per GB\n":                                                   "Monatliche Kosten: %s bei %s pro GB\n",
+	"(files in the scanned directory)":                                                  "(Dateien im gescannten Verzeichnis)",
+	"Modified: %s  Replaced: %s  Vanished: %s  Moved (not counted twice): %s  Size seen: %s\n": "Geändert: %s  Ersetzt: %s  Verschwunden: %s  Verschoben (nicht doppelt gezählt): %s  Gesehene Größe: %s\n",
+	"gone":                    "verschwunden",
+	"recreated":               "neu angelegt",
+	"settled":                 "unverändert seitdem",
+	"still changing, now %s":  "ändert sich noch, jetzt %s",
+	"Root: %s\n":              "Wurzel: %s\n",
+	"Baseline: %s  Now: %s\n": "Basis: %s  Jetzt: %s\n",
+	"Added: %s files %s  Removed: %s files %s  Changed: %s files %s\n": "Hinzugefügt: %s Dateien %s  Entfernt: %s Dateien %s  Geändert: %s Dateien %s\n",
+	"Directories added: %s  removed: %s\n":                             "Verzeichnisse hinzugefügt: %s  entfernt: %s\n",
+	"Net change: %s\n\n":                                               "Nettoänderung: %s\n\n",
+	", of which %s %s":                                                 ", davon %s %s",
+	"Source: %s\n":                                                     "Quelle: %s\n",
+	"Replica %d %s: missing %s  stale %s  differs %s  extra %s\n":      "Replikat %d %s: fehlt %s  veraltet %s  abweichend %s  zusätzlich %s\n",
+	"All replicas match the source":                                    "Alle Replikate stimmen mit der Quelle überein",
+	"Divergent Paths (%s)":                                             "Abweichende Pfade (%s)",
+	"%s %-16s %s files %s  %s %s\n":                                    "%s %-16s %s Dateien %s  %s %s\n",
+	"No baseline found, wrote initial snapshot of %s to %s\n":          "Keine Basis gefunden, erster Snapshot von %s nach %s geschrieben\n",
+	"No material changes in %s (%s files changed, threshold %s)\n":     "Keine wesentlichen Änderungen in %s (%s Dateien geändert, Schwelle %s)\n",
+
+	// Progress
+	" (%s/%s files)":                   " (%s/%s Dateien)",
+	"\n%s Analyzing %s%s...\n\n%s\n\n": "\n%s Analysiere %s%s...\n\n%s\n\n",
+	"paths from stdin":                 "Pfade von stdin",
+	"paths from ":                      "Pfade aus ",
+	"No data available":                "Keine Daten vorhanden",
+
+	// Table headers
+	"Owner":     "Besitzer",
+	"<1y":       "<1J",
+	"1-3y":      "1-3J",
+	">3y":       ">3J",
+	"Total":     "Gesamt",
+	"Format":    "Format",
+	"Apparent":  "Scheinbar",
+	"Allocated": "Belegt",
+	"Virtual":   "Virtuell",
+	"Used":      "Genutzt",
+	"Path":      "Pfad",
+
+	// Categories, sizes and extensions
+	"App":             "Programm",
+	"Code":            "Code",
+	"Document":        "Dokument",
+	"Media":           "Medien",
+	"Archive":         "Archiv",
+	"Special":         "Spezial",
+	"Database":        "Datenbank",
+	"app":             "Programm",
+	"code":            "Code",
+	"doc":             "Dokument",
+	"media":           "Medien",
+	"archive":         "Archiv",
+	"special":         "Spezial",
+	"database":        "Datenbank",
+	"other":           "Sonstige",
+	"no extension":    "ohne Endung",
+	"tiny (<1KB)":     "winzig (<1KB)",
+	"small (<1MB)":    "klein (<1MB)",
+	"medium (<100MB)": "mittel (<100MB)",
+	"large (>100MB)":  "groß (>100MB)",
+
+	// States and reasons
+	"vanished":              "verschwunden",
+	"modified":              "geändert",
+	"replaced":              "ersetzt",
+	"moved":                 "verschoben",
+	"ok":                    "ok",
+	"missing":               "fehlt",
+	"stale":                 "veraltet",
+	"differs":               "abweichend",
+	"extra":                 "zusätzlich",
+	"temp file":             "temporäre Datei",
+	"Office lock file":      "Office-Sperrdatei",
+	"LibreOffice lock file": "LibreOffice-Sperrdatei",
+	"editor swap file":      "Editor-Auslagerungsdatei",
+	"incomplete download":   "unvollständiger Download",
+	"core dump":             "Speicherabzug",
+	"ssn":                   "Sozialversicherungsnummer",
+	"passport/id":           "Reisepass/Ausweis",
+	"payroll":               "Gehaltsabrechnung",
+	"date of birth":         "Geburtsdatum",
+	"bank account":          "Bankkonto",
+	"medical":               "medizinisch",
+	"email address":         "E-Mail-Adresse",
+	"author metadata":       "Autor in Metadaten",
+}
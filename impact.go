@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Volume is the capacity and free space of the scanned file system when the
+// scan started. Both are zero when they couldn't be determined.
+type Volume struct {
+	Capacity  int64
+	Free      int64
+	BlockSize int64
+}
+
+// Effort of a cleanup suggestion, from deleting without a second look to
+// going through the files one by one.
+const (
+	effortLow    = 1
+	effortMedium = 2
+	effortHigh   = 3
+)
+
+var effortNames = map[int]string{
+	effortLow:    "low",
+	effortMedium: "medium",
+	effortHigh:   "high",
+}
+
+// cleanupSuggestion is a group of cleanup candidates the report found, with
+// the bytes removing or compressing them would free.
+type cleanupSuggestion struct {
+	Name   string
+	Action string
+	Bytes  int64
+	Effort int
+}
+
+// impact is the bytes freed per unit of effort.
+func (s cleanupSuggestion) impact() float64 {
+	return float64(s.Bytes) / float64(s.Effort)
+}
+
+// cleanupSuggestions collects the cleanup candidates of all analyzers, the
+// most bytes per effort first.
+func cleanupSuggestions(stats *Stats) []cleanupSuggestion {
+	var tempBytes int64
+	for _, bytes := range stats.Temp.Bytes {
+		tempBytes += bytes
+	}
+	var screenshotBytes int64
+	for dir, count := range stats.Clutter.Screenshots {
+		if count >= minScreenshots {
+			screenshotBytes += stats.Clutter.ScreenshotBytes[dir]
+		}
+	}
+	var forgottenBytes int64
+	for _, dir := range forgottenDirs(stats) {
+		forgottenBytes += dir.Size
+	}
+	_, expiredBytes := violationTotals(stats.Retention.Expired)
+
+	all := []cleanupSuggestion{
+		{"temporary and lock files", "delete", tempBytes, effortLow},
+		{"repeated downloads", "delete", stats.Clutter.CopyBytes, effortLow},
+		{"uncompressed rotated logs", "compress", int64(float64(stats.Logs.UncompressedBytes) * (1 - logCompressionRatio)), effortLow},
+		{"old installers", "delete", stats.Clutter.InstallerBytes, effortMedium},
+		{"unused dev environments", "delete", unusedDevEnvBytes(stats), effortMedium},
+		{"files past retention", "delete", expiredBytes, effortMedium},
+		{"screenshot piles", "review", screenshotBytes, effortHigh},
+		{"forgotten directories", "offload", forgottenBytes, effortHigh},
+	}
+
+	var suggestions []cleanupSuggestion
+	for _, s := range all {
+		if s.Bytes > 0 {
+			suggestions = append(suggestions, s)
+		}
+	}
+	sort.Slice(suggestions, func(i, j int) bool {
+		if suggestions[i].impact() != suggestions[j].impact() {
+			return suggestions[i].impact() > suggestions[j].impact()
+		}
+		return suggestions[i].Name < suggestions[j].Name
+	})
+	return suggestions
+}
+
+func freePercent(free int64, volume Volume) string {
+	return formatPercent(float64(free) / float64(volume.Capacity) * 100)
+}
+
+// displayCleanupImpact lists the cleanup suggestions by impact per effort
+// and, when the volume's capacity is known, the free space after each step.
+// The steps may overlap, e.g. an old installer in a forgotten directory, so
+// the projection is an upper bound.
+func displayCleanupImpact(stats *Stats, result *strings.Builder) {
+	suggestions := cleanupSuggestions(stats)
+	if len(suggestions) == 0 {
+		return
+	}
+
+	volume := stats.Volume
+	result.WriteString(headerStyle.Render(tr("Cleanup Impact")))
+	result.WriteString("\n")
+	if volume.Capacity > 0 {
+		result.WriteString(fmt.Sprintf(tr("Volume: %s free of %s %s\n"),
+			numberStyle.Render(formatMB(volume.Free)),
+			numberStyle.Render(formatMB(volume.Capacity)),
+			percentStyle.Render("("+freePercent(volume.Free, volume)+")")))
+	}
+
+	free := volume.Free
+	for _, s := range suggestions {
+		free += s.Bytes
+		projected := ""
+		if volume.Capacity > 0 {
+			projected = fmt.Sprintf(tr(" -> %s free"), goodStyle.Render(freePercent(min(free, volume.Capacity), volume)))
+		}
+		result.WriteString(fmt.Sprintf(tr("  %s %-9s %-26s effort %-6s%s\n"),
+			numberStyle.Render(fmt.Sprintf("%11s", formatMB(s.Bytes))),
+			tr(s.Action),
+			tr(s.Name),
+			tr(effortNames[s.Effort]),
+			projected))
+	}
+	result.WriteString("\n")
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// scanTimeout stops the whole scan after this long, set by --timeout. The
+// report then covers the files analyzed until then.
+var scanTimeout time.Duration
+
+// ioTimeout is how long a single stat or directory read may take, set by
+// --io-timeout, so a hung network mount can't hang the scan. 0 waits
+// forever.
+var ioTimeout time.Duration
+
+// errIOTimeout is the reason paths that hit --io-timeout are skipped with.
+var errIOTimeout = errors.New("timed out")
+
+// withDeadline runs op on path, giving up after ioTimeout. A blocked system
+// call can't be interrupted, so its goroutine is left behind to finish or
+// hang on its own.
+func withDeadline[T any](op, path string, fn func() (T, error)) (T, error) {
+	if ioTimeout <= 0 {
+		return fn()
+	}
+	type result struct {
+		value T
+		err   error
+	}
+	done := make(chan result, 1)
+	go func() {
+		value, err := fn()
+		done <- result{value, err}
+	}()
+
+	timer := time.NewTimer(ioTimeout)
+	defer timer.Stop()
+	select {
+	case r := <-done:
+		return r.value, r.err
+	case <-timer.C:
+		var zero T
+		return zero, &fs.PathError{Op: op, Path: path, Err: errIOTimeout}
+	}
+}
+
+func lstat(path string) (os.FileInfo, error) {
+	return withDeadline("lstat", path, func() (os.FileInfo, error) {
+		return os.Lstat(path)
+	})
+}
+
+func readDir(path string) ([]os.DirEntry, error) {
+	return withDeadline("readdir", path, func() ([]os.DirEntry, error) {
+		return os.ReadDir(path)
+	})
+}
+
+// walkDir is filepath.WalkDir, reading directories with readDir so that
+// --io-timeout applies to the walk as well.
+func walkDir(root string, fn fs.WalkDirFunc) error {
+	if ioTimeout <= 0 {
+		return filepath.WalkDir(root, fn)
+	}
+	info, err := lstat(root)
+	if err != nil {
+		err = fn(root, nil, err)
+	} else {
+		err = walkDirEntry(root, fs.FileInfoToDirEntry(info), fn)
+	}
+	if err == filepath.SkipDir || err == filepath.SkipAll {
+		return nil
+	}
+	return err
+}
+
+func walkDirEntry(path string, d fs.DirEntry, fn fs.WalkDirFunc) error {
+	if err := fn(path, d, nil); err != nil || !d.IsDir() {
+		if err == filepath.SkipDir && d.IsDir() {
+			err = nil
+		}
+		return err
+	}
+
+	entries, err := readDir(path)
+	if err != nil {
+		// As in filepath.WalkDir, fn is called again with the error
+		if err = fn(path, d, err); err != nil {
+			if err == filepath.SkipDir {
+				err = nil
+			}
+			return err
+		}
+	}
+	for _, entry := range entries {
+		if err := walkDirEntry(filepath.Join(path, entry.Name()), entry, fn); err != nil {
+			if err == filepath.SkipDir {
+				break
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// scanContext applies --timeout and --max-duration to a scan.
+func scanContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if scanBudget() <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, scanBudget())
+}
@@ -0,0 +1,466 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// JournalEntry records one file a clean session moved away, into the
+// session directory Dir, at time At. DirModes are the modes of the
+// directories above Original, for recreating the ones gone by the time the
+// file is restored. The journal of a session is a file of JSON lines, one
+// entry per file, appended as the files are moved.
+type JournalEntry struct {
+	Original string
+	Moved    string
+	Dir      string
+	Size     int64
+	ModTime  time.Time
+	At       time.Time
+	DirModes map[string]os.FileMode `json:",omitempty"`
+}
+
+// cleanSession moves files below Root/ID, mirroring their full paths, and
+// journals every move so the session can be undone. Quarantine sessions
+// also keep a manifest of the moves in the session directory.
+type cleanSession struct {
+	ID       string
+	Root     string
+	journal  *os.File
+	manifest *os.File
+}
+
+// madaaDataDir is where madaa keeps data that must survive cache cleanups,
+// such as the trash and the session journals.
+func madaaDataDir() (string, error) {
+	if dir := os.Getenv("XDG_DATA_HOME"); dir != "" {
+		return filepath.Join(dir, "madaa"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".local", "share", "madaa"), nil
+}
+
+func journalDir() (string, error) {
+	dir, err := madaaDataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "sessions"), nil
+}
+
+func trashDir() (string, error) {
+	dir, err := madaaDataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "trash"), nil
+}
+
+// sessionID matches the names newCleanSession gives sessions.
+var sessionID = regexp.MustCompile(`^\d{8}-\d{6}(-\d+)?$`)
+
+// journalPath is the journal of session id. Ids come from the command line,
+// so only names newCleanSession gives are accepted; others could lead out
+// of the journal directory.
+func journalPath(id string) (string, error) {
+	if !sessionID.MatchString(id) {
+		return "", fmt.Errorf("invalid clean session %q", id)
+	}
+	dir, err := journalDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, id+".jsonl"), nil
+}
+
+// newCleanSession starts a session moving files below root. Sessions are
+// named after the time they started.
+func newCleanSession(root string) (*cleanSession, error) {
+	dir, err := journalDir()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+
+	stamp := time.Now().Format("20060102-150405")
+	for n := 1; ; n++ {
+		id := stamp
+		if n > 1 {
+			id = fmt.Sprintf("%s-%d", stamp, n)
+		}
+		f, err := os.OpenFile(filepath.Join(dir, id+".jsonl"), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if errors.Is(err, fs.ErrExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return &cleanSession{ID: id, Root: root, journal: f}, nil
+	}
+}
+
+// mirrorPath is where path ends up below dir: the full path with the
+// volume name turned into a plain directory, e.g. C:\data\a.tmp becomes
+// dir\C\data\a.tmp.
+func mirrorPath(dir, path string) string {
+	full := fullPath(path)
+	volume := filepath.VolumeName(full)
+	rest := strings.TrimLeft(full[len(volume):], `/\`)
+	return filepath.Join(dir, strings.TrimRight(volume, `:\/`), rest)
+}
+
+func (s *cleanSession) dir() string {
+	return filepath.Join(s.Root, s.ID)
+}
+
+// move moves the file away and journals it. The journal is synced after
+// every entry, so an interrupted session can still be undone up to there.
+func (s *cleanSession) move(path string, info os.FileInfo) error {
+	moved := mirrorPath(s.dir(), path)
+	modes := dirModes(fullPath(path))
+	if err := moveFile(path, moved); err != nil {
+		return err
+	}
+	entry := JournalEntry{
+		Original: fullPath(path),
+		Moved:    moved,
+		Dir:      s.dir(),
+		Size:     info.Size(),
+		ModTime:  info.ModTime(),
+		At:       time.Now(),
+		DirModes: modes,
+	}
+	for _, f := range []*os.File{s.journal, s.manifest} {
+		if f == nil {
+			continue
+		}
+		if err := json.NewEncoder(f).Encode(entry); err != nil {
+			return err
+		}
+		if err := f.Sync(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close ends the session. A session that didn't move anything leaves no
+// journal or manifest behind.
+func (s *cleanSession) Close() error {
+	info, err := s.journal.Stat()
+	closeErr := s.journal.Close()
+	if s.manifest != nil {
+		if err := s.manifest.Close(); closeErr == nil {
+			closeErr = err
+		}
+	}
+	if err == nil && info.Size() == 0 {
+		if s.manifest != nil {
+			os.Remove(s.manifest.Name())
+			pruneEmptyDirs(s.dir())
+		}
+		return os.Remove(s.journal.Name())
+	}
+	return closeErr
+}
+
+// dirModes records the modes of the directories above path.
+func dirModes(path string) map[string]os.FileMode {
+	modes := make(map[string]os.FileMode)
+	for dir := filepath.Dir(path); ; dir = filepath.Dir(dir) {
+		if info, err := os.Stat(dir); err == nil {
+			modes[dir] = info.Mode() & (fs.ModePerm | fs.ModeSetgid | fs.ModeSticky)
+		}
+		if filepath.Dir(dir) == dir {
+			return modes
+		}
+	}
+}
+
+// restoreDirs creates dir and the missing directories above it with the
+// modes they had when the session moved the file. Directories without a
+// recorded mode, as in journals from before modes were recorded, are
+// created readable only by the user.
+func restoreDirs(dir string, modes map[string]os.FileMode) error {
+	var missing []string
+	for ; ; dir = filepath.Dir(dir) {
+		if _, err := os.Lstat(dir); !errors.Is(err, fs.ErrNotExist) {
+			break
+		}
+		missing = append(missing, dir)
+		if filepath.Dir(dir) == dir {
+			break
+		}
+	}
+	for i := len(missing) - 1; i >= 0; i-- {
+		mode, ok := modes[missing[i]]
+		if !ok {
+			mode = 0700
+		}
+		if err := os.Mkdir(missing[i], mode.Perm()); err != nil && !errors.Is(err, fs.ErrExist) {
+			return err
+		}
+		// Mkdir is subject to the umask and ignores the special bits
+		if err := os.Chmod(missing[i], mode); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// moveFile renames src to dst, creating dst's directory. Across file systems
+// the file is copied with its mode and mtime and the original removed.
+func moveFile(src, dst string) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0700); err != nil {
+		return err
+	}
+	err := os.Rename(src, dst)
+	if !errors.Is(err, syscall.EXDEV) {
+		return err
+	}
+	if err := copyFile(src, dst); err != nil {
+		os.Remove(dst)
+		return err
+	}
+	return os.Remove(src)
+}
+
+func copyFile(src, dst string) error {
+	info, err := os.Lstat(src)
+	if err != nil {
+		return err
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	return os.Chtimes(dst, info.ModTime(), info.ModTime())
+}
+
+func readJournal(id string) ([]JournalEntry, error) {
+	path, err := journalPath(id)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := readEntries(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("no clean session %q", id)
+	}
+	return entries, err
+}
+
+// readEntries reads a journal or manifest.
+func readEntries(path string) ([]JournalEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []JournalEntry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var entry JournalEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			// A line cut short by a crash; the file wasn't journaled as moved
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
+
+func writeJournal(id string, entries []JournalEntry) error {
+	path, err := journalPath(id)
+	if err != nil {
+		return err
+	}
+	return writeEntries(path, entries)
+}
+
+// writeEntries replaces a journal or manifest, or removes it if no entries
+// are left.
+func writeEntries(path string, entries []JournalEntry) error {
+	if len(entries) == 0 {
+		return os.Remove(path)
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(f)
+	for _, entry := range entries {
+		if err := enc.Encode(entry); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	return f.Close()
+}
+
+// restoreTarget is where a file is restored to: its original path, or if
+// that is taken by now, the first free "name (restored N).ext" next to it.
+func restoreTarget(original string) string {
+	if _, err := os.Lstat(original); errors.Is(err, fs.ErrNotExist) {
+		return original
+	}
+	ext := filepath.Ext(original)
+	base := strings.TrimSuffix(original, ext)
+	for n := 1; ; n++ {
+		suffix := " (restored)"
+		if n > 1 {
+			suffix = fmt.Sprintf(" (restored %d)", n)
+		}
+		target := base + suffix + ext
+		if _, err := os.Lstat(target); errors.Is(err, fs.ErrNotExist) {
+			return target
+		}
+	}
+}
+
+// undoSession moves the files of a session back. Files whose original path
+// is taken are restored next to it under a new name, listed in renamed.
+// Entries that fail stay in the journal, so the undo can be retried.
+func undoSession(id string) (restored int, renamed map[string]string, errs []error) {
+	entries, err := readJournal(id)
+	if err != nil {
+		return 0, nil, []error{err}
+	}
+
+	renamed = make(map[string]string)
+	var failed []JournalEntry
+	for i := len(entries) - 1; i >= 0; i-- {
+		entry := entries[i]
+		target := restoreTarget(entry.Original)
+		err := restoreDirs(filepath.Dir(target), entry.DirModes)
+		if err == nil {
+			err = moveFile(entry.Moved, target)
+		}
+		if err != nil {
+			errs = append(errs, err)
+			failed = append([]JournalEntry{entry}, failed...)
+			continue
+		}
+		if target != entry.Original {
+			renamed[entry.Original] = target
+		}
+		restored++
+	}
+	if err := writeJournal(id, failed); err != nil {
+		errs = append(errs, err)
+	}
+	if err := updateSessionDirs(entries, failed); err != nil {
+		errs = append(errs, err)
+	}
+	return restored, renamed, errs
+}
+
+// updateSessionDirs leaves only the remaining entries in the manifests of
+// the session directories and removes the directories emptied.
+func updateSessionDirs(entries, remaining []JournalEntry) error {
+	var firstErr error
+	dirs := make(map[string][]JournalEntry)
+	for _, entry := range entries {
+		dirs[entry.Dir] = nil
+	}
+	for _, entry := range remaining {
+		dirs[entry.Dir] = append(dirs[entry.Dir], entry)
+	}
+	for dir, left := range dirs {
+		if dir == "" {
+			continue
+		}
+		manifest := filepath.Join(dir, manifestName)
+		if _, err := os.Lstat(manifest); err == nil {
+			if err := writeEntries(manifest, left); err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+		pruneEmptyDirs(dir)
+	}
+	return firstErr
+}
+
+// pruneEmptyDirs removes root and the directories below it that are empty,
+// deepest first. Directories that still hold files are kept.
+func pruneEmptyDirs(root string) {
+	var dirs []string
+	filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err == nil && d.IsDir() {
+			dirs = append(dirs, path)
+		}
+		return nil
+	})
+	for i := len(dirs) - 1; i >= 0; i-- {
+		os.Remove(dirs[i])
+	}
+}
+
+// cleanSessions lists the sessions that can still be undone, newest first.
+func cleanSessions() ([]string, error) {
+	dir, err := journalDir()
+	if err != nil {
+		return nil, err
+	}
+	matches, err := filepath.Glob(filepath.Join(dir, "*.jsonl"))
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, len(matches))
+	for i, match := range matches {
+		ids[i] = strings.TrimSuffix(filepath.Base(match), ".jsonl")
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(ids)))
+	return ids, nil
+}
+
+func displaySessions(ids []string) string {
+	var result strings.Builder
+	result.WriteString(headerStyle.Render(tr("Clean Sessions")))
+	result.WriteString("\n")
+	for _, id := range ids {
+		entries, err := readJournal(id)
+		if err != nil {
+			continue
+		}
+		var bytes int64
+		for _, entry := range entries {
+			bytes += entry.Size
+		}
+		result.WriteString(fmt.Sprintf(tr("  %s %s files %s\n"),
+			warnStyle.Render(id),
+			numberStyle.Render(fmt.Sprintf("%8s", formatCount(len(entries)))),
+			numberStyle.Render(fmt.Sprintf("%12s", formatMB(bytes)))))
+	}
+	return result.String()
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// trashFiles writes the files, old enough to be cleanup candidates, and
+// moves them to the trash in one session.
+func trashFiles(t *testing.T, paths ...string) string {
+	t.Helper()
+	var files []cleanFile
+	old := time.Now().Add(-2 * tempAge)
+	for _, path := range paths {
+		if err := os.WriteFile(path, []byte(filepath.Base(path)), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, old, old); err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, cleanFile{Path: path})
+	}
+
+	dir, err := trashDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	session, err := newCleanSession(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	removed, _, errs := removeFiles(files, session.move)
+	if err := session.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if removed != len(paths) || len(errs) != 0 {
+		t.Fatalf("trashed %d, errors %v", removed, errs)
+	}
+	return session.ID
+}
+
+func TestUndoTrashSession(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	dir := t.TempDir()
+	a, b := filepath.Join(dir, "a.tmp"), filepath.Join(dir, "b.tmp")
+	id := trashFiles(t, a, b)
+	for _, path := range []string{a, b} {
+		if _, err := os.Lstat(path); !os.IsNotExist(err) {
+			t.Fatalf("%s not moved to the trash: %v", path, err)
+		}
+	}
+
+	// b's path is taken by now, so it comes back under a new name
+	if err := os.WriteFile(b, []byte("new"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	restored, renamed, errs := undoSession(id)
+	if restored != 2 || len(errs) != 0 {
+		t.Fatalf("restored %d, errors %v", restored, errs)
+	}
+	if readFile(t, a) != "a.tmp" {
+		t.Error("a.tmp restored with other content")
+	}
+	if readFile(t, b) != "new" {
+		t.Error("file at b.tmp overwritten")
+	}
+	want := filepath.Join(dir, "b (restored).tmp")
+	if renamed[b] != want || readFile(t, want) != "b.tmp" {
+		t.Errorf("b.tmp restored as %q", renamed[b])
+	}
+
+	ids, err := cleanSessions()
+	if err != nil || len(ids) != 0 {
+		t.Errorf("sessions left after undo: %v %v", ids, err)
+	}
+	trash, _ := trashDir()
+	if _, err := os.Lstat(filepath.Join(trash, id)); !os.IsNotExist(err) {
+		t.Errorf("session directory left in the trash: %v", err)
+	}
+}
+
+func TestUndoSessionKeepsFailedEntries(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	dir := t.TempDir()
+	a, b := filepath.Join(dir, "a.tmp"), filepath.Join(dir, "b.tmp")
+	id := trashFiles(t, a, b)
+
+	entries, err := readJournal(id)
+	if err != nil || len(entries) != 2 {
+		t.Fatalf("journal has %d entries: %v", len(entries), err)
+	}
+	if err := os.Remove(entries[0].Moved); err != nil {
+		t.Fatal(err)
+	}
+
+	restored, _, errs := undoSession(id)
+	if restored != 1 || len(errs) != 1 {
+		t.Fatalf("restored %d, errors %v", restored, errs)
+	}
+	left, err := readJournal(id)
+	if err != nil || len(left) != 1 || left[0].Original != entries[0].Original {
+		t.Errorf("journal left %v, want the entry of %s: %v", left, entries[0].Original, err)
+	}
+}
+
+func TestUndoRestoresDirModes(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	dir := filepath.Join(t.TempDir(), "shared")
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(dir, 0775|os.ModeSetgid); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "a.tmp")
+	id := trashFiles(t, path)
+	if err := os.Remove(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	restored, _, errs := undoSession(id)
+	if restored != 1 || len(errs) != 0 {
+		t.Fatalf("restored %d, errors %v", restored, errs)
+	}
+	info, err := os.Stat(dir)
+	if err != nil || info.Mode() != os.ModeDir|os.ModeSetgid|0775 {
+		t.Errorf("directory recreated with mode %v: %v", info.Mode(), err)
+	}
+}
+
+func TestUndoRejectsForeignIDs(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	outside := filepath.Join(t.TempDir(), "x.jsonl")
+	if err := writeEntries(outside, []JournalEntry{{Original: "/tmp/a", Moved: "/tmp/b"}}); err != nil {
+		t.Fatal(err)
+	}
+	sessions, err := journalDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	escape, err := filepath.Rel(sessions, outside)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, id := range []string{strings.TrimSuffix(escape, ".jsonl"), "../x", "20260101-120000/../../x", ""} {
+		if _, _, errs := undoSession(id); len(errs) != 1 || !strings.Contains(errs[0].Error(), "invalid clean session") {
+			t.Errorf("undo of %q: %v", id, errs)
+		}
+	}
+	if _, err := journalPath("20260101-120000-2"); err != nil {
+		t.Errorf("generated id rejected: %v", err)
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// largestDirs returns the maxCount directories holding the most bytes in
+// their whole tree, like du lists them. A directory and its largest
+// subdirectory both appear when both are among the largest.
+func largestDirs(stats *Stats, maxCount int) []dirSummary {
+	var dirs []dirSummary
+	for dir, tree := range dirTrees(stats) {
+		if tree.Size > 0 {
+			dirs = append(dirs, dirSummary{Path: dir, Size: tree.Size, LastUsed: tree.LastUsed})
+		}
+	}
+	sort.Slice(dirs, func(i, j int) bool {
+		if dirs[i].Size != dirs[j].Size {
+			return dirs[i].Size > dirs[j].Size
+		}
+		return dirs[i].Path < dirs[j].Path
+	})
+	return dirs[:min(maxCount, len(dirs))]
+}
+
+func displayLargestDirs(stats *Stats, maxCount int, result *strings.Builder) {
+	dirs := largestDirs(stats, maxCount)
+	if len(dirs) == 0 {
+		return
+	}
+
+	result.WriteString(headerStyle.Render(fmt.Sprintf(tr("Top %d Largest Directories"), maxCount)))
+	result.WriteString("\n")
+	for _, dir := range dirs {
+		percentage := float64(dir.Size) / float64(stats.TotalSize) * 100
+		result.WriteString(fmt.Sprintf("  %s %s %s\n",
+			getSizeStyle(dir.Size).Render(fmt.Sprintf("%11s", formatMB(dir.Size))),
+			percentStyle.Render(fmt.Sprintf("(%6s)", formatPercent(percentage))),
+			renderPath(dir.Path)))
+	}
+	result.WriteString("\n")
+}
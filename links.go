@@ -0,0 +1,216 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// A directory tree is a symlink farm, like a nix profile, a conda env or a
+// node_modules/.bin, when at least linkFarmShare of its files are symlinks
+// and there are at least linkFarmMinLinks of them.
+const (
+	linkFarmShare    = 0.5
+	linkFarmMinLinks = 50
+)
+
+// LinkStats describes where the symlinks of the scan point. The sizes of the
+// report count a symlink by the few bytes of the link itself; LinkedBytes
+// are the bytes of the files the links point to, which the tree doesn't own,
+// or owns already where the target is inside it.
+type LinkStats struct {
+	Internal    int
+	External    int
+	Broken      int
+	ToDirs      int
+	LinkedBytes int64
+	// ExternalBytes are the LinkedBytes of targets outside the tree
+	ExternalBytes int64
+	Dirs          map[string]*LinkDir
+}
+
+// LinkDir counts the symlinks directly in a directory and the bytes of
+// their targets.
+type LinkDir struct {
+	Links       int
+	LinkedBytes int64
+}
+
+func newLinkStats() LinkStats {
+	return LinkStats{Dirs: make(map[string]*LinkDir)}
+}
+
+// processLink resolves a symlink found below root: whether its target is
+// inside the tree, outside it or missing, and the size of a file target.
+func processLink(path string, info os.FileInfo, stats *Stats, root string) {
+	if info.Mode()&os.ModeSymlink == 0 {
+		return
+	}
+	target, err := withDeadline("stat", path, func() (os.FileInfo, error) {
+		return os.Stat(path)
+	})
+	inside := false
+	if err == nil {
+		inside = linkInside(path, root)
+	}
+
+	stats.mu.Lock()
+	defer stats.mu.Unlock()
+	links := &stats.Links
+	dir := links.Dirs[filepath.Dir(path)]
+	if dir == nil {
+		dir = &LinkDir{}
+		links.Dirs[filepath.Dir(path)] = dir
+	}
+	dir.Links++
+
+	switch {
+	case err != nil:
+		links.Broken++
+		return
+	case inside:
+		links.Internal++
+	default:
+		links.External++
+	}
+	if target.IsDir() {
+		links.ToDirs++
+		return
+	}
+	if target.Mode().IsRegular() {
+		links.LinkedBytes += target.Size()
+		dir.LinkedBytes += target.Size()
+		if !inside {
+			links.ExternalBytes += target.Size()
+		}
+	}
+}
+
+// linkInside reports whether the symlink at path points into root. The
+// target is resolved lexically, a link to another link counts by where that
+// one is.
+func linkInside(path, root string) bool {
+	target, err := os.Readlink(path)
+	if err != nil {
+		return false
+	}
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(filepath.Dir(path), target)
+	} else if abs, err := filepath.Abs(root); err == nil {
+		root = abs
+	}
+	rel, err := filepath.Rel(filepath.Clean(root), target)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+func mergeLinks(dst, src *LinkStats) {
+	dst.Internal += src.Internal
+	dst.External += src.External
+	dst.Broken += src.Broken
+	dst.ToDirs += src.ToDirs
+	dst.LinkedBytes += src.LinkedBytes
+	dst.ExternalBytes += src.ExternalBytes
+	for path, dir := range src.Dirs {
+		if dst.Dirs[path] == nil {
+			dst.Dirs[path] = &LinkDir{}
+		}
+		dst.Dirs[path].Links += dir.Links
+		dst.Dirs[path].LinkedBytes += dir.LinkedBytes
+	}
+}
+
+// linkFarm is a directory tree made up mostly of symlinks.
+type linkFarm struct {
+	Path        string
+	Files       int
+	Links       int
+	LinkedBytes int64
+}
+
+func (f linkFarm) Share() float64 {
+	return float64(f.Links) / float64(f.Files) * 100
+}
+
+// linkFarms returns the outermost symlink farms, most links first. Links and
+// files are rolled up like dominantDirs does, so a farm spread over many
+// small directories, like a nix store, is found as a whole.
+func linkFarms(stats *Stats) []linkFarm {
+	trees := make(map[string]*linkFarm, len(stats.DirDepths))
+	for dir := range stats.DirDepths {
+		trees[dir] = &linkFarm{Path: dir}
+	}
+	rollUp := func(dir string, add func(f *linkFarm)) {
+		for p := dir; ; p = filepath.Dir(p) {
+			tree, ok := trees[p]
+			if !ok {
+				break
+			}
+			add(tree)
+		}
+	}
+	for dir, files := range stats.FilesPerDir {
+		rollUp(dir, func(f *linkFarm) { f.Files += files })
+	}
+	for dir, links := range stats.Links.Dirs {
+		rollUp(dir, func(f *linkFarm) {
+			f.Links += links.Links
+			f.LinkedBytes += links.LinkedBytes
+		})
+	}
+
+	isFarm := func(f *linkFarm) bool {
+		return f != nil && f.Links >= linkFarmMinLinks && float64(f.Links) >= linkFarmShare*float64(f.Files)
+	}
+	var farms []linkFarm
+	for dir, tree := range trees {
+		// Only report the top of a farm, not each directory in it
+		if isFarm(tree) && !isFarm(trees[filepath.Dir(dir)]) {
+			farms = append(farms, *tree)
+		}
+	}
+	sort.Slice(farms, func(i, j int) bool {
+		if farms[i].Links != farms[j].Links {
+			return farms[i].Links > farms[j].Links
+		}
+		return farms[i].Path < farms[j].Path
+	})
+	return farms
+}
+
+// displayLinks tells the bytes the tree owns from the bytes it only links
+// to, and lists the symlink farms.
+func displayLinks(stats *Stats, maxCount int, result *strings.Builder) {
+	links := &stats.Links
+	if stats.Symlinks == 0 {
+		return
+	}
+
+	result.WriteString(headerStyle.Render(tr("Symlinks")))
+	result.WriteString("\n")
+	result.WriteString(fmt.Sprintf(tr("%s symlinks: %s into the scanned tree, %s out of it, %s broken; %s point to directories\n"),
+		numberStyle.Render(formatCount(stats.Symlinks)),
+		numberStyle.Render(formatCount(links.Internal)),
+		numberStyle.Render(formatCount(links.External)),
+		warnStyle.Render(formatCount(links.Broken)),
+		numberStyle.Render(formatCount(links.ToDirs))))
+	result.WriteString(fmt.Sprintf(tr("Owned: %s  Reached through links: %s, %s of it outside the tree\n"),
+		numberStyle.Render(formatMB(stats.TotalSize)),
+		numberStyle.Render(formatMB(links.LinkedBytes)),
+		numberStyle.Render(formatMB(links.ExternalBytes))))
+	result.WriteString(tr("Sizes in this report count the files the tree owns; linked bytes belong to the targets and aren't freed by deleting the links\n"))
+
+	farms := linkFarms(stats)
+	if len(farms) > 0 {
+		result.WriteString(fmt.Sprintf(tr("Symlink farms (%s or more of the files are links):\n"), formatPercent(linkFarmShare*100)))
+		for _, farm := range farms[:min(maxCount, len(farms))] {
+			result.WriteString(fmt.Sprintf(tr("  %s links %s  %s linked  %s\n"),
+				numberStyle.Render(fmt.Sprintf("%9s", formatCount(farm.Links))),
+				percentStyle.Render(fmt.Sprintf("%6s", formatPercent(farm.Share()))),
+				numberStyle.Render(fmt.Sprintf("%11s", formatMB(farm.LinkedBytes))),
+				renderPath(farm.Path)))
+		}
+	}
+	result.WriteString("\n")
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const (
+	// oversizedLogSize is the size from which an active log should have been
+	// rotated.
+	oversizedLogSize = 100 * 1024 * 1024
+	// logCompressionRatio is what gzip typically leaves of a text log.
+	logCompressionRatio = 0.1
+)
+
+var (
+	// rotatedLogPattern matches rotated logs such as app.log.1, app.log.2.gz,
+	// app.log-20240101 or app.log.old.
+	rotatedLogPattern = regexp.MustCompile(`\.log[.-](\d+|old)(\.(gz|bz2|xz|zst))?$`)
+	// rotatedInLogDirPattern matches rotated files in log directories that
+	// don't use the .log extension, e.g. /var/log/syslog.1 or messages-20240101.
+	rotatedInLogDirPattern = regexp.MustCompile(`[.-](\d+)(\.(gz|bz2|xz|zst))?$`)
+	compressedLogPattern   = regexp.MustCompile(`\.(gz|bz2|xz|zst)$`)
+)
+
+// LogStats sums up log files: the ones being written to, rotated ones that
+// are still uncompressed and rotated ones that already are.
+type LogStats struct {
+	Files             int
+	TotalBytes        int64
+	Active            *FileSizeHeap
+	OversizedBytes    int64
+	Uncompressed      *FileSizeHeap
+	UncompressedFiles int
+	UncompressedBytes int64
+	CompressedBytes   int64
+}
+
+func newLogStats() LogStats {
+	return LogStats{Active: &FileSizeHeap{}, Uncompressed: &FileSizeHeap{}}
+}
+
+// inLogDir reports whether one of the parent directories is named log or logs.
+func inLogDir(path string) bool {
+	for _, part := range strings.Split(filepath.Dir(path), string(os.PathSeparator)) {
+		switch strings.ToLower(part) {
+		case "log", "logs":
+			return true
+		}
+	}
+	return false
+}
+
+// classifyLog tells whether the file is a log, and if so whether it was
+// rotated and whether it is compressed.
+func classifyLog(path string) (isLog, rotated, compressed bool) {
+	name := strings.ToLower(filepath.Base(path))
+	compressed = compressedLogPattern.MatchString(name)
+
+	switch {
+	case rotatedLogPattern.MatchString(name):
+		return true, true, compressed
+	case strings.HasSuffix(name, ".log"):
+		return true, false, false
+	case inLogDir(path):
+		return true, rotatedInLogDirPattern.MatchString(name), compressed
+	}
+	return false, false, false
+}
+
+func analyzeLogs(path string, info os.FileInfo, stats *Stats, maxFiles int) {
+	isLog, rotated, compressed := classifyLog(path)
+	if !isLog {
+		return
+	}
+
+	logs := &stats.Logs
+	logs.Files++
+	logs.TotalBytes += info.Size()
+
+	file := FileSize{path, info.Size(), "log"}
+	switch {
+	case !rotated:
+		pushLargest(logs.Active, file, maxFiles)
+		if info.Size() >= oversizedLogSize {
+			logs.OversizedBytes += info.Size()
+		}
+	case compressed:
+		logs.CompressedBytes += info.Size()
+	default:
+		logs.UncompressedFiles++
+		logs.UncompressedBytes += info.Size()
+		pushLargest(logs.Uncompressed, file, maxFiles)
+	}
+}
+
+func mergeLogStats(dst, src *LogStats, maxFiles int) {
+	dst.Files += src.Files
+	dst.TotalBytes += src.TotalBytes
+	dst.OversizedBytes += src.OversizedBytes
+	dst.UncompressedFiles += src.UncompressedFiles
+	dst.UncompressedBytes += src.UncompressedBytes
+	dst.CompressedBytes += src.CompressedBytes
+	mergeLargest(dst.Active, src.Active, maxFiles)
+	mergeLargest(dst.Uncompressed, src.Uncompressed, maxFiles)
+}
+
+// logSavings estimates what rotating oversized logs and compressing all
+// rotated ones, as logrotate with compress would, frees up.
+func logSavings(logs *LogStats) int64 {
+	return int64(float64(logs.UncompressedBytes+logs.OversizedBytes) * (1 - logCompressionRatio))
+}
+
+func displayLogs(stats *Stats, maxCount int, result *strings.Builder) {
+	logs := &stats.Logs
+	if logs.Files == 0 {
+		return
+	}
+
+	result.WriteString(headerStyle.Render(tr("Log Files")))
+	result.WriteString("\n")
+	result.WriteString(fmt.Sprintf(tr("Logs: %s files, %s  Rotated uncompressed: %s files, %s  Compressed: %s\n"),
+		numberStyle.Render(formatCount(logs.Files)),
+		numberStyle.Render(formatMB(logs.TotalBytes)),
+		numberStyle.Render(formatCount(logs.UncompressedFiles)),
+		warnStyle.Render(formatMB(logs.UncompressedBytes)),
+		goodStyle.Render(formatMB(logs.CompressedBytes))))
+	result.WriteString(fmt.Sprintf(tr("Estimated savings with rotation and compression: %s\n\n"),
+		badStyle.Render(formatMB(logSavings(logs)))))
+
+	if logs.Active.Len() > 0 {
+		result.WriteString(fmt.Sprintf(tr("Largest unrotated logs (rotate above %s):\n"), formatMB(oversizedLogSize)))
+		displayLargestFiles(logs.Active, result)
+	}
+	if logs.Uncompressed.Len() > 0 {
+		result.WriteString(tr("Rotated logs worth compressing:\n"))
+		displayLargestFiles(logs.Uncompressed, result)
+	}
+}
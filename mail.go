@@ -0,0 +1,263 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// MailStore is one unit of mail storage: a maildir tree, an mbox file or an
+// Outlook data file. The files of a maildir are only counted here as
+// messages, a maildir of two million tiny files is one store.
+type MailStore struct {
+	Path     string
+	Kind     string
+	Messages int
+	Size     int64
+	// AgeBytes are the bytes of the messages per typeAges bucket, by the
+	// date of each message where the format has one
+	AgeBytes []int64
+}
+
+// MailStats are the mail stores found, by path. maildirs caches whether a
+// directory has the cur/new/tmp layout of a maildir.
+type MailStats struct {
+	Stores   map[string]*MailStore
+	maildirs map[string]bool
+}
+
+func newMailStats() MailStats {
+	return MailStats{
+		Stores:   make(map[string]*MailStore),
+		maildirs: make(map[string]bool),
+	}
+}
+
+// processMail recognizes mail storage. Outlook data files have no message
+// count madaa could read without parsing them, so they are counted by size.
+func processMail(path string, info os.FileInfo, stats *Stats) {
+	if !info.Mode().IsRegular() {
+		return
+	}
+
+	switch ext := strings.ToLower(filepath.Ext(path)); {
+	case ext == ".pst" || ext == ".ost":
+		stats.mu.Lock()
+		defer stats.mu.Unlock()
+		store := mailStore(stats, path, "pst")
+		store.Size += info.Size()
+		store.AgeBytes[typeAgeBucket(info.ModTime())] += info.Size()
+	case ext == ".mbox" || ext == ".mbx" || ext == "" && isMbox(path):
+		messages, ages, err := readMbox(path, info.ModTime())
+		if err != nil {
+			return
+		}
+		stats.mu.Lock()
+		defer stats.mu.Unlock()
+		store := mailStore(stats, path, "mbox")
+		store.Messages += messages
+		store.Size += info.Size()
+		for i, bytes := range ages {
+			store.AgeBytes[i] += bytes
+		}
+	default:
+		box, ok := maildirOf(path, stats)
+		if !ok {
+			return
+		}
+		stats.mu.Lock()
+		defer stats.mu.Unlock()
+		store := mailStore(stats, box, "maildir")
+		store.Messages++
+		store.Size += info.Size()
+		store.AgeBytes[typeAgeBucket(info.ModTime())] += info.Size()
+	}
+}
+
+func mailStore(stats *Stats, path, kind string) *MailStore {
+	store := stats.Mail.Stores[path]
+	if store == nil {
+		store = &MailStore{Path: path, Kind: kind, AgeBytes: make([]int64, len(typeAges))}
+		stats.Mail.Stores[path] = store
+	}
+	return store
+}
+
+// maildirOf returns the maildir a message file in its cur or new directory
+// belongs to. Maildir++ folders (.Sent, .Archive, ...) are counted with the
+// maildir they are in.
+func maildirOf(path string, stats *Stats) (string, bool) {
+	dir := filepath.Dir(path)
+	if base := filepath.Base(dir); base != "cur" && base != "new" {
+		return "", false
+	}
+	box := filepath.Dir(dir)
+	if !isMaildir(box, stats) {
+		return "", false
+	}
+	for strings.HasPrefix(filepath.Base(box), ".") && isMaildir(filepath.Dir(box), stats) {
+		box = filepath.Dir(box)
+	}
+	return box, true
+}
+
+func isMaildir(dir string, stats *Stats) bool {
+	stats.mu.Lock()
+	known, ok := stats.Mail.maildirs[dir]
+	stats.mu.Unlock()
+	if ok {
+		return known
+	}
+
+	known = true
+	for _, sub := range []string{"cur", "new", "tmp"} {
+		if info, err := os.Stat(filepath.Join(dir, sub)); err != nil || !info.IsDir() {
+			known = false
+			break
+		}
+	}
+	stats.mu.Lock()
+	stats.Mail.maildirs[dir] = known
+	stats.mu.Unlock()
+	return known
+}
+
+// isMbox tells whether a file without extension is an mbox, as the folders
+// of Thunderbird and many Unix mail clients are.
+func isMbox(path string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	head := make([]byte, 5)
+	_, err = io.ReadFull(f, head)
+	return err == nil && string(head) == "From "
+}
+
+// readMbox counts the messages of an mbox and the bytes of them per age
+// bucket, dated by their From_ lines, or by fallback if a line has no
+// readable date.
+func readMbox(path string, fallback time.Time) (messages int, ages []int64, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer f.Close()
+
+	ages = make([]int64, len(typeAges))
+	bucket := typeAgeBucket(fallback)
+	reader := bufio.NewReaderSize(f, 64*1024)
+	lineStart, afterBlank := true, true
+	for {
+		line, err := reader.ReadSlice('\n')
+		// A message starts with a From_ line after a blank line; "From"
+		// at the start of a body line is escaped or has no sender and date
+		if lineStart && afterBlank && bytes.HasPrefix(line, []byte("From ")) && len(bytes.Fields(line)) >= 3 {
+			messages++
+			bucket = typeAgeBucket(mboxDate(string(line), fallback))
+		}
+		ages[bucket] += int64(len(line))
+		if lineStart {
+			afterBlank = len(bytes.TrimRight(line, "\r\n")) == 0
+		}
+		// Lines longer than the buffer come in pieces
+		lineStart = err != bufio.ErrBufferFull
+		if !lineStart {
+			afterBlank = false
+			continue
+		}
+		if err == io.EOF {
+			return messages, ages, nil
+		}
+		if err != nil {
+			return 0, nil, err
+		}
+	}
+}
+
+// mboxDate reads the date of a From_ line: "From sender Sat Jan  3 01:05:34 1996".
+func mboxDate(line string, fallback time.Time) time.Time {
+	fields := strings.Fields(line)
+	if len(fields) < 7 {
+		return fallback
+	}
+	t, err := time.Parse(time.ANSIC, strings.Join(fields[2:7], " "))
+	if err != nil {
+		return fallback
+	}
+	return t
+}
+
+func mergeMailStats(dst, src *MailStats) {
+	for path, store := range src.Stores {
+		if dst.Stores[path] == nil {
+			dst.Stores[path] = &MailStore{Path: path, Kind: store.Kind, AgeBytes: make([]int64, len(typeAges))}
+		}
+		dst.Stores[path].Messages += store.Messages
+		dst.Stores[path].Size += store.Size
+		for i := range min(len(store.AgeBytes), len(typeAges)) {
+			dst.Stores[path].AgeBytes[i] += store.AgeBytes[i]
+		}
+	}
+}
+
+func displayMail(stats *Stats, maxCount int, result *strings.Builder) {
+	if len(stats.Mail.Stores) == 0 {
+		return
+	}
+
+	stores := make([]*MailStore, 0, len(stats.Mail.Stores))
+	var messages int
+	var size int64
+	ages := make([]int64, len(typeAges))
+	for _, store := range stats.Mail.Stores {
+		stores = append(stores, store)
+		messages += store.Messages
+		size += store.Size
+		for i := range min(len(store.AgeBytes), len(ages)) {
+			ages[i] += store.AgeBytes[i]
+		}
+	}
+	sort.Slice(stores, func(i, j int) bool {
+		if stores[i].Size != stores[j].Size {
+			return stores[i].Size > stores[j].Size
+		}
+		return stores[i].Path < stores[j].Path
+	})
+
+	result.WriteString(headerStyle.Render(tr("Mail Storage")))
+	result.WriteString("\n")
+	result.WriteString(fmt.Sprintf(tr("Stores: %s  Messages: %s  Size: %s\n"),
+		numberStyle.Render(formatCount(len(stores))),
+		numberStyle.Render(formatCount(messages)),
+		numberStyle.Render(formatMB(size))))
+	for i, bucket := range typeAges {
+		var share float64
+		if size > 0 {
+			share = float64(ages[i]) / float64(size) * 100
+		}
+		result.WriteString(fmt.Sprintf("  %-14s %s %s\n",
+			tr(bucket.name),
+			numberStyle.Render(fmt.Sprintf("%11s", formatMB(ages[i]))),
+			percentStyle.Render(fmt.Sprintf("(%6s)", formatPercent(share)))))
+	}
+	for _, store := range stores[:min(maxCount, len(stores))] {
+		count := "-"
+		if store.Kind != "pst" {
+			count = formatCount(store.Messages)
+		}
+		result.WriteString(fmt.Sprintf(tr("%s %s %s messages %s\n"),
+			numberStyle.Render(fmt.Sprintf("%11s", formatMB(store.Size))),
+			warnStyle.Render(fmt.Sprintf("%-8s", store.Kind)),
+			numberStyle.Render(fmt.Sprintf("%9s", count)),
+			renderPath(store.Path)))
+	}
+	result.WriteString("\n")
+}
@@ -1,26 +1,28 @@
 package main
 
 import (
+	"bytes"
 	"container/heap"
 	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"io/fs"
 	"os"
 	"path/filepath"
 	"runtime"
+	"slices"
 	"sort"
 	"strings"
 	"sync"
 	"sync/atomic"
-	"syscall"
 	"time"
 
 	"github.com/charmbracelet/bubbles/progress"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"golang.org/x/sync/errgroup"
-	"gopkg.in/ini.v1"
 )
 
 const defaultConfigContent = `[file_types]
@@ -113,6 +115,101 @@ const defaultConfigContent = `[file_types]
 # Database 
 .sqlite=database
 .db=database
+
+# Model weights and checkpoints
+.ckpt=model
+.safetensors=model
+.pt=model
+.pth=model
+.onnx=model
+.gguf=model
+.ggml=model
+.tflite=model
+.mlmodel=model
+
+# Colors of the report: default, deuteranopia (red-green color blindness) or
+# high-contrast. The last two also mark warnings with ! and problems with !!.
+[display]
+palette = default
+
+# Saved views, run with: madaa scan --view big-old-media <path>
+[view.big-old-media]
+filter = size>100M && mtime<2020-01-01 && category=media
+count = 10
+sections = overview,largest,age
+
+# Header of printed reports and JSON exports for audit documentation.
+# --operator and --ticket override operator and ticket for one run.
+[report]
+# organization = Example Corp IT
+# operator = jdoe
+# ticket = CHG-1234
+
+# Mount points left out of scans of the trees containing them, in addition
+# to /proc, /sys, /dev, /run and other pseudo file systems. --scan-pseudo
+# scans them all.
+[skip]
+# mounts = /mnt/backup, /snap
+# Patterns left out of every scan, like --exclude
+# exclude = node_modules, *.o
+
+# Changes left out of madaa rescan reports, in --filter syntax
+[diff]
+ignore = path ~ "**/.cache/**" || ext in (tmp,log)
+
+# How long files are kept, by path pattern relative to the scanned directory.
+# Files older than that and files a shorter rule would delete are reported.
+[retention]
+# invoices/** = keep 7y
+# tmp/** = keep 30d
+
+# Guardrails of madaa clean. System directories are always protected; protect
+# adds comma separated path patterns. Cleanups larger than confirm_above have
+# to be confirmed by typing the number of files.
+[clean]
+# protect = /srv/data/legal/**, **/.git/**
+confirm_above = 1G
+# Where clean --quarantine moves files to, mirroring their paths. madaa serve
+# deletes them after quarantine_days.
+# quarantine_root = /srv/quarantine
+quarantine_days = 30
+
+# Access tiers for storage tiering: a file is in the first tier it was last
+# used within, frozen takes the rest. The last use is the latest of the
+# timestamps in use: atime, mtime and ctime.
+[tiers]
+use = atime, mtime
+hot = 7d
+warm = 30d
+cold = 1y
+
+# Limits of madaa artifacts for source repositories: the size budget per
+# file, the size above which binaries are flagged, and generated files,
+# which are only allowed below generated_dirs.
+[artifacts]
+max_size = 5M
+binary_size = 1M
+# generated = *.min.js, *.pb.go, *_generated.*, *.pyc, *.class, *.o
+generated_dirs = gen/**, **/generated/**, third_party/**
+
+# Where madaa self-update looks for releases, by default the GitHub releases
+# of madaa, and the base64 Ed25519 key their checksums must be signed with
+# instead of the release key built into madaa.
+[update]
+# url = https://mirror.example.com/madaa/latest.json
+# public_key = BASE64_KEY
+
+# Limits of the file systems files may end up on. ext4, fat32, exfat, ntfs,
+# onedrive and s3 are built in; a section changes their limits or adds a
+# file system. Profiles with warn = true are checked on every scan under
+# Portability Warnings, paths restricts them to the files destined for the
+# file system. --target NAME checks the whole tree against one profile, also
+# by max_name_length, max_path_length, invalid_chars, reserved_names,
+# windows (naming rules), symlinks and case_insensitive.
+[filesystem.fat32]
+max_file_size = 4G
+max_dir_entries = 65534
+paths = /media/**, /run/media/**, /Volumes/**
 `
 
 type FileSize struct {
@@ -121,16 +218,33 @@ type FileSize struct {
 	Type string
 }
 
+// Less orders files by size, and files of the same size by path, so that
+// the largest files kept don't depend on the order files were scanned in.
+func (f FileSize) Less(other FileSize) bool {
+	if f.Size != other.Size {
+		return f.Size < other.Size
+	}
+	return f.Path > other.Path
+}
+
 type FileAge struct {
 	Path     string
 	ModTime  time.Time
 	IsCreate bool
 }
 
+// olderThan orders files by mtime, and files of the same age by path.
+func (f *FileAge) olderThan(other *FileAge) bool {
+	if !f.ModTime.Equal(other.ModTime) {
+		return f.ModTime.Before(other.ModTime)
+	}
+	return f.Path < other.Path
+}
+
 type FileSizeHeap []FileSize
 
 func (h FileSizeHeap) Len() int           { return len(h) }
-func (h FileSizeHeap) Less(i, j int) bool { return h[i].Size < h[j].Size }
+func (h FileSizeHeap) Less(i, j int) bool { return h[i].Less(h[j]) }
 func (h FileSizeHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
 
 func (h *FileSizeHeap) Push(x interface{}) {
@@ -155,6 +269,11 @@ type Stats struct {
 	TotalSize        int64
 	LargestFiles     *FileSizeHeap
 	LargestByType    map[string]*FileSizeHeap
+	TypeDetails      map[string]*TypeDetail
+	DirTypes         map[string]map[string]int64
+	DatasetDirs      map[string]*DatasetCounts
+	Models           ModelStats
+	Mail             MailStats
 	EmptyFiles       int
 	SizeDistribution map[string]int
 	DirDepths        map[string]int
@@ -167,15 +286,85 @@ type Stats struct {
 	HiddenFiles      int
 	SystemFiles      int
 	Symlinks         int
-	AccessTimes      map[string]int
+	Tiers            map[string]*TierStats
+	Depths           map[int]*DepthStats
+	OwnerAges        map[string]*OwnerAge
+	DirActivity      map[string]*DirActivity
+	Archives         []ArchiveInfo
+	Clutter          Clutter
+	DiskImages       []DiskImage
+	Databases        map[string]*Database
+	Logs             LogStats
+	Temp             TempStats
+	PII              map[string]*PIIDir
+	Retention        RetentionStats
 	WriteProtected   int
 	TotalDirs        int
+	ScanStart        time.Time
+	Volume           Volume
+	Blocks           BlockStats
+	Changes          []ChangedFile
+	CaseCollisions   map[string]map[string][]string
+	Paths            PathStats
+	Oversized        map[string]*LimitFiles
+	TargetFailures   map[string]*LimitFiles
+	Orphans          map[string]*LimitFiles
+	DirPermissions   map[string]map[string]int
+	Exposure         ExposureStats
+	Sample           SampleStats
+	Coverage         Coverage
+	Links            LinkStats
+	HardLinks        map[fileID]*HardLink
+	DevEnvs          map[string]string
+	Games            GameStats
+	Recent           *RecentHeap
+	Skipped          SkippedPaths
+	TimedOut         bool
+	Focus            string
+	OtherFiles       int
+	OtherSize        int64
+	seenFiles        *seenSet
+	caseNames        map[string]map[string]string
+	reachable        map[string]os.FileMode
 	mu               sync.RWMutex
 }
 
 type Config struct {
-	Count int
-	Path  string
+	Count     int
+	Path      string
+	FilesFrom string
+	Files     []string
+	Filter    *Filter
+	Cache     bool
+	// Deterministic processes files one at a time in walk order, so that
+	// repeated scans of an unchanged tree give identical results.
+	Deterministic bool
+	// SkipHidden and SkipSystem leave hidden and system files out of the
+	// scan, pruning the directories among them.
+	SkipHidden bool
+	SkipSystem bool
+	// SkipMounts are the pseudo file systems and [skip] mounts below Path,
+	// as returned by mountSkips.
+	SkipMounts map[string]bool
+	// OnlyCategory restricts the analysis to the files of one category;
+	// the others are only counted.
+	OnlyCategory string
+	// Sample is the share of files --sample analyzes, 0 for all of them.
+	Sample float64
+	// Exclude are the --exclude patterns; Gitignore applies the .gitignore
+	// files of the tree with --respect-gitignore and is nil without it.
+	Exclude   []string
+	Gitignore *gitignores
+}
+
+// View is a named report setup stored in a [view.NAME] config section and
+// selected with --view.
+type View struct {
+	Name         string
+	Filter       string
+	Count        int
+	Sections     string
+	HideSections string
 }
 
 type model struct {
@@ -187,70 +376,190 @@ type model struct {
 	done           bool
 	processedFiles int
 	totalFiles     int
-	progressChan   chan progressMsg
+	recent         []RecentFile
+	events         *eventBus
+	updates        <-chan scanEvent
+	// scan numbers the scans started, so messages of a scan that was
+	// restarted after toggling --skip-hidden or --skip-system are dropped
+	scan   int
+	ctx    context.Context
+	cancel context.CancelFunc
+	// browsing is set once the scan is done and the results view shows
+	// the tab of resultTabs; tabTexts are the rendered text tabs, scroll
+	// the first line shown of the one shown
+	browsing   bool
+	tab        int
+	tabTexts   map[int]string
+	scroll     int
+	types      []string
+	typeCursor int
+	typeDetail string
+	// dirPath is the directory shown by the Dirs tab; recursive switches
+	// its numbers and those of the type details from the files directly in
+	// a directory to its tree
+	dirs      map[string]*dirNode
+	dirPath   string
+	dirCursor int
+	recursive bool
+	// deleted are the directories marked with x to simulate deleting them
+	deleted map[string]bool
+	// width is the width of the terminal paths are shortened to, unless
+	// fullPaths was switched on with p; height is its number of lines
+	width     int
+	height    int
+	fullPaths bool
+	// status reports the last copy to the clipboard until the next key
+	status string
+	// cli is the report setup of the command line and setup the one shown,
+	// cli with the saved view named setup.Name applied; v switches views
+	cli   View
+	setup View
 }
 
 func initialModel(config Config) model {
+	events := newEventBus()
+	ctx, cancel := context.WithCancel(context.Background())
 	return model{
-		analyzing:    true,
-		progress:     progress.New(progress.WithDefaultGradient()),
-		config:       config,
-		progressChan: make(chan progressMsg, 100),
+		analyzing: true,
+		progress:  newProgressBar(),
+		config:    config,
+		events:    events,
+		updates:   events.Subscribe(),
+		ctx:       ctx,
+		cancel:    cancel,
 	}
 }
 
 type analysisMsg struct {
+	scan  int
 	stats *Stats
 	err   error
 }
 
 type progressMsg struct {
+	scan      int
 	processed int
 	total     int
+	recent    []RecentFile
 }
 
 func (m model) Init() tea.Cmd {
 	return tea.Batch(
-		analyzeCmd(m.config, m.progressChan),
+		analyzeCmd(m.ctx, m.scan, m.config, m.events),
 		m.progress.Init(),
-		listenForProgress(m.progressChan),
+		listenForProgress(m.scan, m.updates),
 	)
 }
 
-func analyzeCmd(config Config, progressChan chan progressMsg) tea.Cmd {
+func analyzeCmd(ctx context.Context, scan int, config Config, events *eventBus) tea.Cmd {
 	return func() tea.Msg {
-		stats, err := analyzeDirectory(config.Path, config.Count, progressChan)
-		return analysisMsg{stats: stats, err: err}
+		var stats *Stats
+		var err error
+		if config.FilesFrom != "" {
+			stats, err = analyzeFileList(ctx, config, events)
+		} else {
+			stats, err = analyzeDirectory(ctx, config, events)
+		}
+		return analysisMsg{scan: scan, stats: stats, err: err}
 	}
 }
 
-func listenForProgress(progressChan chan progressMsg) tea.Cmd {
+// listenForProgress turns the next progress event into a progressMsg. It
+// returns no message once the scan's events have ended.
+func listenForProgress(scan int, updates <-chan scanEvent) tea.Cmd {
 	return func() tea.Msg {
-		return <-progressChan
+		for event := range updates {
+			if event.Kind == eventProgress {
+				return progressMsg{scan: scan, processed: event.Processed, total: event.Total, recent: event.Recent}
+			}
+		}
+		return nil
 	}
 }
 
+// restart cancels the running scan and starts over with the current config.
+func (m model) restart() (tea.Model, tea.Cmd) {
+	m.cancel()
+	m.events.Unsubscribe(m.updates)
+	m.scan++
+	m.ctx, m.cancel = context.WithCancel(context.Background())
+	m.events = newEventBus()
+	m.updates = m.events.Subscribe()
+	m.processedFiles, m.totalFiles, m.recent = 0, 0, nil
+	return m, tea.Batch(
+		analyzeCmd(m.ctx, m.scan, m.config, m.events),
+		m.progress.SetPercent(0),
+		listenForProgress(m.scan, m.updates),
+	)
+}
+
 func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		m.height = msg.Height
 	case tea.KeyMsg:
-		if msg.String() == "q" || msg.String() == "ctrl+c" {
+		if msg.String() == "p" {
+			m.fullPaths = !m.fullPaths
+			return m, nil
+		}
+		if m.browsing {
+			m.status = ""
+			switch msg.String() {
+			case "c":
+				return m, copyCmd(m.browseView())
+			case "C":
+				return m, copyCmd(displayResults(m.stats, m.config.Count))
+			case "v":
+				return m.nextView()
+			}
+			return m.updateTabs(msg)
+		}
+		switch msg.String() {
+		case "q", "ctrl+c":
+			m.cancel()
 			return m, tea.Quit
+		case "h":
+			if m.analyzing {
+				m.config.SkipHidden = !m.config.SkipHidden
+				return m.restart()
+			}
+		case "s":
+			if m.analyzing {
+				m.config.SkipSystem = !m.config.SkipSystem
+				return m.restart()
+			}
 		}
 	case analysisMsg:
+		if msg.scan != m.scan {
+			return m, nil
+		}
 		m.analyzing = false
 		m.stats = msg.stats
 		m.err = msg.err
 		m.done = true
+		if m.err == nil && m.stats != nil {
+			m.browsing = true
+			m.types = sortedTypes(m.stats)
+			return m.switchTab(0), nil
+		}
 		return m, tea.Quit
+	case clipboardMsg:
+		m.status = clipboardStatus(msg)
+		return m, nil
 	case progressMsg:
+		if msg.scan != m.scan {
+			return m, nil
+		}
 		m.processedFiles = msg.processed
 		m.totalFiles = msg.total
+		m.recent = msg.recent
 		if m.totalFiles > 0 {
 			percent := float64(m.processedFiles) / float64(m.totalFiles)
 			cmd := m.progress.SetPercent(percent)
-			return m, tea.Batch(cmd, listenForProgress(m.progressChan))
+			return m, tea.Batch(cmd, listenForProgress(m.scan, m.updates))
 		}
-		return m, listenForProgress(m.progressChan)
+		return m, listenForProgress(m.scan, m.updates)
 	case progress.FrameMsg:
 		if m.analyzing {
 			progressModel, cmd := m.progress.Update(msg)
@@ -262,6 +571,18 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 }
 
 func (m model) View() string {
+	width := m.width
+	switch {
+	case m.fullPaths:
+		width = 0
+	case m.done && !m.browsing:
+		// The report stays on the screen, so it is fitted like a printed one
+		width = pathWidth
+	}
+	return fitPaths(m.view(), width)
+}
+
+func (m model) view() string {
 	if m.err != nil {
 		return fmt.Sprintf("Error: %v\n", m.err)
 	}
@@ -269,21 +590,42 @@ func (m model) View() string {
 	if m.analyzing {
 		var progressInfo string
 		if m.totalFiles > 0 {
-			progressInfo = fmt.Sprintf(" (%d/%d files)", m.processedFiles, m.totalFiles)
+			progressInfo = fmt.Sprintf(tr(" (%s/%s files)"), formatCount(m.processedFiles), formatCount(m.totalFiles))
 		}
 
-		return fmt.Sprintf("\n%s Analyzing %s%s...\n\n%s\n\n",
-			lipgloss.NewStyle().Foreground(lipgloss.Color("205")).Render("🔍"),
-			lipgloss.NewStyle().Bold(true).Render(m.config.Path),
+		target := displayPath(m.config.Path)
+		if m.config.FilesFrom == "-" {
+			target = tr("paths from stdin")
+		} else if m.config.FilesFrom != "" {
+			target = tr("paths from ") + m.config.FilesFrom
+		}
+
+		view := fmt.Sprintf(tr("\n%s Analyzing %s%s...\n\n%s\n\n"),
+			titleStyle.Render(plainText("🔍")),
+			lipgloss.NewStyle().Bold(true).Render(target),
 			progressInfo,
 			m.progress.View())
+		view += fmt.Sprintf(tr("h hidden files: %s  s system files: %s  p full paths\n\n"),
+			skipLabel(m.config.SkipHidden), skipLabel(m.config.SkipSystem))
+		if len(m.recent) > 0 {
+			// Live feed of what is being written in the tree right now
+			var feed strings.Builder
+			feed.WriteString(headerStyle.Render(tr("Recent Changes")))
+			feed.WriteString("\n")
+			writeRecentFiles(m.recent, &feed)
+			view += feed.String() + "\n"
+		}
+		return view
 	}
 
 	if m.stats == nil {
-		return "No data available"
+		return tr("No data available")
 	}
-
-	return displayResults(m.stats, m.config.Count)
+	if m.browsing {
+		return m.tabBar() + m.scrolled(m.browseView()) + m.browseHelp()
+	}
+	// Printed by main once the alternate screen is left
+	return ""
 }
 
 var (
@@ -302,6 +644,7 @@ var (
 	archiveStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("133"))
 	databaseStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("144"))
 	specialStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("155"))
+	modelStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("99"))
 
 	goodStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("46"))
 	warnStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("226"))
@@ -312,64 +655,363 @@ var (
 	percentStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("118"))
 
 	// Global config maps
-	fileTypeStyleMap map[string]lipgloss.Style
+	fileTypeStyleMap    map[string]lipgloss.Style
+	fileTypeCategoryMap map[string]string
+	savedViews          map[string]View
+	diffIgnore          string
 )
 
 func loadConfig() error {
 	// Create default config if it doesn't exist
-	configPath := "config.ini"
-	if _, err := os.Stat(configPath); os.IsNotExist(err) {
-		if err := createDefaultConfig(configPath); err != nil {
+	configPath := findConfig()
+	if configPath == "" {
+		path, err := firstRun()
+		if err != nil {
 			return err
 		}
+		configPath = path
 	}
 
-	cfg, err := ini.Load(configPath)
+	cfg, err := loadLayeredConfig(configPath)
 	if err != nil {
 		return err
 	}
+	activeConfig = cfg
 
 	// Initialize maps
 	fileTypeStyleMap = make(map[string]lipgloss.Style)
+	fileTypeCategoryMap = make(map[string]string)
 
 	// Load file types
 	fileTypesSection := cfg.Section("file_types")
 	for _, key := range fileTypesSection.Keys() {
-		ext := key.Name()
-		category := key.Value()
-
-		switch category {
-		case "app":
-			fileTypeStyleMap[ext] = appStyle
-		case "code":
-			fileTypeStyleMap[ext] = codeStyle
-		case "doc":
-			fileTypeStyleMap[ext] = docStyle
-		case "media":
-			fileTypeStyleMap[ext] = mediaStyle
-		case "archive":
-			fileTypeStyleMap[ext] = archiveStyle
-		case "special":
-			fileTypeStyleMap[ext] = specialStyle
-		case "database":
-			fileTypeStyleMap[ext] = databaseStyle
+		fileTypeCategoryMap[key.Name()] = key.Value()
+	}
+	if err := applyPalette(cfg.Section("display").Key("palette").MustString("default")); err != nil {
+		return err
+	}
+
+	diffIgnore = cfg.Section("diff").Key("ignore").String()
+	skipMounts = splitList(cfg.Section("skip").Key("mounts").String())
+	configExcludes = splitList(cfg.Section("skip").Key("exclude").String())
+	loadReportConfig(cfg.Section("report"))
+
+	retentionRules, err = loadRetentionRules(cfg.Section("retention"))
+	if err != nil {
+		return err
+	}
+	if err := loadCleanConfig(cfg.Section("clean")); err != nil {
+		return err
+	}
+	if err := loadFSProfiles(cfg); err != nil {
+		return err
+	}
+	if err := loadTiersConfig(cfg.Section("tiers")); err != nil {
+		return err
+	}
+	if err := loadArtifactsConfig(cfg.Section("artifacts")); err != nil {
+		return err
+	}
+	if err := loadUpdateConfig(cfg.Section("update")); err != nil {
+		return err
+	}
+
+	// Load saved views from [view.NAME] sections
+
+	savedViews = make(map[string]View)
+	for _, section := range cfg.Sections() {
+		name, ok := strings.CutPrefix(section.Name(), "view.")
+		if !ok || name == "" {
+			continue
+		}
+		savedViews[name] = View{
+			Name:         name,
+			Filter:       section.Key("filter").String(),
+			Count:        section.Key("count").MustInt(0),
+			Sections:     section.Key("sections").String(),
+			HideSections: section.Key("hide_sections").String(),
 		}
 	}
 
 	return nil
 }
 
+// categoryStyle returns the style of the file types of a category.
+func categoryStyle(category string) (lipgloss.Style, bool) {
+	switch category {
+	case "app":
+		return appStyle, true
+	case "code":
+		return codeStyle, true
+	case "doc":
+		return docStyle, true
+	case "media":
+		return mediaStyle, true
+	case "archive":
+		return archiveStyle, true
+	case "special":
+		return specialStyle, true
+	case "database":
+		return databaseStyle, true
+	case "model":
+		return modelStyle, true
+	}
+	return lipgloss.Style{}, false
+}
+
+// categoryLabels are the names of the categories in the report.
+var categoryLabels = map[string]string{
+	"app": "App", "code": "Code", "doc": "Document", "media": "Media",
+	"archive": "Archive", "special": "Special", "database": "Database", "model": "Model",
+}
+
+// withView applies the saved view to cli, the report setup of the command
+// line. Both filters apply; a count or sections given on the command line
+// take precedence over the view's.
+func (cli View) withView(view View) View {
+	setup := cli
+	setup.Name = view.Name
+	if view.Filter != "" {
+		if cli.Filter != "" {
+			setup.Filter = "(" + view.Filter + ") && (" + cli.Filter + ")"
+		} else {
+			setup.Filter = view.Filter
+		}
+	}
+	if view.Count > 0 && !flagWasSet("count") {
+		setup.Count = view.Count
+	}
+	if view.Sections != "" && !flagWasSet("sections") {
+		setup.Sections = view.Sections
+	}
+	if view.HideSections != "" && !flagWasSet("hide-sections") {
+		setup.HideSections = view.HideSections
+	}
+	return setup
+}
+
+func viewNames() []string {
+	names := make([]string, 0, len(savedViews))
+	for name := range savedViews {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func flagWasSet(name string) bool {
+	set := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
 func createDefaultConfig(path string) error {
 	return os.WriteFile(path, []byte(defaultConfigContent), 0644)
 }
 
 func main() {
+	defer recoverMain()
+	args := os.Args[1:]
+	if len(args) > 0 {
+		switch args[0] {
+		case "scan":
+			// "scan" is the default command and may be omitted
+			args = args[1:]
+		case "rescan":
+			runRescan(args[1:])
+			return
+		case "coordinator":
+			runCoordinator(args[1:])
+			return
+		case "agent":
+			runAgent(args[1:])
+			return
+		case "serve":
+			runServe(args[1:])
+			return
+		case "verify":
+			runVerify(args[1:])
+			return
+		case "history":
+			runHistory(args[1:])
+			return
+		case "clean":
+			runClean(args[1:])
+			return
+		case "dupes":
+			runDupes(args[1:])
+			return
+		case "unredact":
+			runUnredact(args[1:])
+			return
+		case "schema":
+			runSchema(args[1:])
+			return
+		case "check":
+			runCheck(args[1:])
+			return
+		case "artifacts":
+			runArtifacts(args[1:])
+			return
+		case "merge":
+			runMerge(args[1:])
+			return
+		case "self-update":
+			runSelfUpdate(args[1:])
+			return
+		case "init":
+			runInit(args[1:])
+			return
+		case "snapshot":
+			runSnapshot(args[1:])
+			return
+		case "diff":
+			runDiff(args[1:])
+			return
+		case "query":
+			runQueryCommand(args[1:])
+			return
+		}
+	}
+
 	var count int
+	var filesFrom string
+	var filterExpr string
+	var listFiles bool
+	var viewName string
+	var useCache bool
+	var forgottenDays int
+	var installerDays int
+	var envDays int
+	var tempDays int
+	var listCleanup bool
+	var chargebackCSV string
+	var tieringPlanPath, tieringFormat string
+	var reportPath, reportFormat string
+	var deterministic bool
+	var skipHidden bool
+	var skipSystem bool
+	var onlyCategory string
+	var browse bool
+	var checkpointDays int
+	var targetName string
+	var paletteName string
+	var summary bool
+	var jsonPath string
+	var csvPath string
 	flag.IntVar(&count, "count", 3, "Number of top files to show")
-	flag.Parse()
+	flag.StringVar(&filesFrom, "files-from", "", "Read paths to analyze from file (- for stdin), newline or NUL delimited")
+	flag.StringVar(&filterExpr, "filter", "", "Only analyze files matching the expression, e.g. 'size>100M && ext in (mp4,mkv)'")
+	flag.BoolVar(&listFiles, "list", false, "Print the matching files instead of the report")
+	flag.BoolVar(&summary, "summary", false, "Print a one-line summary (files, directories, size, largest file, stale share) instead of the report")
+	flag.StringVar(&viewName, "view", "", "Apply a saved view from the [view.NAME] config sections")
+	flag.BoolVar(&useCache, "cache", false, "Reuse file details of directories whose mtime and entry count are unchanged since the last cached scan")
+	sampleRate := flag.String("sample", "", "Analyze only this share of the files, e.g. 1%, and estimate totals with confidence intervals")
+	tierBy := flag.String("tier-by", "", "Timestamps whose latest is a file's last use for the access tiers, e.g. atime,mtime (default: use of [tiers])")
+	flag.IntVar(&forgottenDays, "forgotten-days", 365, "List directories whose files haven't been accessed or modified for this many days")
+	flag.IntVar(&installerDays, "installer-days", 90, "Report installers (.dmg, .msi, .exe, .deb, ...) older than this many days as clutter")
+	flag.IntVar(&envDays, "env-days", 90, "Report virtualenvs, conda envs, package caches and SDKs unused for this many days as reclaimable")
+	flag.IntVar(&tempDays, "temp-days", 7, "Report temporary and lock files untouched for this many days as cleanup candidates")
+	flag.BoolVar(&listCleanup, "cleanup-candidates", false, "Print the temporary and lock files that are safe to clean up instead of the report")
+	flag.BoolVar(&piiScan, "pii", false, "Look for PII indicators (SSN-like numbers, passport, payroll, birth dates, ...) in file names")
+	flag.BoolVar(&piiMetadata, "pii-metadata", false, "With --pii, also check CSV headers and Office document properties")
+	flag.BoolVar(&deterministic, "deterministic", false, "Process files one at a time in sorted order so repeated scans of an unchanged tree give identical output")
+	flag.BoolVar(&strictScan, "strict", false, "Abort the scan at the first file or directory that can't be read instead of skipping and listing it")
+	flag.BoolVar(&scanPseudo, "scan-pseudo", false, "Also scan /proc, /sys, /dev, /run, other pseudo file systems and the mounts of [skip], which are left out by default")
+	flag.DurationVar(&scanTimeout, "timeout", 0, "Stop the scan after this long, e.g. 10m, and report the files analyzed until then")
+	flag.BoolVar(&bigFirst, "big-first", false, "Walk the largest directories first, by the sizes of the last --cache scan or else by entry count, so partial results show the biggest consumers")
+	flag.DurationVar(&maxDuration, "max-duration", 0, "Quick scan: walk the tree breadth-first for at most this long, e.g. 2m, and report the directories covered")
+	flag.DurationVar(&ioTimeout, "io-timeout", 0, "Skip files and directories whose stat or listing takes longer than this, e.g. 30s, as on a hung network mount")
+	flag.BoolVar(&recheckChanges, "recheck", false, "Stat files that changed during the scan again at the end")
+	flag.Float64Var(&chargebackRate, "rate", 0, "Storage cost per GB and month for the chargeback report")
+	assumeRate := flag.String("assume", "", "Estimate how long migrating the scanned files takes at this throughput, e.g. 100MB/s or 1Gbit/s")
+	flag.DurationVar(&migrationPerFile, "per-file-overhead", migrationPerFile, "Time each file adds to the migration estimate on top of its bytes")
+	flag.StringVar(&chargebackCurrency, "currency", "$", "Currency symbol for the chargeback report")
+	flag.StringVar(&chargebackBy, "chargeback-by", "dir", "Bill storage by top-level directory (dir) or by owner")
+	flag.StringVar(&jsonPath, "json", "", "Write the results as JSON to FILE (- for stdout) after the scan, see madaa schema")
+	flag.StringVar(&csvPath, "csv", "", "Write the file type, category, size distribution and year tables as CSV to FILE (- for stdout) after the scan")
+	flag.StringVar(&tieringPlanPath, "tiering-plan", "", "Write the directories to move to the cold or frozen tier as CSV to FILE (- for stdout) after the scan")
+	flag.StringVar(&tieringFormat, "tiering-format", "", "Format of --tiering-plan: csv or json (default: json for FILE ending in .json, else csv)")
+	flag.StringVar(&reportPath, "report", "", "Write the report to FILE (- for stdout) after the scan, e.g. for audit deliverables")
+	flag.StringVar(&reportFormat, "report-format", "", "Format of --report: text or pdf (default: pdf for FILE ending in .pdf, else text)")
+	flag.StringVar(&chargebackCSV, "chargeback-csv", "", "Write the chargeback report as CSV to FILE (- for stdout) after the scan")
+	flag.IntVar(&recentCount, "recent", 10, "List this many most recently modified files (0 to leave the list out)")
+	flag.StringVar(&recentCategory, "recent-category", "", "Only list recently modified files of this category, e.g. media")
+	flag.IntVar(&componentLimit, "component-limit", 255, "Report paths with a file or directory name longer than this many bytes")
+	flag.BoolVar(&skipHidden, "skip-hidden", false, "Leave hidden files and dot-directories like .git or .cache out of the scan")
+	var excludes []string
+	flag.Func("exclude", "Leave files and directories matching this pattern out of the scan, e.g. node_modules or 'build/**' (repeatable, comma separated)", func(value string) error {
+		excludes = append(excludes, splitList(value)...)
+		return nil
+	})
+	respectGitignore := flag.Bool("respect-gitignore", false, "Leave out what the .gitignore files of the tree ignore, and .git directories")
+	flag.BoolVar(&skipSystem, "skip-system", false, "Leave system files like .DS_Store, Thumbs.db or $RECYCLE.BIN out of the scan")
+	flag.StringVar(&onlyCategory, "only-category", "", "Analyze and list only the files of this category, e.g. media or code, and just total the rest")
+	flag.BoolVar(&noTUI, "no-tui", false, "Scan without the interactive view and print the report when done (default when stdout is not a terminal)")
+	flag.BoolVar(&browse, "browse", false, "Keep the largest files, age profile and directories of every file type, to drill down into them on the Types tab of the results view")
+	flag.IntVar(&checkpointDays, "checkpoint-days", 90, "Count model files and checkpoints older than this many days as old")
+	flag.StringVar(&paletteName, "palette", "", "Colors of the report: default, deuteranopia or high-contrast (overrides [display] palette)")
+	flag.StringVar(&targetName, "target", "", "Report everything that would fail to copy to this file system: fat32, exfat, ntfs, onedrive, s3 or a [filesystem.NAME] config section")
+	sections := flag.String("sections", "", "Only show these report sections, comma separated, e.g. overview,types,largest")
+	hideSections := flag.String("hide-sections", "", "Leave these report sections out, comma separated")
+	pathLimitList := flag.String("path-limits", "260,4096", "Report paths longer than these numbers of bytes, comma separated")
+	enableRedaction := redactFlags(flag.CommandLine)
+	reportFlags(flag.CommandLine)
+	selectLanguage := localeFlags(flag.CommandLine)
+	flag.CommandLine.Parse(args)
+	enableRedaction()
+	selectLanguage()
+	forgottenAfter = time.Duration(forgottenDays) * 24 * time.Hour
+	installerAge = time.Duration(installerDays) * 24 * time.Hour
+	devEnvAge = time.Duration(envDays) * 24 * time.Hour
+	tempAge = time.Duration(tempDays) * 24 * time.Hour
+	checkpointAge = time.Duration(checkpointDays) * 24 * time.Hour
+	if chargebackBy != "dir" && chargebackBy != "owner" {
+		fmt.Printf("Invalid --chargeback-by %q, want dir or owner\n", chargebackBy)
+		os.Exit(1)
+	}
+	limits, err := parsePathLimits(*pathLimitList)
+	if err != nil {
+		fmt.Printf("Invalid --path-limits: %v\n", err)
+		os.Exit(1)
+	}
+	pathLimits = limits
+	if *assumeRate != "" {
+		rate, err := parseRate(*assumeRate)
+		if err != nil {
+			fmt.Printf("Invalid --assume: %v\n", err)
+			os.Exit(1)
+		}
+		migrationRate = rate
+	}
+	var sample float64
+	if *sampleRate != "" {
+		rate, err := parseSampleRate(*sampleRate)
+		if err != nil {
+			fmt.Printf("Invalid --sample: %v\n", err)
+			os.Exit(1)
+		}
+		if useCache {
+			fmt.Println("--sample can't be combined with --cache")
+			os.Exit(1)
+		}
+		sample = rate
+	}
+	if maxDuration > 0 && useCache {
+		fmt.Println("--max-duration can't be combined with --cache")
+		os.Exit(1)
+	}
+	if bigFirst && useCache {
+		fmt.Println("--big-first can't be combined with --cache, whose last scan it takes the directory sizes from")
+		os.Exit(1)
+	}
 
-	if flag.NArg() < 1 {
-		fmt.Println("Usage: madaa [--count N] <path>")
+	if flag.NArg() < 1 && filesFrom == "" {
+		fmt.Println("Usage: madaa [scan] [--count N] [--files-from FILE|-] [--filter EXPR] [--list] [--view NAME] <path>")
 		os.Exit(1)
 	}
 
@@ -378,20 +1020,215 @@ func main() {
 		fmt.Printf("Error loading config: %v\n", err)
 		os.Exit(1)
 	}
+	if paletteName != "" {
+		if err := applyPalette(paletteName); err != nil {
+			fmt.Printf("Invalid --palette: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if *tierBy != "" {
+		times, err := parseTierTimes(*tierBy)
+		if err != nil {
+			fmt.Printf("Invalid --tier-by: %v\n", err)
+			os.Exit(1)
+		}
+		tierTimes = times
+	}
+	if tieringFormat != "" && tieringFormat != "csv" && tieringFormat != "json" {
+		fmt.Printf("Invalid --tiering-format %q, want csv or json\n", tieringFormat)
+		os.Exit(1)
+	}
+	if reportFormat != "" && reportFormat != "text" && reportFormat != "pdf" {
+		fmt.Printf("Invalid --report-format %q, want text or pdf\n", reportFormat)
+		os.Exit(1)
+	}
 
-	config := Config{
-		Count: count,
-		Path:  flag.Arg(0),
+	cli := View{Filter: filterExpr, Count: count, Sections: *sections, HideSections: *hideSections}
+	setup := cli
+	if viewName != "" {
+		view, ok := savedViews[viewName]
+		if !ok {
+			fmt.Printf("Unknown view %q, available: %s\n", viewName, strings.Join(viewNames(), ", "))
+			os.Exit(1)
+		}
+		setup = cli.withView(view)
+	}
+	filterExpr, count = setup.Filter, setup.Count
+	if err := selectSections(setup.Sections, setup.HideSections); err != nil {
+		fmt.Printf("Invalid --sections: %v\n", err)
+		os.Exit(1)
 	}
 
-	p := tea.NewProgram(initialModel(config))
-	if _, err := p.Run(); err != nil {
-		fmt.Printf("Error: %v", err)
+	config := Config{
+		Count:         count,
+		Path:          flag.Arg(0),
+		FilesFrom:     filesFrom,
+		Cache:         useCache,
+		Deterministic: deterministic,
+		SkipHidden:    skipHidden,
+		SkipSystem:    skipSystem,
+		SkipMounts:    mountSkips(flag.Arg(0)),
+		OnlyCategory:  strings.ToLower(onlyCategory),
+		Sample:        sample,
+		Exclude:       slices.Concat(configExcludes, excludes),
+	}
+	if *respectGitignore {
+		config.Gitignore = newGitignores()
+	}
+	if onlyCategory != "" && !slices.Contains(categoryNames(), config.OnlyCategory) {
+		fmt.Printf("Unknown category %q, available: %s\n", onlyCategory, strings.Join(categoryNames(), ", "))
 		os.Exit(1)
 	}
+	if targetName != "" {
+		copyTarget = fsProfiles[strings.ToLower(targetName)]
+		if copyTarget == nil {
+			fmt.Printf("Unknown target %q, available: %s\n", targetName, strings.Join(fsProfileNames(), ", "))
+			os.Exit(1)
+		}
+	}
+
+	if filterExpr != "" {
+		filter, err := ParseFilter(filterExpr)
+		if err != nil {
+			fmt.Printf("Error parsing filter: %v\n", err)
+			os.Exit(1)
+		}
+		config.Filter = filter
+	}
+
+	var opts []tea.ProgramOption
+	if filesFrom != "" {
+		files, err := loadFileList(filesFrom)
+		if err != nil {
+			fmt.Printf("Error reading file list: %v\n", err)
+			os.Exit(1)
+		}
+		config.Files = files
+		if config.Path == "" {
+			config.Path = "."
+		}
+		// stdin carries the path list, so read keys from the terminal instead
+		if filesFrom == "-" {
+			opts = append(opts, tea.WithInputTTY())
+		}
+	}
+
+	if listFiles {
+		if err := listMatchingFiles(config, os.Stdout, nil); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if listCleanup {
+		if err := listCleanupCandidates(config, os.Stdout); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if summary {
+		if err := printSummary(config); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// An export to stdout replaces the report, so it stays parseable
+	printReport := true
+	for _, path := range []string{jsonPath, csvPath, chargebackCSV, tieringPlanPath, reportPath} {
+		if path == "-" {
+			printReport = false
+		}
+	}
+
+	var stats *Stats
+	if accessible {
+		if browse {
+			fmt.Println("--browse is interactive and can't be combined with --accessible")
+			os.Exit(1)
+		}
+		var err error
+		if stats, err = scanAccessible(config); err != nil {
+			reportCrash(err, config.Path, stats)
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		if printReport {
+			fmt.Print(displayAccessible(stats, count))
+		}
+	} else if noTUI || !isTerminal(os.Stdout) {
+		if browse {
+			fmt.Println("--browse is interactive and needs a terminal, it can't be combined with --no-tui")
+			os.Exit(1)
+		}
+		var err error
+		if stats, err = scanPlain(config); err != nil {
+			reportCrash(err, config.Path, stats)
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		if printReport {
+			fmt.Print(fitPaths(displayResults(stats, count), pathWidth))
+		}
+	} else {
+		browseTypes = browse
+		opts = append(opts, tea.WithAltScreen())
+		m := initialModel(config)
+		m.cli, m.setup = cli, setup
+		p := tea.NewProgram(m, opts...)
+		final, err := p.Run()
+		if errors.Is(err, tea.ErrProgramPanic) {
+			reportCrash(tuiPanic, config.Path, nil)
+		}
+		if err != nil {
+			fmt.Printf("Error: %v", err)
+			os.Exit(1)
+		}
+		stats = final.(model).stats
+		if err := final.(model).err; err != nil {
+			reportCrash(err, config.Path, stats)
+			fmt.Printf("Error: %v\n", err)
+		}
+		if stats != nil && printReport {
+			fmt.Print(fitPaths(displayResults(stats, count), pathWidth))
+		}
+	}
+
+	if jsonPath != "" && stats != nil {
+		if err := saveExport(stats, config.Path, jsonPath); err != nil {
+			fmt.Printf("Error writing JSON: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if csvPath != "" && stats != nil {
+		if err := saveTablesCSV(stats, config.Path, csvPath); err != nil {
+			fmt.Printf("Error writing CSV: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if chargebackCSV != "" && stats != nil {
+		if err := saveChargebackCSV(stats, chargebackCSV); err != nil {
+			fmt.Printf("Error writing chargeback CSV: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if tieringPlanPath != "" && stats != nil {
+		if err := saveTieringPlan(stats, tieringPlanPath, tieringFormat); err != nil {
+			fmt.Printf("Error writing tiering plan: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if reportPath != "" && stats != nil {
+		if err := saveReport(stats, count, reportPath, reportFormat); err != nil {
+			fmt.Printf("Error writing report: %v\n", err)
+			os.Exit(1)
+		}
+	}
 }
 
-func analyzeDirectory(root string, maxFiles int, progressChan chan progressMsg) (*Stats, error) {
+func newStats() *Stats {
 	stats := &Stats{
 		WordFreq:         make(map[string]int),
 		TypeFreq:         make(map[string]int),
@@ -401,36 +1238,326 @@ func analyzeDirectory(root string, maxFiles int, progressChan chan progressMsg)
 		DirDepths:        make(map[string]int),
 		FilesPerDir:      make(map[string]int),
 		YearDistribution: make(map[int]int),
-		AccessTimes:      make(map[string]int),
+		Tiers:            make(map[string]*TierStats),
+		Depths:           make(map[int]*DepthStats),
+		OwnerAges:        make(map[string]*OwnerAge),
+		DirActivity:      make(map[string]*DirActivity),
+		Databases:        make(map[string]*Database),
+		PII:              make(map[string]*PIIDir),
 		LargestFiles:     &FileSizeHeap{},
 		LargestByType:    make(map[string]*FileSizeHeap),
+		TypeDetails:      make(map[string]*TypeDetail),
+		DirTypes:         make(map[string]map[string]int64),
+		DatasetDirs:      make(map[string]*DatasetCounts),
+		Models:           newModelStats(),
+		Mail:             newMailStats(),
+		Clutter:          newClutter(),
+		Logs:             newLogStats(),
+		Temp:             newTempStats(),
+		Retention:        newRetentionStats(),
+		CaseCollisions:   make(map[string]map[string][]string),
+		Paths:            newPathStats(),
+		Oversized:        make(map[string]*LimitFiles),
+		TargetFailures:   make(map[string]*LimitFiles),
+		Orphans:          make(map[string]*LimitFiles),
+		DirPermissions:   make(map[string]map[string]int),
+		Recent:           &RecentHeap{},
+		Sample:           SampleStats{Categories: make(map[string]*SampleSums)},
+		Links:            newLinkStats(),
+		HardLinks:        make(map[fileID]*HardLink),
+		DevEnvs:          make(map[string]string),
+		Games:            newGameStats(),
+		seenFiles:        newSeenSet(),
+		caseNames:        make(map[string]map[string]string),
+		reachable:        make(map[string]os.FileMode),
 	}
 
 	heap.Init(stats.LargestFiles)
+	return stats
+}
+
+// mergeStats adds the statistics in src to dst, e.g. to combine partial
+// results scanned by different agents.
+func mergeStats(dst, src *Stats, maxFiles int) {
+	dst.mu.Lock()
+	defer dst.mu.Unlock()
+
+	for k, v := range src.WordFreq {
+		dst.WordFreq[k] += v
+	}
+	for k, v := range src.TypeFreq {
+		dst.TypeFreq[k] += v
+	}
+	for k, v := range src.TypeSizes {
+		dst.TypeSizes[k] += v
+	}
+	for k, v := range src.Permissions {
+		dst.Permissions[k] += v
+	}
+	for k, v := range src.SizeDistribution {
+		dst.SizeDistribution[k] += v
+	}
+	for k, v := range src.DirDepths {
+		dst.DirDepths[k] = v
+	}
+	for k, v := range src.FilesPerDir {
+		dst.FilesPerDir[k] = v
+	}
+	for k, v := range src.YearDistribution {
+		dst.YearDistribution[k] += v
+	}
+	mergeTiers(dst.Tiers, src.Tiers)
+	mergeDepths(dst.Depths, src.Depths)
+	for owner, ownerAge := range src.OwnerAges {
+		if dst.OwnerAges[owner] == nil {
+			dst.OwnerAges[owner] = &OwnerAge{}
+		}
+		dst.OwnerAges[owner].UpTo1Year += ownerAge.UpTo1Year
+		dst.OwnerAges[owner].UpTo3Years += ownerAge.UpTo3Years
+		dst.OwnerAges[owner].Older += ownerAge.Older
+	}
+	for dir, activity := range src.DirActivity {
+		if dst.DirActivity[dir] == nil {
+			dst.DirActivity[dir] = &DirActivity{}
+		}
+		dst.DirActivity[dir].Files += activity.Files
+		dst.DirActivity[dir].Size += activity.Size
+		dst.DirActivity[dir].addDetails(activity)
+		if activity.LastUsed.After(dst.DirActivity[dir].LastUsed) {
+			dst.DirActivity[dir].LastUsed = activity.LastUsed
+		}
+	}
+
+	mergeLargest(dst.LargestFiles, src.LargestFiles, maxFiles)
+	for ext, files := range src.LargestByType {
+		if dst.LargestByType[ext] == nil {
+			dst.LargestByType[ext] = &FileSizeHeap{}
+		}
+		for _, file := range *files {
+			pushLargest(dst.LargestByType[ext], file, min(maxFiles, 10))
+		}
+	}
+
+	mergeTypeDetails(dst.TypeDetails, src.TypeDetails)
+	mergeDirTypes(dst.DirTypes, src.DirTypes)
+	mergeDatasetDirs(dst.DatasetDirs, src.DatasetDirs)
+	mergeModelStats(&dst.Models, &src.Models)
+	mergeMailStats(&dst.Mail, &src.Mail)
+
+	if src.OldestFile != nil && (dst.OldestFile == nil || src.OldestFile.olderThan(dst.OldestFile)) {
+		dst.OldestFile = src.OldestFile
+	}
+	if src.NewestFile != nil && (dst.NewestFile == nil || dst.NewestFile.olderThan(src.NewestFile)) {
+		dst.NewestFile = src.NewestFile
+	}
+
+	dst.Archives = append(dst.Archives, src.Archives...)
+	dst.DiskImages = append(dst.DiskImages, src.DiskImages...)
+	mergeClutter(&dst.Clutter, &src.Clutter, maxFiles)
+	mergeDatabases(dst.Databases, src.Databases)
+	mergeLogStats(&dst.Logs, &src.Logs, maxFiles)
+	mergeTempStats(&dst.Temp, &src.Temp, maxFiles)
+	mergePII(dst.PII, src.PII)
+	mergeRetentionStats(&dst.Retention, &src.Retention, maxFiles)
+	if src.caseNames != nil {
+		// A worker's shard, whose directories other workers saw files of too
+		mergeCaseNames(dst, src)
+	} else {
+		mergeCaseCollisions(dst.CaseCollisions, src.CaseCollisions)
+	}
+	mergePathStats(&dst.Paths, &src.Paths, maxFiles)
+	mergeLimitFiles(dst.Oversized, src.Oversized, maxFiles)
+	mergeLimitFiles(dst.TargetFailures, src.TargetFailures, maxFiles)
+	mergeLimitFiles(dst.Orphans, src.Orphans, maxFiles)
+	mergeDirPermissions(dst.DirPermissions, src.DirPermissions)
+	mergeExposure(&dst.Exposure, &src.Exposure)
+	mergeSample(&dst.Sample, &src.Sample)
+	mergeCoverage(&dst.Coverage, &src.Coverage)
+	mergeLinks(&dst.Links, &src.Links)
+	mergeHardLinks(dst.HardLinks, src.HardLinks)
+	mergeDevEnvs(dst.DevEnvs, src.DevEnvs)
+	mergeGameStats(&dst.Games, &src.Games, maxFiles)
+	mergeRecent(dst.Recent, src.Recent)
+	mergeSkippedPaths(&dst.Skipped, &src.Skipped)
+	dst.TimedOut = dst.TimedOut || src.TimedOut
+	dst.Changes = append(dst.Changes, src.Changes...)
+	if dst.ScanStart.IsZero() || src.ScanStart.Before(dst.ScanStart) {
+		dst.ScanStart = src.ScanStart
+	}
+	mergeBlockStats(&dst.Blocks, &src.Blocks)
+	if dst.Volume.Capacity == 0 {
+		dst.Volume = src.Volume
+	}
+	if dst.Focus == "" {
+		dst.Focus = src.Focus
+	}
+	dst.OtherFiles += src.OtherFiles
+	dst.OtherSize += src.OtherSize
+
+	dst.RecentMods += src.RecentMods
+	dst.TotalFiles += src.TotalFiles
+	dst.TotalSize += src.TotalSize
+	dst.EmptyFiles += src.EmptyFiles
+	dst.EmptyDirs += src.EmptyDirs
+	dst.StaleFiles += src.StaleFiles
+	dst.HiddenFiles += src.HiddenFiles
+	dst.SystemFiles += src.SystemFiles
+	dst.Symlinks += src.Symlinks
+	dst.WriteProtected += src.WriteProtected
+	dst.TotalDirs += src.TotalDirs
+}
+
+func analyzeDirectory(ctx context.Context, config Config, events *eventBus) (*Stats, error) {
+	root := config.Path
+	ctx, cancel := scanContext(ctx)
+	defer cancel()
+
+	if config.Cache {
+		return analyzeDirectoryCached(ctx, config, events)
+	}
+
+	if maxDuration > 0 {
+		return analyzeQuick(ctx, config, events)
+	}
 
 	// First pass: count total files for progress tracking
 	var totalFiles int64
-	filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+	var population int
+	walkDir(root, func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
 			return nil
 		}
+		if config.skipped(path) {
+			return skipEntry(d)
+		}
 		if !d.IsDir() {
-			atomic.AddInt64(&totalFiles, 1)
+			population++
+			if config.sampled(path) {
+				atomic.AddInt64(&totalFiles, 1)
+			}
+		}
+		return ctx.Err()
+	})
+
+	walk := walkDir
+	if bigFirst {
+		hints := sizeHints(root)
+		walk = func(root string, fn fs.WalkDirFunc) error {
+			return walkBiggestFirst(root, hints, &Coverage{}, fn)
+		}
+	}
+
+	// Walk directory and send paths to workers
+	stats, err := runAnalysis(ctx, config, &totalFiles, events, func(ctx context.Context, pathChan chan<- scanItem) error {
+		return walk(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				// Unreadable directories are recorded by the workers
+				if strictScan && !os.IsNotExist(err) {
+					return err
+				}
+				return nil
+			}
+			if config.skipped(path) {
+				return skipEntry(d)
+			}
+			if !d.IsDir() && !config.sampled(path) {
+				return nil
+			}
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case pathChan <- scanItem{path: path}:
+				return nil
+			}
+		})
+	})
+	if stats != nil && config.Sample > 0 {
+		stats.Sample.Population = population
+	}
+	return stats, err
+}
+
+// analyzeFileList runs the analysis over an explicit list of paths instead of
+// walking a directory tree. config.Path is only used to compute directory
+// depths.
+func analyzeFileList(ctx context.Context, config Config, events *eventBus) (*Stats, error) {
+	ctx, cancel := scanContext(ctx)
+	defer cancel()
+	files, population := config.Files, 0
+	if config.Sample > 0 {
+		files = nil
+		for _, path := range config.Files {
+			if config.skippedListed(path) {
+				continue
+			}
+			population++
+			if config.sampled(path) {
+				files = append(files, path)
+			}
+		}
+	}
+	totalFiles := int64(len(files))
+	stats, err := runAnalysis(ctx, config, &totalFiles, events, func(ctx context.Context, pathChan chan<- scanItem) error {
+		for _, path := range files {
+			if config.skippedListed(path) {
+				continue
+			}
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case pathChan <- scanItem{path: path}:
+			}
 		}
 		return nil
 	})
+	if stats != nil && config.Sample > 0 {
+		stats.Sample.Population = population
+	}
+	return stats, err
+}
+
+// scanItem is a path handed from the walker to the workers. info and listing
+// are filled in when the walker already knows them, e.g. from the scan cache.
+type scanItem struct {
+	path    string
+	info    os.FileInfo
+	listing *dirCacheEntry
+}
+
+// runAnalysis publishes progress events while the scan runs and closes
+// events when it is done. totalFiles may grow while feed runs. Cancelling
+// ctx stops the scan.
+func runAnalysis(ctx context.Context, config Config, totalFiles *int64, events *eventBus, feed func(ctx context.Context, pathChan chan<- scanItem) error) (*Stats, error) {
+	stats := newStats()
+	stats.ScanStart = time.Now()
+	stats.Volume, _ = volumeSpace(config.Path)
+	stats.Focus = config.OnlyCategory
 
 	// Use concurrent processing
-	ctx, cancel := context.WithCancel(context.Background())
+	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
 	g, ctx := errgroup.WithContext(ctx)
-	pathChan := make(chan string, 100)
+	pathChan := make(chan scanItem, 100)
 	numWorkers := runtime.NumCPU()
+	if config.Deterministic {
+		numWorkers = 1
+	}
 
 	// Counter for processed files
 	var processedFiles int64
 
+	// Each worker collects its results in a shard of its own, merged into
+	// stats when the scan is done, so workers don't wait for each other on
+	// stats.mu. The shards share the set of files seen, to recognize files
+	// moved during the scan whichever worker saw them first.
+	shards := make([]*Stats, numWorkers)
+	for i := range shards {
+		shards[i] = newStats()
+		shards[i].ScanStart, shards[i].Volume, shards[i].Focus = stats.ScanStart, stats.Volume, stats.Focus
+		shards[i].seenFiles = stats.seenFiles
+	}
+
 	// Progress ticker
 	go func() {
 		ticker := time.NewTicker(100 * time.Millisecond)
@@ -441,77 +1568,190 @@ func analyzeDirectory(root string, maxFiles int, progressChan chan progressMsg)
 				return
 			case <-ticker.C:
 				processed := atomic.LoadInt64(&processedFiles)
-				total := atomic.LoadInt64(&totalFiles)
-				if total > 0 {
-					select {
-					case progressChan <- progressMsg{processed: int(processed), total: int(total)}:
-					default:
-					}
+				if total := atomic.LoadInt64(totalFiles); total > 0 {
+					events.Publish(scanEvent{Kind: eventProgress, Processed: int(processed), Total: int(total), Recent: recentSnapshot(shards...)})
 				}
 			}
 		}
 	}()
 
-	// Start workers
-	for i := 0; i < numWorkers; i++ {
-		g.Go(func() error {
-			return processWorker(ctx, pathChan, stats, maxFiles, root, &processedFiles)
+	// Start workers. A panic stops the scan with a *panicError, so the TUI
+	// can restore the terminal before it is reported.
+	for _, shard := range shards {
+		g.Go(func() (err error) {
+			defer recoverPanic(&err)
+			return processWorker(ctx, pathChan, shard, config, &processedFiles)
 		})
 	}
 
-	// Walk directory and send paths to workers
-	g.Go(func() error {
+	g.Go(func() (err error) {
 		defer close(pathChan)
-		return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
-			if err != nil {
-				return nil
-			}
-			select {
-			case <-ctx.Done():
-				return ctx.Err()
-			case pathChan <- path:
-				return nil
-			}
-		})
+		defer recoverPanic(&err)
+		return feed(ctx, pathChan)
 	})
 
 	err := g.Wait()
+	for _, shard := range shards {
+		mergeStats(stats, shard, config.Count)
+	}
+	if scanBudget() > 0 && errors.Is(err, context.DeadlineExceeded) {
+		// --timeout or --max-duration ran out: report what was analyzed until then
+		stats.TimedOut = true
+		err = nil
+	}
+	if recheckChanges {
+		recheckChangedFiles(stats)
+	}
+	if err == nil {
+		findModelDuplicates(stats)
+	}
 
 	// Send final progress
-	select {
-	case progressChan <- progressMsg{processed: int(totalFiles), total: int(totalFiles)}:
-	default:
-	}
+	total := int(atomic.LoadInt64(totalFiles))
+	events.Publish(scanEvent{Kind: eventProgress, Processed: total, Total: total, Recent: recentSnapshot(stats)})
+	events.Publish(scanEvent{Kind: eventDone, Processed: int(atomic.LoadInt64(&processedFiles)), Total: total})
+	events.Close()
 
 	return stats, err
 }
 
-func processWorker(ctx context.Context, pathChan <-chan string, stats *Stats, maxFiles int, root string, processedFiles *int64) error {
+// loadFileList reads the paths for --files-from. The list is split on NUL
+// bytes when it contains any (find -print0, fd -0), otherwise on newlines.
+func loadFileList(source string) ([]string, error) {
+	var data []byte
+	var err error
+	if source == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(source)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	sep := "\n"
+	if bytes.IndexByte(data, 0) >= 0 {
+		sep = "\x00"
+	}
+
+	var paths []string
+	for _, line := range strings.Split(string(data), sep) {
+		line = strings.TrimSuffix(line, "\r")
+		if line != "" {
+			paths = append(paths, line)
+		}
+	}
+	return paths, nil
+}
+
+// listMatchingFiles prints the files matching the filter, and also accepted
+// by keep if it is set, one per line.
+func listMatchingFiles(config Config, w io.Writer, keep func(path string, info os.FileInfo) bool) error {
+	return walkMatchingFiles(config, func(path string, info os.FileInfo) {
+		if keep == nil || keep(path, info) {
+			fmt.Fprintln(w, displayPath(path))
+		}
+	})
+}
+
+// walkMatchingFiles calls visit for every file below config.Path, or in
+// config.Files, that matches the filter.
+func walkMatchingFiles(config Config, visit func(path string, info os.FileInfo)) error {
+	visitMatch := func(path string, info os.FileInfo) {
+		if !info.IsDir() && config.inFocus(path) && config.Filter.Match(path, info) {
+			visit(path, info)
+		}
+	}
+
+	if config.FilesFrom != "" {
+		for _, path := range config.Files {
+			if config.skippedListed(path) {
+				continue
+			}
+			if info, err := os.Lstat(path); err == nil {
+				visitMatch(path, info)
+			}
+		}
+		return nil
+	}
+
+	return filepath.WalkDir(config.Path, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if config.skipped(path) {
+			return skipEntry(d)
+		}
+		if info, err := d.Info(); err == nil {
+			visitMatch(path, info)
+		}
+		return nil
+	})
+}
+
+// processWorker analyzes the files sent on pathChan into the worker's shard;
+// see runAnalysis.
+func processWorker(ctx context.Context, pathChan <-chan scanItem, shard *Stats, config Config, processedFiles *int64) error {
 	for {
 		select {
 		case <-ctx.Done():
 			return ctx.Err()
-		case path, ok := <-pathChan:
+		case item, ok := <-pathChan:
 			if !ok {
 				return nil
 			}
 
-			info, err := os.Lstat(path)
-			if err != nil {
-				continue
+			path, info := item.path, item.info
+			if info == nil {
+				var err error
+				info, err = lstat(path)
+				if err != nil {
+					if os.IsNotExist(err) {
+						shard.mu.Lock()
+						recordChange(path, changeVanished, nil, shard)
+						shard.mu.Unlock()
+						continue
+					}
+					if strictScan {
+						return err
+					}
+					recordSkippedPath(shard, path, err)
+					continue
+				}
 			}
 
 			if info.IsDir() {
-				processDirectory(path, stats, root)
+				processDirectory(path, item.listing, shard, config.Path)
+				if path != config.Path {
+					processTarget(path, info, shard, config.Path, config.Count)
+				}
 			} else {
-				processFile(path, info, stats, maxFiles)
+				countSampled(shard, config.Sample)
+				if config.Filter.Match(path, info) && admitFile(path, info, shard) {
+					if config.inFocus(path) {
+						processFile(path, info, shard, config.Count)
+						processSample(info, shard, config.Sample)
+						processLink(path, info, shard, config.Path)
+						processDepth(path, info, shard, config.Path)
+						processArchive(path, info, shard)
+						processDiskImage(path, info, shard)
+						processPII(path, info, shard)
+						processExposure(path, info, shard)
+						processMail(path, info, shard)
+						processGame(path, info, shard, config.Count)
+						processRetention(path, info, shard, config.Path, config.Count)
+						processTarget(path, info, shard, config.Path, config.Count)
+					} else {
+						countOutsideFocus(info.Size(), shard)
+					}
+				}
 				atomic.AddInt64(processedFiles, 1)
 			}
 		}
 	}
 }
 
-func processDirectory(path string, stats *Stats, root string) {
+func processDirectory(path string, listing *dirCacheEntry, stats *Stats, root string) {
 	stats.mu.Lock()
 	defer stats.mu.Unlock()
 
@@ -521,13 +1761,27 @@ func processDirectory(path string, stats *Stats, root string) {
 	depth := strings.Count(relPath, string(os.PathSeparator))
 	if relPath != "." {
 		stats.DirDepths[path] = depth
+		analyzeNameCase(path, stats)
 	}
 
 	if strings.HasPrefix(filepath.Base(path), ".") && path != root {
 		stats.HiddenFiles++
 	}
+	if isSystemName(filepath.Base(path)) && path != root {
+		stats.SystemFiles++
+	}
 
-	entries, err := os.ReadDir(path)
+	if listing != nil {
+		if listing.Entries == 0 {
+			stats.EmptyDirs++
+		}
+		if len(listing.Files) > 0 {
+			stats.FilesPerDir[path] = len(listing.Files)
+		}
+		return
+	}
+
+	entries, err := readDir(path)
 	if err == nil {
 		if len(entries) == 0 {
 			stats.EmptyDirs++
@@ -542,6 +1796,8 @@ func processDirectory(path string, stats *Stats, root string) {
 		if fileCount > 0 {
 			stats.FilesPerDir[path] = fileCount
 		}
+	} else if !os.IsNotExist(err) {
+		addSkippedPath(stats, path, err)
 	}
 }
 
@@ -568,38 +1824,67 @@ func processFile(path string, info os.FileInfo, stats *Stats, maxFiles int) {
 	stats.TypeFreq[ext]++
 	stats.TypeSizes[ext] += info.Size()
 
-	if stats.LargestFiles.Len() < maxFiles {
-		heap.Push(stats.LargestFiles, FileSize{path, info.Size(), ext})
-	} else if info.Size() > (*stats.LargestFiles)[0].Size {
-		heap.Pop(stats.LargestFiles)
-		heap.Push(stats.LargestFiles, FileSize{path, info.Size(), ext})
-	}
+	pushLargest(stats.LargestFiles, FileSize{path, info.Size(), ext}, maxFiles)
 
 	if stats.LargestByType[ext] == nil {
 		stats.LargestByType[ext] = &FileSizeHeap{}
 		heap.Init(stats.LargestByType[ext])
 	}
 
-	typeHeap := stats.LargestByType[ext]
 	topFilesPerType := min(maxFiles, 10) // Limit per-type files
-	if typeHeap.Len() < topFilesPerType {
-		heap.Push(typeHeap, FileSize{path, info.Size(), ext})
-	} else if info.Size() > (*typeHeap)[0].Size {
-		heap.Pop(typeHeap)
-		heap.Push(typeHeap, FileSize{path, info.Size(), ext})
-	}
+	pushLargest(stats.LargestByType[ext], FileSize{path, info.Size(), ext}, topFilesPerType)
+	analyzeTypeDetail(path, ext, info, stats)
+	analyzeDirTypes(path, ext, info.Size(), stats)
+	analyzeDatasetFile(path, ext, info.Size(), stats)
+	analyzeModelFile(path, info, stats)
 
 	// Use separate function for permissions
 	processFilePermissions(info, stats)
+	analyzeDirPermissions(path, info, stats)
 
 	if time.Since(info.ModTime()) <= 30*24*time.Hour {
 		stats.RecentMods++
 	}
 
 	analyzeSizes(info, stats)
+	analyzeBlocks(info, stats)
 	analyzeAge(path, info, stats)
 	analyzeSpecialFiles(path, info, stats)
-	analyzeAccessPatterns(info, stats)
+	analyzeAccessTier(info, stats)
+	analyzeOwnerAge(info, stats)
+	analyzeOrphans(path, info, stats, maxFiles)
+	analyzeDirActivity(path, info, stats)
+	analyzeHardLink(path, info, stats)
+	analyzeDevEnv(path, stats)
+	analyzeClutter(path, info, stats, maxFiles)
+	analyzeDatabases(path, info, stats)
+	analyzeLogs(path, info, stats, maxFiles)
+	analyzeTempFiles(path, info, stats, maxFiles)
+	analyzeNameCase(path, stats)
+	analyzePathLength(path, stats, maxFiles)
+	analyzePortability(path, info.Size(), stats, maxFiles)
+	analyzeRecent(path, info, stats)
+}
+
+// pushLargest keeps the limit largest files in the min-heap h.
+func pushLargest(h *FileSizeHeap, file FileSize, limit int) {
+	if h.Len() < limit {
+		heap.Push(h, file)
+	} else if h.Len() > 0 && (*h)[0].Less(file) {
+		heap.Pop(h)
+		heap.Push(h, file)
+	}
+}
+
+// mergeLargest pushes the files of src into dst. src may be nil, as gob
+// leaves out empty heaps of stats sent over the network.
+func mergeLargest(dst, src *FileSizeHeap, limit int) {
+	if src == nil {
+		return
+	}
+	for _, file := range *src {
+		pushLargest(dst, file, limit)
+	}
 }
 
 func processFilePermissions(info os.FileInfo, stats *Stats) {
@@ -632,20 +1917,24 @@ func analyzeSizes(info os.FileInfo, stats *Stats) {
 	}
 }
 
+// staleAfter is how long a file must have gone unmodified to count as stale.
+const staleAfter = 6 * 30 * 24 * time.Hour
+
 func analyzeAge(path string, info os.FileInfo, stats *Stats) {
 	modTime := info.ModTime()
 
-	if stats.OldestFile == nil || modTime.Before(stats.OldestFile.ModTime) {
-		stats.OldestFile = &FileAge{path, modTime, false}
+	file := &FileAge{path, modTime, false}
+	if stats.OldestFile == nil || file.olderThan(stats.OldestFile) {
+		stats.OldestFile = file
 	}
-	if stats.NewestFile == nil || modTime.After(stats.NewestFile.ModTime) {
-		stats.NewestFile = &FileAge{path, modTime, false}
+	if stats.NewestFile == nil || stats.NewestFile.olderThan(file) {
+		stats.NewestFile = file
 	}
 
 	year := modTime.Year()
 	stats.YearDistribution[year]++
 
-	if time.Since(modTime) > 6*30*24*time.Hour {
+	if time.Since(modTime) > staleAfter {
 		stats.StaleFiles++
 	}
 }
@@ -656,6 +1945,9 @@ func analyzeSpecialFiles(path string, info os.FileInfo, stats *Stats) {
 	if strings.HasPrefix(filename, ".") {
 		stats.HiddenFiles++
 	}
+	if isSystemName(filename) {
+		stats.SystemFiles++
+	}
 
 	if info.Mode()&os.ModeSymlink != 0 {
 		stats.Symlinks++
@@ -663,22 +1955,27 @@ func analyzeSpecialFiles(path string, info os.FileInfo, stats *Stats) {
 
 }
 
-func analyzeAccessPatterns(info os.FileInfo, stats *Stats) {
-	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
-		accessTime := time.Unix(stat.Atim.Sec, stat.Atim.Nsec)
-		daysSinceAccess := int(time.Since(accessTime).Hours() / 24)
+func accessTime(info os.FileInfo) (time.Time, bool) {
+	if cached, ok := info.Sys().(*cachedFile); ok {
+		return cached.ATime, !cached.ATime.IsZero()
+	}
+	return statAccessTime(info)
+}
+
+func fileOwnerIDs(info os.FileInfo) (uid, gid uint32, ok bool) {
+	if cached, ok := info.Sys().(*cachedFile); ok {
+		return cached.Uid, cached.Gid, true
+	}
+	return statOwnerIDs(info)
+}
 
-		switch {
-		case daysSinceAccess <= 7:
-			stats.AccessTimes["last 7 days"]++
-		case daysSinceAccess <= 30:
-			stats.AccessTimes["last 30 days"]++
-		case daysSinceAccess <= 90:
-			stats.AccessTimes["last 90 days"]++
-		default:
-			stats.AccessTimes["older than 90 days"]++
-		}
+// allocatedSize returns the bytes the file occupies on disk, which is less
+// than its size for sparse files.
+func allocatedSize(info os.FileInfo) (int64, bool) {
+	if cached, ok := info.Sys().(*cachedFile); ok {
+		return cached.Allocated, cached.Allocated > 0
 	}
+	return statAllocatedSize(info)
 }
 
 func extractWords(filename string) []string {
@@ -711,62 +2008,47 @@ func getSizeStyle(size int64) lipgloss.Style {
 func displayResults(stats *Stats, maxCount int) string {
 	var result strings.Builder
 
-	result.WriteString(titleStyle.Render("MADAA - Mass Data Analysis Results"))
-	result.WriteString("\n\n")
+	writeTitle(tr("MADAA - Mass Data Analysis Results"), &result)
+
+	for _, section := range reportSections {
+		if !hiddenSections[section.Name] {
+			section.Display(stats, maxCount, &result)
+		}
+	}
+
+	return result.String()
+}
 
-	// Overview section
-	result.WriteString(headerStyle.Render("Overview"))
+// displayOverview shows the totals of the scan and what it was limited to.
+func displayOverview(stats *Stats, maxCount int, result *strings.Builder) {
+	result.WriteString(headerStyle.Render(tr("Overview")))
 	result.WriteString("\n")
-	result.WriteString(fmt.Sprintf("Files: %s  Directories: %s  Size: %s MB\n\n",
-		numberStyle.Render(fmt.Sprintf("%d", stats.TotalFiles)),
-		numberStyle.Render(fmt.Sprintf("%d", stats.TotalDirs)),
-		numberStyle.Render(fmt.Sprintf("%.1f", float64(stats.TotalSize)/(1024*1024)))))
+	result.WriteString(fmt.Sprintf(tr("Files: %s  Directories: %s  Size: %s\n\n"),
+		numberStyle.Render(formatCount(stats.TotalFiles)),
+		numberStyle.Render(formatCount(stats.TotalDirs)),
+		numberStyle.Render(formatMB(stats.TotalSize))))
+	if stats.TimedOut && stats.Coverage.Budget == 0 {
+		result.WriteString(warnStyle.Render(fmt.Sprintf(tr("Scan stopped after %s (--timeout), the report covers only the files analyzed until then"), scanTimeout)))
+		result.WriteString("\n\n")
+	}
+	displayCoverage(stats, result)
+	if stats.Sample.Rate > 0 {
+		result.WriteString(warnStyle.Render(fmt.Sprintf(tr("Only %s of the files were sampled (--sample), the other sections count just those; see the sampling estimates for totals"), formatPercent(stats.Sample.Rate*100))))
+		result.WriteString("\n\n")
+	}
+	displayFocus(stats, result)
+}
 
-	// File Categories section
-	result.WriteString(headerStyle.Render("File Categories"))
+func displayCategories(stats *Stats, maxCount int, result *strings.Builder) {
+	result.WriteString(headerStyle.Render(tr("File Categories")))
 	result.WriteString("\n")
 
 	// Collect category statistics
 	categories := make(map[string]int)
 
-	// Zähle Dateien pro Kategorie
 	for ext, count := range stats.TypeFreq {
-		if _, exists := fileTypeStyleMap[ext]; exists {
-			category := ""
-			//
-			//appStyle      = lipgloss.NewStyle().Foreground(lipgloss.Color("208"))
-			//codeStyle     = lipgloss.NewStyle().Foreground(lipgloss.Color("82"))
-			//docStyle      = lipgloss.NewStyle().Foreground(lipgloss.Color("33"))
-			//mediaStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("165"))
-			//archiveStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("133"))
-			//databaseStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("144"))
-			//specialStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("155"))
-
-			// Extrahiere die Kategorie aus dem Mapping
-			for configExt, configStyle := range fileTypeStyleMap {
-				if configExt == ext {
-					if strings.Contains(configStyle.String(), "208") {
-						category = "App"
-					} else if strings.Contains(configStyle.String(), "82") {
-						category = "Code"
-					} else if strings.Contains(configStyle.String(), "33") {
-						category = "Document"
-					} else if strings.Contains(configStyle.String(), "165") {
-						category = "Media"
-					} else if strings.Contains(configStyle.String(), "133") {
-						category = "Archive"
-					} else if strings.Contains(configStyle.String(), "144") {
-						category = "Special"
-					} else if strings.Contains(configStyle.String(), "155") {
-						category = "Database"
-					}
-					break
-				}
-			}
-
-			if category != "" {
-				categories[category] += count
-			}
+		if category, ok := categoryLabels[fileTypeCategoryMap[ext]]; ok {
+			categories[category] += count
 		}
 	}
 
@@ -783,7 +2065,10 @@ func displayResults(stats *Stats, maxCount int) string {
 		})
 	}
 	sort.Slice(sortedCategories, func(i, j int) bool {
-		return sortedCategories[i].count > sortedCategories[j].count
+		if sortedCategories[i].count != sortedCategories[j].count {
+			return sortedCategories[i].count > sortedCategories[j].count
+		}
+		return sortedCategories[i].name < sortedCategories[j].name
 	})
 
 	// Display categories
@@ -793,46 +2078,38 @@ func displayResults(stats *Stats, maxCount int) string {
 		}
 		percentage := float64(cat.count) / float64(stats.TotalFiles) * 100
 		result.WriteString(fmt.Sprintf("%s %s %s\n",
-			getFileTypeStyle(strings.ToLower(cat.name)).Render(fmt.Sprintf("%-12s", cat.name)),
-			numberStyle.Render(fmt.Sprintf("%6d", cat.count)),
-			percentStyle.Render(fmt.Sprintf("(%5.1f%%)", percentage))))
+			getFileTypeStyle(strings.ToLower(cat.name)).Render(fmt.Sprintf("%-12s", tr(cat.name))),
+			numberStyle.Render(fmt.Sprintf("%6s", formatCount(cat.count))),
+			percentStyle.Render(fmt.Sprintf("(%6s)", formatPercent(percentage)))))
 	}
 	result.WriteString("\n")
+}
 
-	// File Type Details
-	result.WriteString(headerStyle.Render("File Types"))
+func displayTypes(stats *Stats, maxCount int, result *strings.Builder) {
+	result.WriteString(headerStyle.Render(tr("File Types")))
 	result.WriteString("\n")
-	type kv struct {
-		Key   string
-		Value int
-	}
-	var sorted []kv
-	for k, v := range stats.TypeFreq {
-		sorted = append(sorted, kv{k, v})
-	}
-	sort.Slice(sorted, func(i, j int) bool {
-		return sorted[i].Value > sorted[j].Value
-	})
-
+	sorted := sortedTypes(stats)
 	displayCount := min(maxCount, len(sorted))
 	for i := 0; i < displayCount; i++ {
-		item := sorted[i]
-		percentage := float64(item.Value) / float64(stats.TotalFiles) * 100
-		style := getFileTypeStyle(item.Key)
+		ext := sorted[i]
+		percentage := float64(stats.TypeFreq[ext]) / float64(stats.TotalFiles) * 100
+		style := getFileTypeStyle(ext)
 		result.WriteString(fmt.Sprintf("%s %s %s\n",
-			style.Render(fmt.Sprintf("%-12s", item.Key)),
-			numberStyle.Render(fmt.Sprintf("%6d", item.Value)),
-			percentStyle.Render(fmt.Sprintf("(%5.1f%%)", percentage))))
+			style.Render(fmt.Sprintf("%-12s", tr(ext))),
+			numberStyle.Render(fmt.Sprintf("%6s", formatCount(stats.TypeFreq[ext]))),
+			percentStyle.Render(fmt.Sprintf("(%6s)", formatPercent(percentage)))))
 	}
 	result.WriteString("\n")
+}
 
-	// Top N Largest Files section
-	result.WriteString(headerStyle.Render(fmt.Sprintf("Top %d Largest Files", maxCount)))
+func displayLargest(stats *Stats, maxCount int, result *strings.Builder) {
+	result.WriteString(headerStyle.Render(fmt.Sprintf(tr("Top %d Largest Files"), maxCount)))
 	result.WriteString("\n")
-	displayLargestFiles(stats.LargestFiles, &result)
+	displayLargestFiles(stats.LargestFiles, result)
+}
 
-	// Size Distribution section
-	result.WriteString(headerStyle.Render("Size Distribution"))
+func displaySizes(stats *Stats, maxCount int, result *strings.Builder) {
+	result.WriteString(headerStyle.Render(tr("Size Distribution")))
 	result.WriteString("\n")
 	sizeCategories := []struct {
 		name  string
@@ -848,25 +2125,26 @@ func displayResults(stats *Stats, maxCount int) string {
 		if count, ok := stats.SizeDistribution[cat.key]; ok {
 			percentage := float64(count) / float64(stats.TotalFiles) * 100
 			result.WriteString(fmt.Sprintf("%s %s %s\n",
-				cat.style.Render(fmt.Sprintf("%-16s", cat.name)),
-				numberStyle.Render(fmt.Sprintf("%6d", count)),
-				percentStyle.Render(fmt.Sprintf("(%5.1f%%)", percentage))))
+				cat.style.Render(fmt.Sprintf("%-16s", tr(cat.name))),
+				numberStyle.Render(fmt.Sprintf("%6s", formatCount(count))),
+				percentStyle.Render(fmt.Sprintf("(%6s)", formatPercent(percentage)))))
 		}
 	}
 	result.WriteString("\n")
+}
 
-	// Age Analysis section
-	result.WriteString(headerStyle.Render("Age Analysis"))
+func displayAge(stats *Stats, maxCount int, result *strings.Builder) {
+	result.WriteString(headerStyle.Render(tr("Age Analysis")))
 	result.WriteString("\n")
 	if stats.OldestFile != nil {
-		result.WriteString(fmt.Sprintf("Oldest: %s %s\n",
-			pathStyle.Render(stats.OldestFile.Path),
-			goodStyle.Render(stats.OldestFile.ModTime.Format("2006-01-02"))))
+		result.WriteString(fmt.Sprintf(tr("Oldest: %s %s\n"),
+			renderPath(stats.OldestFile.Path),
+			goodStyle.Render(formatDate(stats.OldestFile.ModTime))))
 	}
 	if stats.NewestFile != nil {
-		result.WriteString(fmt.Sprintf("Newest: %s %s\n",
-			pathStyle.Render(stats.NewestFile.Path),
-			goodStyle.Render(stats.NewestFile.ModTime.Format("2006-01-02"))))
+		result.WriteString(fmt.Sprintf(tr("Newest: %s %s\n"),
+			renderPath(stats.NewestFile.Path),
+			goodStyle.Render(formatDate(stats.NewestFile.ModTime))))
 	}
 	stalePercent := float64(stats.StaleFiles) / float64(stats.TotalFiles) * 100
 	staleStyle := goodStyle
@@ -876,45 +2154,49 @@ func displayResults(stats *Stats, maxCount int) string {
 	if stalePercent > 80 {
 		staleStyle = badStyle
 	}
-	result.WriteString(fmt.Sprintf("Stale (>6mo): %s %s\n",
-		numberStyle.Render(fmt.Sprintf("%d", stats.StaleFiles)),
-		staleStyle.Render(fmt.Sprintf("(%.1f%%)", stalePercent))))
+	result.WriteString(fmt.Sprintf(tr("Stale (>6mo): %s %s\n"),
+		numberStyle.Render(formatCount(stats.StaleFiles)),
+		staleStyle.Render(fmt.Sprintf("(%s)", formatPercent(stalePercent)))))
 	result.WriteString("\n")
+}
 
-	// Special Files section
-	result.WriteString(headerStyle.Render("Special Files"))
+func displaySpecialFiles(stats *Stats, maxCount int, result *strings.Builder) {
+	result.WriteString(headerStyle.Render(tr("Special Files")))
 	result.WriteString("\n")
-	result.WriteString(fmt.Sprintf("Hidden: %s  System: %s  Symlinks: %s  Write-protected: %s\n",
-		numberStyle.Render(fmt.Sprintf("%d", stats.HiddenFiles)),
-		numberStyle.Render(fmt.Sprintf("%d", stats.SystemFiles)),
-		numberStyle.Render(fmt.Sprintf("%d", stats.Symlinks)),
-		warnStyle.Render(fmt.Sprintf("%d", stats.WriteProtected))))
+	result.WriteString(fmt.Sprintf(tr("Hidden: %s  System: %s  Symlinks: %s  Write-protected: %s\n"),
+		numberStyle.Render(formatCount(stats.HiddenFiles)),
+		numberStyle.Render(formatCount(stats.SystemFiles)),
+		numberStyle.Render(formatCount(stats.Symlinks)),
+		warnStyle.Render(formatCount(stats.WriteProtected))))
 	result.WriteString("\n")
+}
 
-	// Directory Info section
-	result.WriteString(headerStyle.Render("Directory Info"))
+func displayDirectoryInfo(stats *Stats, maxCount int, result *strings.Builder) {
+	result.WriteString(headerStyle.Render(tr("Directory Info")))
+	result.WriteString("\n")
+	result.WriteString(fmt.Sprintf(tr("Empty dirs: %s  Recent changes: %s %s\n"),
+		numberStyle.Render(formatCount(stats.EmptyDirs)),
+		numberStyle.Render(formatCount(stats.RecentMods)),
+		percentStyle.Render(fmt.Sprintf("(%s)", formatPercent(float64(stats.RecentMods)/float64(stats.TotalFiles)*100)))))
 	result.WriteString("\n")
-	result.WriteString(fmt.Sprintf("Empty dirs: %s  Recent changes: %s %s\n",
-		numberStyle.Render(fmt.Sprintf("%d", stats.EmptyDirs)),
-		numberStyle.Render(fmt.Sprintf("%d", stats.RecentMods)),
-		percentStyle.Render(fmt.Sprintf("(%.1f%%)", float64(stats.RecentMods)/float64(stats.TotalFiles)*100))))
+}
 
-	return result.String()
+func formatMB(size int64) string {
+	return formatFloat(float64(size)/(1024*1024), precision) + " MB"
 }
 
 func displayLargestFiles(heap *FileSizeHeap, result *strings.Builder) {
 	files := make([]FileSize, heap.Len())
 	copy(files, *heap)
 	sort.Slice(files, func(i, j int) bool {
-		return files[i].Size > files[j].Size
+		return files[j].Less(files[i])
 	})
 
 	for _, file := range files {
-		sizeMB := float64(file.Size) / (1024 * 1024)
 		style := getSizeStyle(file.Size)
 		result.WriteString(fmt.Sprintf("  %s %s\n",
-			style.Render(fmt.Sprintf("%8.1f MB", sizeMB)),
-			pathStyle.Render(file.Path)))
+			style.Render(fmt.Sprintf("%11s", formatMB(file.Size))),
+			renderPath(file.Path)))
 	}
 	result.WriteString("\n")
 }
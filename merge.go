@@ -0,0 +1,310 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ExportSource is one of the scans a merged export combines, with its own
+// totals and types, so the combined figures can be broken down by host.
+type ExportSource struct {
+	Host      string       `json:"host"`
+	Root      string       `json:"root"`
+	ScannedAt time.Time    `json:"scanned_at"`
+	Totals    ExportTotals `json:"totals"`
+	Types     []ExportType `json:"types"`
+}
+
+// exportSources returns the scans an export stands for: those it was merged
+// from, or itself. label names the host of exports written before they
+// recorded one.
+func exportSources(export *Export, label string) []ExportSource {
+	if len(export.Sources) > 0 {
+		return export.Sources
+	}
+	host := export.Host
+	if host == "" {
+		host = label
+	}
+	return []ExportSource{{host, export.Root, export.ScannedAt, export.Totals, export.Types}}
+}
+
+func addTotals(dst *ExportTotals, src ExportTotals) {
+	dst.Files += src.Files
+	dst.Dirs += src.Dirs
+	dst.Bytes += src.Bytes
+	dst.EmptyFiles += src.EmptyFiles
+	dst.EmptyDirs += src.EmptyDirs
+	dst.HiddenFiles += src.HiddenFiles
+	dst.SystemFiles += src.SystemFiles
+	dst.Symlinks += src.Symlinks
+	dst.WriteProtected += src.WriteProtected
+	dst.StaleFiles += src.StaleFiles
+	dst.RecentChanges += src.RecentChanges
+}
+
+// mergeExports combines exports of different hosts into one, tagging files
+// with the host they are on. labels[i] is the host of exports[i] if it
+// doesn't name one. The merged export has no root, keeps as many largest
+// files as the longest list of its inputs and is dated by the latest scan.
+func mergeExports(exports []*Export, labels []string) *Export {
+	merged := &Export{
+		Version:          exportVersion,
+		Categories:       []ExportCount{},
+		Types:            []ExportType{},
+		SizeDistribution: []ExportCount{},
+		Years:            []ExportYear{},
+		LargestFiles:     []ExportFile{},
+		Report:           exportReportMeta(),
+	}
+
+	categories := make(map[string]int)
+	types := make(map[string]*ExportType)
+	sizes := make(map[string]int)
+	years := make(map[int]int)
+	var keep int
+	for i, export := range exports {
+		sources := exportSources(export, labels[i])
+		merged.Sources = append(merged.Sources, sources...)
+		if export.ScannedAt.After(merged.ScannedAt) {
+			merged.ScannedAt = export.ScannedAt
+		}
+		addTotals(&merged.Totals, export.Totals)
+		for _, c := range export.Categories {
+			categories[c.Name] += c.Files
+		}
+		for _, t := range export.Types {
+			if types[t.Extension] == nil {
+				types[t.Extension] = &ExportType{Extension: t.Extension, Category: t.Category}
+			}
+			types[t.Extension].Files += t.Files
+			types[t.Extension].Bytes += t.Bytes
+		}
+		for _, c := range export.SizeDistribution {
+			sizes[c.Name] += c.Files
+		}
+		for _, y := range export.Years {
+			years[y.Year] += y.Files
+		}
+
+		// A merged input has tagged its files already
+		host := sources[0].Host
+		keep = max(keep, len(export.LargestFiles))
+		for _, file := range export.LargestFiles {
+			if file.Host == "" {
+				file.Host = host
+			}
+			merged.LargestFiles = append(merged.LargestFiles, file)
+		}
+		if f := export.OldestFile; f != nil && (merged.OldestFile == nil || f.Modified.Before(merged.OldestFile.Modified)) {
+			merged.OldestFile = &ExportFileAge{Path: f.Path, Modified: f.Modified, Host: f.Host}
+			if merged.OldestFile.Host == "" {
+				merged.OldestFile.Host = host
+			}
+		}
+		if f := export.NewestFile; f != nil && (merged.NewestFile == nil || f.Modified.After(merged.NewestFile.Modified)) {
+			merged.NewestFile = &ExportFileAge{Path: f.Path, Modified: f.Modified, Host: f.Host}
+			if merged.NewestFile.Host == "" {
+				merged.NewestFile.Host = host
+			}
+		}
+	}
+
+	for _, name := range sortedKeys(categories) {
+		merged.Categories = append(merged.Categories, ExportCount{name, categories[name]})
+	}
+	for _, t := range types {
+		merged.Types = append(merged.Types, *t)
+	}
+	sort.Slice(merged.Types, func(i, j int) bool {
+		if merged.Types[i].Files != merged.Types[j].Files {
+			return merged.Types[i].Files > merged.Types[j].Files
+		}
+		return merged.Types[i].Extension < merged.Types[j].Extension
+	})
+	for _, bucket := range []string{"tiny", "small", "medium", "large"} {
+		if count, ok := sizes[bucket]; ok {
+			merged.SizeDistribution = append(merged.SizeDistribution, ExportCount{bucket, count})
+		}
+	}
+	for year, count := range years {
+		merged.Years = append(merged.Years, ExportYear{year, count})
+	}
+	sort.Slice(merged.Years, func(i, j int) bool { return merged.Years[i].Year < merged.Years[j].Year })
+	sort.SliceStable(merged.LargestFiles, func(i, j int) bool {
+		return merged.LargestFiles[i].Bytes > merged.LargestFiles[j].Bytes
+	})
+	merged.LargestFiles = merged.LargestFiles[:min(keep, len(merged.LargestFiles))]
+	return merged
+}
+
+func sortedKeys(m map[string]int) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// exportCategory is the label of a category of an exported type.
+func exportCategory(category string) string {
+	if label, ok := categoryLabels[category]; ok {
+		return label
+	}
+	return "Other"
+}
+
+func displayMerged(merged *Export, maxCount int) string {
+	var result strings.Builder
+	share := func(part, whole int64) string {
+		if whole == 0 {
+			return formatPercent(0)
+		}
+		return formatPercent(float64(part) / float64(whole) * 100)
+	}
+	total := merged.Totals.Bytes
+
+	writeTitle(tr("MADAA - Combined Report"), &result)
+	result.WriteString(fmt.Sprintf(tr("Files: %s  Directories: %s  Size: %s\n\n"),
+		numberStyle.Render(formatCount(merged.Totals.Files)),
+		numberStyle.Render(formatCount(merged.Totals.Dirs)),
+		numberStyle.Render(formatMB(total))))
+
+	sources := append([]ExportSource(nil), merged.Sources...)
+	sort.SliceStable(sources, func(i, j int) bool { return sources[i].Totals.Bytes > sources[j].Totals.Bytes })
+	result.WriteString(headerStyle.Render(fmt.Sprintf(tr("Hosts (%s scans)"), formatCount(len(sources)))))
+	result.WriteString("\n")
+	for _, s := range sources {
+		result.WriteString(fmt.Sprintf("%s %s %s %s  %s %s\n",
+			numberStyle.Render(fmt.Sprintf("%14s", formatMB(s.Totals.Bytes))),
+			percentStyle.Render(fmt.Sprintf("%8s", share(s.Totals.Bytes, total))),
+			numberStyle.Render(fmt.Sprintf("%11s", formatCount(s.Totals.Files))),
+			formatDate(s.ScannedAt.Local()),
+			pathStyle.Render(s.Host),
+			renderPath(s.Root)))
+	}
+	result.WriteString("\n")
+
+	// Bytes per category, overall and per host
+	byCategory := make(map[string]int64)
+	byHost := make(map[string]map[string]int64)
+	for _, s := range sources {
+		for _, t := range s.Types {
+			category := exportCategory(t.Category)
+			byCategory[category] += t.Bytes
+			if byHost[category] == nil {
+				byHost[category] = make(map[string]int64)
+			}
+			byHost[category][s.Host] += t.Bytes
+		}
+	}
+	categories := make([]string, 0, len(byCategory))
+	for category := range byCategory {
+		categories = append(categories, category)
+	}
+	sort.Slice(categories, func(i, j int) bool {
+		if byCategory[categories[i]] != byCategory[categories[j]] {
+			return byCategory[categories[i]] > byCategory[categories[j]]
+		}
+		return categories[i] < categories[j]
+	})
+	result.WriteString(headerStyle.Render(tr("Categories by Host")))
+	result.WriteString("\n")
+	for _, category := range categories {
+		result.WriteString(fmt.Sprintf("%s %s  %s\n",
+			numberStyle.Render(fmt.Sprintf("%14s", formatMB(byCategory[category]))),
+			percentStyle.Render(fmt.Sprintf("%8s", share(byCategory[category], total))),
+			tr(category)))
+		hosts := make([]string, 0, len(byHost[category]))
+		for host := range byHost[category] {
+			hosts = append(hosts, host)
+		}
+		sort.Slice(hosts, func(i, j int) bool {
+			a, b := byHost[category][hosts[i]], byHost[category][hosts[j]]
+			if a != b {
+				return a > b
+			}
+			return hosts[i] < hosts[j]
+		})
+		for _, host := range hosts[:min(maxCount, len(hosts))] {
+			result.WriteString(fmt.Sprintf("%s %s    %s\n",
+				numberStyle.Render(fmt.Sprintf("%14s", formatMB(byHost[category][host]))),
+				percentStyle.Render(fmt.Sprintf("%8s", share(byHost[category][host], byCategory[category]))),
+				host))
+		}
+	}
+	result.WriteString("\n")
+
+	if len(merged.LargestFiles) > 0 {
+		result.WriteString(headerStyle.Render(tr("Largest Files")))
+		result.WriteString("\n")
+		for _, file := range merged.LargestFiles[:min(maxCount, len(merged.LargestFiles))] {
+			result.WriteString(fmt.Sprintf("%s  %s %s\n",
+				numberStyle.Render(fmt.Sprintf("%14s", formatMB(file.Bytes))),
+				pathStyle.Render(file.Host),
+				renderPath(file.Path)))
+		}
+		result.WriteString("\n")
+	}
+	return result.String()
+}
+
+// runMerge implements "madaa merge FILE...": it combines the JSON exports
+// of scans on different hosts into one report broken down by host, and
+// optionally into one export. An argument HOST=FILE names the host of an
+// export that doesn't record one; otherwise its file name stands in.
+func runMerge(args []string) {
+	flags := flag.NewFlagSet("merge", flag.ExitOnError)
+	jsonPath := flags.String("json", "", "Write the combined export to FILE (- for stdout) instead of the report")
+	count := flags.Int("count", 3, "Number of hosts per category and of largest files to list")
+	reportFlags(flags)
+	selectLanguage := localeFlags(flags)
+	flags.Parse(args)
+	selectLanguage()
+
+	if flags.NArg() < 1 {
+		fmt.Println("Usage: madaa merge [--json FILE] [--count N] [HOST=]EXPORT...")
+		os.Exit(1)
+	}
+	if err := loadConfig(); err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+
+	var exports []*Export
+	var labels []string
+	for _, arg := range flags.Args() {
+		label, path, ok := strings.Cut(arg, "=")
+		if !ok {
+			path = arg
+			label = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		}
+		export, err := loadExport(path)
+		if err != nil {
+			fmt.Printf("Error loading export: %v\n", err)
+			os.Exit(1)
+		}
+		if ok && len(export.Sources) == 0 {
+			// An explicit name wins over the recorded one
+			export.Host = label
+		}
+		exports = append(exports, export)
+		labels = append(labels, label)
+	}
+
+	merged := mergeExports(exports, labels)
+	if *jsonPath == "" {
+		fmt.Print(fitPaths(displayMerged(merged, *count), pathWidth))
+		return
+	}
+	if err := saveExportFile(merged, *jsonPath); err != nil {
+		fmt.Printf("Error writing JSON: %v\n", err)
+		os.Exit(1)
+	}
+}
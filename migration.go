@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Migration estimate settings from --assume and --per-file-overhead. A rate
+// of zero leaves the estimate out. Every file costs migrationPerFile on top
+// of its bytes: opening, creating and closing it and copying its metadata,
+// which is what makes millions of small files slow to move.
+var (
+	migrationRate    float64
+	migrationPerFile = 10 * time.Millisecond
+)
+
+// parseRate parses a throughput such as 100MB/s, 1.5G/s or 1Gbit/s into
+// bytes per second. Sizes use binary multiples as in parseSize.
+func parseRate(s string) (float64, error) {
+	value := strings.TrimSuffix(strings.ToLower(strings.TrimSpace(s)), "/s")
+	bits := false
+	if trimmed, ok := strings.CutSuffix(value, "bit"); ok {
+		value, bits = trimmed, true
+	}
+	size, err := parseSize(value)
+	if err != nil || size == 0 {
+		return 0, fmt.Errorf("invalid throughput %q", s)
+	}
+	if bits {
+		return float64(size) / 8, nil
+	}
+	return float64(size), nil
+}
+
+// migrationLine is the estimated transfer time of one top-level directory.
+type migrationLine struct {
+	Name  string
+	Files int
+	Bytes int64
+}
+
+func (l migrationLine) Transfer() time.Duration {
+	return time.Duration(float64(l.Bytes) / migrationRate * float64(time.Second))
+}
+
+func (l migrationLine) Overhead() time.Duration {
+	return time.Duration(l.Files) * migrationPerFile
+}
+
+func (l migrationLine) Duration() time.Duration {
+	return l.Transfer() + l.Overhead()
+}
+
+// migrationLines splits the scan by top-level directory, slowest first,
+// like the chargeback report does.
+func migrationLines(stats *Stats) []migrationLine {
+	var lines []migrationLine
+	restFiles, restBytes := stats.TotalFiles, stats.TotalSize
+	for dir, tree := range dirTrees(stats) {
+		if stats.DirDepths[dir] == 0 && tree.Files > 0 {
+			lines = append(lines, migrationLine{displayPath(dir), tree.Files, tree.Size})
+			restFiles -= tree.Files
+			restBytes -= tree.Size
+		}
+	}
+	if restFiles > 0 {
+		lines = append(lines, migrationLine{tr("(files in the scanned directory)"), restFiles, restBytes})
+	}
+	sort.Slice(lines, func(i, j int) bool {
+		if lines[i].Duration() != lines[j].Duration() {
+			return lines[i].Duration() > lines[j].Duration()
+		}
+		return lines[i].Name < lines[j].Name
+	})
+	return lines
+}
+
+// formatDuration rounds durations to their two largest units, such as
+// 3d 4h or 12m 05s.
+func formatDuration(d time.Duration) string {
+	d = d.Round(time.Second)
+	switch {
+	case d >= 24*time.Hour:
+		return fmt.Sprintf("%dd %dh", int(d/(24*time.Hour)), int(d%(24*time.Hour)/time.Hour))
+	case d >= time.Hour:
+		return fmt.Sprintf("%dh %02dm", int(d/time.Hour), int(d%time.Hour/time.Minute))
+	case d >= time.Minute:
+		return fmt.Sprintf("%dm %02ds", int(d/time.Minute), int(d%time.Minute/time.Second))
+	}
+	return fmt.Sprintf("%ds", int(d/time.Second))
+}
+
+func displayMigration(stats *Stats, maxCount int, result *strings.Builder) {
+	if migrationRate <= 0 || stats.TotalFiles == 0 {
+		return
+	}
+	total := migrationLine{Files: stats.TotalFiles, Bytes: stats.TotalSize}
+
+	result.WriteString(headerStyle.Render(tr("Migration Estimate")))
+	result.WriteString("\n")
+	result.WriteString(fmt.Sprintf(tr("%s files, %s at %s/s and %s per file: %s\n"),
+		numberStyle.Render(formatCount(total.Files)),
+		numberStyle.Render(formatMB(total.Bytes)),
+		numberStyle.Render(formatMB(int64(migrationRate))),
+		numberStyle.Render(migrationPerFile.String()),
+		badStyle.Render(formatDuration(total.Duration()))))
+	var share float64
+	if total.Duration() > 0 {
+		share = float64(total.Overhead()) / float64(total.Duration()) * 100
+	}
+	result.WriteString(fmt.Sprintf(tr("Transfer: %s  Per-file overhead: %s (%s)\n"),
+		numberStyle.Render(formatDuration(total.Transfer())),
+		warnStyle.Render(formatDuration(total.Overhead())),
+		percentStyle.Render(formatPercent(share))))
+
+	lines := migrationLines(stats)
+	for _, line := range lines[:min(maxCount, len(lines))] {
+		result.WriteString(fmt.Sprintf("  %s %s %s %s\n",
+			badStyle.Render(fmt.Sprintf("%9s", formatDuration(line.Duration()))),
+			numberStyle.Render(fmt.Sprintf("%12s", formatMB(line.Bytes))),
+			numberStyle.Render(fmt.Sprintf("%10s", formatCount(line.Files))),
+			pathStyle.Render(line.Name)))
+	}
+	result.WriteString("\n")
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// checkpointAge is after how long model files count as old checkpoints
+// (--checkpoint-days).
+var checkpointAge = 90 * 24 * time.Hour
+
+// ModelStats covers the files of the "model" category: checkpoints and
+// weights (.ckpt, .safetensors, .pt, .onnx, .gguf, ...). BySize collects
+// them for the duplicate check at the end of the scan.
+type ModelStats struct {
+	Files      int
+	Bytes      int64
+	OldFiles   int
+	OldBytes   int64
+	BySize     map[int64][]dupFile
+	Duplicates []*dupGroup
+	seen       map[fileID]bool
+}
+
+func newModelStats() ModelStats {
+	return ModelStats{
+		BySize: make(map[int64][]dupFile),
+		seen:   make(map[fileID]bool),
+	}
+}
+
+// analyzeModelFile is called by processFile, with the stats locked.
+func analyzeModelFile(path string, info os.FileInfo, stats *Stats) {
+	if fileCategory(path) != "model" {
+		return
+	}
+	models := &stats.Models
+	models.Files++
+	models.Bytes += info.Size()
+	if time.Since(info.ModTime()) > checkpointAge {
+		models.OldFiles++
+		models.OldBytes += info.Size()
+	}
+
+	// Hardlinks share their data, they are no duplicates of each other
+	if id, links, ok := fileIdentity(info); ok && links > 1 {
+		if models.seen[id] {
+			return
+		}
+		models.seen[id] = true
+	}
+	if info.Size() > 0 {
+		models.BySize[info.Size()] = append(models.BySize[info.Size()], dupFile{path, info.ModTime()})
+	}
+}
+
+// findModelDuplicates hashes the model files of the same size once the scan
+// is done. Copies of the same weights across experiment directories are
+// common, and each of them is large.
+func findModelDuplicates(stats *Stats) {
+	stats.Models.Duplicates = duplicateGroups(stats.Models.BySize)
+}
+
+func mergeModelStats(dst, src *ModelStats) {
+	dst.Files += src.Files
+	dst.Bytes += src.Bytes
+	dst.OldFiles += src.OldFiles
+	dst.OldBytes += src.OldBytes
+	for size, files := range src.BySize {
+		dst.BySize[size] = append(dst.BySize[size], files...)
+	}
+	dst.Duplicates = append(dst.Duplicates, src.Duplicates...)
+}
+
+func displayModels(stats *Stats, maxCount int, result *strings.Builder) {
+	models := stats.Models
+	if models.Files == 0 {
+		return
+	}
+
+	result.WriteString(headerStyle.Render(tr("Model Files")))
+	result.WriteString("\n")
+	result.WriteString(fmt.Sprintf(tr("Files: %s  Size: %s  Older than %d days: %s files, %s\n"),
+		numberStyle.Render(formatCount(models.Files)),
+		numberStyle.Render(formatMB(models.Bytes)),
+		int(checkpointAge.Hours()/24),
+		warnStyle.Render(formatCount(models.OldFiles)),
+		warnStyle.Render(formatMB(models.OldBytes))))
+
+	if len(models.Duplicates) > 0 {
+		files, bytes := dupTotals(models.Duplicates)
+		result.WriteString(fmt.Sprintf(tr("Duplicates: %s redundant copies, %s\n"),
+			badStyle.Render(formatCount(files)),
+			badStyle.Render(formatMB(bytes))))
+		for _, g := range models.Duplicates[:min(maxCount, len(models.Duplicates))] {
+			result.WriteString(fmt.Sprintf(tr("  %s %s copies, %s each\n"),
+				badStyle.Render(fmt.Sprintf("%11s", formatMB(g.Redundant()))),
+				numberStyle.Render(formatCount(len(g.Files))),
+				numberStyle.Render(formatMB(g.Size))))
+			for _, f := range g.Files {
+				result.WriteString(fmt.Sprintf("      %s\n", renderPath(f.Path)))
+			}
+		}
+	}
+	result.WriteString("\n")
+}
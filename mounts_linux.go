@@ -0,0 +1,31 @@
+//go:build linux
+
+package main
+
+import (
+	"bufio"
+	"os"
+	"strings"
+)
+
+// mountPoints returns the mount points of the file systems of the given
+// types, read from /proc/self/mounts.
+func mountPoints(types map[string]bool) []string {
+	f, err := os.Open("/proc/self/mounts")
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	// Spaces, tabs and backslashes in mount points are escaped in octal
+	unescape := strings.NewReplacer(`\040`, " ", `\011`, "\t", `\012`, "\n", `\134`, `\`)
+	var mounts []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 3 && types[fields[2]] {
+			mounts = append(mounts, unescape.Replace(fields[1]))
+		}
+	}
+	return mounts
+}
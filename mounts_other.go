@@ -0,0 +1,9 @@
+//go:build !linux
+
+package main
+
+// mountPoints is not supported here; only the fixed pseudoPaths and the
+// mounts of the [skip] config section are left out.
+func mountPoints(types map[string]bool) []string {
+	return nil
+}
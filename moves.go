@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// FileMove is a file that was moved or renamed between two snapshots,
+// recognized by its content. Paths are relative to the snapshot root.
+type FileMove struct {
+	From string
+	To   string
+	Size int64
+}
+
+// hashSnapshot records the content hash of every non-empty regular file in
+// snap. Files whose size and mtime match a hashed baseline keep the hash
+// from there instead of being read again; files that can't be read are
+// left without one.
+func hashSnapshot(ctx context.Context, snap, baseline *Snapshot) error {
+	var known map[string]*dirCacheEntry
+	if baseline != nil && baseline.Hashed {
+		known = relativeDirs(baseline)
+	}
+
+	for dir, listing := range snap.Dirs {
+		var old map[string]*cachedFile
+		if rel, err := filepath.Rel(snap.Root, dir); err == nil && known[rel] != nil {
+			old = make(map[string]*cachedFile, len(known[rel].Files))
+			for i := range known[rel].Files {
+				old[known[rel].Files[i].Name] = &known[rel].Files[i]
+			}
+		}
+		for i := range listing.Files {
+			f := &listing.Files[i]
+			if len(f.Hash) > 0 || !f.Mode.IsRegular() || f.Size == 0 {
+				continue
+			}
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if before := old[f.Name]; before != nil && len(before.Hash) > 0 &&
+				before.Size == f.Size && before.ModTime.Equal(f.ModTime) {
+				f.Hash = before.Hash
+				continue
+			}
+			if sum, err := hashFile(filepath.Join(dir, f.Name), -1); err == nil {
+				f.Hash = sum[:]
+			}
+		}
+	}
+	snap.Hashed = true
+	return nil
+}
+
+// detectMoves pairs removed and added files of the same content, given their
+// hashes by path, and reports them as moves. Among several candidates a file
+// of the same name is preferred, so copies renamed along with their
+// directory pair up with their originals.
+func detectMoves(diff *SnapshotDiff, added, removed map[string]string) {
+	if len(added) == 0 || len(removed) == 0 {
+		return
+	}
+
+	candidates := make(map[string][]FileChange)
+	for _, change := range diff.Removed {
+		if hash, ok := removed[change.Path]; ok {
+			candidates[hash] = append(candidates[hash], change)
+		}
+	}
+
+	moved := make(map[string]bool)
+	var kept []FileChange
+	for _, change := range diff.Added {
+		hash, ok := added[change.Path]
+		if !ok || len(candidates[hash]) == 0 {
+			kept = append(kept, change)
+			continue
+		}
+		from := candidates[hash]
+		pick := 0
+		for i, c := range from {
+			if filepath.Base(c.Path) == filepath.Base(change.Path) {
+				pick = i
+				break
+			}
+		}
+		diff.Moved = append(diff.Moved, FileMove{From: from[pick].Path, To: change.Path, Size: change.NewSize})
+		moved[from[pick].Path] = true
+		candidates[hash] = slices.Delete(from, pick, pick+1)
+	}
+	diff.Added = kept
+
+	var removedKept []FileChange
+	for _, change := range diff.Removed {
+		if !moved[change.Path] {
+			removedKept = append(removedKept, change)
+		}
+	}
+	diff.Removed = removedKept
+
+	sort.Slice(diff.Moved, func(i, j int) bool {
+		if diff.Moved[i].Size != diff.Moved[j].Size {
+			return diff.Moved[i].Size > diff.Moved[j].Size
+		}
+		return diff.Moved[i].To < diff.Moved[j].To
+	})
+}
+
+func displayMoves(diff *SnapshotDiff, maxCount int, result *strings.Builder) {
+	if len(diff.Moved) == 0 {
+		return
+	}
+	result.WriteString(headerStyle.Render(tr("Largest Moved Files")))
+	result.WriteString("\n")
+	for _, move := range diff.Moved[:min(maxCount, len(diff.Moved))] {
+		result.WriteString(fmt.Sprintf(tr("  %s %s → %s\n"),
+			numberStyle.Render(formatMB(move.Size)),
+			renderPath(move.From),
+			renderPath(move.To)))
+	}
+	result.WriteString("\n")
+}
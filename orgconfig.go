@@ -0,0 +1,232 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/ini.v1"
+)
+
+// baseConfigTimeout bounds the fetch of a base config from a URL, so an
+// unreachable server delays a scan by seconds rather than hanging it.
+const baseConfigTimeout = 10 * time.Second
+
+// baseConfigSource returns where the base config comes from: the
+// MADAA_BASE_CONFIG environment variable, as set by a deployment tool, or
+// base in the [config] section of the local config.ini.
+func baseConfigSource(local *ini.File) string {
+	if source := os.Getenv("MADAA_BASE_CONFIG"); source != "" {
+		return source
+	}
+	return local.Section("config").Key("base").String()
+}
+
+// baseConfigMaxAge is how long a fetched base config is used without asking
+// the server again, unless the server's Cache-Control sets a max-age.
+const baseConfigMaxAge = time.Hour
+
+// baseConfigCachePath is where the last base config fetched from source is
+// kept, to use while it is fresh and to fall back to when the server can't
+// be reached. Its baseConfigMeta is kept next to it, in a .json file.
+func baseConfigCachePath(source string) (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(source))
+	return filepath.Join(dir, "madaa", fmt.Sprintf("base-%x.ini", sum[:8])), nil
+}
+
+// baseConfigMeta is what is known about a cached base config: the ETag to
+// ask the server whether it changed, and until when it is used without
+// asking.
+type baseConfigMeta struct {
+	ETag    string
+	Expires time.Time
+}
+
+// fetchBaseConfig reads the base config from a path or an https URL; plain
+// http URLs are refused unless allowHTTP is set, as anyone on the network
+// could change the config in transit. A fetched config is cached and used
+// without a request until it expires, then revalidated with its ETag. The
+// cached copy is also used with a warning when the fetch fails, so laptops
+// away from the office network keep the org's settings.
+func fetchBaseConfig(source string, allowHTTP bool) ([]byte, error) {
+	if !strings.HasPrefix(source, "http://") && !strings.HasPrefix(source, "https://") {
+		data, err := os.ReadFile(source)
+		if err != nil {
+			return nil, fmt.Errorf("reading base config: %w", err)
+		}
+		return data, nil
+	}
+	if strings.HasPrefix(source, "http://") && !allowHTTP {
+		return nil, fmt.Errorf("base config %s: plain http can be tampered with, use https or set allow_http = true in the [config] section", source)
+	}
+
+	var cached []byte
+	var meta baseConfigMeta
+	cache, cacheErr := baseConfigCachePath(source)
+	if cacheErr == nil {
+		if data, err := os.ReadFile(cache + ".json"); err == nil {
+			json.Unmarshal(data, &meta)
+		}
+		if data, err := os.ReadFile(cache); err == nil {
+			cached = data
+			if time.Now().Before(meta.Expires) {
+				return cached, nil
+			}
+		} else {
+			meta.ETag = ""
+		}
+	}
+
+	data, meta, err := requestBaseConfig(source, meta.ETag)
+	if err == nil {
+		if data == nil {
+			// Not modified
+			data = cached
+		}
+		if cacheErr == nil {
+			saveBaseConfigCache(cache, data, meta)
+		}
+		return data, nil
+	}
+	if cached != nil {
+		fmt.Fprintf(os.Stderr, "Warning: using cached base config, fetching %s failed: %v\n", source, err)
+		return cached, nil
+	}
+	return nil, fmt.Errorf("fetching base config: %w", err)
+}
+
+// requestBaseConfig fetches the base config at url. With the ETag of the
+// cached copy, it returns no data if the config didn't change.
+func requestBaseConfig(url, etag string) ([]byte, baseConfigMeta, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, baseConfigMeta{}, err
+	}
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+	client := &http.Client{Timeout: baseConfigTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, baseConfigMeta{}, err
+	}
+	defer resp.Body.Close()
+
+	meta := baseConfigMeta{ETag: resp.Header.Get("ETag"), Expires: time.Now().Add(maxAge(resp.Header))}
+	switch resp.StatusCode {
+	case http.StatusNotModified:
+		if meta.ETag == "" {
+			meta.ETag = etag
+		}
+		return nil, meta, nil
+	case http.StatusOK:
+		data, err := io.ReadAll(resp.Body)
+		return data, meta, err
+	}
+	return nil, baseConfigMeta{}, fmt.Errorf("%s: %s", url, resp.Status)
+}
+
+// maxAge is how long a response may be used by its Cache-Control header,
+// baseConfigMaxAge if it doesn't say.
+func maxAge(header http.Header) time.Duration {
+	for _, directive := range strings.Split(header.Get("Cache-Control"), ",") {
+		directive = strings.ToLower(strings.TrimSpace(directive))
+		if directive == "no-cache" || directive == "no-store" {
+			return 0
+		}
+		if value, ok := strings.CutPrefix(directive, "max-age="); ok {
+			if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+				return time.Duration(seconds) * time.Second
+			}
+		}
+	}
+	return baseConfigMaxAge
+}
+
+// saveBaseConfigCache keeps the fetched base config for the next runs. It
+// is only readable by the user, like config.ini it may hold paths and
+// policies not meant for everyone on the machine.
+func saveBaseConfigCache(cache string, data []byte, meta baseConfigMeta) {
+	if os.MkdirAll(filepath.Dir(cache), 0700) != nil {
+		return
+	}
+	if writePrivateFile(cache, data) != nil {
+		return
+	}
+	if encoded, err := json.Marshal(meta); err == nil {
+		writePrivateFile(cache+".json", encoded)
+	}
+}
+
+// writePrivateFile replaces the file at path with data, readable only by
+// the user, also if an older version of it was readable by others.
+func writePrivateFile(path string, data []byte) error {
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return err
+	}
+	return os.Chmod(path, 0600)
+}
+
+// fetchURL downloads url, failing on any status but 200 OK.
+func fetchURL(url string, timeout time.Duration) ([]byte, error) {
+	client := &http.Client{Timeout: timeout}
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: %s", url, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// loadLayeredConfig loads the local config at path on top of the base
+// config it names, if any. Local keys override the base key by key, except
+// in the sections the base lists in enforce of its [config] section, whose
+// local settings are ignored; that is how an org keeps categories, excludes
+// or retention rules the same on every machine.
+func loadLayeredConfig(path string) (*ini.File, error) {
+	local, err := ini.Load(path)
+	if err != nil {
+		return nil, err
+	}
+	source := baseConfigSource(local)
+	if source == "" {
+		return local, nil
+	}
+	data, err := fetchBaseConfig(source, local.Section("config").Key("allow_http").MustBool(false))
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := ini.Load(data)
+	if err != nil {
+		return nil, fmt.Errorf("parsing base config %s: %w", source, err)
+	}
+
+	enforced := make(map[string]bool)
+	for _, name := range splitList(cfg.Section("config").Key("enforce").String()) {
+		enforced[name] = true
+	}
+	for _, section := range local.Sections() {
+		if enforced[section.Name()] || section.Name() == "config" {
+			continue
+		}
+		merged := cfg.Section(section.Name())
+		for _, key := range section.Keys() {
+			merged.Key(key.Name()).SetValue(key.Value())
+		}
+	}
+	return cfg, nil
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestFetchBaseConfigCache(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	body, cacheControl := "[skip]\nmounts = nfs\n", ""
+	var requests, revalidated int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("ETag", `"v1"`)
+		if cacheControl != "" {
+			w.Header().Set("Cache-Control", cacheControl)
+		}
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			revalidated++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	fetch := func() string {
+		t.Helper()
+		data, err := fetchBaseConfig(server.URL, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+
+	// Fresh for an hour by default: fetched once
+	if fetch() != body || fetch() != body || requests != 1 {
+		t.Fatalf("%d requests, want 1", requests)
+	}
+	cache, err := baseConfigCachePath(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{cache, cache + ".json"} {
+		if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0600 {
+			t.Errorf("%s: mode %v, want 0600: %v", path, info.Mode().Perm(), err)
+		}
+	}
+
+	// Expired: revalidated with the ETag, and the cached copy used
+	cacheControl = "max-age=0"
+	os.Remove(cache + ".json")
+	saveBaseConfigCache(cache, []byte(body), baseConfigMeta{ETag: `"v1"`})
+	if fetch() != body || requests != 2 || revalidated != 1 {
+		t.Errorf("%d requests, %d revalidated, want 2 and 1", requests, revalidated)
+	}
+	if fetch() != body || requests != 3 {
+		t.Errorf("max-age=0: %d requests, want 3", requests)
+	}
+
+	// Server gone: the cached copy
+	server.Close()
+	if fetch() != body {
+		t.Error("cached copy not used when the server is gone")
+	}
+}
+
+func TestFetchBaseConfigRefusesHTTP(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	_, err := fetchBaseConfig("http://config.example.com/madaa.ini", false)
+	if err == nil || !strings.Contains(err.Error(), "allow_http") {
+		t.Errorf("got %v, want plain http refused", err)
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// analyzeOrphans counts files whose uid or gid belongs to no account, as
+// left behind by departed users and deleted service accounts. The counts
+// are keyed "uid N" and "gid N".
+func analyzeOrphans(path string, info os.FileInfo, stats *Stats, maxFiles int) {
+	uid, gid, ok := fileOwnerIDs(info)
+	if !ok {
+		return
+	}
+	if lookupOwner(uid).orphaned {
+		addLimitFile(stats.Orphans, fmt.Sprintf("uid %d", uid), path, info.Size(), maxFiles)
+	}
+	if lookupGroup(gid).orphaned {
+		addLimitFile(stats.Orphans, fmt.Sprintf("gid %d", gid), path, info.Size(), maxFiles)
+	}
+}
+
+// displayOrphans lists the orphaned uids and gids with their files and
+// bytes, most bytes first, each with its largest files.
+func displayOrphans(stats *Stats, maxCount int, result *strings.Builder) {
+	if len(stats.Orphans) == 0 {
+		return
+	}
+	ids := make([]string, 0, len(stats.Orphans))
+	for id := range stats.Orphans {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		a, b := stats.Orphans[ids[i]], stats.Orphans[ids[j]]
+		if a.Bytes != b.Bytes {
+			return a.Bytes > b.Bytes
+		}
+		return ids[i] < ids[j]
+	})
+
+	result.WriteString(headerStyle.Render(tr("Orphaned Owners")))
+	result.WriteString("\n")
+	result.WriteString(tr("Files of uids and gids without a user or group on this system\n"))
+	for _, id := range ids {
+		files := stats.Orphans[id]
+		result.WriteString(fmt.Sprintf(tr("%s: %s files, %s\n"),
+			warnStyle.Render(strings.ToUpper(id)),
+			numberStyle.Render(formatCount(files.Files)),
+			numberStyle.Render(formatMB(files.Bytes))))
+		displayLimitFiles(files, maxCount, result)
+	}
+	result.WriteString("\n")
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/user"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// OwnerAge splits the bytes owned by one user by file age.
+type OwnerAge struct {
+	UpTo1Year  int64
+	UpTo3Years int64
+	Older      int64
+}
+
+func (o *OwnerAge) Total() int64 {
+	return o.UpTo1Year + o.UpTo3Years + o.Older
+}
+
+// idName is a resolved uid or gid. Orphaned ids are known not to belong to
+// any account; ids whose lookup failed otherwise are not.
+type idName struct {
+	name     string
+	orphaned bool
+}
+
+var (
+	ownerNamesMu sync.Mutex
+	ownerNames   = make(map[uint32]idName)
+	groupNames   = make(map[uint32]idName)
+)
+
+// lookupID resolves an id through lookup, caching the result in names.
+func lookupID(names map[uint32]idName, id uint32, lookup func(id string) (string, error)) idName {
+	ownerNamesMu.Lock()
+	defer ownerNamesMu.Unlock()
+
+	if name, ok := names[id]; ok {
+		return name
+	}
+	name := idName{name: strconv.FormatUint(uint64(id), 10)}
+	resolved, err := lookup(name.name)
+	var unknownUser user.UnknownUserIdError
+	var unknownGroup user.UnknownGroupIdError
+	switch {
+	case err == nil:
+		name.name = resolved
+	case errors.As(err, &unknownUser), errors.As(err, &unknownGroup):
+		name.orphaned = true
+	}
+	names[id] = name
+	return name
+}
+
+func lookupOwner(uid uint32) idName {
+	return lookupID(ownerNames, uid, func(id string) (string, error) {
+		u, err := user.LookupId(id)
+		if err != nil {
+			return "", err
+		}
+		return u.Username, nil
+	})
+}
+
+func lookupGroup(gid uint32) idName {
+	return lookupID(groupNames, gid, func(id string) (string, error) {
+		g, err := user.LookupGroupId(id)
+		if err != nil {
+			return "", err
+		}
+		return g.Name, nil
+	})
+}
+
+// ownerName resolves a uid to a user name, falling back to the number for
+// uids without an account.
+func ownerName(uid uint32) string {
+	return lookupOwner(uid).name
+}
+
+func analyzeOwnerAge(info os.FileInfo, stats *Stats) {
+	uid, _, ok := fileOwnerIDs(info)
+	if !ok {
+		return
+	}
+	owner := ownerName(uid)
+
+	ownerAge := stats.OwnerAges[owner]
+	if ownerAge == nil {
+		ownerAge = &OwnerAge{}
+		stats.OwnerAges[owner] = ownerAge
+	}
+
+	const year = 365 * 24 * time.Hour
+	switch age := time.Since(info.ModTime()); {
+	case age <= year:
+		ownerAge.UpTo1Year += info.Size()
+	case age <= 3*year:
+		ownerAge.UpTo3Years += info.Size()
+	default:
+		ownerAge.Older += info.Size()
+	}
+}
+
+func displayOwnerAge(stats *Stats, maxCount int, result *strings.Builder) {
+	if len(stats.OwnerAges) == 0 {
+		return
+	}
+
+	owners := make([]string, 0, len(stats.OwnerAges))
+	for owner := range stats.OwnerAges {
+		owners = append(owners, owner)
+	}
+	sort.Slice(owners, func(i, j int) bool {
+		if stats.OwnerAges[owners[i]].Total() != stats.OwnerAges[owners[j]].Total() {
+			return stats.OwnerAges[owners[i]].Total() > stats.OwnerAges[owners[j]].Total()
+		}
+		return owners[i] < owners[j]
+	})
+
+	result.WriteString(headerStyle.Render(tr("Owners by Age")))
+	result.WriteString("\n")
+	result.WriteString(fmt.Sprintf("%-12s %12s %12s %12s %12s\n", tr("Owner"), tr("<1y"), tr("1-3y"), tr(">3y"), tr("Total")))
+	for _, owner := range owners[:min(maxCount, len(owners))] {
+		ownerAge := stats.OwnerAges[owner]
+		result.WriteString(fmt.Sprintf("%s %s %s %s %s\n",
+			pathStyle.Render(fmt.Sprintf("%-12s", displayName(owner))),
+			goodStyle.Render(fmt.Sprintf("%12s", formatMB(ownerAge.UpTo1Year))),
+			warnStyle.Render(fmt.Sprintf("%12s", formatMB(ownerAge.UpTo3Years))),
+			badStyle.Render(fmt.Sprintf("%12s", formatMB(ownerAge.Older))),
+			numberStyle.Render(fmt.Sprintf("%12s", formatMB(ownerAge.Total())))))
+	}
+	result.WriteString("\n")
+}
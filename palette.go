@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// palette assigns the 256-color codes of the report. With Symbols, warnings
+// and problems are marked with ! and !! next to their color, so severity
+// doesn't rely on telling the colors apart.
+type palette struct {
+	Title, Header                  string
+	Tiny, Small, Medium, Large     string
+	App, Code, Doc, Media, Archive string
+	Database, Special, Model       string
+	Good, Warn, Bad                string
+	Path, Number, Percent          string
+	Bold, Symbols                  bool
+}
+
+// palettes are selected with [display] palette in config.ini or --palette.
+// The deuteranopia palette sticks to blues, oranges and yellows, which stay
+// apart with red-green color blindness; high-contrast uses the bright base
+// colors that every terminal theme keeps readable.
+var palettes = map[string]palette{
+	"default": {
+		Title: "205", Header: "39",
+		Tiny: "240", Small: "34", Medium: "220", Large: "196",
+		App: "208", Code: "82", Doc: "33", Media: "165", Archive: "133",
+		Database: "144", Special: "155", Model: "99",
+		Good: "46", Warn: "226", Bad: "196",
+		Path: "244", Number: "51", Percent: "118",
+	},
+	"deuteranopia": {
+		Title: "214", Header: "33",
+		Tiny: "244", Small: "75", Medium: "220", Large: "202",
+		App: "208", Code: "39", Doc: "117", Media: "178", Archive: "25",
+		Database: "187", Special: "229", Model: "141",
+		Good: "33", Warn: "220", Bad: "202",
+		Path: "250", Number: "153", Percent: "223",
+		Symbols: true,
+	},
+	"high-contrast": {
+		Title: "15", Header: "15",
+		Tiny: "7", Small: "15", Medium: "11", Large: "9",
+		App: "11", Code: "10", Doc: "14", Media: "13", Archive: "12",
+		Database: "14", Special: "11", Model: "13",
+		Good: "10", Warn: "11", Bad: "9",
+		Path: "15", Number: "14", Percent: "15",
+		Bold: true, Symbols: true,
+	},
+}
+
+func paletteNames() []string {
+	names := make([]string, 0, len(palettes))
+	for name := range palettes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// severityMark puts mark in front of a value rendered in a warning style.
+// It takes the place of padding where there is some, so columns stay
+// aligned.
+func severityMark(mark string) func(string) string {
+	return func(s string) string {
+		trimmed := strings.TrimLeft(s, " ")
+		if pad := len(s) - len(trimmed); pad > len(mark) {
+			return s[:pad-len(mark)-1] + mark + " " + trimmed
+		}
+		if trimmed = strings.TrimRight(s, " "); len(s)-len(trimmed) > len(mark) {
+			return mark + " " + s[:len(s)-len(mark)-1]
+		}
+		return mark + " " + s
+	}
+}
+
+// applyPalette sets the styles of the report to the named palette. Under
+// --accessible the report has no colors and spells out the severities
+// whatever the palette.
+func applyPalette(name string) error {
+	p, ok := palettes[name]
+	if !ok {
+		return fmt.Errorf("unknown palette %q, available: %s", name, strings.Join(paletteNames(), ", "))
+	}
+	color := func(code string) lipgloss.Style {
+		if accessible {
+			return lipgloss.NewStyle()
+		}
+		return lipgloss.NewStyle().Foreground(lipgloss.Color(code)).Bold(p.Bold)
+	}
+
+	titleStyle = color(p.Title).Bold(true)
+	headerStyle = color(p.Header).Bold(true)
+	tinyStyle, smallStyle, mediumStyle, largeStyle = color(p.Tiny), color(p.Small), color(p.Medium), color(p.Large)
+	appStyle, codeStyle, docStyle, mediaStyle = color(p.App), color(p.Code), color(p.Doc), color(p.Media)
+	archiveStyle, databaseStyle, specialStyle, modelStyle = color(p.Archive), color(p.Database), color(p.Special), color(p.Model)
+	goodStyle, warnStyle, badStyle = color(p.Good), color(p.Warn), color(p.Bad)
+	switch {
+	case accessible:
+		warnStyle = warnStyle.Transform(severityLabel(tr("warning:")))
+		badStyle = badStyle.Transform(severityLabel(tr("problem:")))
+	case p.Symbols:
+		warnStyle = warnStyle.Transform(severityMark("!"))
+		badStyle = badStyle.Transform(severityMark("!!"))
+	}
+	pathStyle, numberStyle, percentStyle = color(p.Path), color(p.Number), color(p.Percent)
+
+	for ext, category := range fileTypeCategoryMap {
+		if style, ok := categoryStyle(category); ok {
+			fileTypeStyleMap[ext] = style
+		}
+	}
+	return nil
+}
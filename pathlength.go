@@ -0,0 +1,162 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Path length limits in bytes from --component-limit and --path-limits. 255
+// is the name limit of most file systems, 260 the Windows MAX_PATH and 4096
+// the Linux PATH_MAX.
+var (
+	componentLimit = 255
+	pathLimits     = []int{260, 4096}
+)
+
+// pathLengthBuckets are the upper bounds of the path length distribution.
+var pathLengthBuckets = []struct {
+	name  string
+	limit int
+}{
+	{"<100", 100},
+	{"100-199", 200},
+	{"200-259", 260},
+	{"260-511", 512},
+	{"512-1023", 1024},
+	{"1024+", 0},
+}
+
+// PathStats describes how long the full paths of the scanned files are.
+// Longest reuses FileSize with the length in bytes as size.
+type PathStats struct {
+	Lengths       map[string]int
+	OverComponent int
+	OverLimit     map[int]int
+	Longest       *FileSizeHeap
+}
+
+func newPathStats() PathStats {
+	return PathStats{
+		Lengths:   make(map[string]int),
+		OverLimit: make(map[int]int),
+		Longest:   &FileSizeHeap{},
+	}
+}
+
+// parsePathLimits parses the comma separated --path-limits.
+func parsePathLimits(value string) ([]int, error) {
+	var limits []int
+	for _, field := range strings.Split(value, ",") {
+		limit, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil || limit <= 0 {
+			return nil, fmt.Errorf("invalid path limit %q", field)
+		}
+		limits = append(limits, limit)
+	}
+	sort.Ints(limits)
+	return limits, nil
+}
+
+var workingDir = sync.OnceValue(func() string {
+	dir, _ := os.Getwd()
+	return dir
+})
+
+// fullPath makes path absolute without asking for the working directory
+// for every file.
+func fullPath(path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(workingDir(), path)
+}
+
+func analyzePathLength(path string, stats *Stats, maxFiles int) {
+	full := fullPath(path)
+	length := len(full)
+
+	for _, bucket := range pathLengthBuckets {
+		if bucket.limit == 0 || length < bucket.limit {
+			stats.Paths.Lengths[bucket.name]++
+			break
+		}
+	}
+	for _, limit := range pathLimits {
+		if length > limit {
+			stats.Paths.OverLimit[limit]++
+		}
+	}
+	for _, component := range strings.Split(full, string(os.PathSeparator)) {
+		if len(component) > componentLimit {
+			stats.Paths.OverComponent++
+			break
+		}
+	}
+	pushLargest(stats.Paths.Longest, FileSize{Path: full, Size: int64(length)}, maxFiles)
+}
+
+func mergePathStats(dst, src *PathStats, maxFiles int) {
+	for bucket, count := range src.Lengths {
+		dst.Lengths[bucket] += count
+	}
+	for limit, count := range src.OverLimit {
+		dst.OverLimit[limit] += count
+	}
+	dst.OverComponent += src.OverComponent
+	mergeLargest(dst.Longest, src.Longest, maxFiles)
+}
+
+func displayPathLengths(stats *Stats, maxCount int, result *strings.Builder) {
+	paths := &stats.Paths
+	if len(paths.Lengths) == 0 {
+		return
+	}
+
+	result.WriteString(headerStyle.Render(tr("Path Lengths")))
+	result.WriteString("\n")
+	for _, bucket := range pathLengthBuckets {
+		if count, ok := paths.Lengths[bucket.name]; ok {
+			percentage := float64(count) / float64(stats.TotalFiles) * 100
+			result.WriteString(fmt.Sprintf("%s %s %s\n",
+				pathStyle.Render(fmt.Sprintf("%-16s", fmt.Sprintf(tr("%s bytes"), bucket.name))),
+				numberStyle.Render(fmt.Sprintf("%6s", formatCount(count))),
+				percentStyle.Render(fmt.Sprintf("(%6s)", formatPercent(percentage)))))
+		}
+	}
+
+	limits := make([]string, 0, len(pathLimits)+1)
+	for _, limit := range pathLimits {
+		limits = append(limits, fmt.Sprintf(tr("over %s bytes: %s"),
+			formatCount(limit), limitStyle(paths.OverLimit[limit]).Render(formatCount(paths.OverLimit[limit]))))
+	}
+	limits = append(limits, fmt.Sprintf(tr("name over %s bytes: %s"),
+		formatCount(componentLimit), limitStyle(paths.OverComponent).Render(formatCount(paths.OverComponent))))
+	result.WriteString(strings.Join(limits, "  "))
+	result.WriteString("\n")
+
+	longest := make([]FileSize, paths.Longest.Len())
+	copy(longest, *paths.Longest)
+	sort.Slice(longest, func(i, j int) bool {
+		return longest[j].Less(longest[i])
+	})
+	for _, file := range longest[:min(maxCount, len(longest))] {
+		result.WriteString(fmt.Sprintf("  %s %s\n",
+			numberStyle.Render(fmt.Sprintf("%6s", formatCount(file.Size))),
+			renderPath(file.Path)))
+	}
+	result.WriteString("\n")
+}
+
+func limitStyle(count int) lipgloss.Style {
+	if count > 0 {
+		return badStyle
+	}
+	return goodStyle
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Directories need permMinFiles files to be checked for inconsistent
+// permissions; a few files of different owners are normal. Each lists at
+// most permMaxModes of its combinations.
+const (
+	permMinFiles = 5
+	permMaxModes = 4
+)
+
+// fileAccess is the owner and permission bits of a file, e.g. "0644 alice".
+func fileAccess(info os.FileInfo) string {
+	access := fmt.Sprintf("%04o", info.Mode().Perm())
+	if uid, _, ok := fileOwnerIDs(info); ok {
+		access += " " + ownerName(uid)
+	}
+	return access
+}
+
+// analyzeDirPermissions counts the owner and permission combinations of the
+// regular files per directory.
+func analyzeDirPermissions(path string, info os.FileInfo, stats *Stats) {
+	if !info.Mode().IsRegular() {
+		return
+	}
+	dir := filepath.Dir(path)
+	modes := stats.DirPermissions[dir]
+	if modes == nil {
+		modes = make(map[string]int)
+		stats.DirPermissions[dir] = modes
+	}
+	modes[fileAccess(info)]++
+}
+
+func mergeDirPermissions(dst, src map[string]map[string]int) {
+	for dir, modes := range src {
+		if dst[dir] == nil {
+			dst[dir] = make(map[string]int)
+		}
+		for access, count := range modes {
+			dst[dir][access] += count
+		}
+	}
+}
+
+// accessCount is a combination of owner and permissions with its files.
+type accessCount struct {
+	Access string
+	Files  int
+}
+
+// permissionOutlier is a directory whose files don't share one owner and
+// set of permissions. Deviating counts the files outside the most common
+// combination.
+type permissionOutlier struct {
+	Path      string
+	Files     int
+	Deviating int
+	Modes     []accessCount
+}
+
+// permissionOutliers returns the directories with inconsistent permissions,
+// most deviating files first.
+func permissionOutliers(stats *Stats) []permissionOutlier {
+	var outliers []permissionOutlier
+	for dir, modes := range stats.DirPermissions {
+		if len(modes) < 2 {
+			continue
+		}
+		outlier := permissionOutlier{Path: dir}
+		for access, count := range modes {
+			outlier.Files += count
+			outlier.Modes = append(outlier.Modes, accessCount{access, count})
+		}
+		if outlier.Files < permMinFiles {
+			continue
+		}
+		sort.Slice(outlier.Modes, func(i, j int) bool {
+			if outlier.Modes[i].Files != outlier.Modes[j].Files {
+				return outlier.Modes[i].Files > outlier.Modes[j].Files
+			}
+			return outlier.Modes[i].Access < outlier.Modes[j].Access
+		})
+		outlier.Deviating = outlier.Files - outlier.Modes[0].Files
+		outliers = append(outliers, outlier)
+	}
+	sort.Slice(outliers, func(i, j int) bool {
+		if outliers[i].Deviating != outliers[j].Deviating {
+			return outliers[i].Deviating > outliers[j].Deviating
+		}
+		return outliers[i].Path < outliers[j].Path
+	})
+	return outliers
+}
+
+func displayPermissionOutliers(stats *Stats, maxCount int, result *strings.Builder) {
+	outliers := permissionOutliers(stats)
+	if len(outliers) == 0 {
+		return
+	}
+
+	result.WriteString(headerStyle.Render(tr("Inconsistent Permissions")))
+	result.WriteString("\n")
+	result.WriteString(fmt.Sprintf(tr("%s directories mix owners or permissions, most deviating files first\n"),
+		warnStyle.Render(formatCount(len(outliers)))))
+	for _, outlier := range outliers[:min(maxCount, len(outliers))] {
+		result.WriteString(fmt.Sprintf(tr("%s of %s files differ  %s\n"),
+			warnStyle.Render(formatCount(outlier.Deviating)),
+			numberStyle.Render(formatCount(outlier.Files)),
+			renderPath(outlier.Path)))
+		for _, mode := range outlier.Modes[:min(permMaxModes, len(outlier.Modes))] {
+			owner := mode.Access
+			if perm, name, ok := strings.Cut(mode.Access, " "); ok {
+				owner = perm + " " + displayName(name)
+			}
+			result.WriteString(fmt.Sprintf("  %s %s\n",
+				numberStyle.Render(fmt.Sprintf("%9s", formatCount(mode.Files))),
+				owner))
+		}
+		if rest := len(outlier.Modes) - permMaxModes; rest > 0 {
+			result.WriteString(fmt.Sprintf(tr("  and %s more combinations\n"), formatCount(rest)))
+		}
+	}
+	result.WriteString("\n")
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// PII scanning is opt-in: --pii checks file names, --pii-metadata also reads
+// CSV headers and Office document properties.
+var (
+	piiScan     bool
+	piiMetadata bool
+)
+
+var piiPatterns = []struct {
+	indicator string
+	pattern   *regexp.Regexp
+}{
+	{"ssn", regexp.MustCompile(`(^|\D)\d{3}-\d{2}-\d{4}(\D|$)|(?i)\bssn\b|social[ _-]?security`)},
+	{"passport/id", regexp.MustCompile(`(?i)passport|reisepass|personalausweis|id[ _-]?card`)},
+	{"payroll", regexp.MustCompile(`(?i)payroll|salary|salaries|gehalt|lohnabrechnung|payslip|w-?2\b`)},
+	{"date of birth", regexp.MustCompile(`(?i)\bdob\b|birth[ _-]?date|date[ _-]?of[ _-]?birth|geburtsdatum`)},
+	{"bank account", regexp.MustCompile(`(?i)\biban\b|bank[ _-]?statement|kontoauszug|(^|[^A-Z0-9])[A-Z]{2}\d{2}[A-Z0-9]{11,30}([^A-Z0-9]|$)`)},
+	{"medical", regexp.MustCompile(`(?i)medical|diagnos[ie]s|patient|krankmeldung|arztbrief`)},
+	{"email address", regexp.MustCompile(`[\w.+-]+@[\w-]+\.[\w.]+`)},
+}
+
+// PIIDir counts the files in one directory that carry PII indicators.
+type PIIDir struct {
+	Files      int
+	Indicators map[string]int
+}
+
+// piiIndicators returns the indicators found in text.
+func piiIndicators(text string) []string {
+	var found []string
+	for _, p := range piiPatterns {
+		if p.pattern.MatchString(text) {
+			found = append(found, p.indicator)
+		}
+	}
+	return found
+}
+
+// csvHeaderIndicators looks at the column names in the first line of a CSV
+// file.
+func csvHeaderIndicators(path string) []string {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	line, err := bufio.NewReader(io.LimitReader(f, 64*1024)).ReadString('\n')
+	if err != nil && line == "" {
+		return nil
+	}
+	var found []string
+	for _, column := range strings.FieldsFunc(line, func(r rune) bool { return r == ',' || r == ';' || r == '\t' }) {
+		found = append(found, piiIndicators(strings.Trim(strings.TrimSpace(column), `"`))...)
+	}
+	return found
+}
+
+// officeIndicators reads docProps/core.xml of an Office Open XML document.
+// A recorded author counts as an indicator of its own.
+func officeIndicators(path string) []string {
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		return nil
+	}
+	defer zr.Close()
+
+	for _, file := range zr.File {
+		if file.Name != "docProps/core.xml" {
+			continue
+		}
+		r, err := file.Open()
+		if err != nil {
+			return nil
+		}
+		defer r.Close()
+
+		var props struct {
+			Title          string `xml:"title"`
+			Subject        string `xml:"subject"`
+			Keywords       string `xml:"keywords"`
+			Description    string `xml:"description"`
+			Creator        string `xml:"creator"`
+			LastModifiedBy string `xml:"lastModifiedBy"`
+		}
+		if err := xml.NewDecoder(io.LimitReader(r, 1024*1024)).Decode(&props); err != nil {
+			return nil
+		}
+		found := piiIndicators(strings.Join([]string{props.Title, props.Subject, props.Keywords, props.Description}, " "))
+		if props.Creator != "" || props.LastModifiedBy != "" {
+			found = append(found, "author metadata")
+		}
+		return found
+	}
+	return nil
+}
+
+// processPII checks the file for PII indicators when --pii is set. Metadata
+// is read without holding the stats lock.
+func processPII(path string, info os.FileInfo, stats *Stats) {
+	if !piiScan {
+		return
+	}
+
+	indicators := piiIndicators(filepath.Base(path))
+	if piiMetadata && info.Mode().IsRegular() {
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".csv", ".tsv":
+			indicators = append(indicators, csvHeaderIndicators(path)...)
+		case ".docx", ".xlsx", ".pptx":
+			indicators = append(indicators, officeIndicators(path)...)
+		}
+	}
+	if len(indicators) == 0 {
+		return
+	}
+
+	stats.mu.Lock()
+	defer stats.mu.Unlock()
+
+	dir := filepath.Dir(path)
+	piiDir := stats.PII[dir]
+	if piiDir == nil {
+		piiDir = &PIIDir{Indicators: make(map[string]int)}
+		stats.PII[dir] = piiDir
+	}
+	piiDir.Files++
+	seen := make(map[string]bool)
+	for _, indicator := range indicators {
+		if !seen[indicator] {
+			seen[indicator] = true
+			piiDir.Indicators[indicator]++
+		}
+	}
+}
+
+func mergePII(dst, src map[string]*PIIDir) {
+	for dir, piiDir := range src {
+		if dst[dir] == nil {
+			dst[dir] = &PIIDir{Indicators: make(map[string]int)}
+		}
+		dst[dir].Files += piiDir.Files
+		for indicator, count := range piiDir.Indicators {
+			dst[dir].Indicators[indicator] += count
+		}
+	}
+}
+
+func displayPII(stats *Stats, maxCount int, result *strings.Builder) {
+	if len(stats.PII) == 0 {
+		return
+	}
+
+	totals := make(map[string]int)
+	files := 0
+	dirs := make([]string, 0, len(stats.PII))
+	for dir, piiDir := range stats.PII {
+		dirs = append(dirs, dir)
+		files += piiDir.Files
+		for indicator, count := range piiDir.Indicators {
+			totals[indicator] += count
+		}
+	}
+	sort.Slice(dirs, func(i, j int) bool {
+		if stats.PII[dirs[i]].Files != stats.PII[dirs[j]].Files {
+			return stats.PII[dirs[i]].Files > stats.PII[dirs[j]].Files
+		}
+		return dirs[i] < dirs[j]
+	})
+
+	result.WriteString(headerStyle.Render(tr("PII Indicators")))
+	result.WriteString("\n")
+	result.WriteString(fmt.Sprintf(tr("Files: %s in %s directories\n"),
+		badStyle.Render(formatCount(files)),
+		numberStyle.Render(formatCount(len(dirs)))))
+	result.WriteString(fmt.Sprintf("%s\n", formatIndicatorCounts(totals)))
+	for _, dir := range dirs[:min(maxCount, len(dirs))] {
+		result.WriteString(fmt.Sprintf(tr("  %s files %s: %s\n"),
+			numberStyle.Render(fmt.Sprintf("%5s", formatCount(stats.PII[dir].Files))),
+			renderPath(dir),
+			formatIndicatorCounts(stats.PII[dir].Indicators)))
+	}
+	result.WriteString("\n")
+}
+
+// formatIndicatorCounts lists indicators by how often they were found.
+func formatIndicatorCounts(counts map[string]int) string {
+	indicators := make([]string, 0, len(counts))
+	for indicator := range counts {
+		indicators = append(indicators, indicator)
+	}
+	sort.Slice(indicators, func(i, j int) bool {
+		if counts[indicators[i]] != counts[indicators[j]] {
+			return counts[indicators[i]] > counts[indicators[j]]
+		}
+		return indicators[i] < indicators[j]
+	})
+
+	parts := make([]string, len(indicators))
+	for i, indicator := range indicators {
+		parts[i] = fmt.Sprintf("%s %s", warnStyle.Render(tr(indicator)), numberStyle.Render(formatCount(counts[indicator])))
+	}
+	return strings.Join(parts, ", ")
+}
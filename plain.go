@@ -0,0 +1,27 @@
+package main
+
+import (
+	"context"
+	"os"
+)
+
+// noTUI is set by --no-tui to scan without the interactive view and print
+// the report when done. It is implied when stdout is not a terminal, as
+// when the report is redirected to a file or madaa runs over ssh without
+// one.
+var noTUI bool
+
+// isTerminal reports whether f is a terminal.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// scanPlain runs the scan without the TUI and without showing progress.
+func scanPlain(config Config) (*Stats, error) {
+	events := newEventBus()
+	if config.FilesFrom != "" {
+		return analyzeFileList(context.Background(), config, events)
+	}
+	return analyzeDirectory(context.Background(), config, events)
+}
@@ -0,0 +1,274 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/ini.v1"
+)
+
+// fsNearShare is how close to the entry limit of a file system a directory
+// gets before it is warned about.
+const fsNearShare = 0.8
+
+// fsProfile describes the limits of a file system files may end up on. A
+// limit of 0 means there is none worth checking. Paths restricts a profile
+// to the files destined for it, like removable media mounted below /media;
+// without paths it applies to every file. Only profiles with Warn are
+// checked on every scan, the others with --target.
+type fsProfile struct {
+	Name          string
+	Warn          bool
+	MaxFileSize   int64
+	MaxDirEntries int
+	Paths         []string
+	// MaxNameLength and MaxPathLength are in bytes, or in UTF-16 units for
+	// Windows file systems. Paths count from the scanned directory, which
+	// is the top directory on the target.
+	MaxNameLength int
+	MaxPathLength int
+	InvalidChars  string
+	// Windows forbids control characters, names ending in a dot or space
+	// and the device names CON, NUL, COM1, ... with any extension
+	Windows         bool
+	ReservedNames   []string
+	NoSymlinks      bool
+	CaseInsensitive bool
+}
+
+// windowsInvalidChars can't be in names on Windows file systems.
+const windowsInvalidChars = `"*:<>?\|`
+
+// defaultFSProfiles are the profiles used unless the config changes them
+// in [filesystem.NAME] sections. 10 million entries is where ext4
+// directories get slow and its htree runs full in practice; Windows paths
+// are limited to MAX_PATH unless long paths are enabled.
+func defaultFSProfiles() map[string]*fsProfile {
+	return map[string]*fsProfile{
+		"ext4": {Name: "ext4", Warn: true, MaxDirEntries: 10_000_000,
+			MaxNameLength: 255, MaxPathLength: 4095},
+		"fat32": {Name: "fat32", Warn: true, MaxFileSize: 4*1024*1024*1024 - 1, MaxDirEntries: 65_534,
+			Paths:         []string{"/media/**", "/run/media/**", "/Volumes/**"},
+			MaxNameLength: 255, MaxPathLength: 259, InvalidChars: windowsInvalidChars,
+			Windows: true, NoSymlinks: true, CaseInsensitive: true},
+		"exfat": {Name: "exfat", MaxDirEntries: 2_796_202,
+			MaxNameLength: 255, MaxPathLength: 259, InvalidChars: windowsInvalidChars,
+			Windows: true, NoSymlinks: true, CaseInsensitive: true},
+		"ntfs": {Name: "ntfs",
+			MaxNameLength: 255, MaxPathLength: 259, InvalidChars: windowsInvalidChars,
+			Windows: true, CaseInsensitive: true},
+		"onedrive": {Name: "onedrive", MaxFileSize: 250 * 1024 * 1024 * 1024,
+			MaxNameLength: 255, MaxPathLength: 400, InvalidChars: windowsInvalidChars,
+			Windows: true, ReservedNames: []string{".lock", "desktop.ini"}, NoSymlinks: true, CaseInsensitive: true},
+		"s3": {Name: "s3", MaxFileSize: 5 * 1024 * 1024 * 1024 * 1024,
+			MaxPathLength: 1024, NoSymlinks: true},
+	}
+}
+
+var fsProfiles = defaultFSProfiles()
+
+// loadFSProfiles reads the [filesystem.NAME] sections. A section changes the
+// limits of a default profile of that name or adds a profile.
+func loadFSProfiles(cfg *ini.File) error {
+	fsProfiles = defaultFSProfiles()
+	for _, section := range cfg.Sections() {
+		name, ok := strings.CutPrefix(section.Name(), "filesystem.")
+		if !ok || name == "" {
+			continue
+		}
+		profile := fsProfiles[name]
+		if profile == nil {
+			profile = &fsProfile{Name: name}
+			fsProfiles[name] = profile
+		}
+		if value := section.Key("max_file_size").String(); value != "" {
+			size, err := parseSize(value)
+			if err != nil {
+				return fmt.Errorf("filesystem.%s max_file_size: %v", name, err)
+			}
+			profile.MaxFileSize = size
+		}
+		if section.HasKey("warn") {
+			profile.Warn = section.Key("warn").MustBool(false)
+		}
+		if section.HasKey("max_dir_entries") {
+			profile.MaxDirEntries = section.Key("max_dir_entries").MustInt(0)
+		}
+		if section.HasKey("paths") {
+			profile.Paths = splitList(section.Key("paths").String())
+		}
+		if section.HasKey("max_name_length") {
+			profile.MaxNameLength = section.Key("max_name_length").MustInt(0)
+		}
+		if section.HasKey("max_path_length") {
+			profile.MaxPathLength = section.Key("max_path_length").MustInt(0)
+		}
+		if section.HasKey("invalid_chars") {
+			profile.InvalidChars = section.Key("invalid_chars").String()
+		}
+		if section.HasKey("windows") {
+			profile.Windows = section.Key("windows").MustBool(false)
+		}
+		if section.HasKey("reserved_names") {
+			profile.ReservedNames = splitList(section.Key("reserved_names").String())
+		}
+		if section.HasKey("symlinks") {
+			profile.NoSymlinks = !section.Key("symlinks").MustBool(true)
+		}
+		if section.HasKey("case_insensitive") {
+			profile.CaseInsensitive = section.Key("case_insensitive").MustBool(false)
+		}
+	}
+	return nil
+}
+
+// splitList splits a comma separated config value.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func fsProfileNames() []string {
+	names := make([]string, 0, len(fsProfiles))
+	for name := range fsProfiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// applies tells whether the profile covers path.
+func (p *fsProfile) applies(path string) bool {
+	if len(p.Paths) == 0 {
+		return true
+	}
+	full := fullPath(path)
+	for _, pattern := range p.Paths {
+		if matchGlob(pattern, full) {
+			return true
+		}
+	}
+	return false
+}
+
+// LimitFiles are the files over one limit of a file system.
+type LimitFiles struct {
+	Files   int
+	Bytes   int64
+	Largest *FileSizeHeap
+}
+
+func addLimitFile(files map[string]*LimitFiles, key, path string, size int64, maxFiles int) {
+	limit := files[key]
+	if limit == nil {
+		limit = &LimitFiles{Largest: &FileSizeHeap{}}
+		files[key] = limit
+	}
+	limit.Files++
+	limit.Bytes += size
+	pushLargest(limit.Largest, FileSize{Path: path, Size: size}, maxFiles)
+}
+
+func mergeLimitFiles(dst, src map[string]*LimitFiles, maxFiles int) {
+	for key, files := range src {
+		if dst[key] == nil {
+			dst[key] = &LimitFiles{Largest: &FileSizeHeap{}}
+		}
+		dst[key].Files += files.Files
+		dst[key].Bytes += files.Bytes
+		mergeLargest(dst[key].Largest, files.Largest, maxFiles)
+	}
+}
+
+// displayLimitFiles lists the largest of the files, largest first.
+func displayLimitFiles(files *LimitFiles, maxCount int, result *strings.Builder) {
+	largest := make([]FileSize, files.Largest.Len())
+	copy(largest, *files.Largest)
+	sort.Slice(largest, func(i, j int) bool {
+		return largest[j].Less(largest[i])
+	})
+	for _, file := range largest[:min(maxCount, len(largest))] {
+		result.WriteString(fmt.Sprintf("  %s %s\n",
+			numberStyle.Render(fmt.Sprintf("%11s", formatMB(file.Size))),
+			renderPath(file.Path)))
+	}
+}
+
+// analyzePortability is called by processFile, with the stats locked.
+func analyzePortability(path string, size int64, stats *Stats, maxFiles int) {
+	for name, profile := range fsProfiles {
+		if !profile.Warn || profile.MaxFileSize == 0 || size <= profile.MaxFileSize || !profile.applies(path) {
+			continue
+		}
+		addLimitFile(stats.Oversized, name, path, size, maxFiles)
+	}
+}
+
+// crowdedDirs returns the directories with at least fsNearShare of the
+// entries the profile allows, fullest first.
+func crowdedDirs(stats *Stats, profile *fsProfile) []string {
+	if profile.MaxDirEntries == 0 {
+		return nil
+	}
+	var dirs []string
+	for dir, files := range stats.FilesPerDir {
+		if float64(files) >= fsNearShare*float64(profile.MaxDirEntries) && profile.applies(dir) {
+			dirs = append(dirs, dir)
+		}
+	}
+	sort.Slice(dirs, func(i, j int) bool {
+		if stats.FilesPerDir[dirs[i]] != stats.FilesPerDir[dirs[j]] {
+			return stats.FilesPerDir[dirs[i]] > stats.FilesPerDir[dirs[j]]
+		}
+		return dirs[i] < dirs[j]
+	})
+	return dirs
+}
+
+func displayPortability(stats *Stats, maxCount int, result *strings.Builder) {
+	var section strings.Builder
+	for _, name := range fsProfileNames() {
+		profile := fsProfiles[name]
+		if oversized := stats.Oversized[name]; oversized != nil {
+			section.WriteString(fmt.Sprintf(tr("%s: %s files over %s, %s\n"),
+				titleStyle.Render(name),
+				badStyle.Render(formatCount(oversized.Files)),
+				numberStyle.Render(formatMB(profile.MaxFileSize)),
+				badStyle.Render(formatMB(oversized.Bytes))))
+			displayLimitFiles(oversized, maxCount, &section)
+		}
+
+		dirs := crowdedDirs(stats, profile)
+		if !profile.Warn || len(dirs) == 0 {
+			continue
+		}
+		section.WriteString(fmt.Sprintf(tr("%s: %s directories near the limit of %s entries\n"),
+			titleStyle.Render(name),
+			warnStyle.Render(formatCount(len(dirs))),
+			numberStyle.Render(formatCount(profile.MaxDirEntries))))
+		for _, dir := range dirs[:min(maxCount, len(dirs))] {
+			entries := stats.FilesPerDir[dir]
+			style := warnStyle
+			if entries > profile.MaxDirEntries {
+				style = badStyle
+			}
+			section.WriteString(fmt.Sprintf("  %s %s\n",
+				style.Render(fmt.Sprintf("%11s", formatCount(entries))),
+				renderPath(dir)))
+		}
+	}
+	if section.Len() == 0 {
+		return
+	}
+
+	result.WriteString(headerStyle.Render(tr("Portability Warnings")))
+	result.WriteString("\n")
+	result.WriteString(section.String())
+	result.WriteString("\n")
+}
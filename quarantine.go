@@ -0,0 +1,129 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Quarantine settings from the [clean] section: where clean --quarantine
+// moves files to, and after how many days madaa serve purges them.
+var (
+	quarantineRoot string
+	quarantineDays = 30
+)
+
+// manifestName is the manifest of a quarantine session, kept at the top of
+// the session directory next to the mirrored paths.
+const manifestName = ".madaa-manifest.jsonl"
+
+// newQuarantineSession starts a session moving files below root, with a
+// manifest only the user cleaning up can change, as madaa serve deletes
+// the files it lists.
+func newQuarantineSession(root string) (*cleanSession, error) {
+	session, err := newCleanSession(root)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(session.dir(), 0755); err != nil {
+		session.Close()
+		return nil, err
+	}
+	session.manifest, err = os.OpenFile(filepath.Join(session.dir(), manifestName), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		session.Close()
+		return nil, err
+	}
+	return session, nil
+}
+
+// purgeQuarantine deletes the quarantined files below root that have been
+// there longer than after. Only files listed in a manifest are deleted;
+// anything else put into the quarantine is left alone, and so are entries
+// of a corrupt or tampered manifest naming files outside its session
+// directory.
+func purgeQuarantine(root string, after time.Duration) (purged int, bytes int64, errs []error) {
+	manifests, err := filepath.Glob(filepath.Join(root, "*", manifestName))
+	if err != nil {
+		return 0, 0, []error{err}
+	}
+
+	cutoff := time.Now().Add(-after)
+	for _, manifest := range manifests {
+		entries, err := readEntries(manifest)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		sessionDir := filepath.Dir(manifest)
+		var kept []JournalEntry
+		for _, entry := range entries {
+			if entry.At.After(cutoff) {
+				kept = append(kept, entry)
+				continue
+			}
+			if _, err := os.Lstat(entry.Moved); errors.Is(err, fs.ErrNotExist) {
+				// Removed by hand already
+				purged++
+				bytes += entry.Size
+				continue
+			}
+			if !inSessionDir(entry.Moved, sessionDir) {
+				errs = append(errs, fmt.Errorf("%s: %s is outside the session directory, not deleted", manifest, entry.Moved))
+				kept = append(kept, entry)
+				continue
+			}
+			if err := os.Remove(entry.Moved); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				errs = append(errs, err)
+				kept = append(kept, entry)
+				continue
+			}
+			purged++
+			bytes += entry.Size
+		}
+		if len(kept) == len(entries) {
+			continue
+		}
+		if err := updateSessionDirs(entries, kept); err != nil {
+			errs = append(errs, err)
+		}
+		if len(kept) == 0 {
+			// The session can't be undone any more; its journal is only
+			// found if the purge runs as the user who cleaned up
+			writeJournal(filepath.Base(filepath.Dir(manifest)), nil)
+		}
+	}
+	return purged, bytes, errs
+}
+
+// inSessionDir tells whether moved is below dir once symlinks are
+// resolved, so neither .. nor a symlinked directory lead out of it.
+func inSessionDir(moved, dir string) bool {
+	dir, err := filepath.EvalSymlinks(dir)
+	if err != nil || !filepath.IsAbs(moved) {
+		return false
+	}
+	parent, err := filepath.EvalSymlinks(filepath.Dir(moved))
+	if err != nil {
+		return false
+	}
+	target := filepath.Join(parent, filepath.Base(moved))
+	return target != dir && withinDir(target, dir)
+}
+
+// runQuarantinePurger purges the quarantine every interval, for madaa serve.
+func runQuarantinePurger(root string, after, interval time.Duration) {
+	for {
+		purged, bytes, errs := purgeQuarantine(root, after)
+		for _, err := range errs {
+			fmt.Printf("Error purging quarantine: %v\n", err)
+		}
+		if purged > 0 {
+			fmt.Printf("Purged %d quarantined files (%s) from %s\n", purged, formatMB(bytes), root)
+		}
+		time.Sleep(interval)
+	}
+}
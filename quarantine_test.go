@@ -0,0 +1,64 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPurgeQuarantineStaysInSessionDir(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	root := t.TempDir()
+	session := filepath.Join(root, "20260101-120000")
+	if err := os.MkdirAll(session, 0755); err != nil {
+		t.Fatal(err)
+	}
+	inside := filepath.Join(session, "a.tmp")
+	outside := filepath.Join(t.TempDir(), "b.txt")
+	escape := filepath.Join(session, "link")
+	for _, path := range []string{inside, outside} {
+		if err := os.WriteFile(path, []byte("data"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink(filepath.Dir(outside), escape); err != nil {
+		t.Fatal(err)
+	}
+
+	up, err := filepath.Rel(session, outside)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	old := time.Now().Add(-48 * time.Hour)
+	entries := []JournalEntry{
+		{Moved: inside, Dir: session, Size: 4, At: old},
+		{Moved: outside, Dir: session, Size: 4, At: old},
+		{Moved: session + string(filepath.Separator) + up, Dir: session, Size: 4, At: old},
+		{Moved: filepath.Join(escape, filepath.Base(outside)), Dir: session, Size: 4, At: old},
+	}
+	manifest := filepath.Join(session, manifestName)
+	if err := writeEntries(manifest, entries); err != nil {
+		t.Fatal(err)
+	}
+
+	purged, _, errs := purgeQuarantine(root, time.Hour)
+	if purged != 1 || len(errs) != 3 {
+		t.Fatalf("purged %d, errors %v", purged, errs)
+	}
+	if _, err := os.Lstat(inside); !os.IsNotExist(err) {
+		t.Errorf("quarantined file not purged: %v", err)
+	}
+	if readFile(t, outside) != "data" {
+		t.Error("file outside the session directory was deleted")
+	}
+	left, err := readEntries(manifest)
+	if err != nil || len(left) != 3 {
+		t.Errorf("manifest has %d entries left, want 3: %v", len(left), err)
+	}
+	info, err := os.Stat(manifest)
+	if err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("manifest mode %v, want 0600: %v", info.Mode().Perm(), err)
+	}
+}
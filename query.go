@@ -0,0 +1,255 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// queryGroups are what a query can list besides files, each file counting
+// for the group it belongs to.
+var queryGroups = map[string]func(path string, f *cachedFile) string{
+	"dirs": func(path string, _ *cachedFile) string { return filepath.Dir(path) },
+	"exts": func(path string, _ *cachedFile) string {
+		if ext := strings.ToLower(filepath.Ext(path)); ext != "" {
+			return ext
+		}
+		return "no extension"
+	},
+	"owners": func(_ string, f *cachedFile) string { return ownerName(f.Uid) },
+	"categories": func(path string, _ *cachedFile) string {
+		if category, ok := categoryLabels[fileTypeCategoryMap[strings.ToLower(filepath.Ext(path))]]; ok {
+			return category
+		}
+		return "Other"
+	},
+}
+
+// queryGroupLabels head the key column of the groups.
+var queryGroupLabels = map[string]string{
+	"dirs": "Directory", "exts": "Extension", "owners": "Owner", "categories": "Category",
+}
+
+// Query is a parsed query of madaa query, in a mini-language like
+//
+//	top 10 dirs by size where mtime<2020-01-01 && category=media
+//
+// Every part is optional: top N limits the rows, files (the default), dirs,
+// exts, owners or categories choose what is listed, by size, count, mtime,
+// atime or name orders it, descending unless followed by asc, and where
+// takes a --filter expression selecting the files.
+type Query struct {
+	Limit  int
+	Group  string
+	By     string
+	Asc    bool
+	Filter *Filter
+}
+
+// ParseQuery compiles a query.
+func ParseQuery(text string) (*Query, error) {
+	q := &Query{Limit: 20, Group: "files", By: "size"}
+	fields := strings.Fields(text)
+	for i := 0; i < len(fields); i++ {
+		word := strings.ToLower(fields[i])
+		switch {
+		case word == "where":
+			// The rest is a filter, which keeps its own spacing and quoting
+			rest := text
+			for _, field := range fields[:i+1] {
+				rest = rest[strings.Index(rest, field)+len(field):]
+			}
+			filter, err := ParseFilter(rest)
+			if err != nil {
+				return nil, err
+			}
+			q.Filter = filter
+			return q, nil
+		case word == "top":
+			if i+1 == len(fields) {
+				return nil, fmt.Errorf("top needs a number")
+			}
+			i++
+			n, err := strconv.Atoi(fields[i])
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("invalid number %q after top", fields[i])
+			}
+			q.Limit = n
+		case word == "by":
+			if i+1 == len(fields) {
+				return nil, fmt.Errorf("by needs size, count, mtime, atime or name")
+			}
+			i++
+			switch by := strings.ToLower(fields[i]); by {
+			case "size", "count", "mtime", "atime", "name":
+				q.By = by
+			default:
+				return nil, fmt.Errorf("can't order by %q, want size, count, mtime, atime or name", fields[i])
+			}
+		case word == "asc" || word == "desc":
+			q.Asc = word == "asc"
+		case word == "files" || queryGroups[word] != nil:
+			q.Group = word
+		default:
+			return nil, fmt.Errorf("unexpected %q in query, want top, files, dirs, exts, owners, categories, by, asc or where", fields[i])
+		}
+	}
+	return q, nil
+}
+
+// QueryRow is a file or a group of files in the result of a query. For a
+// file, Files is 1 and Key its path.
+type QueryRow struct {
+	Key      string    `json:"key"`
+	Files    int       `json:"files"`
+	Size     int64     `json:"size"`
+	Modified time.Time `json:"modified"`
+	Accessed time.Time `json:"accessed,omitempty"`
+}
+
+// runQuery evaluates q over the files of a snapshot.
+func runQuery(snap *Snapshot, q *Query) []QueryRow {
+	groups := make(map[string]*QueryRow)
+	var rows []QueryRow
+	for dir, listing := range snap.Dirs {
+		for i := range listing.Files {
+			f := &listing.Files[i]
+			path := filepath.Join(dir, f.Name)
+			if !q.Filter.Match(path, cachedFileInfo{f}) {
+				continue
+			}
+			if q.Group == "files" {
+				rows = append(rows, QueryRow{Key: path, Files: 1, Size: f.Size, Modified: f.ModTime, Accessed: f.ATime})
+				continue
+			}
+			key := queryGroups[q.Group](path, f)
+			row := groups[key]
+			if row == nil {
+				row = &QueryRow{Key: key}
+				groups[key] = row
+			}
+			row.Files++
+			row.Size += f.Size
+			if f.ModTime.After(row.Modified) {
+				row.Modified = f.ModTime
+			}
+			if f.ATime.After(row.Accessed) {
+				row.Accessed = f.ATime
+			}
+		}
+	}
+	for _, row := range groups {
+		rows = append(rows, *row)
+	}
+
+	less := func(a, b QueryRow) int {
+		switch q.By {
+		case "count":
+			return compareInt64(int64(a.Files), int64(b.Files))
+		case "mtime":
+			return a.Modified.Compare(b.Modified)
+		case "atime":
+			return a.Accessed.Compare(b.Accessed)
+		case "name":
+			return strings.Compare(a.Key, b.Key)
+		}
+		return compareInt64(a.Size, b.Size)
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		cmp := less(rows[i], rows[j])
+		if !q.Asc {
+			cmp = -cmp
+		}
+		if cmp != 0 {
+			return cmp < 0
+		}
+		return rows[i].Key < rows[j].Key
+	})
+	return rows[:min(q.Limit, len(rows))]
+}
+
+func displayQuery(q *Query, rows []QueryRow) string {
+	var result strings.Builder
+	if q.Group == "files" {
+		result.WriteString(fmt.Sprintf("%11s  %-10s  %s\n", tr("Size"), tr("Modified"), tr("Path")))
+		for _, row := range rows {
+			result.WriteString(fmt.Sprintf("%s  %s  %s\n",
+				numberStyle.Render(fmt.Sprintf("%11s", formatMB(row.Size))),
+				goodStyle.Render(fmt.Sprintf("%-10s", formatDate(row.Modified))),
+				renderPath(row.Key)))
+		}
+		return result.String()
+	}
+
+	result.WriteString(fmt.Sprintf("%11s  %9s  %-10s  %s\n", tr("Size"), tr("Files"), tr("Newest"), tr(queryGroupLabels[q.Group])))
+	for _, row := range rows {
+		key := tr(row.Key)
+		if q.Group == "dirs" {
+			key = renderPath(row.Key)
+		}
+		result.WriteString(fmt.Sprintf("%s  %s  %s  %s\n",
+			numberStyle.Render(fmt.Sprintf("%11s", formatMB(row.Size))),
+			numberStyle.Render(fmt.Sprintf("%9s", formatCount(row.Files))),
+			goodStyle.Render(fmt.Sprintf("%-10s", formatDate(row.Modified))),
+			key))
+	}
+	return result.String()
+}
+
+// runQueryCommand implements "madaa query <snapshot|path> <query>": ad-hoc
+// queries over the files of a saved snapshot, or of a directory scanned for
+// the query, printed as a table or as JSON.
+func runQueryCommand(args []string) {
+	flags := flag.NewFlagSet("query", flag.ExitOnError)
+	asJSON := flags.Bool("json", false, "Print the rows as JSON")
+	enableRedaction := redactFlags(flags)
+	selectLanguage := localeFlags(flags)
+	flags.Parse(args)
+	enableRedaction()
+	selectLanguage()
+
+	if flags.NArg() != 2 {
+		fmt.Println("Usage: madaa query [--json] <snapshot|path> \"[top N] [files|dirs|exts|owners|categories] [by size|count|mtime|atime|name [asc]] [where FILTER]\"")
+		os.Exit(1)
+	}
+	if err := loadConfig(); err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+	q, err := ParseQuery(flags.Arg(1))
+	if err != nil {
+		fmt.Printf("Error parsing query: %v\n", err)
+		os.Exit(1)
+	}
+	snap, err := openSnapshot(flags.Arg(0), nil)
+	if err == nil {
+		err = requireListings(flags.Arg(0), snap)
+	}
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	rows := runQuery(snap, q)
+	if *asJSON {
+		if rows == nil {
+			rows = []QueryRow{}
+		}
+		for i := range rows {
+			if q.Group == "files" || q.Group == "dirs" {
+				rows[i].Key = displayPath(rows[i].Key)
+			}
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(rows)
+		return
+	}
+	fmt.Print(fitPaths(displayQuery(q, rows), pathWidth))
+}
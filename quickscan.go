@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// maxDuration is the time budget of a quick scan, set by --max-duration. A
+// quick scan walks the tree breadth-first, so that the budget is spent on
+// the upper levels of every branch instead of the depths of the first one.
+var maxDuration time.Duration
+
+// Coverage is how much of the tree a quick scan got to: the directories
+// whose entries were all handed to the analysis, of those it found.
+type Coverage struct {
+	Budget         time.Duration
+	Order          string
+	DirsVisited    int
+	DirsDiscovered int
+}
+
+func mergeCoverage(dst, src *Coverage) {
+	dst.Budget = max(dst.Budget, src.Budget)
+	if src.Order != "" {
+		dst.Order = src.Order
+	}
+	dst.DirsVisited += src.DirsVisited
+	dst.DirsDiscovered += src.DirsDiscovered
+}
+
+// scanBudget is how long a scan may take at most, the shorter of --timeout
+// and --max-duration, 0 for no limit.
+func scanBudget() time.Duration {
+	if scanTimeout > 0 && (maxDuration <= 0 || scanTimeout < maxDuration) {
+		return scanTimeout
+	}
+	return maxDuration
+}
+
+// walkBreadthFirst calls fn for root and everything below it like walkDir,
+// but level by level: all entries of a directory before any of its
+// subdirectories' entries. Directories are counted in coverage as they are
+// discovered and once all their entries were passed to fn.
+func walkBreadthFirst(root string, coverage *Coverage, fn fs.WalkDirFunc) error {
+	info, err := lstat(root)
+	if err != nil {
+		return fn(root, nil, err)
+	}
+	if err := fn(root, fs.FileInfoToDirEntry(info), nil); err != nil || !info.IsDir() {
+		if err == filepath.SkipDir || err == filepath.SkipAll {
+			return nil
+		}
+		return err
+	}
+
+	queue := []string{root}
+	coverage.DirsDiscovered++
+	for len(queue) > 0 {
+		dir := queue[0]
+		queue = queue[1:]
+
+		entries, err := readDir(dir)
+		if err != nil {
+			if err := fn(dir, nil, err); err != nil && err != filepath.SkipDir {
+				if err == filepath.SkipAll {
+					return nil
+				}
+				return err
+			}
+			continue
+		}
+		for _, entry := range entries {
+			path := filepath.Join(dir, entry.Name())
+			err := fn(path, entry, nil)
+			if err == filepath.SkipAll {
+				return nil
+			}
+			if err == filepath.SkipDir {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			if entry.IsDir() {
+				queue = append(queue, path)
+				coverage.DirsDiscovered++
+			}
+		}
+		coverage.DirsVisited++
+	}
+	return nil
+}
+
+// analyzeQuick scans config.Path breadth-first, or with --big-first biggest
+// first, until ctx runs out. There is no counting pass, which would spend
+// the budget on its own, so progress is shown against the files found so
+// far.
+func analyzeQuick(ctx context.Context, config Config, events *eventBus) (*Stats, error) {
+	coverage := Coverage{Budget: maxDuration, Order: "breadth-first"}
+	walk := walkBreadthFirst
+	if bigFirst {
+		coverage.Order = "biggest first"
+		hints := sizeHints(config.Path)
+		walk = func(root string, coverage *Coverage, fn fs.WalkDirFunc) error {
+			return walkBiggestFirst(root, hints, coverage, fn)
+		}
+	}
+	var found int64
+	stats, err := runAnalysis(ctx, config, &found, events, func(ctx context.Context, pathChan chan<- scanItem) error {
+		return walk(config.Path, &coverage, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				// Unreadable directories are recorded by the workers
+				if strictScan && !os.IsNotExist(err) {
+					return err
+				}
+				return nil
+			}
+			if config.skipped(path) {
+				return skipEntry(d)
+			}
+			if !d.IsDir() {
+				if !config.sampled(path) {
+					return nil
+				}
+				atomic.AddInt64(&found, 1)
+			}
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case pathChan <- scanItem{path: path}:
+				return nil
+			}
+		})
+	})
+	if stats != nil {
+		stats.Coverage = coverage
+	}
+	return stats, err
+}
+
+// displayCoverage tells how much of the tree a quick scan covered, so that
+// its report is read as a first impression rather than the whole picture.
+func displayCoverage(stats *Stats, result *strings.Builder) {
+	c := stats.Coverage
+	if c.Budget <= 0 || c.DirsDiscovered == 0 {
+		return
+	}
+
+	style := goodStyle
+	if c.DirsVisited < c.DirsDiscovered {
+		style = warnStyle
+	}
+	result.WriteString(style.Render(fmt.Sprintf(tr("Quick scan within %s (--max-duration): %s of %s directories found were visited (%s), %s"),
+		c.Budget,
+		formatCount(c.DirsVisited),
+		formatCount(c.DirsDiscovered),
+		formatPercent(float64(c.DirsVisited)/float64(c.DirsDiscovered)*100),
+		tr(c.Order))))
+	result.WriteString("\n\n")
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"container/heap"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// The recent changes feed lists the recentCount most recently modified
+// files, optionally only those of recentCategory. Set by --recent and
+// --recent-category; a count of zero leaves the feed out.
+var (
+	recentCount    = 10
+	recentCategory string
+)
+
+// RecentFile is a file in the recent changes feed.
+type RecentFile struct {
+	Path    string
+	Size    int64
+	ModTime time.Time
+}
+
+// newerThan orders files by mtime, and files of the same age by path.
+func (f RecentFile) newerThan(other RecentFile) bool {
+	if !f.ModTime.Equal(other.ModTime) {
+		return f.ModTime.After(other.ModTime)
+	}
+	return f.Path > other.Path
+}
+
+// RecentHeap is a min-heap keeping the oldest of the recent files on top.
+type RecentHeap []RecentFile
+
+func (h RecentHeap) Len() int           { return len(h) }
+func (h RecentHeap) Less(i, j int) bool { return h[j].newerThan(h[i]) }
+func (h RecentHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+
+func (h *RecentHeap) Push(x interface{}) {
+	*h = append(*h, x.(RecentFile))
+}
+
+func (h *RecentHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[0 : n-1]
+	return x
+}
+
+// pushRecent keeps the limit most recently modified files in h.
+func pushRecent(h *RecentHeap, file RecentFile, limit int) {
+	if h.Len() < limit {
+		heap.Push(h, file)
+	} else if h.Len() > 0 && file.newerThan((*h)[0]) {
+		heap.Pop(h)
+		heap.Push(h, file)
+	}
+}
+
+// sortedRecent returns the files in h, newest first.
+func sortedRecent(h *RecentHeap) []RecentFile {
+	if h == nil {
+		return nil
+	}
+	files := make([]RecentFile, h.Len())
+	copy(files, *h)
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].newerThan(files[j])
+	})
+	return files
+}
+
+func analyzeRecent(path string, info os.FileInfo, stats *Stats) {
+	if recentCount <= 0 || !info.Mode().IsRegular() {
+		return
+	}
+	if recentCategory != "" && !strings.EqualFold(fileTypeCategoryMap[strings.ToLower(filepath.Ext(path))], recentCategory) {
+		return
+	}
+	pushRecent(stats.Recent, RecentFile{path, info.Size(), info.ModTime()}, recentCount)
+}
+
+func mergeRecent(dst, src *RecentHeap) {
+	if src == nil {
+		return
+	}
+	for _, file := range *src {
+		pushRecent(dst, file, recentCount)
+	}
+}
+
+// recentSnapshot copies the feed for the TUI while the scan is running,
+// combining those of the workers' shards.
+func recentSnapshot(shards ...*Stats) []RecentFile {
+	var recent RecentHeap
+	for _, shard := range shards {
+		shard.mu.Lock()
+		mergeRecent(&recent, shard.Recent)
+		shard.mu.Unlock()
+	}
+	return sortedRecent(&recent)
+}
+
+func writeRecentFiles(files []RecentFile, result *strings.Builder) {
+	for _, file := range files {
+		result.WriteString(fmt.Sprintf("  %s %s %s\n",
+			warnStyle.Render(fmt.Sprintf("%-16s", formatDateTime(file.ModTime))),
+			numberStyle.Render(fmt.Sprintf("%11s", formatMB(file.Size))),
+			renderPath(file.Path)))
+	}
+}
+
+func displayRecent(stats *Stats, result *strings.Builder) {
+	files := sortedRecent(stats.Recent)
+	if len(files) == 0 {
+		return
+	}
+
+	title := tr("Recent Changes")
+	if recentCategory != "" {
+		title = fmt.Sprintf(tr("Recent Changes (%s)"), recentCategory)
+	}
+	result.WriteString(headerStyle.Render(title))
+	result.WriteString("\n")
+	writeRecentFiles(files, result)
+	result.WriteString("\n")
+}
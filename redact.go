@@ -0,0 +1,167 @@
+package main
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// redactor hides file names in reports when --redact is set, nil otherwise.
+var redactor *pathRedactor
+
+// redactTokenPrefix marks redacted names so that unredact can find them.
+const redactTokenPrefix = "_r"
+
+var (
+	redactEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+	redactToken    = regexp.MustCompile(redactTokenPrefix + `[a-z2-7]{15,}`)
+)
+
+// pathRedactor replaces each path segment by a deterministic encryption of
+// it: the same name always gives the same token, so the structure of the
+// tree stays visible, and only the holder of the key can turn tokens back
+// into names. The nonce is an HMAC of the name, which makes the encryption
+// deterministic and lets decryption verify the key.
+type pathRedactor struct {
+	block  cipher.Block
+	macKey []byte
+}
+
+func newPathRedactor(key string) *pathRedactor {
+	encKey := sha256.Sum256([]byte("madaa-redact-enc:" + key))
+	macKey := sha256.Sum256([]byte("madaa-redact-mac:" + key))
+	block, _ := aes.NewCipher(encKey[:])
+	return &pathRedactor{block: block, macKey: macKey[:]}
+}
+
+func (r *pathRedactor) crypt(nonce, data []byte) []byte {
+	iv := make([]byte, aes.BlockSize)
+	copy(iv, nonce)
+	out := make([]byte, len(data))
+	cipher.NewCTR(r.block, iv).XORKeyStream(out, data)
+	return out
+}
+
+func (r *pathRedactor) nonce(name string) []byte {
+	mac := hmac.New(sha256.New, r.macKey)
+	mac.Write([]byte(name))
+	return mac.Sum(nil)[:8]
+}
+
+// name redacts a single name. Extensions are kept so that file type
+// statistics still make sense.
+func (r *pathRedactor) name(name string) string {
+	switch name {
+	case "", ".", "..":
+		return name
+	}
+	ext := filepath.Ext(name)
+	if ext == name {
+		ext = ""
+	}
+	stem := strings.TrimSuffix(name, ext)
+	nonce := r.nonce(stem)
+	token := append(nonce, r.crypt(nonce, []byte(stem))...)
+	return redactTokenPrefix + strings.ToLower(redactEncoding.EncodeToString(token)) + ext
+}
+
+// reveal turns a token back into the name, or reports false if the token
+// wasn't made with this key.
+func (r *pathRedactor) reveal(token string) (string, bool) {
+	data, err := redactEncoding.DecodeString(strings.ToUpper(strings.TrimPrefix(token, redactTokenPrefix)))
+	if err != nil || len(data) <= 8 {
+		return "", false
+	}
+	stem := string(r.crypt(data[:8], data[8:]))
+	if !hmac.Equal(r.nonce(stem), data[:8]) {
+		return "", false
+	}
+	return stem, true
+}
+
+// displayPath returns path as it may be shown in reports and exports: with
+// every segment redacted under --redact and unchanged otherwise.
+func displayPath(path string) string {
+	if redactor == nil {
+		return path
+	}
+	segments := strings.Split(path, string(os.PathSeparator))
+	for i, segment := range segments {
+		segments[i] = redactor.name(segment)
+	}
+	return strings.Join(segments, string(os.PathSeparator))
+}
+
+// displayName is displayPath for a single name such as an owner.
+func displayName(name string) string {
+	if redactor == nil {
+		return name
+	}
+	return redactor.name(name)
+}
+
+// redactFlags registers --redact and --redact-key on flags. The returned
+// function enables redaction after parsing; without a key it makes up a
+// random one and prints it to stderr.
+func redactFlags(flags *flag.FlagSet) func() {
+	redact := flags.Bool("redact", false, "Replace file and directory names in reports by tokens only the redaction key can reverse")
+	key := flags.String("redact-key", "", "Key for --redact (default: $MADAA_REDACT_KEY or a random key)")
+	return func() {
+		if !*redact {
+			return
+		}
+		if *key == "" {
+			*key = os.Getenv("MADAA_REDACT_KEY")
+		}
+		if *key == "" {
+			random := make([]byte, 16)
+			rand.Read(random)
+			*key = hex.EncodeToString(random)
+			fmt.Fprintf(os.Stderr, "Redaction key: %s\n", *key)
+		}
+		redactor = newPathRedactor(*key)
+	}
+}
+
+// runUnredact implements "madaa unredact": it copies stdin to stdout with
+// every token made with the key replaced by the original name.
+func runUnredact(args []string) {
+	flags := flag.NewFlagSet("unredact", flag.ExitOnError)
+	key := flags.String("redact-key", "", "Key the report was redacted with (default: $MADAA_REDACT_KEY)")
+	flags.Parse(args)
+
+	if *key == "" {
+		*key = os.Getenv("MADAA_REDACT_KEY")
+	}
+	if *key == "" {
+		fmt.Println("Usage: madaa unredact --redact-key KEY < report")
+		os.Exit(1)
+	}
+	r := newPathRedactor(*key)
+
+	scanner := bufio.NewScanner(os.Stdin)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		fmt.Println(redactToken.ReplaceAllStringFunc(scanner.Text(), func(token string) string {
+			if name, ok := r.reveal(token); ok {
+				return name
+			}
+			return token
+		}))
+	}
+	if err := scanner.Err(); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+}
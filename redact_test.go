@@ -0,0 +1,98 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPathRedactorKeys(t *testing.T) {
+	r, same, other := newPathRedactor("k1"), newPathRedactor("k1"), newPathRedactor("k2")
+	for _, name := range []string{"report", "report.pdf", ".bashrc", "Ünïcode name.tar.gz"} {
+		token := r.name(name)
+		if token == name || !redactToken.MatchString(token) {
+			t.Errorf("%q redacted as %q", name, token)
+		}
+		if again := same.name(name); again != token {
+			t.Errorf("%q: %q, then %q under the same key", name, token, again)
+		}
+		if different := other.name(name); different == token {
+			t.Errorf("%q: %q under both keys", name, token)
+		}
+
+		// The extension is kept, unless the whole name is one
+		ext := filepath.Ext(name)
+		if ext == name {
+			ext = ""
+		}
+		stem := strings.TrimSuffix(token, ext)
+		if revealed, ok := r.reveal(stem); !ok || revealed != strings.TrimSuffix(name, ext) {
+			t.Errorf("%q: revealed %q, %v", name, revealed, ok)
+		}
+		if _, ok := other.reveal(stem); ok {
+			t.Errorf("%q revealed with another key", name)
+		}
+	}
+}
+
+func TestDisplayPathKeepsStructure(t *testing.T) {
+	defer func(r *pathRedactor) { redactor = r }(redactor)
+	redactor = newPathRedactor("k1")
+
+	sep := string(os.PathSeparator)
+	path := sep + strings.Join([]string{"home", "alice", "..", "alice", "notes.txt"}, sep)
+	segments := strings.Split(displayPath(path), sep)
+	want := strings.Split(path, sep)
+	if len(segments) != len(want) {
+		t.Fatalf("%q redacted as %q", path, displayPath(path))
+	}
+	if segments[0] != "" || segments[3] != ".." {
+		t.Errorf("root or .. not kept: %q", segments)
+	}
+	if segments[2] != segments[4] || segments[2] == "alice" {
+		t.Errorf("alice redacted as %q and %q", segments[2], segments[4])
+	}
+	if !strings.HasSuffix(segments[5], ".txt") || strings.Contains(segments[5], "notes") {
+		t.Errorf("notes.txt redacted as %q", segments[5])
+	}
+	if displayPath("relative"+sep) != redactor.name("relative")+sep {
+		t.Errorf("trailing separator not kept: %q", displayPath("relative"+sep))
+	}
+}
+
+// TestViewsRedact checks that the Dirs tab and the JSON export show no names
+// under --redact.
+func TestViewsRedact(t *testing.T) {
+	defer func(r *pathRedactor) { redactor = r }(redactor)
+	redactor = newPathRedactor("k1")
+
+	root := filepath.Join(t.TempDir(), "customer-data")
+	secret := filepath.Join(root, "acquisition-plans")
+	stats := newStats()
+	stats.DirDepths[secret] = 1
+	stats.DirActivity[secret] = &DirActivity{Files: 1, Size: 10}
+	pushLargest(stats.LargestFiles, FileSize{Path: filepath.Join(secret, "target.xlsx"), Size: 10, Type: ".xlsx"}, 10)
+
+	m := initialModel(Config{Path: root, Count: 10})
+	m.stats = stats
+	m.dirs, m.dirPath = dirNodes(stats, root), root
+	dirs := m.dirsView()
+
+	export := newExport(stats, root)
+	var exported strings.Builder
+	if err := writeExport(export, &exported); err != nil {
+		t.Fatal(err)
+	}
+
+	for view, text := range map[string]string{"dirs": dirs, "export": exported.String()} {
+		for _, name := range []string{"customer-data", "acquisition-plans", "target"} {
+			if strings.Contains(text, name) {
+				t.Errorf("%s view shows %q:\n%s", view, name, text)
+			}
+		}
+		if !strings.Contains(text, redactor.name("acquisition-plans")) {
+			t.Errorf("%s view lacks the redacted directory:\n%s", view, text)
+		}
+	}
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+
+package main
+
+import (
+	"errors"
+	"os"
+)
+
+// cloneFile is not supported here; reflink fails for every file.
+func cloneFile(src, dst string) error {
+	return &os.PathError{Op: "reflink", Path: src, Err: errors.ErrUnsupported}
+}
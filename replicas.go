@@ -0,0 +1,209 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Replica states of a path, relative to the source tree.
+const (
+	replicaOK      = "ok"
+	replicaMissing = "missing"
+	replicaStale   = "stale"
+	replicaDiffers = "differs"
+	replicaExtra   = "extra"
+)
+
+// Divergence is a path that isn't identical in every replica. Status holds
+// one of the replica states per replica, in the order they were given.
+type Divergence struct {
+	Path   string
+	Size   int64
+	Status []string
+}
+
+// snapshotFiles returns every file of the snapshot keyed by its path
+// relative to the root.
+func snapshotFiles(snap *Snapshot) map[string]cachedFile {
+	files := make(map[string]cachedFile)
+	for dir, listing := range relativeDirs(snap) {
+		for _, f := range listing.Files {
+			files[filepath.Join(dir, f.Name)] = f
+		}
+	}
+	return files
+}
+
+// replicaState compares a replica's copy of a file to the source. A copy
+// with an older mtime is stale; one that is as new but different in size
+// differs.
+func replicaState(source, replica cachedFile, exists bool) string {
+	switch {
+	case !exists:
+		return replicaMissing
+	case replica.ModTime.Before(source.ModTime):
+		return replicaStale
+	case replica.Size != source.Size:
+		return replicaDiffers
+	}
+	return replicaOK
+}
+
+// compareReplicas checks every replica against the source and returns the
+// paths that diverge in at least one of them, largest first.
+func compareReplicas(source *Snapshot, replicas []*Snapshot) []Divergence {
+	sourceFiles := snapshotFiles(source)
+	replicaFiles := make([]map[string]cachedFile, len(replicas))
+	for i, replica := range replicas {
+		replicaFiles[i] = snapshotFiles(replica)
+	}
+
+	var divergences []Divergence
+	for path, f := range sourceFiles {
+		status := make([]string, len(replicas))
+		diverged := false
+		for i, files := range replicaFiles {
+			replica, exists := files[path]
+			status[i] = replicaState(f, replica, exists)
+			diverged = diverged || status[i] != replicaOK
+		}
+		if diverged {
+			divergences = append(divergences, Divergence{Path: path, Size: f.Size, Status: status})
+		}
+	}
+
+	// Files only some replicas have were deleted from the source or never
+	// belonged there
+	seen := make(map[string]bool)
+	for _, files := range replicaFiles {
+		for path, f := range files {
+			if _, ok := sourceFiles[path]; ok || seen[path] {
+				continue
+			}
+			seen[path] = true
+			status := make([]string, len(replicas))
+			for i, other := range replicaFiles {
+				if _, ok := other[path]; ok {
+					status[i] = replicaExtra
+				} else {
+					status[i] = replicaOK
+				}
+			}
+			divergences = append(divergences, Divergence{Path: path, Size: f.Size, Status: status})
+		}
+	}
+
+	sort.Slice(divergences, func(i, j int) bool {
+		if divergences[i].Size != divergences[j].Size {
+			return divergences[i].Size > divergences[j].Size
+		}
+		return divergences[i].Path < divergences[j].Path
+	})
+	return divergences
+}
+
+func replicaStyle(status string) string {
+	switch status {
+	case replicaOK:
+		return goodStyle.Render(tr(status))
+	case replicaExtra:
+		return warnStyle.Render(tr(status))
+	}
+	return badStyle.Render(tr(status))
+}
+
+func displayReplicas(source *Snapshot, replicas []*Snapshot, divergences []Divergence, maxCount int) string {
+	var result strings.Builder
+
+	writeTitle(tr("MADAA - Replica Verification"), &result)
+
+	result.WriteString(headerStyle.Render(tr("Overview")))
+	result.WriteString("\n")
+	result.WriteString(fmt.Sprintf(tr("Source: %s\n"), renderPath(source.Root)))
+	for i, replica := range replicas {
+		counts := make(map[string]int)
+		for _, d := range divergences {
+			counts[d.Status[i]]++
+		}
+		result.WriteString(fmt.Sprintf(tr("Replica %d %s: missing %s  stale %s  differs %s  extra %s\n"),
+			i+1,
+			renderPath(replica.Root),
+			numberStyle.Render(formatCount(counts[replicaMissing])),
+			numberStyle.Render(formatCount(counts[replicaStale])),
+			numberStyle.Render(formatCount(counts[replicaDiffers])),
+			numberStyle.Render(formatCount(counts[replicaExtra]))))
+	}
+	result.WriteString("\n")
+
+	if len(divergences) == 0 {
+		result.WriteString(goodStyle.Render(tr("All replicas match the source")))
+		result.WriteString("\n")
+		return result.String()
+	}
+
+	result.WriteString(headerStyle.Render(fmt.Sprintf(tr("Divergent Paths (%s)"), formatCount(len(divergences)))))
+	result.WriteString("\n")
+	for _, d := range divergences[:min(maxCount, len(divergences))] {
+		states := make([]string, len(d.Status))
+		for i, status := range d.Status {
+			states[i] = fmt.Sprintf("%d:%s", i+1, replicaStyle(status))
+		}
+		result.WriteString(fmt.Sprintf("  %s %s %s\n",
+			numberStyle.Render(fmt.Sprintf("%10s", formatMB(d.Size))),
+			strings.Join(states, " "),
+			renderPath(d.Path)))
+	}
+	result.WriteString("\n")
+	return result.String()
+}
+
+// runVerify implements "madaa verify <source> <replica>...": every replica
+// is compared against the source and each diverging path is reported with
+// the state of every replica. Trees may be given as directories or saved
+// snapshots. Exits with status 1 if any replica diverges.
+func runVerify(args []string) {
+	flags := flag.NewFlagSet("verify", flag.ExitOnError)
+	count := flags.Int("count", 10, "Number of divergent paths to show")
+	enableRedaction := redactFlags(flags)
+	reportFlags(flags)
+	selectLanguage := localeFlags(flags)
+	flags.Parse(args)
+	enableRedaction()
+	selectLanguage()
+
+	if flags.NArg() < 2 {
+		fmt.Println("Usage: madaa verify [--count N] <source> <replica> [<replica>...]")
+		os.Exit(1)
+	}
+
+	source, err := openSnapshot(flags.Arg(0), nil)
+	if err == nil {
+		err = requireListings(flags.Arg(0), source)
+	}
+	if err != nil {
+		fmt.Printf("Error reading %s: %v\n", flags.Arg(0), err)
+		os.Exit(1)
+	}
+	var replicas []*Snapshot
+	for _, arg := range flags.Args()[1:] {
+		replica, err := openSnapshot(arg, nil)
+		if err == nil {
+			err = requireListings(arg, replica)
+		}
+		if err != nil {
+			fmt.Printf("Error reading %s: %v\n", arg, err)
+			os.Exit(1)
+		}
+		replicas = append(replicas, replica)
+	}
+
+	divergences := compareReplicas(source, replicas)
+	fmt.Print(fitPaths(displayReplicas(source, replicas, divergences, *count), pathWidth))
+	if len(divergences) > 0 {
+		os.Exit(1)
+	}
+}
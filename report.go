@@ -0,0 +1,191 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/x/ansi"
+)
+
+// The PDF report is set in Courier on A4 pages, which takes pdfColumns
+// characters per line and pdfLines lines per page.
+const (
+	pdfPageWidth  = 595
+	pdfPageHeight = 842
+	pdfMargin     = 40
+	pdfFontSize   = 8
+	pdfLeading    = 10
+	pdfColumns    = (pdfPageWidth - 2*pdfMargin) * 10 / (pdfFontSize * 6)
+	pdfLines      = (pdfPageHeight-2*pdfMargin)/pdfLeading - 2
+)
+
+// saveReport writes the printed report of a scan to path (- for stdout),
+// as plain text or as a paginated PDF. format defaults to pdf for a path
+// ending in .pdf and to text otherwise.
+func saveReport(stats *Stats, maxCount int, path, format string) error {
+	if format == "" {
+		format = "text"
+		if strings.HasSuffix(strings.ToLower(path), ".pdf") {
+			format = "pdf"
+		}
+	}
+
+	var write func(w io.Writer) error
+	switch format {
+	case "text":
+		report := ansi.Strip(fitPaths(displayResults(stats, maxCount), pathWidth))
+		write = func(w io.Writer) error {
+			_, err := io.WriteString(w, report)
+			return err
+		}
+	case "pdf":
+		// Courier has no emoji or arrows, and paths have to fit the page
+		ascii := asciiOnly
+		asciiOnly = true
+		report := ansi.Strip(fitPaths(displayResults(stats, maxCount), pdfColumns))
+		asciiOnly = ascii
+		write = func(w io.Writer) error {
+			return writePDF(w, tr("MADAA - Mass Data Analysis Results"), report)
+		}
+	default:
+		return fmt.Errorf("unknown format %q, want text or pdf", format)
+	}
+
+	if path == "-" {
+		return write(os.Stdout)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// pdfPages breaks the report into lines of at most pdfColumns characters
+// and the lines into pages. A page doesn't start with blank lines.
+func pdfPages(report string) [][]string {
+	var lines []string
+	for _, line := range strings.Split(strings.TrimRight(report, "\n"), "\n") {
+		runes := []rune(strings.TrimRight(line, " "))
+		for len(runes) > pdfColumns {
+			lines = append(lines, string(runes[:pdfColumns]))
+			runes = append([]rune("    "), runes[pdfColumns:]...)
+		}
+		lines = append(lines, string(runes))
+	}
+
+	var pages [][]string
+	var page []string
+	for _, line := range lines {
+		if len(page) == 0 && line == "" {
+			continue
+		}
+		page = append(page, line)
+		if len(page) == pdfLines {
+			pages = append(pages, page)
+			page = nil
+		}
+	}
+	if len(page) > 0 || len(pages) == 0 {
+		pages = append(pages, page)
+	}
+	return pages
+}
+
+// winAnsi maps the characters outside Latin-1 that WinAnsiEncoding has.
+var winAnsi = map[rune]byte{
+	'€': 0x80, '…': 0x85, '‘': 0x91, '’': 0x92, '“': 0x93, '”': 0x94,
+	'•': 0x95, '–': 0x96, '—': 0x97,
+}
+
+// pdfText encodes s as a PDF string in WinAnsiEncoding, the encoding of the
+// standard fonts. Characters it lacks become ?.
+func pdfText(s string) string {
+	var b strings.Builder
+	b.WriteByte('(')
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r >= 0x20 && r < 0x7f:
+			b.WriteRune(r)
+		case r >= 0xa0 && r <= 0xff:
+			b.WriteByte(byte(r))
+		case winAnsi[r] != 0:
+			b.WriteByte(winAnsi[r])
+		default:
+			b.WriteByte('?')
+		}
+	}
+	b.WriteByte(')')
+	return b.String()
+}
+
+// writePDF sets the report as a PDF document with numbered pages. The
+// report metadata becomes the author and subject of the document.
+func writePDF(w io.Writer, title, report string) error {
+	pages := pdfPages(report)
+
+	// Objects 1 to 3 are the catalog, the page tree and the font, 4 the
+	// document information; each page is followed by its content stream
+	var objects []string
+	kids := make([]string, len(pages))
+	for i := range pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 5+2*i)
+	}
+	objects = append(objects,
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Courier /Encoding /WinAnsiEncoding >>")
+
+	info := fmt.Sprintf("<< /Title %s /Producer (madaa) /CreationDate (D:%s)",
+		pdfText(title), time.Now().UTC().Format("20060102150405Z"))
+	if reportMeta.Operator != "" {
+		info += " /Author " + pdfText(reportMeta.Operator)
+	}
+	if subject := strings.TrimSpace(reportMeta.Organization + " " + reportMeta.Ticket); subject != "" {
+		info += " /Subject " + pdfText(subject)
+	}
+	objects = append(objects, info+" >>")
+
+	for i, page := range pages {
+		var content bytes.Buffer
+		fmt.Fprintf(&content, "BT /F1 %d Tf %d TL %d %d Td\n", pdfFontSize, pdfLeading, pdfMargin, pdfPageHeight-pdfMargin-pdfFontSize)
+		for _, line := range page {
+			fmt.Fprintf(&content, "%s '\n", pdfText(line))
+		}
+		fmt.Fprintf(&content, "ET\nBT /F1 %d Tf %d %d Td %s Tj ET\n", pdfFontSize, pdfMargin, pdfMargin/2,
+			pdfText(fmt.Sprintf(tr("Page %d of %d"), i+1, len(pages))))
+
+		objects = append(objects,
+			fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>",
+				pdfPageWidth, pdfPageHeight, 6+2*i),
+			fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", content.Len(), content.String()))
+	}
+
+	var doc bytes.Buffer
+	doc.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	offsets := make([]int, len(objects))
+	for i, object := range objects {
+		offsets[i] = doc.Len()
+		fmt.Fprintf(&doc, "%d 0 obj\n%s\nendobj\n", i+1, object)
+	}
+	xref := doc.Len()
+	fmt.Fprintf(&doc, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&doc, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&doc, "trailer\n<< /Size %d /Root 1 0 R /Info 4 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+
+	_, err := w.Write(doc.Bytes())
+	return err
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"gopkg.in/ini.v1"
+)
+
+// ReportMeta identifies a report for audit documentation: the organization
+// it was made for, who ran the scan and the ticket it belongs to. It comes
+// from the [report] section of config.ini; --operator and --ticket override
+// it for one run.
+type ReportMeta struct {
+	Organization string `json:"organization,omitempty"`
+	Operator     string `json:"operator,omitempty"`
+	Ticket       string `json:"ticket,omitempty"`
+}
+
+var reportMeta ReportMeta
+
+func (m ReportMeta) empty() bool {
+	return m == ReportMeta{}
+}
+
+// reportFlags adds the flags overriding the [report] section to flags.
+func reportFlags(flags *flag.FlagSet) {
+	flags.StringVar(&reportMeta.Operator, "operator", "", "Name of who ran the scan, shown in the report header (overrides [report] operator)")
+	flags.StringVar(&reportMeta.Ticket, "ticket", "", "Ticket or change number shown in the report header (overrides [report] ticket)")
+}
+
+// loadReportConfig fills the fields not set on the command line.
+func loadReportConfig(section *ini.Section) {
+	for key, field := range map[string]*string{
+		"organization": &reportMeta.Organization,
+		"operator":     &reportMeta.Operator,
+		"ticket":       &reportMeta.Ticket,
+	} {
+		if *field == "" {
+			*field = strings.TrimSpace(section.Key(key).String())
+		}
+	}
+}
+
+// exportReportMeta is the report metadata as exported, nil if none is set.
+func exportReportMeta() *ReportMeta {
+	if reportMeta.empty() {
+		return nil
+	}
+	meta := reportMeta
+	return &meta
+}
+
+// writeTitle starts a report with its title and the report metadata, if
+// any is set.
+func writeTitle(title string, result *strings.Builder) {
+	result.WriteString(titleStyle.Render(title))
+	result.WriteString("\n")
+	var fields []string
+	for _, field := range []struct{ label, value string }{
+		{tr("Organization"), reportMeta.Organization},
+		{tr("Operator"), reportMeta.Operator},
+		{tr("Ticket"), reportMeta.Ticket},
+	} {
+		if field.value != "" {
+			fields = append(fields, fmt.Sprintf("%s: %s", field.label, numberStyle.Render(field.value)))
+		}
+	}
+	if len(fields) > 0 {
+		result.WriteString(strings.Join(fields, "  "))
+		result.WriteString("\n")
+	}
+	result.WriteString("\n")
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// resultTabs are the tabs of the results view shown after the scan. Types
+// and Dirs are interactive; the others show report sections and scroll.
+var resultTabs = []struct {
+	Name     string
+	Sections []string
+}{
+	{"Overview", []string{"overview", "categories", "top-level", "sizes"}},
+	{"Types", nil},
+	{"Largest", []string{"largest", "largest-dirs"}},
+	{"Age", []string{"age", "tiers", "forgotten"}},
+	{"Dirs", nil},
+}
+
+const (
+	tabTypes = 1
+	tabDirs  = 4
+)
+
+// tabChrome is the number of lines around the content of a tab: the tab
+// bar with its blank line, and the help below.
+const tabChrome = 6
+
+// tabText renders the report sections of a tab. Sections hidden with
+// --sections or --hide-sections are left out here too.
+func tabText(stats *Stats, tab, maxCount int) string {
+	var result strings.Builder
+	for _, name := range resultTabs[tab].Sections {
+		if hiddenSections[name] {
+			continue
+		}
+		for _, section := range reportSections {
+			if section.Name == name {
+				section.Display(stats, maxCount, &result)
+			}
+		}
+	}
+	return result.String()
+}
+
+// switchTab shows tab, rendering its sections the first time.
+func (m model) switchTab(tab int) model {
+	m.tab, m.scroll = tab, 0
+	if resultTabs[tab].Sections != nil {
+		if m.tabTexts == nil {
+			m.tabTexts = make(map[int]string)
+		}
+		if _, ok := m.tabTexts[tab]; !ok {
+			m.tabTexts[tab] = tabText(m.stats, tab, m.config.Count)
+		}
+	}
+	if tab == tabDirs && m.dirPath == "" {
+		if m.dirs == nil {
+			m.dirs = dirNodes(m.stats, m.config.Path)
+		}
+		m.dirPath, m.dirCursor = filepath.Clean(m.config.Path), 0
+	}
+	return m
+}
+
+// updateTabs handles the keys switching tabs and scrolling the text tabs,
+// and passes the others on to the view of the tab.
+func (m model) updateTabs(key tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch k := key.String(); {
+	case k == "tab":
+		return m.switchTab((m.tab + 1) % len(resultTabs)), nil
+	case k == "shift+tab":
+		return m.switchTab((m.tab + len(resultTabs) - 1) % len(resultTabs)), nil
+	case len(k) == 1 && k[0] >= '1' && int(k[0]-'1') < len(resultTabs):
+		return m.switchTab(int(k[0] - '1')), nil
+	}
+
+	switch m.tab {
+	case tabTypes:
+		return m.updateBrowse(key)
+	case tabDirs:
+		return m.updateDirs(key)
+	}
+	lines := strings.Count(m.tabTexts[m.tab], "\n")
+	page := max(m.height-tabChrome, 1)
+	switch key.String() {
+	case "q", "ctrl+c":
+		m.browsing = false
+		return m, tea.Quit
+	case "up", "k":
+		m.scroll--
+	case "down", "j":
+		m.scroll++
+	case "pgup", "b":
+		m.scroll -= page
+	case "pgdown", " ":
+		m.scroll += page
+	case "home", "g":
+		m.scroll = 0
+	case "end", "G":
+		m.scroll = lines
+	}
+	m.scroll = max(min(m.scroll, lines-page), 0)
+	return m, nil
+}
+
+// nextView shows the results with the next saved view applied, in the
+// order of their names, and after the last one with the setup of the
+// command line again. A view filtering or counting differently rescans the
+// tree; one only choosing other sections renders the tabs again.
+func (m model) nextView() (tea.Model, tea.Cmd) {
+	names := viewNames()
+	if len(names) == 0 {
+		return m, nil
+	}
+	setup := m.cli
+	if i := slices.Index(names, m.setup.Name); i+1 < len(names) {
+		setup = m.cli.withView(savedViews[names[i+1]])
+	}
+	var filter *Filter
+	if setup.Filter != "" {
+		var err error
+		if filter, err = ParseFilter(setup.Filter); err != nil {
+			m.status = fmt.Sprintf(tr("Error in view %s: %v"), setup.Name, err)
+			return m, nil
+		}
+	}
+	if err := selectSections(setup.Sections, setup.HideSections); err != nil {
+		m.status = fmt.Sprintf(tr("Error in view %s: %v"), setup.Name, err)
+		return m, nil
+	}
+
+	rescan := setup.Filter != m.setup.Filter || setup.Count != m.setup.Count
+	m.setup, m.tabTexts = setup, nil
+	if !rescan {
+		return m.switchTab(m.tab), nil
+	}
+	m.config.Filter, m.config.Count = filter, setup.Count
+	m.analyzing, m.done, m.browsing, m.stats = true, false, false, nil
+	m.types, m.typeCursor, m.typeDetail = nil, 0, ""
+	m.dirs, m.dirPath, m.dirCursor, m.deleted = nil, "", 0, nil
+	return m.restart()
+}
+
+// tabBar lists the tabs with their numbers, the one shown highlighted.
+func (m model) tabBar() string {
+	names := make([]string, len(resultTabs))
+	for i, tab := range resultTabs {
+		name := strconv.Itoa(i+1) + " " + tr(tab.Name)
+		if i == m.tab {
+			names[i] = titleStyle.Render("[" + name + "]")
+		} else {
+			names[i] = pathStyle.Render(" " + name + " ")
+		}
+	}
+	bar := strings.Join(names, " ")
+	if m.setup.Name != "" {
+		bar += "  " + warnStyle.Render(fmt.Sprintf(tr("view %s"), m.setup.Name))
+	}
+	return bar + "\n\n"
+}
+
+// scrolled cuts the text of a text tab to the lines that fit the terminal.
+func (m model) scrolled(text string) string {
+	if resultTabs[m.tab].Sections == nil || m.height == 0 {
+		return text
+	}
+	lines := strings.SplitAfter(text, "\n")
+	first := min(m.scroll, len(lines))
+	return strings.Join(lines[first:min(first+max(m.height-tabChrome, 1), len(lines))], "")
+}
+
+// tabHelp lists the keys of a text tab.
+func (m model) tabHelp() string {
+	help := tr("↑/↓ scroll  pgup/pgdn page  p full paths  q quit")
+	if lines := strings.Count(m.tabTexts[m.tab], "\n"); m.height > 0 && lines > m.height-tabChrome {
+		help += fmt.Sprintf(tr("  (line %d of %d)"), m.scroll+1, lines)
+	}
+	return help
+}
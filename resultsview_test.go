@@ -0,0 +1,47 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestNextView(t *testing.T) {
+	defer func(views map[string]View) { savedViews = views }(savedViews)
+	defer selectSections("", "")
+	savedViews = map[string]View{
+		"big":   {Name: "big", Filter: "size > 100MB", Count: 20},
+		"brief": {Name: "brief", Sections: "overview"},
+	}
+
+	m := initialModel(Config{Path: t.TempDir(), Count: 10})
+	m.cli = View{Count: 10}
+	m.setup = m.cli
+	m.analyzing, m.done, m.browsing, m.stats = false, true, true, newStats()
+
+	next, cmd := m.nextView()
+	m = next.(model)
+	if m.setup.Name != "big" || !m.analyzing || m.browsing || cmd == nil {
+		t.Fatalf("big: view %q, analyzing %v, browsing %v", m.setup.Name, m.analyzing, m.browsing)
+	}
+	if m.config.Filter == nil || m.config.Count != 20 {
+		t.Errorf("big: filter %v, count %d", m.config.Filter, m.config.Count)
+	}
+
+	m.analyzing, m.done, m.browsing, m.stats = false, true, true, newStats()
+	next, _ = m.nextView()
+	m = next.(model)
+	if m.setup.Name != "brief" || m.config.Filter != nil || m.config.Count != 10 {
+		t.Fatalf("brief: view %q, filter %v, count %d", m.setup.Name, m.config.Filter, m.config.Count)
+	}
+	if !hiddenSections["largest"] || hiddenSections["overview"] {
+		t.Errorf("brief: hidden sections %v", hiddenSections)
+	}
+
+	// After the last view, the command line's setup again. Only the
+	// sections differ, so the results are kept.
+	m.analyzing, m.done, m.browsing, m.stats = false, true, true, newStats()
+	next, cmd = m.nextView()
+	m = next.(model)
+	if m.setup.Name != "" || m.analyzing || cmd != nil || hiddenSections["largest"] {
+		t.Errorf("back: view %q, analyzing %v, hidden sections %v", m.setup.Name, m.analyzing, hiddenSections)
+	}
+}
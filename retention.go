@@ -0,0 +1,184 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/ini.v1"
+)
+
+// retentionRules are loaded from the [retention] config section. The scan
+// checks every file against them when there are any.
+var retentionRules []RetentionRule
+
+// RetentionRule says how long files matching Pattern, relative to the scan
+// root, are kept: "invoices/** = keep 7y".
+type RetentionRule struct {
+	Pattern string
+	Keep    string
+	Period  time.Duration
+}
+
+// RetentionViolation counts the files violating one rule.
+type RetentionViolation struct {
+	Files   int
+	Bytes   int64
+	Largest *FileSizeHeap
+}
+
+// RetentionStats holds the policy violations keyed by rule pattern. A file
+// is expired when it is older than every rule it matches allows, and it is a
+// conflict when a shorter rule would delete it while a longer one, under
+// which it is counted, still requires it to be kept.
+type RetentionStats struct {
+	Expired   map[string]*RetentionViolation
+	Conflicts map[string]*RetentionViolation
+}
+
+func newRetentionStats() RetentionStats {
+	return RetentionStats{
+		Expired:   make(map[string]*RetentionViolation),
+		Conflicts: make(map[string]*RetentionViolation),
+	}
+}
+
+func loadRetentionRules(section *ini.Section) ([]RetentionRule, error) {
+	var rules []RetentionRule
+	for _, key := range section.Keys() {
+		fields := strings.Fields(key.Value())
+		if len(fields) != 2 || fields[0] != "keep" {
+			return nil, fmt.Errorf("retention rule %s: want \"keep AGE\", got %q", key.Name(), key.Value())
+		}
+		period, err := parseAge(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("retention rule %s: %v", key.Name(), err)
+		}
+		rules = append(rules, RetentionRule{Pattern: key.Name(), Keep: fields[1], Period: period})
+	}
+	return rules, nil
+}
+
+func addViolation(violations map[string]*RetentionViolation, pattern string, file FileSize, maxFiles int) {
+	v := violations[pattern]
+	if v == nil {
+		v = &RetentionViolation{Largest: &FileSizeHeap{}}
+		violations[pattern] = v
+	}
+	v.Files++
+	v.Bytes += file.Size
+	pushLargest(v.Largest, file, maxFiles)
+}
+
+func processRetention(path string, info os.FileInfo, stats *Stats, root string, maxFiles int) {
+	if len(retentionRules) == 0 {
+		return
+	}
+	relPath, err := filepath.Rel(root, path)
+	if err != nil {
+		return
+	}
+
+	var shortest, longest *RetentionRule
+	for i := range retentionRules {
+		rule := &retentionRules[i]
+		if !matchGlob(rule.Pattern, relPath) {
+			continue
+		}
+		if shortest == nil || rule.Period < shortest.Period {
+			shortest = rule
+		}
+		if longest == nil || rule.Period > longest.Period {
+			longest = rule
+		}
+	}
+	if longest == nil {
+		return
+	}
+
+	age := time.Since(info.ModTime())
+	file := FileSize{path, info.Size(), strings.ToLower(filepath.Ext(path))}
+
+	stats.mu.Lock()
+	defer stats.mu.Unlock()
+	switch {
+	case age > longest.Period:
+		addViolation(stats.Retention.Expired, longest.Pattern, file, maxFiles)
+	case age > shortest.Period:
+		addViolation(stats.Retention.Conflicts, longest.Pattern, file, maxFiles)
+	}
+}
+
+func mergeViolations(dst, src map[string]*RetentionViolation, maxFiles int) {
+	for pattern, v := range src {
+		if dst[pattern] == nil {
+			dst[pattern] = &RetentionViolation{Largest: &FileSizeHeap{}}
+		}
+		dst[pattern].Files += v.Files
+		dst[pattern].Bytes += v.Bytes
+		mergeLargest(dst[pattern].Largest, v.Largest, maxFiles)
+	}
+}
+
+func mergeRetentionStats(dst, src *RetentionStats, maxFiles int) {
+	mergeViolations(dst.Expired, src.Expired, maxFiles)
+	mergeViolations(dst.Conflicts, src.Conflicts, maxFiles)
+}
+
+func violationTotals(violations map[string]*RetentionViolation) (files int, bytes int64) {
+	for _, v := range violations {
+		files += v.Files
+		bytes += v.Bytes
+	}
+	return files, bytes
+}
+
+func displayViolations(title string, violations map[string]*RetentionViolation, maxCount int, result *strings.Builder) {
+	keep := make(map[string]string)
+	for _, rule := range retentionRules {
+		keep[rule.Pattern] = rule.Keep
+	}
+	patterns := make([]string, 0, len(violations))
+	for pattern := range violations {
+		patterns = append(patterns, pattern)
+	}
+	sort.Slice(patterns, func(i, j int) bool {
+		if violations[patterns[i]].Bytes != violations[patterns[j]].Bytes {
+			return violations[patterns[i]].Bytes > violations[patterns[j]].Bytes
+		}
+		return patterns[i] < patterns[j]
+	})
+
+	for _, pattern := range patterns[:min(maxCount, len(patterns))] {
+		v := violations[pattern]
+		result.WriteString(fmt.Sprintf(tr("%s %s (keep %s): %s files, %s\n"),
+			title,
+			pathStyle.Render(pattern),
+			keep[pattern],
+			numberStyle.Render(formatCount(v.Files)),
+			numberStyle.Render(formatMB(v.Bytes))))
+		displayLargestFiles(v.Largest, result)
+	}
+}
+
+func displayRetention(stats *Stats, maxCount int, result *strings.Builder) {
+	expiredFiles, expiredBytes := violationTotals(stats.Retention.Expired)
+	conflictFiles, conflictBytes := violationTotals(stats.Retention.Conflicts)
+	if expiredFiles == 0 && conflictFiles == 0 {
+		return
+	}
+
+	result.WriteString(headerStyle.Render(tr("Retention Policy")))
+	result.WriteString("\n")
+	result.WriteString(fmt.Sprintf(tr("Too old to keep: %s in %s files  Must-keep in deletable areas: %s in %s files\n\n"),
+		badStyle.Render(formatMB(expiredBytes)),
+		numberStyle.Render(formatCount(expiredFiles)),
+		warnStyle.Render(formatMB(conflictBytes)),
+		numberStyle.Render(formatCount(conflictFiles))))
+
+	displayViolations(tr("Expired under"), stats.Retention.Expired, maxCount, result)
+	displayViolations(tr("Kept under"), stats.Retention.Conflicts, maxCount, result)
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// sampleZ is the z-score of the 95% confidence intervals of the estimates.
+const sampleZ = 1.96
+
+// SampleSums are the sums over the sampled files the estimates are computed
+// from.
+type SampleSums struct {
+	Files   int
+	Bytes   int64
+	Squares float64
+}
+
+func (s *SampleSums) add(size int64) {
+	s.Files++
+	s.Bytes += size
+	s.Squares += float64(size) * float64(size)
+}
+
+// SampleStats describes a --sample scan: the rate files were sampled at,
+// the number of files the walk saw and of those it sampled, and the sums of
+// the sampled files that were analyzed, overall and per category.
+type SampleStats struct {
+	Rate       float64
+	Population int
+	Sampled    int
+	Total      SampleSums
+	Categories map[string]*SampleSums
+}
+
+// parseSampleRate reads the rate of --sample, e.g. "1%" or "0.01".
+func parseSampleRate(s string) (float64, error) {
+	value, percent := strings.CutSuffix(strings.TrimSpace(s), "%")
+	rate, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid rate %q", s)
+	}
+	if percent {
+		rate /= 100
+	}
+	if rate <= 0 || rate > 1 {
+		return 0, fmt.Errorf("rate %q must be above 0 and at most 100%%", s)
+	}
+	return rate, nil
+}
+
+// sampled reports whether the file at path is in the sample. The choice
+// hashes the path, so it doesn't depend on size, age or walk order, and
+// repeated scans of a tree sample the same files.
+func (c Config) sampled(path string) bool {
+	if c.Sample <= 0 || c.Sample >= 1 {
+		return true
+	}
+	h := fnv.New64a()
+	h.Write([]byte(path))
+	return float64(h.Sum64()>>11)/(1<<53) < c.Sample
+}
+
+// countSampled counts a sampled file, whether or not filters let it into
+// the analysis.
+func countSampled(stats *Stats, rate float64) {
+	if rate <= 0 {
+		return
+	}
+	stats.mu.Lock()
+	defer stats.mu.Unlock()
+	stats.Sample.Rate = rate
+	stats.Sample.Sampled++
+}
+
+// processSample adds an analyzed sampled file to the sums of its category.
+func processSample(info os.FileInfo, stats *Stats, rate float64) {
+	if rate <= 0 {
+		return
+	}
+	category, ok := categoryLabels[fileTypeCategoryMap[strings.ToLower(filepath.Ext(info.Name()))]]
+	if !ok {
+		category = "Other"
+	}
+
+	stats.mu.Lock()
+	defer stats.mu.Unlock()
+	stats.Sample.Total.add(info.Size())
+	sums := stats.Sample.Categories[category]
+	if sums == nil {
+		sums = &SampleSums{}
+		stats.Sample.Categories[category] = sums
+	}
+	sums.add(info.Size())
+}
+
+func mergeSample(dst, src *SampleStats) {
+	if src.Rate > 0 {
+		dst.Rate = src.Rate
+	}
+	dst.Population += src.Population
+	dst.Sampled += src.Sampled
+	addSampleSums(&dst.Total, src.Total)
+	for category, sums := range src.Categories {
+		if dst.Categories[category] == nil {
+			dst.Categories[category] = &SampleSums{}
+		}
+		addSampleSums(dst.Categories[category], *sums)
+	}
+}
+
+func addSampleSums(dst *SampleSums, src SampleSums) {
+	dst.Files += src.Files
+	dst.Bytes += src.Bytes
+	dst.Squares += src.Squares
+}
+
+// estimate extrapolates the total of a value over all files from its sum
+// and sum of squares over the n sampled files, where files the analysis
+// left out count as zero: the sample mean times the population, with the
+// half width of its 95% confidence interval. The population is the number
+// of files the walk saw, or n/rate if it didn't count them.
+func (s *SampleStats) estimate(sum, squares float64) (total, margin float64) {
+	n := float64(s.Sampled)
+	if n == 0 {
+		return 0, 0
+	}
+	population := float64(s.Population)
+	if population < n {
+		population = n / s.Rate
+	}
+	mean := sum / n
+	var variance float64
+	if n > 1 {
+		variance = max(0, (squares-n*mean*mean)/(n-1))
+	}
+	fpc := max(0, 1-n/population)
+	return population * mean, sampleZ * population * math.Sqrt(fpc*variance/n)
+}
+
+// displaySample extrapolates the number and size of the files, overall and
+// per category, from a --sample scan.
+func displaySample(stats *Stats, result *strings.Builder) {
+	s := &stats.Sample
+	if s.Rate <= 0 {
+		return
+	}
+
+	result.WriteString(headerStyle.Render(tr("Sampling Estimates")))
+	result.WriteString("\n")
+	result.WriteString(fmt.Sprintf(tr("%s of %s files sampled (%s), estimates with 95%% confidence intervals\n"),
+		numberStyle.Render(formatCount(s.Sampled)),
+		numberStyle.Render(formatCount(max(s.Population, s.Sampled))),
+		formatPercent(s.Rate*100)))
+
+	row := func(name string, count, countMargin float64, bytes SampleSums) {
+		size, sizeMargin := s.estimate(float64(bytes.Bytes), bytes.Squares)
+		margin := ""
+		if countMargin > 0 {
+			margin = "± " + formatCount(int(math.Round(countMargin)))
+		}
+		result.WriteString(fmt.Sprintf("  %-10s %s %-12s %s %s\n",
+			name,
+			numberStyle.Render(fmt.Sprintf("%13s", formatCount(int(math.Round(count))))),
+			margin,
+			numberStyle.Render(fmt.Sprintf("%14s", formatMB(int64(size)))),
+			"± "+formatMB(int64(sizeMargin))))
+	}
+	result.WriteString(fmt.Sprintf("  %-10s %13s %-12s %14s\n", "", tr("Files"), "", tr("Size")))
+	// Counting files sums ones and zeros, whose squares sum to the count
+	// again; the count is exact when every sampled file was analyzed
+	count, margin := s.estimate(float64(s.Total.Files), float64(s.Total.Files))
+	row(tr("Total"), count, margin, s.Total)
+
+	categories := make([]string, 0, len(s.Categories))
+	for category := range s.Categories {
+		categories = append(categories, category)
+	}
+	sort.Slice(categories, func(i, j int) bool {
+		a, b := s.Categories[categories[i]], s.Categories[categories[j]]
+		if a.Bytes != b.Bytes {
+			return a.Bytes > b.Bytes
+		}
+		return categories[i] < categories[j]
+	})
+	for _, category := range categories {
+		sums := s.Categories[category]
+		count, margin := s.estimate(float64(sums.Files), float64(sums.Files))
+		row(tr(category), count, margin, *sums)
+	}
+	result.WriteString("\n")
+}
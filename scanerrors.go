@@ -0,0 +1,114 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strings"
+)
+
+// strictScan aborts the scan at the first path that can't be read, as set
+// by --strict. Otherwise such paths are skipped and listed in the report.
+var strictScan bool
+
+// maxSkippedPaths is how many skipped paths are kept for the report; the
+// rest are only counted.
+const maxSkippedPaths = 1000
+
+// SkippedPath is a file or directory the scan couldn't read.
+type SkippedPath struct {
+	Path   string
+	Reason string
+}
+
+// SkippedPaths counts the paths left out of the scan by reason and keeps
+// the first maxSkippedPaths of them.
+type SkippedPaths struct {
+	Count    int
+	ByReason map[string]int
+	Paths    []SkippedPath
+}
+
+// skipReason is the error without the operation and path a *fs.PathError
+// adds, e.g. "permission denied".
+func skipReason(err error) string {
+	var pathErr *fs.PathError
+	if errors.As(err, &pathErr) {
+		err = pathErr.Err
+	}
+	return err.Error()
+}
+
+// addSkippedPath records a path that couldn't be read. The stats must be
+// locked.
+func addSkippedPath(stats *Stats, path string, err error) {
+	skipped := &stats.Skipped
+	if skipped.ByReason == nil {
+		skipped.ByReason = make(map[string]int)
+	}
+	reason := skipReason(err)
+	skipped.Count++
+	skipped.ByReason[reason]++
+	if len(skipped.Paths) < maxSkippedPaths {
+		skipped.Paths = append(skipped.Paths, SkippedPath{path, reason})
+	}
+}
+
+func recordSkippedPath(stats *Stats, path string, err error) {
+	stats.mu.Lock()
+	defer stats.mu.Unlock()
+	addSkippedPath(stats, path, err)
+}
+
+func mergeSkippedPaths(dst, src *SkippedPaths) {
+	if src.Count == 0 {
+		return
+	}
+	if dst.ByReason == nil {
+		dst.ByReason = make(map[string]int)
+	}
+	dst.Count += src.Count
+	for reason, count := range src.ByReason {
+		dst.ByReason[reason] += count
+	}
+	dst.Paths = append(dst.Paths, src.Paths[:min(len(src.Paths), maxSkippedPaths-len(dst.Paths))]...)
+}
+
+// displaySkippedPaths lists what the scan couldn't read, so the totals of
+// the report can be judged. Workers record paths in any order, so they are
+// shown sorted.
+func displaySkippedPaths(stats *Stats, maxCount int, result *strings.Builder) {
+	skipped := stats.Skipped
+	if skipped.Count == 0 {
+		return
+	}
+
+	result.WriteString(headerStyle.Render(tr("Skipped Paths")))
+	result.WriteString("\n")
+	result.WriteString(fmt.Sprintf(tr("Could not be read: %s paths, not included in the totals\n"),
+		warnStyle.Render(formatCount(skipped.Count))))
+
+	reasons := make([]string, 0, len(skipped.ByReason))
+	for reason := range skipped.ByReason {
+		reasons = append(reasons, reason)
+	}
+	sort.Slice(reasons, func(i, j int) bool {
+		if skipped.ByReason[reasons[i]] != skipped.ByReason[reasons[j]] {
+			return skipped.ByReason[reasons[i]] > skipped.ByReason[reasons[j]]
+		}
+		return reasons[i] < reasons[j]
+	})
+	for _, reason := range reasons {
+		result.WriteString(fmt.Sprintf("  %s %s\n",
+			numberStyle.Render(fmt.Sprintf("%9s", formatCount(skipped.ByReason[reason]))),
+			warnStyle.Render(reason)))
+	}
+
+	paths := append([]SkippedPath(nil), skipped.Paths...)
+	sort.Slice(paths, func(i, j int) bool { return paths[i].Path < paths[j].Path })
+	for _, p := range paths[:min(maxCount, len(paths))] {
+		result.WriteString(fmt.Sprintf("  %s (%s)\n", renderPath(p.Path), p.Reason))
+	}
+	result.WriteString("\n")
+}
@@ -0,0 +1,305 @@
+// The Scanner API of "madaa serve". The Go code next to this file is
+// generated from it, see the go:generate line in server.go; clients in other
+// languages are generated from it the usual way.
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: scanner.proto
+
+package scannerpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Scanner_StartScan_FullMethodName      = "/madaa.scanner.v1.Scanner/StartScan"
+	Scanner_StreamProgress_FullMethodName = "/madaa.scanner.v1.Scanner/StreamProgress"
+	Scanner_GetResults_FullMethodName     = "/madaa.scanner.v1.Scanner/GetResults"
+	Scanner_CancelScan_FullMethodName     = "/madaa.scanner.v1.Scanner/CancelScan"
+	Scanner_Diff_FullMethodName           = "/madaa.scanner.v1.Scanner/Diff"
+)
+
+// ScannerClient is the client API for Scanner service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Scanner starts scans on the host running "madaa serve" and hands out their
+// progress and results. Unless the daemon listens on loopback without a
+// token, every call needs the metadata "authorization: Bearer <token>".
+type ScannerClient interface {
+	// StartScan starts scanning a directory and returns the id of the scan.
+	// It fails with RESOURCE_EXHAUSTED while --max-scans scans are running.
+	StartScan(ctx context.Context, in *StartScanRequest, opts ...grpc.CallOption) (*StartScanResponse, error)
+	// StreamProgress sends the status of a scan right away and again whenever
+	// it changes, the last time with done set.
+	StreamProgress(ctx context.Context, in *ProgressRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ScanStatus], error)
+	// GetResults returns the results of a finished scan. They are kept for an
+	// hour after the scan finished.
+	GetResults(ctx context.Context, in *ResultsRequest, opts ...grpc.CallOption) (*ScanResults, error)
+	// CancelScan stops a running scan.
+	CancelScan(ctx context.Context, in *CancelScanRequest, opts ...grpc.CallOption) (*ScanStatus, error)
+	// Diff compares two snapshots in the snapshot directory of the daemon.
+	Diff(ctx context.Context, in *DiffRequest, opts ...grpc.CallOption) (*SnapshotDiff, error)
+}
+
+type scannerClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewScannerClient(cc grpc.ClientConnInterface) ScannerClient {
+	return &scannerClient{cc}
+}
+
+func (c *scannerClient) StartScan(ctx context.Context, in *StartScanRequest, opts ...grpc.CallOption) (*StartScanResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(StartScanResponse)
+	err := c.cc.Invoke(ctx, Scanner_StartScan_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *scannerClient) StreamProgress(ctx context.Context, in *ProgressRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ScanStatus], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Scanner_ServiceDesc.Streams[0], Scanner_StreamProgress_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[ProgressRequest, ScanStatus]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Scanner_StreamProgressClient = grpc.ServerStreamingClient[ScanStatus]
+
+func (c *scannerClient) GetResults(ctx context.Context, in *ResultsRequest, opts ...grpc.CallOption) (*ScanResults, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ScanResults)
+	err := c.cc.Invoke(ctx, Scanner_GetResults_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *scannerClient) CancelScan(ctx context.Context, in *CancelScanRequest, opts ...grpc.CallOption) (*ScanStatus, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ScanStatus)
+	err := c.cc.Invoke(ctx, Scanner_CancelScan_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *scannerClient) Diff(ctx context.Context, in *DiffRequest, opts ...grpc.CallOption) (*SnapshotDiff, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SnapshotDiff)
+	err := c.cc.Invoke(ctx, Scanner_Diff_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ScannerServer is the server API for Scanner service.
+// All implementations must embed UnimplementedScannerServer
+// for forward compatibility.
+//
+// Scanner starts scans on the host running "madaa serve" and hands out their
+// progress and results. Unless the daemon listens on loopback without a
+// token, every call needs the metadata "authorization: Bearer <token>".
+type ScannerServer interface {
+	// StartScan starts scanning a directory and returns the id of the scan.
+	// It fails with RESOURCE_EXHAUSTED while --max-scans scans are running.
+	StartScan(context.Context, *StartScanRequest) (*StartScanResponse, error)
+	// StreamProgress sends the status of a scan right away and again whenever
+	// it changes, the last time with done set.
+	StreamProgress(*ProgressRequest, grpc.ServerStreamingServer[ScanStatus]) error
+	// GetResults returns the results of a finished scan. They are kept for an
+	// hour after the scan finished.
+	GetResults(context.Context, *ResultsRequest) (*ScanResults, error)
+	// CancelScan stops a running scan.
+	CancelScan(context.Context, *CancelScanRequest) (*ScanStatus, error)
+	// Diff compares two snapshots in the snapshot directory of the daemon.
+	Diff(context.Context, *DiffRequest) (*SnapshotDiff, error)
+	mustEmbedUnimplementedScannerServer()
+}
+
+// UnimplementedScannerServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedScannerServer struct{}
+
+func (UnimplementedScannerServer) StartScan(context.Context, *StartScanRequest) (*StartScanResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method StartScan not implemented")
+}
+func (UnimplementedScannerServer) StreamProgress(*ProgressRequest, grpc.ServerStreamingServer[ScanStatus]) error {
+	return status.Errorf(codes.Unimplemented, "method StreamProgress not implemented")
+}
+func (UnimplementedScannerServer) GetResults(context.Context, *ResultsRequest) (*ScanResults, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetResults not implemented")
+}
+func (UnimplementedScannerServer) CancelScan(context.Context, *CancelScanRequest) (*ScanStatus, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CancelScan not implemented")
+}
+func (UnimplementedScannerServer) Diff(context.Context, *DiffRequest) (*SnapshotDiff, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Diff not implemented")
+}
+func (UnimplementedScannerServer) mustEmbedUnimplementedScannerServer() {}
+func (UnimplementedScannerServer) testEmbeddedByValue()                 {}
+
+// UnsafeScannerServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ScannerServer will
+// result in compilation errors.
+type UnsafeScannerServer interface {
+	mustEmbedUnimplementedScannerServer()
+}
+
+func RegisterScannerServer(s grpc.ServiceRegistrar, srv ScannerServer) {
+	// If the following call pancis, it indicates UnimplementedScannerServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Scanner_ServiceDesc, srv)
+}
+
+func _Scanner_StartScan_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StartScanRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ScannerServer).StartScan(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Scanner_StartScan_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ScannerServer).StartScan(ctx, req.(*StartScanRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Scanner_StreamProgress_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ProgressRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ScannerServer).StreamProgress(m, &grpc.GenericServerStream[ProgressRequest, ScanStatus]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Scanner_StreamProgressServer = grpc.ServerStreamingServer[ScanStatus]
+
+func _Scanner_GetResults_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ResultsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ScannerServer).GetResults(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Scanner_GetResults_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ScannerServer).GetResults(ctx, req.(*ResultsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Scanner_CancelScan_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CancelScanRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ScannerServer).CancelScan(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Scanner_CancelScan_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ScannerServer).CancelScan(ctx, req.(*CancelScanRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Scanner_Diff_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DiffRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ScannerServer).Diff(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Scanner_Diff_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ScannerServer).Diff(ctx, req.(*DiffRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Scanner_ServiceDesc is the grpc.ServiceDesc for Scanner service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Scanner_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "madaa.scanner.v1.Scanner",
+	HandlerType: (*ScannerServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "StartScan",
+			Handler:    _Scanner_StartScan_Handler,
+		},
+		{
+			MethodName: "GetResults",
+			Handler:    _Scanner_GetResults_Handler,
+		},
+		{
+			MethodName: "CancelScan",
+			Handler:    _Scanner_CancelScan_Handler,
+		},
+		{
+			MethodName: "Diff",
+			Handler:    _Scanner_Diff_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamProgress",
+			Handler:       _Scanner_StreamProgress_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "scanner.proto",
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// reportSection is a part of the report, shown in the order of
+// reportSections. Sections without anything to report leave themselves out.
+type reportSection struct {
+	Name    string
+	Display func(stats *Stats, maxCount int, result *strings.Builder)
+}
+
+// withoutCount adapts the sections that don't list the top entries.
+func withoutCount(display func(*Stats, *strings.Builder)) func(*Stats, int, *strings.Builder) {
+	return func(stats *Stats, _ int, result *strings.Builder) {
+		display(stats, result)
+	}
+}
+
+var reportSections = []reportSection{
+	{"overview", displayOverview},
+	{"sample", withoutCount(displaySample)},
+	{"skipped", displaySkippedPaths},
+	{"top-level", displayTopLevelDirs},
+	{"categories", displayCategories},
+	{"types", displayTypes},
+	{"largest", displayLargest},
+	{"largest-dirs", displayLargestDirs},
+	{"sizes", displaySizes},
+	{"blocks", withoutCount(displayBlocks)},
+	{"age", displayAge},
+	{"tiers", displayTiers},
+	{"special", displaySpecialFiles},
+	{"links", displayLinks},
+	{"sharing", displaySharing},
+	{"directories", displayDirectoryInfo},
+	{"depths", displayDepths},
+	{"recent", withoutCount(displayRecent)},
+	{"owners", displayOwnerAge},
+	{"orphans", displayOrphans},
+	{"permissions", displayPermissionOutliers},
+	{"forgotten", displayForgottenDirs},
+	{"dominant", displayDominantDirs},
+	{"datasets", displayDatasets},
+	{"models", displayModels},
+	{"mail", displayMail},
+	{"archives", displayArchives},
+	{"clutter", displayClutter},
+	{"environments", displayDevEnvs},
+	{"apps", displayAppUsage},
+	{"games", displayGames},
+	{"disk-images", displayDiskImages},
+	{"databases", displayDatabases},
+	{"logs", displayLogs},
+	{"temp", displayTempFiles},
+	{"pii", displayPII},
+	{"exposure", displayExposure},
+	{"case-collisions", displayCaseCollisions},
+	{"path-lengths", displayPathLengths},
+	{"portability", displayPortability},
+	{"target", displayTarget},
+	{"retention", displayRetention},
+	{"chargeback", displayChargeback},
+	{"migration", displayMigration},
+	{"impact", withoutCount(displayCleanupImpact)},
+	{"changes", displayChangedFiles},
+}
+
+// hiddenSections are left out of the report, as chosen with --sections and
+// --hide-sections.
+var hiddenSections = map[string]bool{}
+
+func sectionNames() []string {
+	names := make([]string, len(reportSections))
+	for i, section := range reportSections {
+		names[i] = section.Name
+	}
+	return names
+}
+
+// selectSections hides every section not in show, unless show is empty,
+// and then those in hide. Both are comma separated lists of section names.
+func selectSections(show, hide string) error {
+	known := make(map[string]bool)
+	for _, name := range sectionNames() {
+		known[name] = true
+	}
+	check := func(list string) ([]string, error) {
+		names := splitList(strings.ToLower(list))
+		for _, name := range names {
+			if !known[name] {
+				return nil, fmt.Errorf("unknown section %q, available: %s", name, strings.Join(sectionNames(), ", "))
+			}
+		}
+		return names, nil
+	}
+
+	shown, err := check(show)
+	if err != nil {
+		return err
+	}
+	hidden, err := check(hide)
+	if err != nil {
+		return err
+	}
+	hiddenSections = make(map[string]bool)
+	if len(shown) > 0 {
+		for name := range known {
+			hiddenSections[name] = true
+		}
+		for _, name := range shown {
+			delete(hiddenSections, name)
+		}
+	}
+	for _, name := range hidden {
+		hiddenSections[name] = true
+	}
+	return nil
+}
@@ -0,0 +1,385 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/ini.v1"
+)
+
+// version is the version of this build, set by the release build with
+// -ldflags "-X main.version=v1.2.3".
+var version = "dev"
+
+// releaseKey is the base64 Ed25519 key madaa releases are signed with, set
+// by the release build with -ldflags "-X main.releaseKey=...". Builds
+// without it only self-update with [update] public_key configured.
+var releaseKey string
+
+// currentVersion returns version, or the module version go install
+// recorded, or "dev" for a build from a checkout.
+func currentVersion() string {
+	if version != "dev" {
+		return version
+	}
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		return info.Main.Version
+	}
+	return version
+}
+
+// defaultReleaseURL is the release metadata madaa self-update reads, in
+// the format of the GitHub releases API.
+const defaultReleaseURL = "https://api.github.com/repos/dahead/madaa/releases/latest"
+
+// The files of a release: a binary per platform, checksums.txt with their
+// SHA-256 in sha256sum format, and checksums.txt.sig, the base64 Ed25519
+// signature of checksums.txt.
+const (
+	checksumsAsset = "checksums.txt"
+	signatureAsset = "checksums.txt.sig"
+)
+
+// releaseTimeout bounds each download of madaa self-update.
+const releaseTimeout = 5 * time.Minute
+
+// Settings of the [update] config section: url replaces defaultReleaseURL,
+// e.g. with a mirror inside the fleet's network, and public_key replaces
+// releaseKey as the key the checksums must be signed with, e.g. for a
+// mirror signing its own builds.
+var (
+	releaseURL       = defaultReleaseURL
+	releasePublicKey ed25519.PublicKey
+)
+
+func loadUpdateConfig(section *ini.Section) error {
+	if url := section.Key("url").String(); url != "" {
+		releaseURL = url
+	}
+	key := section.Key("public_key").String()
+	if key == "" {
+		key = releaseKey
+	}
+	if key != "" {
+		raw, err := base64.StdEncoding.DecodeString(key)
+		if err != nil || len(raw) != ed25519.PublicKeySize {
+			return fmt.Errorf("update public_key: not a base64 Ed25519 public key")
+		}
+		releasePublicKey = raw
+	}
+	return nil
+}
+
+// parseVersion splits a version like v1.2.3 or v1.2.3-rc.1 into its
+// numbers and pre-release, ignoring build metadata after a +.
+func parseVersion(v string) (numbers [3]int, pre string, ok bool) {
+	v, _, _ = strings.Cut(strings.TrimPrefix(v, "v"), "+")
+	v, pre, _ = strings.Cut(v, "-")
+	parts := strings.Split(v, ".")
+	if len(parts) != 3 {
+		return numbers, "", false
+	}
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return numbers, "", false
+		}
+		numbers[i] = n
+	}
+	return numbers, pre, true
+}
+
+// compareVersions compares two semantic versions like v1.2.3: -1 if a is
+// older than b, 0 if they are the same, 1 if a is newer. ok is false if
+// either isn't a version, e.g. "dev".
+func compareVersions(a, b string) (cmp int, ok bool) {
+	na, prea, oka := parseVersion(a)
+	nb, preb, okb := parseVersion(b)
+	if !oka || !okb {
+		return 0, false
+	}
+	for i := range na {
+		if na[i] != nb[i] {
+			return compareInts(na[i], nb[i]), true
+		}
+	}
+	// A pre-release comes before its release
+	switch {
+	case prea == preb:
+		return 0, true
+	case prea == "":
+		return 1, true
+	case preb == "":
+		return -1, true
+	}
+	ida, idb := strings.Split(prea, "."), strings.Split(preb, ".")
+	for i := 0; i < min(len(ida), len(idb)); i++ {
+		if ida[i] == idb[i] {
+			continue
+		}
+		x, errx := strconv.Atoi(ida[i])
+		y, erry := strconv.Atoi(idb[i])
+		switch {
+		case errx == nil && erry == nil:
+			return compareInts(x, y), true
+		case errx == nil:
+			// Numeric identifiers come before alphanumeric ones
+			return -1, true
+		case erry == nil:
+			return 1, true
+		}
+		return strings.Compare(ida[i], idb[i]), true
+	}
+	return compareInts(len(ida), len(idb)), true
+}
+
+func compareInts(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// Release is the part of the release metadata self-update needs.
+type Release struct {
+	Tag    string `json:"tag_name"`
+	Assets []struct {
+		Name string `json:"name"`
+		URL  string `json:"browser_download_url"`
+	} `json:"assets"`
+}
+
+// asset returns the download URL of the file called name.
+func (r *Release) asset(name string) (string, bool) {
+	for _, asset := range r.Assets {
+		if asset.Name == name {
+			return asset.URL, true
+		}
+	}
+	return "", false
+}
+
+// binaryAsset is the name of the release binary for this platform, e.g.
+// madaa_linux_amd64 or madaa_windows_amd64.exe.
+func binaryAsset() string {
+	name := fmt.Sprintf("madaa_%s_%s", runtime.GOOS, runtime.GOARCH)
+	if runtime.GOOS == "windows" {
+		name += ".exe"
+	}
+	return name
+}
+
+// releaseChecksum returns the SHA-256 that checksums lists for name.
+func releaseChecksum(checksums []byte, name string) ([]byte, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(checksums))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == name {
+			return hex.DecodeString(fields[0])
+		}
+	}
+	return nil, fmt.Errorf("%s has no checksum for %s", checksumsAsset, name)
+}
+
+// signedChecksums downloads the checksums of the release and verifies them
+// against their signature by the release key. The checksums name the
+// version they belong to in a "version v1.2.3" line, which is returned; it
+// has to match the tag, as the release metadata itself isn't signed and a
+// mirror could otherwise pass an old signed release off as a new one.
+func signedChecksums(release *Release) (checksums []byte, version string, err error) {
+	if releasePublicKey == nil {
+		return nil, "", fmt.Errorf("this build has no release key to verify the download with, set public_key in the [update] section")
+	}
+	checksumsURL, ok := release.asset(checksumsAsset)
+	if !ok {
+		return nil, "", fmt.Errorf("release %s has no %s", release.Tag, checksumsAsset)
+	}
+	signatureURL, ok := release.asset(signatureAsset)
+	if !ok {
+		return nil, "", fmt.Errorf("release %s is not signed", release.Tag)
+	}
+	checksums, err = fetchURL(checksumsURL, releaseTimeout)
+	if err != nil {
+		return nil, "", err
+	}
+	encoded, err := fetchURL(signatureURL, releaseTimeout)
+	if err != nil {
+		return nil, "", err
+	}
+	signature, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(encoded)))
+	if err != nil || !ed25519.Verify(releasePublicKey, checksums, signature) {
+		return nil, "", fmt.Errorf("the signature of %s doesn't match the release key", checksumsAsset)
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(checksums))
+	for scanner.Scan() {
+		if fields := strings.Fields(scanner.Text()); len(fields) == 2 && fields[0] == "version" {
+			version = fields[1]
+		}
+	}
+	if version == "" {
+		return nil, "", fmt.Errorf("%s of release %s names no version", checksumsAsset, release.Tag)
+	}
+	if cmp, ok := compareVersions(version, release.Tag); !ok || cmp != 0 {
+		return nil, "", fmt.Errorf("release %s is signed as version %s", release.Tag, version)
+	}
+	return checksums, version, nil
+}
+
+// downloadRelease downloads the binary for this platform and verifies it
+// against the signed checksums.
+func downloadRelease(release *Release, checksums []byte) ([]byte, error) {
+	name := binaryAsset()
+	binaryURL, ok := release.asset(name)
+	if !ok {
+		return nil, fmt.Errorf("release %s has no binary for %s/%s", release.Tag, runtime.GOOS, runtime.GOARCH)
+	}
+	want, err := releaseChecksum(checksums, name)
+	if err != nil {
+		return nil, err
+	}
+	binary, err := fetchURL(binaryURL, releaseTimeout)
+	if err != nil {
+		return nil, err
+	}
+	if sum := sha256.Sum256(binary); !bytes.Equal(sum[:], want) {
+		return nil, fmt.Errorf("%s doesn't match its checksum, the download may be corrupt", name)
+	}
+	return binary, nil
+}
+
+// replaceExecutable puts binary in place of the executable at path. The
+// new file is written next to it under a fresh temporary name and renamed
+// over it, so an interrupted update leaves the old one working. Windows
+// doesn't allow replacing a running executable, but renaming it, so there
+// it is moved aside to path.old first.
+func replaceExecutable(path string, binary []byte) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".new-*")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	_, err = f.Write(binary)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmp, info.Mode().Perm())
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if runtime.GOOS == "windows" {
+		old := path + ".old"
+		os.Remove(old)
+		if err := os.Rename(path, old); err != nil {
+			os.Remove(tmp)
+			return err
+		}
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// runSelfUpdate implements "madaa self-update": it replaces the running
+// binary with the one of the latest release, for machines without a
+// package manager.
+func runSelfUpdate(args []string) {
+	flags := flag.NewFlagSet("self-update", flag.ExitOnError)
+	check := flags.Bool("check", false, "Only report whether a newer release is available")
+	force := flags.Bool("force", false, "Install the latest release even if it isn't newer than the version running, or this is a development build")
+	selectLanguage := localeFlags(flags)
+	flags.Parse(args)
+	selectLanguage()
+
+	if err := loadConfig(); err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+
+	data, err := fetchURL(releaseURL, releaseTimeout)
+	if err != nil {
+		fmt.Printf("Error checking for updates: %v\n", err)
+		os.Exit(1)
+	}
+	var release Release
+	if err := json.Unmarshal(data, &release); err != nil || release.Tag == "" {
+		fmt.Printf("Error checking for updates: %s is not release metadata\n", releaseURL)
+		os.Exit(1)
+	}
+
+	// Only the signed version counts, not the tag of the metadata
+	checksums, latest, err := signedChecksums(&release)
+	if err != nil {
+		fmt.Printf("Error checking for updates: %v\n", err)
+		os.Exit(1)
+	}
+	current := currentVersion()
+	cmp, ok := compareVersions(latest, current)
+	switch {
+	case !ok:
+		fmt.Printf(tr("madaa %s is available, this is a development build (%s)\n"), latest, current)
+		if !*force && !*check {
+			fmt.Println(tr("Use --force to replace it with the release."))
+			os.Exit(1)
+		}
+	case cmp == 0 && !*force:
+		fmt.Printf(tr("madaa %s is the latest release\n"), current)
+		return
+	case cmp < 0 && !*force:
+		fmt.Printf(tr("The latest release %s is older than this version %s; use --force to downgrade.\n"), latest, current)
+		if *check {
+			return
+		}
+		os.Exit(1)
+	case cmp > 0 && *check:
+		fmt.Printf(tr("madaa %s is available, this is %s\n"), latest, current)
+	}
+	if *check {
+		return
+	}
+
+	exe, err := os.Executable()
+	if err == nil {
+		exe, err = filepath.EvalSymlinks(exe)
+	}
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	binary, err := downloadRelease(&release, checksums)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	if err := replaceExecutable(exe, binary); err != nil {
+		fmt.Printf("Error replacing %s: %v\n", exe, err)
+		os.Exit(1)
+	}
+	fmt.Printf(tr("Updated %s from %s to %s; restart running madaa serve and agent processes to use it\n"), exe, current, latest)
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCompareVersions(t *testing.T) {
+	for _, tt := range []struct {
+		a, b string
+		cmp  int
+		ok   bool
+	}{
+		{"v1.2.3", "v1.2.3", 0, true},
+		{"v1.2.4", "v1.2.3", 1, true},
+		{"v1.10.0", "v1.9.9", 1, true},
+		{"v1.2.3", "v2.0.0", -1, true},
+		{"1.2.3", "v1.2.3+build.7", 0, true},
+		{"v1.2.3-rc.1", "v1.2.3", -1, true},
+		{"v1.2.3-rc.2", "v1.2.3-rc.10", -1, true},
+		{"v1.2.3-beta", "v1.2.3-alpha", 1, true},
+		{"v1.2.3-rc.1", "v1.2.3-rc.1.1", -1, true},
+		{"v1.2.3", "dev", 0, false},
+		{"latest", "v1.2.3", 0, false},
+	} {
+		cmp, ok := compareVersions(tt.a, tt.b)
+		if cmp != tt.cmp || ok != tt.ok {
+			t.Errorf("compareVersions(%q, %q) = %d, %v, want %d, %v", tt.a, tt.b, cmp, ok, tt.cmp, tt.ok)
+		}
+	}
+}
+
+// serveRelease serves a release of binary tagged tag, with checksums naming
+// version and signed by key unless it is nil.
+func serveRelease(t *testing.T, binary []byte, tag, version string, key ed25519.PrivateKey) *Release {
+	t.Helper()
+	sum := sha256.Sum256(binary)
+	checksums := fmt.Sprintf("version %s\n%s  %s\n", version, hex.EncodeToString(sum[:]), binaryAsset())
+	files := map[string]string{
+		binaryAsset():  string(binary),
+		checksumsAsset: checksums,
+	}
+	if key != nil {
+		files[signatureAsset] = base64.StdEncoding.EncodeToString(ed25519.Sign(key, []byte(checksums)))
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, ok := files[strings.TrimPrefix(r.URL.Path, "/")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(data))
+	}))
+	t.Cleanup(server.Close)
+
+	release := &Release{Tag: tag}
+	for name := range files {
+		release.Assets = append(release.Assets, struct {
+			Name string `json:"name"`
+			URL  string `json:"browser_download_url"`
+		}{name, server.URL + "/" + name})
+	}
+	return release
+}
+
+// fetchRelease verifies and downloads the release like madaa self-update.
+func fetchRelease(release *Release) ([]byte, string, error) {
+	checksums, version, err := signedChecksums(release)
+	if err != nil {
+		return nil, "", err
+	}
+	binary, err := downloadRelease(release, checksums)
+	return binary, version, err
+}
+
+func TestDownloadReleaseVerifiesSignature(t *testing.T) {
+	defer func(key ed25519.PublicKey) { releasePublicKey = key }(releasePublicKey)
+	public, private, _ := ed25519.GenerateKey(nil)
+	_, other, _ := ed25519.GenerateKey(nil)
+	binary := []byte("new madaa")
+
+	releasePublicKey = nil
+	if _, _, err := fetchRelease(serveRelease(t, binary, "v1.2.3", "v1.2.3", private)); err == nil {
+		t.Error("downloaded without a release key")
+	}
+
+	releasePublicKey = public
+	data, version, err := fetchRelease(serveRelease(t, binary, "v1.2.3", "v1.2.3", private))
+	if err != nil || string(data) != string(binary) || version != "v1.2.3" {
+		t.Errorf("signed release: %q, %s, %v", data, version, err)
+	}
+	if _, _, err := fetchRelease(serveRelease(t, binary, "v1.2.3", "v1.2.3", nil)); err == nil {
+		t.Error("downloaded an unsigned release")
+	}
+	if _, _, err := fetchRelease(serveRelease(t, binary, "v1.2.3", "v1.2.3", other)); err == nil {
+		t.Error("downloaded a release signed by another key")
+	}
+}
+
+func TestSignedChecksumsRejectsRetaggedRelease(t *testing.T) {
+	defer func(key ed25519.PublicKey) { releasePublicKey = key }(releasePublicKey)
+	public, private, _ := ed25519.GenerateKey(nil)
+	releasePublicKey = public
+
+	// An old, validly signed release passed off as a newer one
+	if _, _, err := signedChecksums(serveRelease(t, []byte("old madaa"), "v9.0.0", "v1.0.0", private)); err == nil {
+		t.Error("accepted a release whose tag isn't the signed version")
+	}
+}
+
+func TestReplaceExecutable(t *testing.T) {
+	dir := t.TempDir()
+	exe := filepath.Join(dir, "madaa")
+	if err := os.WriteFile(exe, []byte("old"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	// Left by someone else, not to be touched
+	if err := os.WriteFile(exe+".new", []byte("other"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := replaceExecutable(exe, []byte("new")); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(exe)
+	if err != nil || readFile(t, exe) != "new" || info.Mode().Perm() != 0755 {
+		t.Errorf("executable not replaced with its mode kept: %v", err)
+	}
+	if readFile(t, exe+".new") != "other" {
+		t.Error("madaa.new was overwritten")
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 2 {
+		t.Errorf("%d files left, want 2", len(entries))
+	}
+}
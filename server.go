@@ -0,0 +1,494 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"crypto/tls"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"madaa/scannerpb"
+)
+
+//go:generate protoc -I scannerpb --go_out=scannerpb --go_opt=paths=source_relative --go-grpc_out=scannerpb --go-grpc_opt=paths=source_relative scanner.proto
+
+type ScanStatus struct {
+	ID        int
+	Path      string
+	Processed int
+	Total     int
+	Done      bool
+	Err       string
+}
+
+func (s ScanStatus) message() *scannerpb.ScanStatus {
+	return &scannerpb.ScanStatus{
+		Id:        int64(s.ID),
+		Path:      s.Path,
+		Processed: int64(s.Processed),
+		Total:     int64(s.Total),
+		Done:      s.Done,
+		Error:     s.Err,
+	}
+}
+
+// Scanner implements the Scanner gRPC service of "madaa serve", see
+// scannerpb/scanner.proto.
+type Scanner struct {
+	scannerpb.UnimplementedScannerServer
+
+	// snapshotDir holds the snapshots Diff compares; it reads no others
+	snapshotDir string
+	// maxScans is how many scans may run at the same time
+	maxScans int
+
+	mu      sync.Mutex
+	nextID  int
+	running int
+	scans   map[int]*scanJob
+}
+
+type scanJob struct {
+	status   ScanStatus
+	stats    *Stats
+	cancel   context.CancelFunc
+	changed  chan struct{}
+	finished time.Time
+}
+
+// scanRetention is how long the results of a finished scan are kept for
+// GetResults before the scan is forgotten.
+const scanRetention = time.Hour
+
+func newScanner(snapshotDir string, maxScans int) *Scanner {
+	return &Scanner{snapshotDir: snapshotDir, maxScans: maxScans, scans: make(map[int]*scanJob)}
+}
+
+// update applies fn to the job's status and wakes up waiting progress calls.
+func (s *Scanner) update(job *scanJob, fn func(status *ScanStatus)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fn(&job.status)
+	close(job.changed)
+	job.changed = make(chan struct{})
+}
+
+func (s *Scanner) StartScan(ctx context.Context, req *scannerpb.StartScanRequest) (*scannerpb.StartScanResponse, error) {
+	count := int(req.Count)
+	if count <= 0 {
+		count = 3
+	}
+	config := Config{Count: count, Path: req.Path, SkipMounts: mountSkips(req.Path)}
+	if req.Filter != "" {
+		filter, err := ParseFilter(req.Filter)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		config.Filter = filter
+	}
+	if _, err := os.Stat(req.Path); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	s.mu.Lock()
+	s.evictScans(time.Now())
+	if s.running >= s.maxScans {
+		s.mu.Unlock()
+		return nil, status.Errorf(codes.ResourceExhausted, "%d scans are running, the limit is %d", s.running, s.maxScans)
+	}
+	// The scan outlives the call that started it, so its context doesn't
+	// derive from ctx; CancelScan ends it
+	scanCtx, cancel := context.WithCancel(context.Background())
+	s.running++
+	s.nextID++
+	job := &scanJob{
+		status:  ScanStatus{ID: s.nextID, Path: req.Path},
+		cancel:  cancel,
+		changed: make(chan struct{}),
+	}
+	s.scans[job.status.ID] = job
+	s.mu.Unlock()
+
+	go s.run(scanCtx, job, config)
+	return &scannerpb.StartScanResponse{Id: int64(job.status.ID)}, nil
+}
+
+// run scans for job until the scan is done or ctx is canceled.
+func (s *Scanner) run(ctx context.Context, job *scanJob, config Config) {
+	defer job.cancel()
+
+	events := newEventBus()
+	updates := events.Subscribe()
+	drained := make(chan struct{})
+	go func() {
+		defer close(drained)
+		for event := range updates {
+			if event.Kind != eventProgress {
+				continue
+			}
+			s.update(job, func(status *ScanStatus) {
+				status.Processed = event.Processed
+				status.Total = event.Total
+			})
+		}
+	}()
+
+	stats, err := analyzeDirectory(ctx, config, events)
+	// Let late progress events land before the final status
+	<-drained
+	s.update(job, func(status *ScanStatus) {
+		s.running--
+		job.finished = time.Now()
+		status.Done = true
+		if ctx.Err() != nil {
+			status.Err = "canceled"
+			return
+		}
+		job.stats = stats
+		if err != nil {
+			status.Err = err.Error()
+		}
+		if stats != nil {
+			status.Processed = stats.TotalFiles
+			status.Total = max(status.Total, stats.TotalFiles)
+		}
+	})
+}
+
+// evictScans forgets the scans that finished more than scanRetention ago, so
+// a long-running daemon doesn't keep the stats of every scan. s.mu must be
+// held.
+func (s *Scanner) evictScans(now time.Time) {
+	for id, job := range s.scans {
+		if job.status.Done && now.Sub(job.finished) > scanRetention {
+			delete(s.scans, id)
+		}
+	}
+}
+
+func (s *Scanner) StreamProgress(req *scannerpb.ProgressRequest, stream grpc.ServerStreamingServer[scannerpb.ScanStatus]) error {
+	for {
+		s.mu.Lock()
+		job, ok := s.scans[int(req.Id)]
+		if !ok {
+			s.mu.Unlock()
+			return status.Errorf(codes.NotFound, "unknown scan %d", req.Id)
+		}
+		current := job.status
+		changed := job.changed
+		s.mu.Unlock()
+
+		if err := stream.Send(current.message()); err != nil {
+			return err
+		}
+		if current.Done {
+			return nil
+		}
+		select {
+		case <-changed:
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		}
+	}
+}
+
+func (s *Scanner) CancelScan(ctx context.Context, req *scannerpb.CancelScanRequest) (*scannerpb.ScanStatus, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.scans[int(req.Id)]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "unknown scan %d", req.Id)
+	}
+	job.cancel()
+	return job.status.message(), nil
+}
+
+func (s *Scanner) GetResults(ctx context.Context, req *scannerpb.ResultsRequest) (*scannerpb.ScanResults, error) {
+	s.mu.Lock()
+	job, ok := s.scans[int(req.Id)]
+	if !ok {
+		s.mu.Unlock()
+		return nil, status.Errorf(codes.NotFound, "unknown scan %d", req.Id)
+	}
+	current, stats := job.status, job.stats
+	s.mu.Unlock()
+
+	if !current.Done {
+		return nil, status.Errorf(codes.FailedPrecondition, "scan %d is still running", req.Id)
+	}
+	if stats == nil {
+		return nil, status.Errorf(codes.FailedPrecondition, "scan %d failed: %s", req.Id, current.Err)
+	}
+
+	// The scan is finished, so its stats are no longer written to
+	results := resultsMessage(newExport(stats, current.Path))
+	results.Id = req.Id
+	return results, nil
+}
+
+// Diff compares two snapshots in the snapshot directory.
+func (s *Scanner) Diff(ctx context.Context, req *scannerpb.DiffRequest) (*scannerpb.SnapshotDiff, error) {
+	ignore, err := diffIgnoreFilter(req.Ignore)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	var snaps [2]*Snapshot
+	for i, name := range []string{req.Old, req.New} {
+		path, err := s.snapshotPath(name)
+		if err != nil {
+			return nil, err
+		}
+		if snaps[i], err = loadSnapshot(path); err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+	}
+	return diffMessage(diffSnapshots(snaps[0], snaps[1], DiffOptions{Ignore: ignore, MinChange: req.MinChange})), nil
+}
+
+// snapshotPath resolves the name of a snapshot in the snapshot directory.
+// Names leading out of it, directly or through symlinks, are refused, so
+// clients can't have the daemon open other files.
+func (s *Scanner) snapshotPath(name string) (string, error) {
+	if !filepath.IsLocal(name) {
+		return "", status.Errorf(codes.InvalidArgument, "%q is not the name of a snapshot in the snapshot directory", name)
+	}
+	dir, err := filepath.EvalSymlinks(s.snapshotDir)
+	if err != nil {
+		return "", status.Errorf(codes.FailedPrecondition, "snapshot directory: %v", err)
+	}
+	path, err := filepath.EvalSymlinks(filepath.Join(dir, name))
+	if err != nil {
+		return "", status.Errorf(codes.NotFound, "snapshot %s: %v", name, err)
+	}
+	if !withinDir(path, dir) {
+		return "", status.Errorf(codes.InvalidArgument, "%q is not the name of a snapshot in the snapshot directory", name)
+	}
+	return path, nil
+}
+
+func timestamp(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
+
+func resultsMessage(export *Export) *scannerpb.ScanResults {
+	results := &scannerpb.ScanResults{
+		Host:      export.Host,
+		Root:      export.Root,
+		ScannedAt: timestamp(export.ScannedAt),
+		Totals: &scannerpb.Totals{
+			Files:          int64(export.Totals.Files),
+			Dirs:           int64(export.Totals.Dirs),
+			Bytes:          export.Totals.Bytes,
+			EmptyFiles:     int64(export.Totals.EmptyFiles),
+			EmptyDirs:      int64(export.Totals.EmptyDirs),
+			HiddenFiles:    int64(export.Totals.HiddenFiles),
+			SystemFiles:    int64(export.Totals.SystemFiles),
+			Symlinks:       int64(export.Totals.Symlinks),
+			WriteProtected: int64(export.Totals.WriteProtected),
+			StaleFiles:     int64(export.Totals.StaleFiles),
+			RecentChanges:  int64(export.Totals.RecentChanges),
+		},
+		SampleRate: export.SampleRate,
+	}
+	for _, c := range export.Categories {
+		results.Categories = append(results.Categories, &scannerpb.Count{Name: c.Name, Files: int64(c.Files)})
+	}
+	for _, t := range export.Types {
+		results.Types = append(results.Types, &scannerpb.FileType{Extension: t.Extension, Category: t.Category, Files: int64(t.Files), Bytes: t.Bytes})
+	}
+	for _, c := range export.SizeDistribution {
+		results.SizeDistribution = append(results.SizeDistribution, &scannerpb.Count{Name: c.Name, Files: int64(c.Files)})
+	}
+	for _, y := range export.Years {
+		results.Years = append(results.Years, &scannerpb.Year{Year: int32(y.Year), Files: int64(y.Files)})
+	}
+	for _, f := range export.LargestFiles {
+		results.LargestFiles = append(results.LargestFiles, &scannerpb.File{Path: f.Path, Bytes: f.Bytes})
+	}
+	if f := export.OldestFile; f != nil {
+		results.OldestFile = &scannerpb.FileAge{Path: f.Path, Modified: timestamp(f.Modified)}
+	}
+	if f := export.NewestFile; f != nil {
+		results.NewestFile = &scannerpb.FileAge{Path: f.Path, Modified: timestamp(f.Modified)}
+	}
+	return results
+}
+
+func diffMessage(diff *SnapshotDiff) *scannerpb.SnapshotDiff {
+	changes := func(changes []FileChange) []*scannerpb.FileChange {
+		var list []*scannerpb.FileChange
+		for _, c := range changes {
+			list = append(list, &scannerpb.FileChange{
+				Path:       c.Path,
+				OldSize:    c.OldSize,
+				NewSize:    c.NewSize,
+				OldModTime: timestamp(c.OldModTime),
+				NewModTime: timestamp(c.NewModTime),
+			})
+		}
+		return list
+	}
+	message := &scannerpb.SnapshotDiff{
+		Root:        diff.Root,
+		OldCreated:  timestamp(diff.OldCreated),
+		NewCreated:  timestamp(diff.NewCreated),
+		OldLabel:    diff.OldLabel,
+		NewLabel:    diff.NewLabel,
+		Added:       changes(diff.Added),
+		Removed:     changes(diff.Removed),
+		Changed:     changes(diff.Changed),
+		AddedDirs:   int64(diff.AddedDirs),
+		RemovedDirs: int64(diff.RemovedDirs),
+		Stale:       changes(diff.Stale),
+		Compact:     diff.Compact,
+	}
+	for _, d := range diff.Drift {
+		message.Drift = append(message.Drift, &scannerpb.PermissionDrift{Path: d.Path, Mode: uint32(d.Mode), Norm: uint32(d.Norm), Files: int64(d.Files)})
+	}
+	for _, m := range diff.Moved {
+		message.Moved = append(message.Moved, &scannerpb.FileMove{From: m.From, To: m.To, Size: m.Size})
+	}
+	return message
+}
+
+// checkToken accepts calls whose metadata carries "authorization: Bearer
+// <token>".
+func checkToken(ctx context.Context, token string) error {
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, value := range md.Get("authorization") {
+		sent, ok := strings.CutPrefix(value, "Bearer ")
+		if ok && subtle.ConstantTimeCompare([]byte(sent), []byte(token)) == 1 {
+			return nil
+		}
+	}
+	return status.Error(codes.Unauthenticated, "missing or wrong token")
+}
+
+// tokenInterceptors reject every call that doesn't carry the token.
+func tokenInterceptors(token string) []grpc.ServerOption {
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+			if err := checkToken(ctx, token); err != nil {
+				return nil, err
+			}
+			return handler(ctx, req)
+		}),
+		grpc.ChainStreamInterceptor(func(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			if err := checkToken(stream.Context(), token); err != nil {
+				return err
+			}
+			return handler(srv, stream)
+		}),
+	}
+}
+
+// serverTLS loads the certificate to listen on address with. Off loopback
+// one is required, so that tokens and results don't cross the network in
+// clear; on loopback without one, the config is nil.
+func serverTLS(address, certFile, keyFile string) (*tls.Config, error) {
+	if certFile == "" && keyFile == "" {
+		if !isLoopback(address) {
+			return nil, errors.New("serving on a non-loopback address needs TLS, see --tls-cert and --tls-key")
+		}
+		return nil, nil
+	}
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+	return &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}, nil
+}
+
+// isLoopback tells whether the listen address only accepts connections from
+// this host.
+func isLoopback(address string) bool {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// runServe implements "madaa serve", the long-running daemon other services
+// use to start scans and fetch results without shelling out.
+func runServe(args []string) {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	listen := flags.String("listen", "127.0.0.1:7071", "Address to serve the Scanner API on")
+	token := flags.String("token", "", "Token clients must send as \"authorization: Bearer TOKEN\" metadata (default: $MADAA_SERVE_TOKEN)")
+	certFile := flags.String("tls-cert", "", "TLS certificate file, required off loopback")
+	keyFile := flags.String("tls-key", "", "TLS key file of --tls-cert")
+	snapshotDir := flags.String("snapshot-dir", "", "Directory of the snapshots Diff may compare (default: snapshots in madaa's data directory)")
+	maxScans := flags.Int("max-scans", 2, "Number of scans that may run at the same time")
+	flags.Parse(args)
+
+	if *token == "" {
+		*token = os.Getenv("MADAA_SERVE_TOKEN")
+	}
+	if *token == "" && !isLoopback(*listen) {
+		fmt.Println("Error: serving on a non-loopback address needs a token, see --token")
+		os.Exit(1)
+	}
+	tlsConfig, err := serverTLS(*listen, *certFile, *keyFile)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	if *snapshotDir == "" {
+		dir, err := madaaDataDir()
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		*snapshotDir = filepath.Join(dir, "snapshots")
+	}
+	if err := loadConfig(); err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+
+	var options []grpc.ServerOption
+	if *token != "" {
+		options = append(options, tokenInterceptors(*token)...)
+	}
+	if tlsConfig != nil {
+		options = append(options, grpc.Creds(credentials.NewTLS(tlsConfig)))
+	}
+	server := grpc.NewServer(options...)
+	scannerpb.RegisterScannerServer(server, newScanner(*snapshotDir, *maxScans))
+
+	listener, err := net.Listen("tcp", *listen)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Serving Scanner API on %s\n", listener.Addr())
+	if quarantineRoot != "" {
+		go runQuarantinePurger(quarantineRoot, time.Duration(quarantineDays)*24*time.Hour, time.Hour)
+	}
+	if err := server.Serve(listener); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"madaa/scannerpb"
+)
+
+// serveScanner serves scanner with the token on a loopback port and returns
+// a client sending the token.
+func serveScanner(t *testing.T, scanner *Scanner, token string) (scannerpb.ScannerClient, context.Context) {
+	t.Helper()
+	server := grpc.NewServer(tokenInterceptors(token)...)
+	scannerpb.RegisterScannerServer(server, scanner)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient(listener.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+token)
+	return scannerpb.NewScannerClient(conn), ctx
+}
+
+func TestScannerAPI(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.txt", "b.go"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	client, ctx := serveScanner(t, newScanner(t.TempDir(), 1), "secret")
+
+	wrong := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer guess")
+	if _, err := client.StartScan(wrong, &scannerpb.StartScanRequest{Path: dir}); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("call with the wrong token: %v", err)
+	}
+
+	started, err := client.StartScan(ctx, &scannerpb.StartScanRequest{Path: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream, err := client.StreamProgress(ctx, &scannerpb.ProgressRequest{Id: started.Id})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var last *scannerpb.ScanStatus
+	for {
+		update, err := stream.Recv()
+		if err != nil {
+			break
+		}
+		last = update
+	}
+	if last == nil || !last.Done || last.Error != "" {
+		t.Fatalf("last status %v", last)
+	}
+
+	results, err := client.GetResults(ctx, &scannerpb.ResultsRequest{Id: started.Id})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results.Totals.Files != 2 || len(results.Types) != 2 {
+		t.Errorf("results: %v", results)
+	}
+	if _, err := client.GetResults(ctx, &scannerpb.ResultsRequest{Id: 99}); status.Code(err) != codes.NotFound {
+		t.Errorf("results of an unknown scan: %v", err)
+	}
+}
+
+func TestStartScanLimit(t *testing.T) {
+	s := newScanner(t.TempDir(), 1)
+	s.running = 1
+	_, err := s.StartScan(context.Background(), &scannerpb.StartScanRequest{Path: t.TempDir()})
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("scan over the limit: %v", err)
+	}
+}
+
+func TestCancelScan(t *testing.T) {
+	s := newScanner(t.TempDir(), 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	s.scans[1] = &scanJob{status: ScanStatus{ID: 1}, cancel: cancel, changed: make(chan struct{})}
+	if _, err := s.CancelScan(context.Background(), &scannerpb.CancelScanRequest{Id: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if ctx.Err() == nil {
+		t.Error("scan context not canceled")
+	}
+}
+
+func TestDiffStaysInSnapshotDir(t *testing.T) {
+	snapshots, outside := t.TempDir(), t.TempDir()
+	tree := t.TempDir()
+	snap, err := takeSnapshot(context.Background(), tree, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{filepath.Join(snapshots, "old.madaa"), filepath.Join(snapshots, "new.madaa"), filepath.Join(outside, "other.madaa")} {
+		if err := saveSnapshot(path, snap); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink(filepath.Join(outside, "other.madaa"), filepath.Join(snapshots, "link.madaa")); err != nil {
+		t.Fatal(err)
+	}
+
+	s := newScanner(snapshots, 1)
+	if _, err := s.Diff(context.Background(), &scannerpb.DiffRequest{Old: "old.madaa", New: "new.madaa"}); err != nil {
+		t.Errorf("diff of snapshots in the directory: %v", err)
+	}
+	for _, name := range []string{filepath.Join(outside, "other.madaa"), "../" + filepath.Base(outside) + "/other.madaa", "link.madaa"} {
+		_, err := s.Diff(context.Background(), &scannerpb.DiffRequest{Old: "old.madaa", New: name})
+		if status.Code(err) != codes.InvalidArgument {
+			t.Errorf("diff against %s: %v", name, err)
+		}
+	}
+}
+
+func TestServerTLS(t *testing.T) {
+	if config, err := serverTLS("127.0.0.1:7071", "", ""); config != nil || err != nil {
+		t.Errorf("loopback without certificate: %v %v", config, err)
+	}
+	if _, err := serverTLS(":7071", "", ""); err == nil {
+		t.Error("non-loopback address without certificate accepted")
+	}
+}
+
+func TestEvictScans(t *testing.T) {
+	now := time.Now()
+	s := newScanner("", 1)
+	s.scans[1] = &scanJob{status: ScanStatus{Done: true}, finished: now.Add(-2 * scanRetention)}
+	s.scans[2] = &scanJob{status: ScanStatus{Done: true}, finished: now.Add(-time.Minute)}
+	s.scans[3] = &scanJob{}
+	s.evictScans(now)
+	if _, ok := s.scans[1]; ok || len(s.scans) != 2 {
+		t.Errorf("scans left: %v", s.scans)
+	}
+}
+
+func TestIsLoopback(t *testing.T) {
+	for address, want := range map[string]bool{
+		"127.0.0.1:7071": true,
+		"[::1]:7071":     true,
+		"localhost:7071": true,
+		":7071":          false,
+		"0.0.0.0:7071":   false,
+		"10.0.0.5:7071":  false,
+	} {
+		if got := isLoopback(address); got != want {
+			t.Errorf("isLoopback(%q) = %v, want %v", address, got, want)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// HardLink is a file with more than one name. Dirs holds the directory of
+// each name seen by the scan; Links is the link count of the inode, which
+// also counts names outside the scanned tree.
+type HardLink struct {
+	Size  int64
+	Links uint64
+	Dirs  []string
+}
+
+// analyzeHardLink records the names of files with several hardlinks, to
+// tell the bytes a directory has to itself from those it shares.
+func analyzeHardLink(path string, info os.FileInfo, stats *Stats) {
+	id, links, ok := fileIdentity(info)
+	if !ok || links < 2 || !info.Mode().IsRegular() {
+		return
+	}
+	link := stats.HardLinks[id]
+	if link == nil {
+		link = &HardLink{Size: info.Size(), Links: links}
+		stats.HardLinks[id] = link
+	}
+	link.Dirs = append(link.Dirs, filepath.Dir(path))
+}
+
+func mergeHardLinks(dst, src map[fileID]*HardLink) {
+	for id, link := range src {
+		if dst[id] == nil {
+			dst[id] = &HardLink{Size: link.Size, Links: link.Links}
+		}
+		dst[id].Dirs = append(dst[id].Dirs, link.Dirs...)
+	}
+}
+
+// dirSharing is the size of a directory tree split like btrfs filesystem du
+// does: Exclusive bytes are freed by deleting the tree, Shared bytes are
+// hardlinked from outside it and stay. Each file counts once, however many
+// names it has in the tree.
+type dirSharing struct {
+	Path      string
+	Exclusive int64
+	Shared    int64
+}
+
+func (d dirSharing) Total() int64 {
+	return d.Exclusive + d.Shared
+}
+
+// dirSharings returns the directories sharing bytes with other directories,
+// largest first. A file is exclusive to a directory when all its links are
+// in the directory's tree.
+func dirSharings(stats *Stats) []dirSharing {
+	if len(stats.HardLinks) == 0 {
+		return nil
+	}
+	trees := dirTrees(stats)
+
+	type linkedBytes struct {
+		counted   int64 // the bytes of every name, as in the tree size
+		exclusive int64
+		shared    int64
+	}
+	dirs := make(map[string]*linkedBytes)
+	for _, link := range stats.HardLinks {
+		names := make(map[string]uint64)
+		for _, dir := range link.Dirs {
+			for p := dir; trees[p] != nil; p = filepath.Dir(p) {
+				names[p]++
+				if p == filepath.Dir(p) {
+					break
+				}
+			}
+		}
+		for dir, n := range names {
+			bytes := dirs[dir]
+			if bytes == nil {
+				bytes = &linkedBytes{}
+				dirs[dir] = bytes
+			}
+			bytes.counted += int64(n) * link.Size
+			if n >= link.Links {
+				bytes.exclusive += link.Size
+			} else {
+				bytes.shared += link.Size
+			}
+		}
+	}
+
+	var sharings []dirSharing
+	for dir, bytes := range dirs {
+		if bytes.shared == 0 {
+			continue
+		}
+		sharings = append(sharings, dirSharing{
+			Path:      dir,
+			Exclusive: trees[dir].Size - bytes.counted + bytes.exclusive,
+			Shared:    bytes.shared,
+		})
+	}
+	sort.Slice(sharings, func(i, j int) bool {
+		if sharings[i].Total() != sharings[j].Total() {
+			return sharings[i].Total() > sharings[j].Total()
+		}
+		return sharings[i].Path < sharings[j].Path
+	})
+	return sharings
+}
+
+// displaySharing lists the directories holding hardlinked files shared with
+// other directories, with the bytes deleting each would really free.
+func displaySharing(stats *Stats, maxCount int, result *strings.Builder) {
+	sharings := dirSharings(stats)
+	if len(sharings) == 0 {
+		return
+	}
+
+	var files int
+	var extra int64
+	for _, link := range stats.HardLinks {
+		files++
+		extra += int64(len(link.Dirs)-1) * link.Size
+	}
+	result.WriteString(headerStyle.Render(tr("Exclusive and Shared Size")))
+	result.WriteString("\n")
+	result.WriteString(fmt.Sprintf(tr("%s hardlinked files, %s counted more than once in the directory sizes\n"),
+		numberStyle.Render(formatCount(files)),
+		numberStyle.Render(formatMB(extra))))
+	result.WriteString(fmt.Sprintf("%11s %11s %11s  %s\n", tr("Total"), tr("Exclusive"), tr("Shared"), tr("Directory")))
+	for _, sharing := range sharings[:min(maxCount, len(sharings))] {
+		result.WriteString(fmt.Sprintf("%s %s %s  %s\n",
+			numberStyle.Render(fmt.Sprintf("%11s", formatMB(sharing.Total()))),
+			goodStyle.Render(fmt.Sprintf("%11s", formatMB(sharing.Exclusive))),
+			warnStyle.Render(fmt.Sprintf("%11s", formatMB(sharing.Shared))),
+			renderPath(sharing.Path)))
+	}
+	result.WriteString("\n")
+}
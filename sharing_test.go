@@ -0,0 +1,24 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestHardLinksJSON(t *testing.T) {
+	stats := newStats()
+	stats.HardLinks[fileID{Dev: 2049, Ino: 131}] = &HardLink{Size: 4096, Links: 2, Dirs: []string{"/a", "/b"}}
+
+	data, err := json.Marshal(stats)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded Stats
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded.HardLinks, stats.HardLinks) {
+		t.Errorf("got %v, want %v", decoded.HardLinks, stats.HardLinks)
+	}
+}
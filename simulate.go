@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// deletionImpact is what deleting the directories marked with x in the
+// --browse directory view would free. Nothing is deleted; the numbers are
+// recomputed from the scanned tree whenever a mark changes.
+type deletionImpact struct {
+	Dirs       int
+	Files      int
+	Bytes      int64
+	Categories map[string]int64
+}
+
+// insideMarked reports whether dir is inside a directory marked for
+// deletion.
+func insideMarked(marked map[string]bool, dir string) bool {
+	for p := filepath.Dir(dir); ; p = filepath.Dir(p) {
+		if marked[p] {
+			return true
+		}
+		if p == filepath.Dir(p) {
+			return false
+		}
+	}
+}
+
+// markedRoots returns the marked directories not inside another marked one.
+func markedRoots(marked map[string]bool) []string {
+	var roots []string
+	for dir := range marked {
+		if !insideMarked(marked, dir) {
+			roots = append(roots, dir)
+		}
+	}
+	sort.Strings(roots)
+	return roots
+}
+
+// simulateDeletion totals the files and bytes below the marked directories,
+// and their bytes per category from the bytes per extension of each
+// directory.
+func simulateDeletion(stats *Stats, nodes map[string]*dirNode, marked map[string]bool) deletionImpact {
+	impact := deletionImpact{Categories: make(map[string]int64)}
+	roots := markedRoots(marked)
+	for _, dir := range roots {
+		if node := nodes[dir]; node != nil {
+			impact.Dirs++
+			impact.Files += node.Recursive.Files
+			impact.Bytes += node.Recursive.Size
+		}
+	}
+	for dir, types := range stats.DirTypes {
+		for _, root := range roots {
+			if !isBelow(dir, root) {
+				continue
+			}
+			for ext, bytes := range types {
+				category, ok := categoryLabels[fileTypeCategoryMap[ext]]
+				if !ok {
+					category = "Other"
+				}
+				impact.Categories[category] += bytes
+			}
+			break
+		}
+	}
+	return impact
+}
+
+// deletedBelow returns the files and bytes of the marked directories inside
+// dir's tree, to be taken off its recursive totals.
+func deletedBelow(nodes map[string]*dirNode, marked map[string]bool, dir string) DirTotals {
+	var totals DirTotals
+	for _, root := range markedRoots(marked) {
+		if node := nodes[root]; node != nil && isBelow(root, dir) {
+			totals.Files += node.Recursive.Files
+			totals.Size += node.Recursive.Size
+		}
+	}
+	return totals
+}
+
+// remainingTotals returns the totals of node shown by the directory view,
+// less what the simulated deletion removes.
+func (m model) remainingTotals(node *dirNode) DirTotals {
+	if m.deleted[node.Path] || insideMarked(m.deleted, node.Path) {
+		return DirTotals{}
+	}
+	totals := node.Totals(m.recursive)
+	if m.recursive {
+		gone := deletedBelow(m.dirs, m.deleted, node.Path)
+		totals.Files -= gone.Files
+		totals.Size -= gone.Size
+	}
+	return totals
+}
+
+// simulationView shows the impact of the marked deletions on the scanned
+// totals, the categories and the free space of the volume.
+func (m model) simulationView() string {
+	if len(m.deleted) == 0 {
+		return ""
+	}
+	impact := simulateDeletion(m.stats, m.dirs, m.deleted)
+
+	var result strings.Builder
+	result.WriteString(headerStyle.Render(tr("Simulated Deletion (nothing is deleted)")))
+	result.WriteString("\n")
+	result.WriteString(fmt.Sprintf(tr("%s directories, %s files, %s\n"),
+		numberStyle.Render(formatCount(impact.Dirs)),
+		numberStyle.Render(formatCount(impact.Files)),
+		badStyle.Render(formatMB(impact.Bytes))))
+	result.WriteString(fmt.Sprintf(tr("Scanned: %s -> %s\n"),
+		numberStyle.Render(formatMB(m.stats.TotalSize)),
+		goodStyle.Render(formatMB(m.stats.TotalSize-impact.Bytes))))
+	if volume := m.stats.Volume; volume.Capacity > 0 {
+		free := min(volume.Free+impact.Bytes, volume.Capacity)
+		result.WriteString(fmt.Sprintf(tr("Volume: %s free -> %s free (%s)\n"),
+			numberStyle.Render(formatMB(volume.Free)),
+			goodStyle.Render(formatMB(free)),
+			percentStyle.Render(freePercent(free, volume))))
+	}
+
+	categories := make([]string, 0, len(impact.Categories))
+	for category := range impact.Categories {
+		categories = append(categories, category)
+	}
+	sort.Slice(categories, func(i, j int) bool {
+		a, b := impact.Categories[categories[i]], impact.Categories[categories[j]]
+		if a != b {
+			return a > b
+		}
+		return categories[i] < categories[j]
+	})
+	for _, category := range categories {
+		result.WriteString(fmt.Sprintf("  %s %s\n",
+			getFileTypeStyle(strings.ToLower(category)).Render(fmt.Sprintf("%-12s", tr(category))),
+			numberStyle.Render(fmt.Sprintf("%11s", "-"+formatMB(impact.Categories[category])))))
+	}
+	result.WriteString("\n")
+	return result.String()
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"io/fs"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// systemNames are the files and directories operating systems and file
+// systems keep for themselves: Finder and Explorer metadata, recycle bins,
+// indexes and fsck leftovers. Matched case-insensitively.
+var systemNames = map[string]bool{
+	".ds_store":                 true,
+	".appledouble":              true,
+	".spotlight-v100":           true,
+	".fseventsd":                true,
+	".trashes":                  true,
+	".temporaryitems":           true,
+	".documentrevisions-v100":   true,
+	"thumbs.db":                 true,
+	"ehthumbs.db":               true,
+	"desktop.ini":               true,
+	"$recycle.bin":              true,
+	"system volume information": true,
+	"lost+found":                true,
+}
+
+// pseudoPaths and the mount points of pseudoFSTypes hold kernel and device
+// state rather than files, so they are left out of scans of the trees
+// containing them, like a scan of /. skipMounts adds the mounts of the
+// [skip] config section; --scan-pseudo includes them all.
+var (
+	pseudoPaths   = []string{"/proc", "/sys", "/dev", "/run"}
+	pseudoFSTypes = map[string]bool{
+		"proc": true, "sysfs": true, "devtmpfs": true, "devpts": true, "devfs": true,
+		"cgroup": true, "cgroup2": true, "securityfs": true, "selinuxfs": true,
+		"debugfs": true, "tracefs": true, "pstore": true, "bpf": true,
+		"configfs": true, "fusectl": true, "mqueue": true, "hugetlbfs": true,
+		"binfmt_misc": true, "efivarfs": true, "rpc_pipefs": true, "nsfs": true,
+		"autofs": true,
+	}
+	skipMounts []string
+	scanPseudo bool
+
+	// configExcludes are the exclude patterns of the [skip] section, used
+	// before those of --exclude.
+	configExcludes []string
+)
+
+// mountSkips returns the pseudo file systems and configured mounts below
+// root, as paths the walk of root reaches them by, for Config.SkipMounts.
+func mountSkips(root string) map[string]bool {
+	if scanPseudo || root == "" {
+		return nil
+	}
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return nil
+	}
+	skips := make(map[string]bool)
+	for _, mount := range slices.Concat(pseudoPaths, mountPoints(pseudoFSTypes), skipMounts) {
+		rel, err := filepath.Rel(absRoot, filepath.Clean(mount))
+		if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		skips[filepath.Join(root, rel)] = true
+	}
+	return skips
+}
+
+func isHiddenName(name string) bool {
+	return strings.HasPrefix(name, ".") && name != "." && name != ".."
+}
+
+func isSystemName(name string) bool {
+	return systemNames[strings.ToLower(name)] || strings.HasPrefix(name, "._")
+}
+
+// skipped tells whether --skip-hidden, --skip-system, --exclude,
+// --respect-gitignore or the skipped mounts leave the file or directory at
+// path out of the scan. Skipped directories are pruned with everything below
+// them. The scanned root itself is never skipped.
+func (c Config) skipped(path string) bool {
+	if path == c.Path {
+		return false
+	}
+	if c.SkipMounts[path] || c.excluded(path) {
+		return true
+	}
+	if !c.SkipHidden && !c.SkipSystem {
+		return false
+	}
+	name := filepath.Base(path)
+	return c.SkipHidden && isHiddenName(name) || c.SkipSystem && isSystemName(name)
+}
+
+// skippedListed is skipped for a path from --files-from, which is also
+// skipped if any directory it is in would have been pruned.
+func (c Config) skippedListed(path string) bool {
+	if c.excludedListed(path) {
+		return true
+	}
+	if !c.SkipHidden && !c.SkipSystem {
+		return false
+	}
+	for _, name := range strings.FieldsFunc(filepath.ToSlash(filepath.Clean(path)), func(r rune) bool { return r == '/' }) {
+		if c.SkipHidden && isHiddenName(name) || c.SkipSystem && isSystemName(name) {
+			return true
+		}
+	}
+	return false
+}
+
+func skipLabel(skip bool) string {
+	if skip {
+		return warnStyle.Render(tr("skipped"))
+	}
+	return goodStyle.Render(tr("included"))
+}
+
+// skipEntry is what a WalkDir callback returns for a skipped entry: a
+// skipped directory is not descended into.
+func skipEntry(d fs.DirEntry) error {
+	if d.IsDir() {
+		return filepath.SkipDir
+	}
+	return nil
+}
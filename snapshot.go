@@ -0,0 +1,452 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/gob"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Snapshot is a saved scan: the listing of every directory below Root, keyed
+// by path, as produced by walkListings. Label and Note are set by the user to
+// tell snapshots apart later. A compact snapshot has no listings but Seen, a
+// bloom filter of its files, and only tells which files are new. Hashed is
+// set when the files carry their content hashes.
+type Snapshot struct {
+	Root    string
+	Created time.Time
+	Label   string
+	Note    string
+	Dirs    map[string]*dirCacheEntry
+	Seen    *bloomFilter
+	Hashed  bool
+}
+
+// compact reports whether the snapshot only has the bloom filter.
+func (s *Snapshot) compact() bool {
+	return s.Seen != nil
+}
+
+// requireListings rejects compact snapshots where the listings are needed.
+func requireListings(path string, snap *Snapshot) error {
+	if snap.compact() {
+		return fmt.Errorf("%s is a compact snapshot, which only tells new files apart; this needs a full one", path)
+	}
+	return nil
+}
+
+// takeSnapshot walks root and records its directory listings. Directories
+// whose mtime and entry count match the baseline are taken over from it
+// without re-reading their files.
+func takeSnapshot(ctx context.Context, root string, baseline *Snapshot) (*Snapshot, error) {
+	root = filepath.Clean(root)
+
+	old := newScanCache(root)
+	if baseline != nil {
+		old.Dirs = baseline.Dirs
+	}
+	fresh := newScanCache(root)
+
+	err := walkListings(ctx, root, old, fresh, nil, func(scanItem) error { return nil })
+	if err != nil {
+		return nil, err
+	}
+	return &Snapshot{Root: root, Created: time.Now(), Dirs: fresh.Dirs}, nil
+}
+
+// openSnapshot loads a saved snapshot, or takes a new one if path is a
+// directory.
+func openSnapshot(path string, baseline *Snapshot) (*Snapshot, error) {
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		return takeSnapshot(context.Background(), path, baseline)
+	}
+	return loadSnapshot(path)
+}
+
+// snapshotFormat is the newest version of the snapshot file format. It
+// follows a header line of snapshotMagic at the start of the gzipped data;
+// snapshots written before the header was introduced are format 0. Format 2
+// added compact snapshots.
+const (
+	snapshotMagic  = "madaa-snapshot"
+	snapshotFormat = 2
+)
+
+// format is the format the snapshot is written in, the oldest that holds
+// it: older versions of madaa keep reading full snapshots, and reject
+// compact ones instead of misreading them as empty.
+func (s *Snapshot) format() int {
+	if s.compact() {
+		return 2
+	}
+	return 1
+}
+
+// snapshotReaders decode each snapshot format this version still reads into
+// the current Snapshot. A reader is kept for every older format, so saved
+// snapshots and history survive upgrades; changing Snapshot in a way gob
+// can't bridge means a new format and a reader converting the old one.
+var snapshotReaders = map[int]func(r io.Reader) (*Snapshot, error){
+	0: decodeSnapshot,
+	1: decodeSnapshot,
+	2: decodeSnapshot,
+}
+
+func decodeSnapshot(r io.Reader) (*Snapshot, error) {
+	var snap Snapshot
+	if err := gob.NewDecoder(r).Decode(&snap); err != nil {
+		return nil, err
+	}
+	return &snap, nil
+}
+
+// snapshotHeader reads the format from the header line, or returns format 0
+// for a snapshot without one.
+func snapshotHeader(r *bufio.Reader) (int, error) {
+	prefix, err := r.Peek(len(snapshotMagic) + 1)
+	if err != nil || !bytes.Equal(prefix, []byte(snapshotMagic+" ")) {
+		return 0, nil
+	}
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, snapshotMagic)))
+}
+
+func loadSnapshot(path string) (*Snapshot, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s is not a madaa snapshot: %w", path, err)
+	}
+	defer zr.Close()
+
+	br := bufio.NewReader(zr)
+	format, err := snapshotHeader(br)
+	if err != nil {
+		return nil, fmt.Errorf("%s is not a madaa snapshot: %w", path, err)
+	}
+	read, ok := snapshotReaders[format]
+	if !ok {
+		return nil, fmt.Errorf("%s is a snapshot of format %d, this version of madaa reads up to format %d", path, format, snapshotFormat)
+	}
+	snap, err := read(br)
+	if err != nil {
+		return nil, fmt.Errorf("%s is not a madaa snapshot: %w", path, err)
+	}
+	if snap.Dirs == nil {
+		snap.Dirs = make(map[string]*dirCacheEntry)
+	}
+	return snap, nil
+}
+
+func saveSnapshot(path string, snap *Snapshot) error {
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+
+	zw := gzip.NewWriter(f)
+	_, err = fmt.Fprintf(zw, "%s %d\n", snapshotMagic, snap.format())
+	if err == nil {
+		err = gob.NewEncoder(zw).Encode(snap)
+	}
+	if err == nil {
+		err = zw.Close()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// runRescan implements "madaa rescan --baseline FILE <path>": it rescans path
+// reusing unchanged directories from the baseline snapshot, writes the
+// updated snapshot and prints what changed.
+func runRescan(args []string) {
+	flags := flag.NewFlagSet("rescan", flag.ExitOnError)
+	baselinePath := flags.String("baseline", "", "Snapshot of a previous scan (created if it doesn't exist)")
+	outputPath := flags.String("output", "", "Where to write the updated snapshot (default: replace the baseline)")
+	count := flags.Int("count", 3, "Number of top changes to show")
+	ignoreExpr := flags.String("ignore", "", "Leave out changes to files matching the expression, e.g. 'path ~ \"**/cache/**\" || ext=log'")
+	minChange := flags.String("min-change", "0", "Leave out files whose size changed by less than this, e.g. 1M")
+	minChanges := flags.Int("min-changes", 0, "Only report if at least this many files changed")
+	listPrefix := flags.String("lists", "", "Write rsync --files-from lists of the changes to PREFIX-copy.txt, PREFIX-delete.txt and PREFIX-changed.txt")
+	null := flags.Bool("null", false, "Separate the --lists entries with NUL instead of newlines (for rsync --from0)")
+	hash := flags.Bool("hash", false, "Record the content hash of every file, so files moved or renamed since a hashed baseline show as moves instead of removed and added (kept on while the baseline is hashed)")
+	compact := flags.Bool("compact", false, "Store only a bloom filter of the files in the new snapshot, against which the next rescan reports just the new files")
+	label := flags.String("label", "", "Label stored with the new snapshot, e.g. pre-migration")
+	note := flags.String("note", "", "Free text note stored with the new snapshot")
+	enableRedaction := redactFlags(flags)
+	reportFlags(flags)
+	selectLanguage := localeFlags(flags)
+	flags.Parse(args)
+	enableRedaction()
+	selectLanguage()
+
+	if *baselinePath == "" || flags.NArg() < 1 {
+		fmt.Println("Usage: madaa rescan --baseline FILE [--output FILE] [--count N] [--ignore EXPR] [--min-change SIZE] [--min-changes N] [--lists PREFIX [--null]] [--hash] [--compact] [--label NAME] [--note TEXT] <path>")
+		os.Exit(1)
+	}
+	if *outputPath == "" {
+		*outputPath = *baselinePath
+	}
+	if err := loadConfig(); err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+
+	var opts DiffOptions
+	var err error
+	if opts.MinChange, err = parseSize(*minChange); err != nil {
+		fmt.Printf("Error parsing --min-change: %v\n", err)
+		os.Exit(1)
+	}
+	if opts.Ignore, err = diffIgnoreFilter(*ignoreExpr); err != nil {
+		fmt.Printf("Error parsing ignore rules: %v\n", err)
+		os.Exit(1)
+	}
+
+	baseline, err := loadSnapshot(*baselinePath)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		fmt.Printf("Error loading baseline: %v\n", err)
+		os.Exit(1)
+	}
+
+	snap, err := takeSnapshot(context.Background(), flags.Arg(0), baseline)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	if *hash || baseline != nil && baseline.Hashed {
+		if err := hashSnapshot(context.Background(), snap, baseline); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	snap.Label = *label
+	snap.Note = *note
+	saved := snap
+	if *compact {
+		saved = compactSnapshot(snap)
+	}
+	if err := saveSnapshot(*outputPath, saved); err != nil {
+		fmt.Printf("Error writing snapshot: %v\n", err)
+		os.Exit(1)
+	}
+
+	if baseline == nil {
+		fmt.Printf(tr("No baseline found, wrote initial snapshot of %s to %s\n"), snap.Root, *outputPath)
+		return
+	}
+	diff := diffSnapshots(baseline, snap, opts)
+	if *listPrefix != "" {
+		sep := byte('\n')
+		if *null {
+			sep = 0
+		}
+		if err := writeFileLists(diff, *listPrefix, sep); err != nil {
+			fmt.Printf("Error writing file lists: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if changed := len(diff.Added) + len(diff.Removed) + len(diff.Changed) + len(diff.Moved); changed < *minChanges {
+		fmt.Printf(tr("No material changes in %s (%s files changed, threshold %s)\n"), snap.Root, formatCount(changed), formatCount(*minChanges))
+		return
+	}
+	fmt.Print(fitPaths(displayDiff(diff, *count), pathWidth))
+}
+
+// runSnapshot implements "madaa snapshot <path>": it scans path and saves
+// the listings as a snapshot, to compare against later with madaa diff.
+func runSnapshot(args []string) {
+	flags := flag.NewFlagSet("snapshot", flag.ExitOnError)
+	outputPath := flags.String("output", "", "Where to write the snapshot (default: NAME-YYYYMMDD-HHMMSS.madaa after the scanned directory)")
+	hash := flags.Bool("hash", false, "Record the content hash of every file, so madaa diff can tell moved and renamed files")
+	label := flags.String("label", "", "Label stored with the snapshot, e.g. pre-migration")
+	note := flags.String("note", "", "Free text note stored with the snapshot")
+	selectLanguage := localeFlags(flags)
+	flags.Parse(args)
+	selectLanguage()
+
+	if flags.NArg() != 1 {
+		fmt.Println("Usage: madaa snapshot [--output FILE] [--hash] [--label NAME] [--note TEXT] <path>")
+		os.Exit(1)
+	}
+	snap, err := takeSnapshot(context.Background(), flags.Arg(0), nil)
+	if err == nil && *hash {
+		err = hashSnapshot(context.Background(), snap, nil)
+	}
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	snap.Label = *label
+	snap.Note = *note
+	if *outputPath == "" {
+		name := filepath.Base(snap.Root)
+		if name == string(filepath.Separator) || name == "." {
+			name = "root"
+		}
+		*outputPath = fmt.Sprintf("%s-%s.madaa", name, snap.Created.Format("20060102-150405"))
+	}
+	if err := saveSnapshot(*outputPath, snap); err != nil {
+		fmt.Printf("Error writing snapshot: %v\n", err)
+		os.Exit(1)
+	}
+
+	var files int
+	var size int64
+	for _, listing := range snap.Dirs {
+		files += len(listing.Files)
+		for _, f := range listing.Files {
+			size += f.Size
+		}
+	}
+	fmt.Printf(tr("Wrote snapshot of %s (%s files, %s) to %s\n"), snap.Root, formatCount(files), formatMB(size), *outputPath)
+}
+
+// runDiff implements "madaa diff <old> <new|path>": the change report of
+// rescan between two saved snapshots, or between a snapshot and the tree as
+// it is now.
+func runDiff(args []string) {
+	flags := flag.NewFlagSet("diff", flag.ExitOnError)
+	count := flags.Int("count", 3, "Number of top changes to show")
+	ignoreExpr := flags.String("ignore", "", "Leave out changes to files matching the expression, e.g. 'path ~ \"**/cache/**\" || ext=log'")
+	minChange := flags.String("min-change", "0", "Leave out files whose size changed by less than this, e.g. 1M")
+	enableRedaction := redactFlags(flags)
+	reportFlags(flags)
+	selectLanguage := localeFlags(flags)
+	flags.Parse(args)
+	enableRedaction()
+	selectLanguage()
+
+	if flags.NArg() != 2 {
+		fmt.Println("Usage: madaa diff [--count N] [--ignore EXPR] [--min-change SIZE] <old snapshot> <new snapshot|path>")
+		os.Exit(1)
+	}
+	if err := loadConfig(); err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+
+	var opts DiffOptions
+	var err error
+	if opts.MinChange, err = parseSize(*minChange); err != nil {
+		fmt.Printf("Error parsing --min-change: %v\n", err)
+		os.Exit(1)
+	}
+	if opts.Ignore, err = diffIgnoreFilter(*ignoreExpr); err != nil {
+		fmt.Printf("Error parsing ignore rules: %v\n", err)
+		os.Exit(1)
+	}
+
+	old, err := loadSnapshot(flags.Arg(0))
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	// A directory is scanned reusing the unchanged listings of the old one
+	baseline := old
+	if old.compact() {
+		baseline = nil
+	}
+	cur, err := openSnapshot(flags.Arg(1), baseline)
+	if err == nil {
+		err = requireListings(flags.Arg(1), cur)
+	}
+	if info, statErr := os.Stat(flags.Arg(1)); err == nil && statErr == nil && info.IsDir() && old.Hashed {
+		err = hashSnapshot(context.Background(), cur, old)
+	}
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Print(fitPaths(displayDiff(diffSnapshots(old, cur, opts), *count), pathWidth))
+}
+
+// runHistory implements "madaa history <snapshot>...": it lists the given
+// snapshots oldest first with their labels and notes.
+func runHistory(args []string) {
+	if len(args) > 0 && args[0] == "growth" {
+		runHistoryGrowth(args[1:])
+		return
+	}
+
+	flags := flag.NewFlagSet("history", flag.ExitOnError)
+	selectLanguage := localeFlags(flags)
+	flags.Parse(args)
+	selectLanguage()
+
+	if flags.NArg() < 1 {
+		fmt.Println("Usage: madaa history <snapshot> [<snapshot>...]")
+		fmt.Println("       madaa history growth [--since AGE] [--count N] <path> <snapshot> [<snapshot>...]")
+		os.Exit(1)
+	}
+
+	type entry struct {
+		path string
+		snap *Snapshot
+	}
+	var entries []entry
+	for _, path := range flags.Args() {
+		snap, err := loadSnapshot(path)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		entries = append(entries, entry{path, snap})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].snap.Created.Before(entries[j].snap.Created) })
+
+	var result strings.Builder
+	result.WriteString(headerStyle.Render(tr("Snapshots")))
+	result.WriteString("\n")
+	for _, e := range entries {
+		var files int
+		var size int64
+		if e.snap.compact() {
+			files, size = e.snap.Seen.Files, e.snap.Seen.Bytes
+		}
+		for _, listing := range e.snap.Dirs {
+			files += len(listing.Files)
+			for _, f := range listing.Files {
+				size += f.Size
+			}
+		}
+		result.WriteString(fmt.Sprintf(tr("%s %-16s %s files %s  %s %s\n"),
+			goodStyle.Render(formatDateTime(e.snap.Created)),
+			warnStyle.Render(e.snap.Label),
+			numberStyle.Render(fmt.Sprintf("%8s", formatCount(files))),
+			numberStyle.Render(fmt.Sprintf("%12s", formatMB(size))),
+			pathStyle.Render(e.snap.Root),
+			pathStyle.Render("("+e.path+")")))
+		if e.snap.Note != "" {
+			result.WriteString(fmt.Sprintf("  %s\n", e.snap.Note))
+		}
+	}
+	fmt.Print(fitPaths(result.String(), pathWidth))
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSnapshotFormat(t *testing.T) {
+	dir := t.TempDir()
+	full := &Snapshot{Root: "/data", Created: time.Now(), Dirs: map[string]*dirCacheEntry{}}
+	compact := &Snapshot{Root: "/data", Created: time.Now(), Seen: newBloomFilter(10)}
+
+	for _, tt := range []struct {
+		name   string
+		snap   *Snapshot
+		format int
+	}{
+		{"full.madaa", full, 1},
+		{"compact.madaa", compact, 2},
+	} {
+		path := filepath.Join(dir, tt.name)
+		if err := saveSnapshot(path, tt.snap); err != nil {
+			t.Fatal(err)
+		}
+
+		f, err := os.Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		zr, err := gzip.NewReader(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		format, err := snapshotHeader(bufio.NewReader(zr))
+		f.Close()
+		if err != nil || format != tt.format {
+			t.Errorf("%s: format %d, want %d: %v", tt.name, format, tt.format, err)
+		}
+
+		snap, err := loadSnapshot(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if snap.compact() != tt.snap.compact() {
+			t.Errorf("%s: compact %v after loading", tt.name, snap.compact())
+		}
+	}
+}
@@ -0,0 +1,55 @@
+//go:build darwin
+
+package main
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+func statAccessTime(info os.FileInfo) (time.Time, bool) {
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		return time.Unix(stat.Atimespec.Sec, stat.Atimespec.Nsec), true
+	}
+	return time.Time{}, false
+}
+
+func statOwnerIDs(info os.FileInfo) (uid, gid uint32, ok bool) {
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		return stat.Uid, stat.Gid, true
+	}
+	return 0, 0, false
+}
+
+func statAllocatedSize(info os.FileInfo) (int64, bool) {
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		return stat.Blocks * 512, true
+	}
+	return 0, false
+}
+
+// changeTime returns the inode change time, which renames and replacements
+// update even when they keep the mtime.
+func changeTime(info os.FileInfo) (time.Time, bool) {
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		return time.Unix(stat.Ctimespec.Sec, stat.Ctimespec.Nsec), true
+	}
+	return time.Time{}, false
+}
+
+// birthTime returns when the file was created.
+func birthTime(info os.FileInfo) (time.Time, bool) {
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		return time.Unix(stat.Birthtimespec.Sec, stat.Birthtimespec.Nsec), true
+	}
+	return time.Time{}, false
+}
+
+// fileIdentity returns the device and inode of the file and its link count.
+func fileIdentity(info os.FileInfo) (id fileID, links uint64, ok bool) {
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		return fileID{uint64(stat.Dev), stat.Ino}, uint64(stat.Nlink), true
+	}
+	return fileID{}, 0, false
+}
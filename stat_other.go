@@ -0,0 +1,35 @@
+//go:build !linux && !darwin && !windows
+
+package main
+
+import (
+	"os"
+	"time"
+)
+
+// The file metadata beyond os.FileInfo is not supported here; the analyses
+// using it fall back to the mtime or leave it out.
+
+func statAccessTime(info os.FileInfo) (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func statOwnerIDs(info os.FileInfo) (uid, gid uint32, ok bool) {
+	return 0, 0, false
+}
+
+func statAllocatedSize(info os.FileInfo) (int64, bool) {
+	return 0, false
+}
+
+func changeTime(info os.FileInfo) (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func birthTime(info os.FileInfo) (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func fileIdentity(info os.FileInfo) (id fileID, links uint64, ok bool) {
+	return fileID{}, 0, false
+}
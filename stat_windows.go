@@ -0,0 +1,50 @@
+//go:build windows
+
+package main
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// statAccessTime returns the last access time NTFS keeps. Windows updates
+// it lazily, within an hour, and not at all when last access updates are
+// disabled, as on many volumes; such files then look unused since creation.
+func statAccessTime(info os.FileInfo) (time.Time, bool) {
+	if data, ok := info.Sys().(*syscall.Win32FileAttributeData); ok {
+		return time.Unix(0, data.LastAccessTime.Nanoseconds()), true
+	}
+	return time.Time{}, false
+}
+
+// statOwnerIDs is not supported: files are owned by SIDs, not numeric ids.
+func statOwnerIDs(info os.FileInfo) (uid, gid uint32, ok bool) {
+	return 0, 0, false
+}
+
+// statAllocatedSize is not supported; sparse and compressed files count
+// with their size.
+func statAllocatedSize(info os.FileInfo) (int64, bool) {
+	return 0, false
+}
+
+// changeTime is not supported: the change time isn't part of the attribute
+// data os.Lstat returns.
+func changeTime(info os.FileInfo) (time.Time, bool) {
+	return time.Time{}, false
+}
+
+// birthTime returns when the file was created.
+func birthTime(info os.FileInfo) (time.Time, bool) {
+	if data, ok := info.Sys().(*syscall.Win32FileAttributeData); ok {
+		return time.Unix(0, data.CreationTime.Nanoseconds()), true
+	}
+	return time.Time{}, false
+}
+
+// fileIdentity is not supported: the file index takes opening the file, so
+// hardlinks aren't recognized.
+func fileIdentity(info os.FileInfo) (id fileID, links uint64, ok bool) {
+	return fileID{}, 0, false
+}
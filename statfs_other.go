@@ -0,0 +1,9 @@
+//go:build !linux && !darwin
+
+package main
+
+// volumeSpace is not supported here; the cleanup impact is reported without
+// the projected free space.
+func volumeSpace(path string) (Volume, bool) {
+	return Volume{}, false
+}
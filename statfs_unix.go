@@ -0,0 +1,19 @@
+//go:build linux || darwin
+
+package main
+
+import "syscall"
+
+// volumeSpace returns the capacity, the space available to unprivileged
+// users and the block size of the file system holding path.
+func volumeSpace(path string) (Volume, bool) {
+	var fs syscall.Statfs_t
+	if err := syscall.Statfs(path, &fs); err != nil {
+		return Volume{}, false
+	}
+	return Volume{
+		Capacity:  int64(fs.Blocks) * int64(fs.Bsize),
+		Free:      int64(fs.Bavail) * int64(fs.Bsize),
+		BlockSize: int64(fs.Bsize),
+	}, true
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"fmt"
+)
+
+// summaryLine renders the scan as a single line for shell prompts, MOTD
+// scripts and quick checks. It is left unstyled so it can be embedded
+// anywhere.
+func summaryLine(stats *Stats) string {
+	line := fmt.Sprintf(tr("%s files, %s dirs, %s"),
+		formatCount(stats.TotalFiles), formatCount(stats.TotalDirs), formatMB(stats.TotalSize))
+
+	var largest *FileSize
+	for i, file := range *stats.LargestFiles {
+		if largest == nil || largest.Less(file) {
+			largest = &(*stats.LargestFiles)[i]
+		}
+	}
+	if largest != nil {
+		line += fmt.Sprintf(tr(", largest %s (%s)"),
+			truncateMiddle(displayPath(largest.Path), pathWidth), formatMB(largest.Size))
+	}
+
+	var stale float64
+	if stats.TotalFiles > 0 {
+		stale = float64(stats.StaleFiles) / float64(stats.TotalFiles) * 100
+	}
+	return line + fmt.Sprintf(tr(", %s stale"), formatPercent(stale))
+}
+
+// printSummary scans without the progress view and prints the summary line.
+func printSummary(config Config) error {
+	var stats *Stats
+	var err error
+	if config.FilesFrom != "" {
+		stats, err = analyzeFileList(context.Background(), config, newEventBus())
+	} else {
+		stats, err = analyzeDirectory(context.Background(), config, newEventBus())
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Println(summaryLine(stats))
+	return nil
+}
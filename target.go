@@ -0,0 +1,177 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode/utf16"
+)
+
+// copyTarget is the file system of --target the scanned tree is checked
+// against, as if it were copied there.
+var copyTarget *fsProfile
+
+// Why a file or directory can't be copied to the target, in the order they
+// are checked and shown. Each file is counted with the first reason only.
+const (
+	failSymlink      = "symlink"
+	failTooLarge     = "too large"
+	failInvalidChars = "invalid characters"
+	failReserved     = "reserved name"
+	failTrailing     = "ends in a dot or space"
+	failNameLength   = "name too long"
+	failPathLength   = "path too long"
+)
+
+var targetFailures = []string{
+	failSymlink, failTooLarge, failInvalidChars, failReserved,
+	failTrailing, failNameLength, failPathLength,
+}
+
+var windowsDeviceNames = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true,
+	"COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true,
+	"LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+// length is the length of a name or path as the file system counts it.
+func (p *fsProfile) length(s string) int {
+	if p.Windows {
+		return len(utf16.Encode([]rune(s)))
+	}
+	return len(s)
+}
+
+// failure tells why the file or directory at rel, its path from the top
+// directory on the target, can't be copied there, or returns "" if it can.
+func (p *fsProfile) failure(rel string, info os.FileInfo) string {
+	name := filepath.Base(rel)
+	switch {
+	case p.NoSymlinks && info.Mode()&os.ModeSymlink != 0:
+		return failSymlink
+	case p.MaxFileSize > 0 && info.Mode().IsRegular() && info.Size() > p.MaxFileSize:
+		return failTooLarge
+	case strings.ContainsAny(name, p.InvalidChars):
+		return failInvalidChars
+	case p.Windows && strings.ContainsFunc(name, func(r rune) bool { return r < 0x20 }):
+		return failInvalidChars
+	case p.Windows && windowsDeviceNames[strings.ToUpper(strings.TrimRight(strings.SplitN(name, ".", 2)[0], " "))]:
+		return failReserved
+	case p.Windows && (strings.HasSuffix(name, ".") || strings.HasSuffix(name, " ")):
+		return failTrailing
+	case p.MaxNameLength > 0 && p.length(name) > p.MaxNameLength:
+		return failNameLength
+	case p.MaxPathLength > 0 && p.length(rel) > p.MaxPathLength:
+		return failPathLength
+	}
+	for _, reserved := range p.ReservedNames {
+		if strings.EqualFold(name, reserved) {
+			return failReserved
+		}
+	}
+	return ""
+}
+
+// processTarget checks a file or directory against --target. The scanned
+// directory itself becomes the top directory on the target, so paths are
+// taken from its parent.
+func processTarget(path string, info os.FileInfo, stats *Stats, root string, maxFiles int) {
+	if copyTarget == nil {
+		return
+	}
+	rel, err := filepath.Rel(filepath.Dir(fullPath(root)), fullPath(path))
+	if err != nil {
+		rel = path
+	}
+	reason := copyTarget.failure(rel, info)
+	if reason == "" {
+		return
+	}
+	var size int64
+	if info.Mode().IsRegular() {
+		size = info.Size()
+	}
+
+	stats.mu.Lock()
+	defer stats.mu.Unlock()
+	addLimitFile(stats.TargetFailures, reason, path, size, maxFiles)
+}
+
+// displayTarget reports everything that would fail to copy to --target:
+// the files and directories it can't store, directories with more entries
+// than it allows, and names that collide on a case-insensitive target.
+func displayTarget(stats *Stats, maxCount int, result *strings.Builder) {
+	if copyTarget == nil {
+		return
+	}
+
+	var files int
+	var bytes int64
+	for _, failed := range stats.TargetFailures {
+		files += failed.Files
+		bytes += failed.Bytes
+	}
+	var crowded []string
+	if copyTarget.MaxDirEntries > 0 {
+		for dir, entries := range stats.FilesPerDir {
+			if entries > copyTarget.MaxDirEntries {
+				crowded = append(crowded, dir)
+			}
+		}
+		sort.Slice(crowded, func(i, j int) bool {
+			if stats.FilesPerDir[crowded[i]] != stats.FilesPerDir[crowded[j]] {
+				return stats.FilesPerDir[crowded[i]] > stats.FilesPerDir[crowded[j]]
+			}
+			return crowded[i] < crowded[j]
+		})
+	}
+	var collisions int
+	if copyTarget.CaseInsensitive {
+		for _, names := range stats.CaseCollisions {
+			collisions += len(names)
+		}
+	}
+
+	result.WriteString(headerStyle.Render(fmt.Sprintf(tr("Copying to %s"), copyTarget.Name)))
+	result.WriteString("\n")
+	if files == 0 && len(crowded) == 0 && collisions == 0 {
+		result.WriteString(goodStyle.Render(tr("Everything would copy cleanly")))
+		result.WriteString("\n\n")
+		return
+	}
+	result.WriteString(fmt.Sprintf(tr("Would fail: %s files and directories, %s\n"),
+		badStyle.Render(formatCount(files)),
+		badStyle.Render(formatMB(bytes))))
+
+	for _, reason := range targetFailures {
+		failed := stats.TargetFailures[reason]
+		if failed == nil {
+			continue
+		}
+		result.WriteString(fmt.Sprintf("%s: %s\n",
+			warnStyle.Render(tr(reason)),
+			numberStyle.Render(formatCount(failed.Files))))
+		displayLimitFiles(failed, maxCount, result)
+	}
+	if len(crowded) > 0 {
+		result.WriteString(fmt.Sprintf(tr("%s: %s directories over %s entries\n"),
+			warnStyle.Render(tr("too many entries")),
+			numberStyle.Render(formatCount(len(crowded))),
+			numberStyle.Render(formatCount(copyTarget.MaxDirEntries))))
+		for _, dir := range crowded[:min(maxCount, len(crowded))] {
+			result.WriteString(fmt.Sprintf("  %s %s\n",
+				badStyle.Render(fmt.Sprintf("%11s", formatCount(stats.FilesPerDir[dir]))),
+				renderPath(dir)))
+		}
+	}
+	if collisions > 0 {
+		result.WriteString(fmt.Sprintf(tr("%s: %s names, only one of each would be kept (see Case-Insensitive Name Collisions)\n"),
+			warnStyle.Render(tr("case collisions")),
+			numberStyle.Render(formatCount(collisions))))
+	}
+	result.WriteString("\n")
+}
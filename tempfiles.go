@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// tempAge is how long a temporary or lock file must be untouched before it
+// counts as left over. Set by --temp-days.
+var tempAge = 7 * 24 * time.Hour
+
+// tempPatterns are matched against the file name, in order.
+var tempPatterns = []struct {
+	glob   string
+	reason string
+}{
+	{"*.tmp", "temp file"},
+	{"*.temp", "temp file"},
+	{"~$*", "Office lock file"},
+	{".~lock.*#", "LibreOffice lock file"},
+	{"*.swp", "editor swap file"},
+	{"*.swo", "editor swap file"},
+	{"*.partial", "incomplete download"},
+	{"*.crdownload", "incomplete download"},
+	{"*.part", "incomplete download"},
+	{"core", "core dump"},
+	{"core.[0-9]*", "core dump"},
+}
+
+// TempStats counts left over temporary and lock files by reason.
+type TempStats struct {
+	Files   map[string]int
+	Bytes   map[string]int64
+	Largest *FileSizeHeap
+}
+
+func newTempStats() TempStats {
+	return TempStats{
+		Files:   make(map[string]int),
+		Bytes:   make(map[string]int64),
+		Largest: &FileSizeHeap{},
+	}
+}
+
+// cleanupCandidate reports whether the file is a temporary or lock file old
+// enough to be removed without asking, and why.
+func cleanupCandidate(path string, info os.FileInfo) (string, bool) {
+	if !info.Mode().IsRegular() || time.Since(info.ModTime()) <= tempAge {
+		return "", false
+	}
+	name := filepath.Base(path)
+	for _, p := range tempPatterns {
+		if ok, _ := filepath.Match(p.glob, name); ok {
+			return p.reason, true
+		}
+	}
+	return "", false
+}
+
+func analyzeTempFiles(path string, info os.FileInfo, stats *Stats, maxFiles int) {
+	reason, ok := cleanupCandidate(path, info)
+	if !ok {
+		return
+	}
+	stats.Temp.Files[reason]++
+	stats.Temp.Bytes[reason] += info.Size()
+	pushLargest(stats.Temp.Largest, FileSize{path, info.Size(), reason}, maxFiles)
+}
+
+func mergeTempStats(dst, src *TempStats, maxFiles int) {
+	for reason, count := range src.Files {
+		dst.Files[reason] += count
+		dst.Bytes[reason] += src.Bytes[reason]
+	}
+	mergeLargest(dst.Largest, src.Largest, maxFiles)
+}
+
+// listCleanupCandidates prints every cleanup candidate below the configured
+// path, one per line, for --cleanup-candidates.
+func listCleanupCandidates(config Config, w io.Writer) error {
+	return listMatchingFiles(config, w, func(path string, info os.FileInfo) bool {
+		_, ok := cleanupCandidate(path, info)
+		return ok
+	})
+}
+
+func displayTempFiles(stats *Stats, maxCount int, result *strings.Builder) {
+	temp := &stats.Temp
+	if len(temp.Files) == 0 {
+		return
+	}
+
+	reasons := make([]string, 0, len(temp.Files))
+	var total int64
+	for reason := range temp.Files {
+		reasons = append(reasons, reason)
+		total += temp.Bytes[reason]
+	}
+	sort.Slice(reasons, func(i, j int) bool {
+		if temp.Bytes[reasons[i]] != temp.Bytes[reasons[j]] {
+			return temp.Bytes[reasons[i]] > temp.Bytes[reasons[j]]
+		}
+		return reasons[i] < reasons[j]
+	})
+
+	result.WriteString(headerStyle.Render(fmt.Sprintf(tr("Temporary and Lock Files (older than %d days)"), int(tempAge.Hours()/24))))
+	result.WriteString("\n")
+	result.WriteString(fmt.Sprintf(tr("Reclaimable: %s (list them with --cleanup-candidates)\n"),
+		badStyle.Render(formatMB(total))))
+	for _, reason := range reasons {
+		result.WriteString(fmt.Sprintf(tr("  %-22s %s files, %s\n"),
+			tr(reason),
+			numberStyle.Render(formatCount(temp.Files[reason])),
+			numberStyle.Render(formatMB(temp.Bytes[reason]))))
+	}
+	result.WriteString("\n")
+	displayLargestFiles(temp.Largest, result)
+}
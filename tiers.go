@@ -0,0 +1,306 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/ini.v1"
+)
+
+// accessTier is a storage tier files are classified into by their last use.
+// A file belongs to the first tier whose MaxAge its last use is within; the
+// last tier has none and takes the rest.
+type accessTier struct {
+	Name   string
+	MaxAge time.Duration
+	Label  string
+}
+
+// accessTiers and tierTimes are set in the [tiers] config section, and
+// tierTimes also by --tier-by.
+var (
+	accessTiers = [...]accessTier{
+		{"hot", 7 * 24 * time.Hour, "7d"},
+		{"warm", 30 * 24 * time.Hour, "30d"},
+		{"cold", 365 * 24 * time.Hour, "1y"},
+		{"frozen", 0, ""},
+	}
+	tierTimes = []string{"atime", "mtime"}
+)
+
+// archiveTier is the first of accessTiers a directory tree has to be in
+// entirely for the tiering plan to propose moving it.
+const archiveTier = 2
+
+// tierTimestamps are the timestamps a file's last use can be taken from.
+var tierTimestamps = []string{"atime", "mtime", "ctime"}
+
+// TierStats sums the files of one access tier.
+type TierStats struct {
+	Files int
+	Bytes int64
+}
+
+// parseTierTimes parses a comma separated list of timestamps, such as
+// "atime,mtime".
+func parseTierTimes(value string) ([]string, error) {
+	times := splitList(strings.ToLower(value))
+	if len(times) == 0 {
+		return nil, fmt.Errorf("no timestamps given, want atime, mtime or ctime")
+	}
+	for _, name := range times {
+		if !slices.Contains(tierTimestamps, name) {
+			return nil, fmt.Errorf("unknown timestamp %q, want atime, mtime or ctime", name)
+		}
+	}
+	return times, nil
+}
+
+func loadTiersConfig(section *ini.Section) error {
+	if value := section.Key("use").String(); value != "" {
+		times, err := parseTierTimes(value)
+		if err != nil {
+			return fmt.Errorf("tiers use: %v", err)
+		}
+		tierTimes = times
+	}
+	var previous time.Duration
+	for i := range accessTiers[:len(accessTiers)-1] {
+		tier := &accessTiers[i]
+		if value := section.Key(tier.Name).String(); value != "" {
+			age, err := parseAge(value)
+			if err != nil {
+				return fmt.Errorf("tiers %s: %v", tier.Name, err)
+			}
+			tier.MaxAge, tier.Label = age, value
+		}
+		if tier.MaxAge <= previous {
+			return fmt.Errorf("tiers %s: %s must be longer than the tier before", tier.Name, tier.Label)
+		}
+		previous = tier.MaxAge
+	}
+	return nil
+}
+
+// tierTime is the latest of the tierTimes of a file. Timestamps the platform
+// or the scan cache don't provide are left out, and the mtime stands in if
+// none is left.
+func tierTime(info os.FileInfo) time.Time {
+	var last time.Time
+	for _, name := range tierTimes {
+		var t time.Time
+		switch name {
+		case "atime":
+			t, _ = accessTime(info)
+		case "mtime":
+			t = info.ModTime()
+		case "ctime":
+			t, _ = changeTime(info)
+		}
+		if t.After(last) {
+			last = t
+		}
+	}
+	if last.IsZero() {
+		return info.ModTime()
+	}
+	return last
+}
+
+// fileTier returns the index of the file's tier in accessTiers.
+func fileTier(info os.FileInfo) int {
+	age := time.Since(tierTime(info))
+	for i, tier := range accessTiers {
+		if tier.MaxAge == 0 || age <= tier.MaxAge {
+			return i
+		}
+	}
+	return len(accessTiers) - 1
+}
+
+func analyzeAccessTier(info os.FileInfo, stats *Stats) {
+	name := accessTiers[fileTier(info)].Name
+	tier := stats.Tiers[name]
+	if tier == nil {
+		tier = &TierStats{}
+		stats.Tiers[name] = tier
+	}
+	tier.Files++
+	tier.Bytes += info.Size()
+}
+
+func mergeTiers(dst, src map[string]*TierStats) {
+	for name, tier := range src {
+		if dst[name] == nil {
+			dst[name] = &TierStats{}
+		}
+		dst[name].Files += tier.Files
+		dst[name].Bytes += tier.Bytes
+	}
+}
+
+// displayTiers shows the bytes per access tier, the numbers to decide what
+// to move to cheaper storage by.
+func displayTiers(stats *Stats, maxCount int, result *strings.Builder) {
+	if stats.TotalFiles == 0 {
+		return
+	}
+
+	result.WriteString(headerStyle.Render(tr("Access Tiers")))
+	result.WriteString("\n")
+	result.WriteString(fmt.Sprintf(tr("Last use by: %s\n"), strings.Join(tierTimes, ", ")))
+	for _, tier := range accessTiers {
+		t := stats.Tiers[tier.Name]
+		if t == nil {
+			t = &TierStats{}
+		}
+		var share float64
+		if stats.TotalSize > 0 {
+			share = float64(t.Bytes) / float64(stats.TotalSize) * 100
+		}
+		limit := fmt.Sprintf(tr("used within %s"), tier.Label)
+		if tier.MaxAge == 0 {
+			limit = tr("older")
+		}
+		result.WriteString(fmt.Sprintf("  %-11s %s %s %s %s\n",
+			tr(tier.Name),
+			numberStyle.Render(fmt.Sprintf("%9s", formatCount(t.Files))),
+			numberStyle.Render(fmt.Sprintf("%14s", formatMB(t.Bytes))),
+			percentStyle.Render(fmt.Sprintf("%8s", "("+formatPercent(share)+")")),
+			limit))
+	}
+	result.WriteString("\n")
+}
+
+// TierCandidate is a directory tree of the tiering plan: all its files are
+// in Tier or colder, and the directory above it isn't.
+type TierCandidate struct {
+	Path     string           `json:"path"`
+	Tier     string           `json:"tier"`
+	Files    int              `json:"files"`
+	Bytes    int64            `json:"bytes"`
+	ByTier   map[string]int64 `json:"bytes_by_tier"`
+	LastUsed time.Time        `json:"last_used"`
+}
+
+// tieringPlan returns the outermost directory trees whose files are all in
+// the archive tiers, largest first. Each is proposed for the warmest tier
+// among its files, so nothing is moved to a colder tier than it belongs to.
+func tieringPlan(stats *Stats) []TierCandidate {
+	trees := dirTrees(stats)
+	warmest := func(dir string) int {
+		tree := trees[dir]
+		if tree == nil || tree.Files == 0 {
+			return -1
+		}
+		for i, files := range tree.TierFiles {
+			if files > 0 {
+				return i
+			}
+		}
+		return -1
+	}
+
+	var plan []TierCandidate
+	for dir, tree := range trees {
+		tier := warmest(dir)
+		if tier < archiveTier || warmest(filepath.Dir(dir)) >= archiveTier {
+			continue
+		}
+		candidate := TierCandidate{
+			Path:     displayPath(dir),
+			Tier:     accessTiers[tier].Name,
+			Files:    tree.Files,
+			Bytes:    tree.Size,
+			ByTier:   make(map[string]int64),
+			LastUsed: tree.LastUsed.UTC(),
+		}
+		for i, bytes := range tree.TierBytes {
+			candidate.ByTier[accessTiers[i].Name] = bytes
+		}
+		plan = append(plan, candidate)
+	}
+	sort.Slice(plan, func(i, j int) bool {
+		if plan[i].Bytes != plan[j].Bytes {
+			return plan[i].Bytes > plan[j].Bytes
+		}
+		return plan[i].Path < plan[j].Path
+	})
+	return plan
+}
+
+// writeTieringPlanCSV writes the plan with one column of bytes per tier,
+// for lifecycle and HSM tooling.
+func writeTieringPlanCSV(plan []TierCandidate, w io.Writer) error {
+	cw := csv.NewWriter(w)
+	header := []string{"path", "tier", "files", "bytes"}
+	for _, tier := range accessTiers {
+		header = append(header, tier.Name+"_bytes")
+	}
+	cw.Write(append(header, "last_used"))
+	for _, candidate := range plan {
+		record := []string{
+			candidate.Path,
+			candidate.Tier,
+			strconv.Itoa(candidate.Files),
+			strconv.FormatInt(candidate.Bytes, 10),
+		}
+		for _, tier := range accessTiers {
+			record = append(record, strconv.FormatInt(candidate.ByTier[tier.Name], 10))
+		}
+		cw.Write(append(record, candidate.LastUsed.Format(time.RFC3339)))
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func writeTieringPlanJSON(plan []TierCandidate, w io.Writer) error {
+	if plan == nil {
+		plan = []TierCandidate{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(plan)
+}
+
+// saveTieringPlan writes the plan to path, or to stdout for "-", as CSV or,
+// for format json or a path ending in .json, as JSON.
+func saveTieringPlan(stats *Stats, path, format string) error {
+	if format == "" {
+		format = "csv"
+		if strings.HasSuffix(strings.ToLower(path), ".json") {
+			format = "json"
+		}
+	}
+	write := writeTieringPlanCSV
+	switch format {
+	case "csv":
+	case "json":
+		write = writeTieringPlanJSON
+	default:
+		return fmt.Errorf("unknown format %q, want csv or json", format)
+	}
+
+	plan := tieringPlan(stats)
+	if path == "-" {
+		return write(plan, os.Stdout)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(plan, f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
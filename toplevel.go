@@ -0,0 +1,144 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// topLevelRows is how many children of the root the table lists; the rest
+// are summed up in one line.
+const topLevelRows = 20
+
+// topLevelDir is a row of the top-level directory table: a child of the
+// scanned directory with everything below it, or with Path "" the files
+// directly in the scanned directory.
+type topLevelDir struct {
+	Path      string
+	Files     int
+	Size      int64
+	Stale     int
+	NewestMod time.Time
+	Category  string
+}
+
+// topLevelDirs rolls the per-directory activity and bytes per extension up
+// into the children of the root, largest first. The category of a child is
+// the one holding most of its bytes.
+func topLevelDirs(stats *Stats) []topLevelDir {
+	// topOf returns the child of the root dir is in, or "" for the root
+	topOf := func(dir string) string {
+		for p := dir; ; p = filepath.Dir(p) {
+			depth, ok := stats.DirDepths[p]
+			if !ok {
+				return ""
+			}
+			if depth == 0 {
+				return p
+			}
+		}
+	}
+
+	rows := make(map[string]*topLevelDir)
+	for dir, activity := range stats.DirActivity {
+		top := topOf(dir)
+		row := rows[top]
+		if row == nil {
+			row = &topLevelDir{Path: top}
+			rows[top] = row
+		}
+		row.Files += activity.Files
+		row.Size += activity.Size
+		row.Stale += activity.Stale
+		if activity.NewestMod.After(row.NewestMod) {
+			row.NewestMod = activity.NewestMod
+		}
+	}
+
+	categories := make(map[string]map[string]int64)
+	for dir, types := range stats.DirTypes {
+		top := topOf(dir)
+		if categories[top] == nil {
+			categories[top] = make(map[string]int64)
+		}
+		for ext, size := range types {
+			category, ok := categoryLabels[fileTypeCategoryMap[ext]]
+			if !ok {
+				category = "Other"
+			}
+			categories[top][category] += size
+		}
+	}
+
+	var dirs []topLevelDir
+	for top, row := range rows {
+		var most int64 = -1
+		for category, size := range categories[top] {
+			if size > most || size == most && category < row.Category {
+				row.Category, most = category, size
+			}
+		}
+		dirs = append(dirs, *row)
+	}
+	sort.Slice(dirs, func(i, j int) bool {
+		if dirs[i].Size != dirs[j].Size {
+			return dirs[i].Size > dirs[j].Size
+		}
+		return dirs[i].Path < dirs[j].Path
+	})
+	return dirs
+}
+
+// displayTopLevelDirs compares the children of the scanned directory, the
+// big buckets of the tree.
+func displayTopLevelDirs(stats *Stats, maxCount int, result *strings.Builder) {
+	dirs := topLevelDirs(stats)
+	if len(dirs) == 0 || len(dirs) == 1 && dirs[0].Path == "" {
+		return
+	}
+
+	result.WriteString(headerStyle.Render(tr("Top-Level Directories")))
+	result.WriteString("\n")
+	result.WriteString(fmt.Sprintf("%14s %8s %9s %10s %-9s %7s  %s\n",
+		tr("Size"), tr("Share"), tr("Files"), tr("Newest"), tr("Category"), tr("Stale"), tr("Directory")))
+	share := func(part, whole int64) string {
+		if whole == 0 {
+			return formatPercent(0)
+		}
+		return formatPercent(float64(part) / float64(whole) * 100)
+	}
+
+	for _, d := range dirs[:min(topLevelRows, len(dirs))] {
+		name := renderPath(d.Path)
+		if d.Path == "" {
+			name = tr("(files directly in the root)")
+		}
+		newest := "-"
+		if !d.NewestMod.IsZero() {
+			newest = formatDate(d.NewestMod)
+		}
+		staleStyle := goodStyle
+		if d.Files > 0 && d.Stale*2 > d.Files {
+			staleStyle = warnStyle
+		}
+		result.WriteString(fmt.Sprintf("%s %s %s %s %s %s  %s\n",
+			numberStyle.Render(fmt.Sprintf("%14s", formatMB(d.Size))),
+			percentStyle.Render(fmt.Sprintf("%8s", share(d.Size, stats.TotalSize))),
+			numberStyle.Render(fmt.Sprintf("%9s", formatCount(d.Files))),
+			fmt.Sprintf("%10s", newest),
+			fmt.Sprintf("%-9s", tr(d.Category)),
+			staleStyle.Render(fmt.Sprintf("%7s", share(int64(d.Stale), int64(d.Files)))),
+			name))
+	}
+	if rest := dirs[min(topLevelRows, len(dirs)):]; len(rest) > 0 {
+		var size int64
+		for _, d := range rest {
+			size += d.Size
+		}
+		result.WriteString(fmt.Sprintf(tr("%s more directories holding %s\n"),
+			formatCount(len(rest)), numberStyle.Render(formatMB(size))))
+	}
+	result.WriteString("\n")
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// pathWidth is the width printed reports are fitted to with --width, by
+// shortening their paths in the middle. 0 keeps paths whole. The views of
+// the TUI fit the terminal instead, until p switches to full paths.
+var pathWidth int
+
+// minPathWidth is how short a path gets at most, however little room the
+// rest of the line leaves.
+const minPathWidth = 16
+
+// Paths in report tables are written between these markers by renderPath,
+// so fitPaths can shorten them once the whole line is known.
+const (
+	pathOpen  = "\x02"
+	pathClose = "\x03"
+)
+
+// renderPath writes a path into a report table. The report has to go
+// through fitPaths before it is shown.
+func renderPath(path string) string {
+	return pathOpen + displayPath(path) + pathClose
+}
+
+// truncateMiddle shortens s to width characters by cutting out its middle,
+// keeping more of the end, where the file name is.
+func truncateMiddle(s string, width int) string {
+	runes := []rune(s)
+	if width <= 0 || len(runes) <= width {
+		return s
+	}
+	ellipsis := plainText("…")
+	keep := max(width-len([]rune(ellipsis)), 2)
+	head := keep / 3
+	return string(runes[:head]) + ellipsis + string(runes[len(runes)-(keep-head):])
+}
+
+// fitPaths shortens the paths marked by renderPath so each line of s fits
+// width columns, and styles them. With a width of 0 paths are kept whole.
+func fitPaths(s string, width int) string {
+	if !strings.Contains(s, pathOpen) {
+		return s
+	}
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		parts := strings.Split(line, pathOpen)
+		if len(parts) == 1 {
+			continue
+		}
+		// parts[0] is text, every following part is a path, its closing
+		// marker and the text up to the next path
+		paths := make([]string, len(parts)-1)
+		rest := lipgloss.Width(parts[0])
+		for j, part := range parts[1:] {
+			path, text, _ := strings.Cut(part, pathClose)
+			paths[j] = path
+			rest += lipgloss.Width(text)
+		}
+		var budget int
+		if width > 0 {
+			budget = max((width-rest)/len(paths), minPathWidth)
+		}
+
+		var b strings.Builder
+		b.WriteString(parts[0])
+		for j, part := range parts[1:] {
+			_, text, _ := strings.Cut(part, pathClose)
+			b.WriteString(pathStyle.Render(truncateMiddle(paths[j], budget)))
+			b.WriteString(text)
+		}
+		lines[i] = b.String()
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,274 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// browseTypes keeps the details of every extension during the scan for the
+// drill-down view of --browse. Off by default, since the directories per
+// extension take memory on large trees.
+var browseTypes bool
+
+// typeDetailFiles is the number of largest files kept per extension.
+const typeDetailFiles = 25
+
+// typePageSize is the number of extensions listed at once by --browse.
+const typePageSize = 20
+
+// typeAges are the buckets of the age profile of an extension, by mtime.
+var typeAges = []struct {
+	name   string
+	within time.Duration
+}{
+	{"last 30 days", 30 * 24 * time.Hour},
+	{"last year", 365 * 24 * time.Hour},
+	{"last 3 years", 3 * 365 * 24 * time.Hour},
+	{"older", 0},
+}
+
+// TypeDetail is what the drill-down view shows about one extension.
+type TypeDetail struct {
+	Largest  *FileSizeHeap
+	AgeFiles []int
+	AgeBytes []int64
+	// DirBytes are the bytes of the extension in each directory
+	DirBytes map[string]int64
+}
+
+func newTypeDetail() *TypeDetail {
+	return &TypeDetail{
+		Largest:  &FileSizeHeap{},
+		AgeFiles: make([]int, len(typeAges)),
+		AgeBytes: make([]int64, len(typeAges)),
+		DirBytes: make(map[string]int64),
+	}
+}
+
+func typeAgeBucket(modTime time.Time) int {
+	age := time.Since(modTime)
+	for i, bucket := range typeAges {
+		if bucket.within == 0 || age <= bucket.within {
+			return i
+		}
+	}
+	return len(typeAges) - 1
+}
+
+// analyzeTypeDetail is called by processFile, with the stats locked.
+func analyzeTypeDetail(path, ext string, info os.FileInfo, stats *Stats) {
+	if !browseTypes {
+		return
+	}
+	detail := stats.TypeDetails[ext]
+	if detail == nil {
+		detail = newTypeDetail()
+		stats.TypeDetails[ext] = detail
+	}
+	pushLargest(detail.Largest, FileSize{path, info.Size(), ext}, typeDetailFiles)
+	bucket := typeAgeBucket(info.ModTime())
+	detail.AgeFiles[bucket]++
+	detail.AgeBytes[bucket] += info.Size()
+	detail.DirBytes[filepath.Dir(path)] += info.Size()
+}
+
+func mergeTypeDetails(dst, src map[string]*TypeDetail) {
+	for ext, detail := range src {
+		if dst[ext] == nil {
+			dst[ext] = newTypeDetail()
+		}
+		mergeLargest(dst[ext].Largest, detail.Largest, typeDetailFiles)
+		for i := range min(len(detail.AgeFiles), len(typeAges)) {
+			dst[ext].AgeFiles[i] += detail.AgeFiles[i]
+			dst[ext].AgeBytes[i] += detail.AgeBytes[i]
+		}
+		for dir, bytes := range detail.DirBytes {
+			dst[ext].DirBytes[dir] += bytes
+		}
+	}
+}
+
+// sortedTypes lists the extensions by number of files, as in the File Types
+// section of the report.
+func sortedTypes(stats *Stats) []string {
+	types := make([]string, 0, len(stats.TypeFreq))
+	for ext := range stats.TypeFreq {
+		types = append(types, ext)
+	}
+	sort.Slice(types, func(i, j int) bool {
+		if stats.TypeFreq[types[i]] != stats.TypeFreq[types[j]] {
+			return stats.TypeFreq[types[i]] > stats.TypeFreq[types[j]]
+		}
+		return types[i] < types[j]
+	})
+	return types
+}
+
+func (m model) updateBrowse(key tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch key.String() {
+	case "q", "ctrl+c":
+		m.browsing = false
+		return m, tea.Quit
+	case "up", "k":
+		if m.typeDetail == "" {
+			m.typeCursor = max(m.typeCursor-1, 0)
+		}
+	case "down", "j":
+		if m.typeDetail == "" {
+			m.typeCursor = min(m.typeCursor+1, len(m.types)-1)
+		}
+	case "enter":
+		if m.typeDetail == "" && len(m.types) > 0 {
+			m.typeDetail = m.types[m.typeCursor]
+		}
+	case "esc", "backspace":
+		m.typeDetail = ""
+	case "d":
+		if m.typeDetail == "" {
+			return m.switchTab(tabDirs), nil
+		}
+	case "r":
+		m.recursive = !m.recursive
+	}
+	return m, nil
+}
+
+// browseView shows the tab of the results view: the extensions like the
+// File Types section or the details of the one selected, the directories,
+// or the report sections of a text tab, unscrolled.
+func (m model) browseView() string {
+	switch m.tab {
+	case tabDirs:
+		return m.dirsView()
+	case tabTypes:
+	default:
+		return m.tabTexts[m.tab]
+	}
+	if m.typeDetail != "" {
+		return displayTypeDetail(m.stats, m.typeDetail, m.config.Count, m.config.Path, m.recursive)
+	}
+
+	var result strings.Builder
+	result.WriteString(headerStyle.Render(tr("File Types")))
+	result.WriteString("\n")
+	first := max(0, min(m.typeCursor-typePageSize/2, len(m.types)-typePageSize))
+	for i := first; i < min(first+typePageSize, len(m.types)); i++ {
+		ext := m.types[i]
+		marker := "  "
+		if i == m.typeCursor {
+			marker = "> "
+		}
+		percentage := float64(m.stats.TypeFreq[ext]) / float64(m.stats.TotalFiles) * 100
+		result.WriteString(fmt.Sprintf("%s%s %s %s %s\n",
+			marker,
+			getFileTypeStyle(ext).Render(fmt.Sprintf("%-12s", tr(ext))),
+			numberStyle.Render(fmt.Sprintf("%6s", formatCount(m.stats.TypeFreq[ext]))),
+			percentStyle.Render(fmt.Sprintf("(%6s)", formatPercent(percentage))),
+			numberStyle.Render(fmt.Sprintf("%11s", formatMB(m.stats.TypeSizes[ext])))))
+	}
+	result.WriteString("\n")
+	return result.String()
+}
+
+// browseHelp lists the keys of the view shown, and the outcome of the last
+// copy to the clipboard.
+func (m model) browseHelp() string {
+	help := tr("↑/↓ select  enter details  d directories  p full paths  q quit")
+	switch {
+	case m.tab == tabDirs:
+		help = tr("↑/↓ select  enter open  backspace up  r direct/recursive  x simulate deletion  X undo all  p full paths  q quit")
+	case m.tab != tabTypes:
+		help = m.tabHelp()
+	case m.typeDetail != "":
+		help = tr("r direct/recursive  p full paths  esc back  q quit")
+	}
+	help += "\n" + tr("tab/1-5 switch tabs  c copy view  C copy report")
+	if len(savedViews) > 0 {
+		help += tr("  v next view")
+	}
+	help += "\n"
+	if m.status != "" {
+		help += m.status + "\n"
+	}
+	return help
+}
+
+// displayTypeDetail shows the total, largest files, age profile and main
+// directories of one extension. With recursive, the bytes of a directory
+// include those in its subdirectories below root.
+func displayTypeDetail(stats *Stats, ext string, maxCount int, root string, recursive bool) string {
+	var result strings.Builder
+
+	result.WriteString(titleStyle.Render(fmt.Sprintf(tr("File Type %s"), tr(ext))))
+	result.WriteString("\n\n")
+	var share float64
+	if stats.TotalSize > 0 {
+		share = float64(stats.TypeSizes[ext]) / float64(stats.TotalSize) * 100
+	}
+	result.WriteString(fmt.Sprintf(tr("Files: %s  Size: %s (%s of the total)\n\n"),
+		numberStyle.Render(formatCount(stats.TypeFreq[ext])),
+		numberStyle.Render(formatMB(stats.TypeSizes[ext])),
+		percentStyle.Render(formatPercent(share))))
+
+	detail := stats.TypeDetails[ext]
+	if detail == nil {
+		return result.String()
+	}
+
+	result.WriteString(headerStyle.Render(tr("Largest Files")))
+	result.WriteString("\n")
+	var largest FileSizeHeap
+	for _, file := range *detail.Largest {
+		pushLargest(&largest, file, max(maxCount, 10))
+	}
+	displayLargestFiles(&largest, &result)
+
+	result.WriteString(headerStyle.Render(tr("Age")))
+	result.WriteString("\n")
+	for i, bucket := range typeAges {
+		result.WriteString(fmt.Sprintf(tr("  %-14s %s files %s\n"),
+			tr(bucket.name),
+			numberStyle.Render(fmt.Sprintf("%8s", formatCount(detail.AgeFiles[i]))),
+			numberStyle.Render(fmt.Sprintf("%11s", formatMB(detail.AgeBytes[i])))))
+	}
+	result.WriteString("\n")
+
+	result.WriteString(headerStyle.Render(fmt.Sprintf(tr("Directories (%s)"), dirModeLabel(recursive))))
+	result.WriteString("\n")
+	dirBytes := detail.DirBytes
+	if recursive {
+		dirBytes = make(map[string]int64, len(detail.DirBytes))
+		root = filepath.Clean(root)
+		for dir, bytes := range detail.DirBytes {
+			for p := dir; ; p = filepath.Dir(p) {
+				dirBytes[p] += bytes
+				if p == root || p == filepath.Dir(p) {
+					break
+				}
+			}
+		}
+	}
+	dirs := make([]string, 0, len(dirBytes))
+	for dir := range dirBytes {
+		dirs = append(dirs, dir)
+	}
+	sort.Slice(dirs, func(i, j int) bool {
+		if dirBytes[dirs[i]] != dirBytes[dirs[j]] {
+			return dirBytes[dirs[i]] > dirBytes[dirs[j]]
+		}
+		return dirs[i] < dirs[j]
+	})
+	for _, dir := range dirs[:min(max(maxCount, 10), len(dirs))] {
+		result.WriteString(fmt.Sprintf("  %s %s\n",
+			numberStyle.Render(fmt.Sprintf("%11s", formatMB(dirBytes[dir]))),
+			renderPath(dir)))
+	}
+	result.WriteString("\n")
+	return result.String()
+}
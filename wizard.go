@@ -0,0 +1,371 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"gopkg.in/ini.v1"
+)
+
+// configPaths are where config.ini is looked for, in order: the current
+// directory, then the user's config directory, e.g. ~/.config/madaa.
+func configPaths() []string {
+	paths := []string{"config.ini"}
+	if dir, err := os.UserConfigDir(); err == nil {
+		paths = append(paths, filepath.Join(dir, "madaa", "config.ini"))
+	}
+	return paths
+}
+
+// findConfig returns the first of configPaths that exists, or "" on the
+// first run.
+func findConfig() string {
+	for _, path := range configPaths() {
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return ""
+}
+
+// firstRun creates the config when there is none yet and returns its path.
+// On a terminal the wizard asks for the settings; elsewhere, or if the
+// wizard is cancelled, the defaults are written to the current directory.
+func firstRun() (string, error) {
+	if isTerminal(os.Stdin) && isTerminal(os.Stdout) && !accessible {
+		cfg, err := ini.Load([]byte(defaultConfigContent))
+		if err != nil {
+			return "", err
+		}
+		path, err := runWizard(cfg, 0)
+		if err != nil || path != "" {
+			return path, err
+		}
+		fmt.Println(tr("Using the default settings; run madaa init to change them."))
+	}
+	return "config.ini", createDefaultConfig("config.ini")
+}
+
+// The pages of the wizard, in order.
+const (
+	wizardLocation = iota
+	wizardExcludes
+	wizardPalette
+	wizardTiers
+	wizardCategories
+	wizardConfirm
+)
+
+// wizardModel is the TUI of madaa init: a page per group of settings,
+// prefilled from the config being edited, which is only written on the last
+// page.
+type wizardModel struct {
+	cfg        *ini.File
+	page       int
+	locations  []string
+	location   int
+	excludes   string
+	palettes   []string
+	palette    int
+	tiers      [3]string
+	tier       int
+	categories []string
+	disabled   map[string]bool
+	cursor     int
+	err        string
+	saved      string
+}
+
+// wizardTierKeys are the [tiers] keys the wizard asks for; frozen is the rest.
+var wizardTierKeys = [3]string{"hot", "warm", "cold"}
+
+func newWizardModel(cfg *ini.File, location int) wizardModel {
+	m := wizardModel{
+		cfg:       cfg,
+		locations: configPaths(),
+		location:  location,
+		excludes:  cfg.Section("skip").Key("exclude").String(),
+		palettes:  paletteNames(),
+		disabled:  make(map[string]bool),
+	}
+	m.palette = max(slices.Index(m.palettes, cfg.Section("display").Key("palette").MustString("default")), 0)
+	for i, name := range wizardTierKeys {
+		m.tiers[i] = cfg.Section("tiers").Key(name).String()
+	}
+	for _, key := range cfg.Section("file_types").Keys() {
+		if !slices.Contains(m.categories, key.Value()) {
+			m.categories = append(m.categories, key.Value())
+		}
+	}
+	sort.Strings(m.categories)
+	return m
+}
+
+func (m wizardModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m wizardModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	key, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return m, nil
+	}
+	m.err = ""
+	switch key.String() {
+	case "ctrl+c":
+		return m, tea.Quit
+	case "esc":
+		m.page = max(m.page-1, 0)
+		return m, nil
+	case "enter":
+		if err := m.validate(); err != nil {
+			m.err = err.Error()
+			return m, nil
+		}
+		if m.page < wizardConfirm {
+			m.page++
+			return m, nil
+		}
+		if err := m.save(); err != nil {
+			m.err = err.Error()
+			return m, nil
+		}
+		return m, tea.Quit
+	}
+
+	switch m.page {
+	case wizardLocation:
+		m.location = cycleOption(m.location, len(m.locations), key.String())
+	case wizardPalette:
+		m.palette = cycleOption(m.palette, len(m.palettes), key.String())
+	case wizardExcludes:
+		m.excludes = editText(m.excludes, key)
+	case wizardTiers:
+		switch key.String() {
+		case "up":
+			m.tier = cycleOption(m.tier, len(m.tiers), "up")
+		case "down", "tab":
+			m.tier = cycleOption(m.tier, len(m.tiers), "down")
+		default:
+			m.tiers[m.tier] = editText(m.tiers[m.tier], key)
+		}
+	case wizardCategories:
+		switch key.String() {
+		case " ", "x":
+			category := m.categories[m.cursor]
+			m.disabled[category] = !m.disabled[category]
+		default:
+			m.cursor = cycleOption(m.cursor, len(m.categories), key.String())
+		}
+	}
+	return m, nil
+}
+
+// cycleOption moves a selection among n options with the arrow keys.
+func cycleOption(i, n int, key string) int {
+	switch key {
+	case "up", "k", "left":
+		return (i + n - 1) % n
+	case "down", "j", "right":
+		return (i + 1) % n
+	}
+	return i
+}
+
+// editText applies a key typed into a text field.
+func editText(text string, key tea.KeyMsg) string {
+	switch key.Type {
+	case tea.KeyBackspace:
+		if text != "" {
+			runes := []rune(text)
+			return string(runes[:len(runes)-1])
+		}
+	case tea.KeyRunes, tea.KeySpace:
+		return text + string(key.Runes)
+	}
+	return text
+}
+
+// validate checks the page before moving on from it.
+func (m wizardModel) validate() error {
+	if m.page != wizardTiers {
+		return nil
+	}
+	var previous int64
+	for i, value := range m.tiers {
+		age, err := parseAge(strings.TrimSpace(value))
+		if err != nil {
+			return fmt.Errorf("%s: %v", wizardTierKeys[i], err)
+		}
+		if int64(age) <= previous {
+			return fmt.Errorf(tr("%s must be longer than the tier before"), wizardTierKeys[i])
+		}
+		previous = int64(age)
+	}
+	return nil
+}
+
+// save writes the settings into the config and the config to the chosen
+// location.
+func (m *wizardModel) save() error {
+	m.cfg.Section("skip").Key("exclude").SetValue(strings.Join(splitList(m.excludes), ", "))
+	m.cfg.Section("display").Key("palette").SetValue(m.palettes[m.palette])
+	for i, name := range wizardTierKeys {
+		m.cfg.Section("tiers").Key(name).SetValue(strings.TrimSpace(m.tiers[i]))
+	}
+	types := m.cfg.Section("file_types")
+	for _, key := range types.Keys() {
+		if m.disabled[key.Value()] {
+			types.DeleteKey(key.Name())
+		}
+	}
+
+	path := m.locations[m.location]
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	// Written without aligning the values, as config.ini is by hand
+	ini.PrettyFormat = false
+	if err := m.cfg.SaveTo(path); err != nil {
+		return err
+	}
+	m.saved = path
+	return nil
+}
+
+func (m wizardModel) View() string {
+	var result strings.Builder
+	writeTitle(tr("MADAA - Setup"), &result)
+	result.WriteString(fmt.Sprintf(tr("Step %d of %d\n\n"), m.page+1, wizardConfirm+1))
+
+	option := func(selected bool, text string) {
+		if selected {
+			result.WriteString("> " + goodStyle.Render(text) + "\n")
+		} else {
+			result.WriteString("  " + text + "\n")
+		}
+	}
+	switch m.page {
+	case wizardLocation:
+		result.WriteString(headerStyle.Render(tr("Where to keep the settings")))
+		result.WriteString("\n")
+		result.WriteString(tr("The current directory applies only when madaa runs from here.") + "\n\n")
+		for i, path := range m.locations {
+			option(i == m.location, path)
+		}
+	case wizardExcludes:
+		result.WriteString(headerStyle.Render(tr("Left out of every scan")))
+		result.WriteString("\n")
+		result.WriteString(tr("Comma separated patterns, like --exclude, e.g. node_modules, *.o, build/**") + "\n\n")
+		result.WriteString("> " + m.excludes + "█\n")
+	case wizardPalette:
+		result.WriteString(headerStyle.Render(tr("Colors")))
+		result.WriteString("\n\n")
+		for i, name := range m.palettes {
+			option(i == m.palette, tr(name))
+		}
+	case wizardTiers:
+		result.WriteString(headerStyle.Render(tr("Access tiers")))
+		result.WriteString("\n")
+		result.WriteString(tr("A file is in the first tier it was used within, e.g. 7d, 6mo or 1y; older files are frozen.") + "\n\n")
+		for i, name := range wizardTierKeys {
+			value := m.tiers[i]
+			if i == m.tier {
+				value += "█"
+			}
+			option(i == m.tier, fmt.Sprintf("%-5s %s", tr(name), value))
+		}
+	case wizardCategories:
+		result.WriteString(headerStyle.Render(tr("File type categories")))
+		result.WriteString("\n")
+		result.WriteString(tr("Files of unchecked categories are counted as other files.") + "\n\n")
+		for i, category := range m.categories {
+			check := "[x]"
+			if m.disabled[category] {
+				check = "[ ]"
+			}
+			label := category
+			if name, ok := categoryLabels[category]; ok {
+				label = tr(name)
+			}
+			option(i == m.cursor, check+" "+label)
+		}
+	case wizardConfirm:
+		result.WriteString(headerStyle.Render(tr("Save settings")))
+		result.WriteString("\n")
+		result.WriteString(fmt.Sprintf(tr("Writing %s\n"), pathStyle.Render(m.locations[m.location])))
+		result.WriteString(fmt.Sprintf(tr("Excludes: %s\n"), m.excludes))
+		result.WriteString(fmt.Sprintf(tr("Colors: %s\n"), tr(m.palettes[m.palette])))
+		result.WriteString(fmt.Sprintf(tr("Tiers: hot %s, warm %s, cold %s\n"), m.tiers[0], m.tiers[1], m.tiers[2]))
+	}
+
+	if m.err != "" {
+		result.WriteString("\n" + badStyle.Render(m.err) + "\n")
+	}
+	result.WriteString("\n")
+	switch m.page {
+	case wizardConfirm:
+		result.WriteString(tr("enter save  esc back  ctrl+c cancel"))
+	case wizardCategories:
+		result.WriteString(tr("↑/↓ select  space toggle  enter next  esc back  ctrl+c cancel"))
+	case wizardExcludes:
+		result.WriteString(tr("enter next  esc back  ctrl+c cancel"))
+	default:
+		result.WriteString(tr("↑/↓ select  enter next  esc back  ctrl+c cancel"))
+	}
+	result.WriteString("\n")
+	return result.String()
+}
+
+// runWizard edits cfg in the wizard, starting with the location at index
+// location of configPaths. It returns where the config was written, or ""
+// if the wizard was cancelled.
+func runWizard(cfg *ini.File, location int) (string, error) {
+	final, err := tea.NewProgram(newWizardModel(cfg, location)).Run()
+	if err != nil {
+		return "", err
+	}
+	return final.(wizardModel).saved, nil
+}
+
+// runInit implements "madaa init": the wizard of the first run, editing
+// the config in use or a new one.
+func runInit(args []string) {
+	flags := flag.NewFlagSet("init", flag.ExitOnError)
+	selectLanguage := localeFlags(flags)
+	flags.Parse(args)
+	selectLanguage()
+
+	path := findConfig()
+	source := []byte(defaultConfigContent)
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		source = data
+	}
+	cfg, err := ini.Load(source)
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+
+	saved, err := runWizard(cfg, max(slices.Index(configPaths(), path), 0))
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	if saved == "" {
+		fmt.Println(tr("Cancelled, nothing was written."))
+		return
+	}
+	fmt.Printf(tr("Wrote %s\n"), saved)
+}